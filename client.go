@@ -463,16 +463,35 @@ func (c *Client) url(elem ...string) string {
 
 	// Aliases may contain a trailing slash
 	if strings.HasPrefix(path, "1.0/images/aliases") {
-		return uri
+		return c.projectURL(path, uri)
 	}
 
 	// File paths may contain a trailing slash
 	if strings.Contains(path, "?") {
-		return uri
+		return c.projectURL(path, uri)
 	}
 
 	// Nothing else should contain a trailing slash
-	return strings.TrimSuffix(uri, "/")
+	return c.projectURL(path, strings.TrimSuffix(uri, "/"))
+}
+
+// projectURL restricts the request to the project of the remote, if any.
+func (c *Client) projectURL(path string, uri string) string {
+	if c.Remote == nil || c.Remote.Project == "" || c.Remote.Project == "default" {
+		return uri
+	}
+
+	// Projects themselves aren't namespaced
+	if !strings.HasPrefix(path, version.APIVersion+"/") || strings.HasPrefix(path, version.APIVersion+"/projects") {
+		return uri
+	}
+
+	query := url.Values{"project": []string{c.Remote.Project}}
+	if strings.Contains(uri, "?") {
+		return uri + "&" + query.Encode()
+	}
+
+	return uri + "?" + query.Encode()
 }
 
 func (c *Client) GetServerConfig() (*api.Response, error) {
@@ -1239,6 +1258,30 @@ func (c *Client) CertificateAdd(cert *x509.Certificate, name string) error {
 	return err
 }
 
+// CertificateAddRestricted adds a certificate which is only granted access
+// to the given containers.
+func (c *Client) CertificateAddRestricted(cert *x509.Certificate, name string, containers []string) error {
+	if c.Remote.Public {
+		return fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	b64 := base64.StdEncoding.EncodeToString(cert.Raw)
+	body := shared.Jmap{"type": "client", "certificate": b64, "name": name, "restricted": true, "containers": containers}
+	_, err := c.post("certificates", body, api.SyncResponse)
+	return err
+}
+
+// CertificateAddMetrics adds a certificate which may only read metrics.
+func (c *Client) CertificateAddMetrics(cert *x509.Certificate, name string) error {
+	if c.Remote.Public {
+		return fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	b64 := base64.StdEncoding.EncodeToString(cert.Raw)
+	_, err := c.post("certificates", shared.Jmap{"type": "metrics", "certificate": b64, "name": name}, api.SyncResponse)
+	return err
+}
+
 func (c *Client) CertificateRemove(fingerprint string) error {
 	if c.Remote.Public {
 		return fmt.Errorf("This function isn't supported by public remotes.")
@@ -1284,6 +1327,12 @@ func (c *Client) GetAlias(alias string) string {
 // Init creates a container from either a fingerprint or an alias; you must
 // provide at least one.
 func (c *Client) Init(name string, imgremote string, image string, profiles *[]string, config map[string]string, devices map[string]map[string]string, ephem bool) (*api.Response, error) {
+	return c.InitWithInstanceType(name, imgremote, image, profiles, config, devices, ephem, "")
+}
+
+// InitWithInstanceType is the same as Init but also sets the instance type
+// the limits of the container are based on.
+func (c *Client) InitWithInstanceType(name string, imgremote string, image string, profiles *[]string, config map[string]string, devices map[string]map[string]string, ephem bool, instanceType string) (*api.Response, error) {
 	if c.Remote.Public {
 		return nil, fmt.Errorf("This function isn't supported by public remotes.")
 	}
@@ -1393,6 +1442,10 @@ func (c *Client) Init(name string, imgremote string, image string, profiles *[]s
 		body["ephemeral"] = ephem
 	}
 
+	if instanceType != "" {
+		body["instance_type"] = instanceType
+	}
+
 	var resp *api.Response
 
 	if imgremote != c.Name {
@@ -1680,6 +1733,25 @@ func (c *Client) ServerStatus() (*api.Server, error) {
 	return &ss, nil
 }
 
+// ServerResources returns the resources available on the server.
+func (c *Client) ServerResources() (*api.Resources, error) {
+	if c.Remote.Public {
+		return nil, fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	resp, err := c.get("resources")
+	if err != nil {
+		return nil, err
+	}
+
+	res := api.Resources{}
+	if err := resp.MetadataAsStruct(&res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
 func (c *Client) ContainerInfo(name string) (*api.Container, error) {
 	if c.Remote.Public {
 		return nil, fmt.Errorf("This function isn't supported by public remotes.")
@@ -2008,7 +2080,7 @@ func (c *Client) MigrateFrom(name string, operation string, certificate string,
 	sourceSecrets map[string]string, architecture string, config map[string]string,
 	devices map[string]map[string]string, profiles []string,
 	baseImage string, ephemeral bool, push bool, sourceClient *Client,
-	sourceOperation string) (*api.Response, error) {
+	sourceOperation string, refresh bool) (*api.Response, error) {
 	if c.Remote.Public {
 		return nil, fmt.Errorf("This function isn't supported by public remotes.")
 	}
@@ -2018,6 +2090,10 @@ func (c *Client) MigrateFrom(name string, operation string, certificate string,
 		"base-image": baseImage,
 	}
 
+	if refresh {
+		source["refresh"] = true
+	}
+
 	if push {
 		source["mode"] = "push"
 		source["live"] = false
@@ -2202,6 +2278,21 @@ func (c *Client) Rename(name string, newName string) (*api.Response, error) {
 	return c.post(fmt.Sprintf("containers/%s/snapshots/%s", oldNameParts[0], oldNameParts[1]), body, api.AsyncResponse)
 }
 
+// MoveToPool moves a stopped container and its snapshots to another storage
+// pool of the same server.
+func (c *Client) MoveToPool(name string, pool string) (*api.Response, error) {
+	if c.Remote.Public {
+		return nil, fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	if strings.Contains(name, "/") {
+		return nil, fmt.Errorf("Snapshots can't be moved to another storage pool.")
+	}
+
+	body := shared.Jmap{"pool": pool}
+	return c.post(fmt.Sprintf("containers/%s", name), body, api.AsyncResponse)
+}
+
 /* Wait for an operation */
 func (c *Client) WaitFor(waitURL string) (*api.Operation, error) {
 	if len(waitURL) < 1 {
@@ -2248,6 +2339,102 @@ func (c *Client) WaitForSuccessOp(waitURL string) (*api.Operation, error) {
 	return op, fmt.Errorf(op.Err)
 }
 
+func (c *Client) CreateContainerBackup(container string, name string, containerOnly bool, optimizedStorage bool) (*api.Response, error) {
+	if c.Remote.Public {
+		return nil, fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	body := api.ContainerBackupsPost{
+		Name:             name,
+		ContainerOnly:    containerOnly,
+		OptimizedStorage: optimizedStorage,
+	}
+
+	return c.post(fmt.Sprintf("containers/%s/backups", container), body, api.AsyncResponse)
+}
+
+func (c *Client) DeleteContainerBackup(container string, name string) (*api.Response, error) {
+	if c.Remote.Public {
+		return nil, fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	return c.delete(fmt.Sprintf("containers/%s/backups/%s", container, name), nil, api.AsyncResponse)
+}
+
+func (c *Client) ExportContainerBackup(container string, name string, target string) (string, error) {
+	if c.Remote.Public {
+		return "", fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	uri := c.url(version.APIVersion, "containers", container, "backups", name, "export")
+	raw, err := c.getRaw(uri)
+	if err != nil {
+		return "", err
+	}
+	defer raw.Body.Close()
+
+	if target == "-" {
+		_, err = io.Copy(os.Stdout, raw.Body)
+		return "stdout", err
+	}
+
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, raw.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return target, nil
+}
+
+func (c *Client) ImportContainer(backupFile string, pool string, progressHandler func(int64, int64)) (*api.Response, error) {
+	if c.Remote.Public {
+		return nil, fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	f, err := os.Open(backupFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	progress := &ioprogress.ProgressReader{
+		ReadCloser: f,
+		Tracker: &ioprogress.ProgressTracker{
+			Length:  stat.Size(),
+			Handler: progressHandler,
+		},
+	}
+
+	req, err := http.NewRequest("POST", c.url(version.APIVersion, "containers"), progress)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", version.UserAgent)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if pool != "" {
+		req.Header.Set("X-LXD-pool", pool)
+	}
+
+	raw, err := c.Http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return HoistResponse(raw, api.AsyncResponse)
+}
+
 func (c *Client) RestoreSnapshot(container string, snapshotName string, stateful bool) (*api.Response, error) {
 	if c.Remote.Public {
 		return nil, fmt.Errorf("This function isn't supported by public remotes.")
@@ -2789,6 +2976,45 @@ func (c *Client) NetworkGet(name string) (api.Network, error) {
 	return network, nil
 }
 
+// NetworkLeases returns the DHCP leases and static address reservations of a
+// managed network.
+func (c *Client) NetworkLeases(name string) ([]api.NetworkLease, error) {
+	if c.Remote.Public {
+		return nil, fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	resp, err := c.get(fmt.Sprintf("networks/%s/leases", name))
+	if err != nil {
+		return nil, err
+	}
+
+	leases := []api.NetworkLease{}
+	if err := resp.MetadataAsStruct(&leases); err != nil {
+		return nil, err
+	}
+
+	return leases, nil
+}
+
+// NetworkState returns the state of a network interface.
+func (c *Client) NetworkState(name string) (*api.NetworkState, error) {
+	if c.Remote.Public {
+		return nil, fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	resp, err := c.get(fmt.Sprintf("networks/%s/state", name))
+	if err != nil {
+		return nil, err
+	}
+
+	state := api.NetworkState{}
+	if err := resp.MetadataAsStruct(&state); err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
 func (c *Client) NetworkPut(name string, network api.NetworkPut) error {
 	if c.Remote.Public {
 		return fmt.Errorf("This function isn't supported by public remotes.")
@@ -2825,6 +3051,157 @@ func (c *Client) ListNetworks() ([]api.Network, error) {
 	return networks, nil
 }
 
+// Network ACL functions
+func (c *Client) NetworkACLCreate(name string, acl api.NetworkACLPut) error {
+	if c.Remote.Public {
+		return fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	body := api.NetworkACLsPost{Name: name, NetworkACLPut: acl}
+
+	_, err := c.post("network-acls", body, api.SyncResponse)
+	return err
+}
+
+func (c *Client) NetworkACLGet(name string) (*api.NetworkACL, error) {
+	if c.Remote.Public {
+		return nil, fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	resp, err := c.get(fmt.Sprintf("network-acls/%s", name))
+	if err != nil {
+		return nil, err
+	}
+
+	acl := api.NetworkACL{}
+	if err := resp.MetadataAsStruct(&acl); err != nil {
+		return nil, err
+	}
+
+	return &acl, nil
+}
+
+func (c *Client) NetworkACLPut(name string, acl api.NetworkACLPut) error {
+	if c.Remote.Public {
+		return fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	_, err := c.put(fmt.Sprintf("network-acls/%s", name), acl, api.SyncResponse)
+	return err
+}
+
+func (c *Client) NetworkACLRename(name string, newName string) error {
+	if c.Remote.Public {
+		return fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	_, err := c.post(fmt.Sprintf("network-acls/%s", name), api.NetworkACLPost{Name: newName}, api.SyncResponse)
+	return err
+}
+
+func (c *Client) NetworkACLDelete(name string) error {
+	if c.Remote.Public {
+		return fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	_, err := c.delete(fmt.Sprintf("network-acls/%s", name), nil, api.SyncResponse)
+	return err
+}
+
+func (c *Client) ListNetworkACLs() ([]api.NetworkACL, error) {
+	if c.Remote.Public {
+		return nil, fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	resp, err := c.get("network-acls?recursion=1")
+	if err != nil {
+		return nil, err
+	}
+
+	acls := []api.NetworkACL{}
+	if err := resp.MetadataAsStruct(&acls); err != nil {
+		return nil, err
+	}
+
+	return acls, nil
+}
+
+// Project functions
+func (c *Client) ProjectCreate(name string, config map[string]string) error {
+	if c.Remote.Public {
+		return fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	body := api.ProjectsPost{Name: name}
+	body.Config = config
+
+	_, err := c.post("projects", body, api.SyncResponse)
+	return err
+}
+
+func (c *Client) ProjectGet(name string) (api.Project, error) {
+	if c.Remote.Public {
+		return api.Project{}, fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	resp, err := c.get(fmt.Sprintf("projects/%s", name))
+	if err != nil {
+		return api.Project{}, err
+	}
+
+	project := api.Project{}
+	if err := resp.MetadataAsStruct(&project); err != nil {
+		return api.Project{}, err
+	}
+
+	return project, nil
+}
+
+func (c *Client) ProjectPut(name string, project api.ProjectPut) error {
+	if c.Remote.Public {
+		return fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	_, err := c.put(fmt.Sprintf("projects/%s", name), project, api.SyncResponse)
+	return err
+}
+
+func (c *Client) ProjectRename(name string, newName string) error {
+	if c.Remote.Public {
+		return fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	_, err := c.post(fmt.Sprintf("projects/%s", name), api.ProjectPost{Name: newName}, api.SyncResponse)
+	return err
+}
+
+func (c *Client) ProjectDelete(name string) error {
+	if c.Remote.Public {
+		return fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	_, err := c.delete(fmt.Sprintf("projects/%s", name), nil, api.SyncResponse)
+	return err
+}
+
+func (c *Client) ListProjects() ([]api.Project, error) {
+	if c.Remote.Public {
+		return nil, fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	resp, err := c.get("projects?recursion=1")
+	if err != nil {
+		return nil, err
+	}
+
+	projects := []api.Project{}
+	if err := resp.MetadataAsStruct(&projects); err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
 // Storage functions
 func (c *Client) ListStoragePools() ([]api.StoragePool, error) {
 	if c.Remote.Public {
@@ -2882,6 +3259,89 @@ func (c *Client) StoragePoolGet(name string) (api.StoragePool, error) {
 	return pools, nil
 }
 
+// StoragePoolResources returns the resources available to a storage pool.
+func (c *Client) StoragePoolResources(name string) (*api.ResourcesStoragePool, error) {
+	if c.Remote.Public {
+		return nil, fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	resp, err := c.get(fmt.Sprintf("storage-pools/%s/resources", name))
+	if err != nil {
+		return nil, err
+	}
+
+	res := api.ResourcesStoragePool{}
+	if err := resp.MetadataAsStruct(&res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// StoragePoolGC looks for unreferenced image volumes on a storage pool and
+// reclaims them unless dryRun is set.
+func (c *Client) StoragePoolGC(name string, dryRun bool) (*api.StoragePoolGC, error) {
+	if c.Remote.Public {
+		return nil, fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	body := api.StoragePoolGCPost{DryRun: dryRun}
+	resp, err := c.post(fmt.Sprintf("storage-pools/%s/gc", name), body, api.AsyncResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := c.WaitForSuccessOp(resp.Operation)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(op.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	res := api.StoragePoolGC{}
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// StorageCheck compares the database with what exists on the storage pools
+// and repairs what can be fixed safely if repair is set.
+func (c *Client) StorageCheck(repair bool) (*api.StorageCheck, error) {
+	if c.Remote.Public {
+		return nil, fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	body := api.StorageCheckPost{Repair: repair}
+	resp, err := c.post("storage-check", body, api.AsyncResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := c.WaitForSuccessOp(resp.Operation)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(op.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	res := api.StorageCheck{}
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
 func (c *Client) StoragePoolPut(name string, pool api.StoragePool) error {
 	if c.Remote.Public {
 		return fmt.Errorf("This function isn't supported by public remotes.")
@@ -2945,6 +3405,25 @@ func (c *Client) StoragePoolVolumeTypeGet(pool string, volume string, volumeType
 	return vol, nil
 }
 
+// /1.0/storage-pools/{pool}/volumes/{type}/{name}/state
+func (c *Client) StoragePoolVolumeTypeState(pool string, volume string, volumeType string) (api.StorageVolumeState, error) {
+	if c.Remote.Public {
+		return api.StorageVolumeState{}, fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	resp, err := c.get(fmt.Sprintf("storage-pools/%s/volumes/%s/%s/state", pool, volumeType, volume))
+	if err != nil {
+		return api.StorageVolumeState{}, err
+	}
+
+	state := api.StorageVolumeState{}
+	if err := json.Unmarshal(resp.Metadata, &state); err != nil {
+		return api.StorageVolumeState{}, err
+	}
+
+	return state, nil
+}
+
 // /1.0/storage-pools/{pool}/volumes/{type}/{name}
 func (c *Client) StoragePoolVolumeTypePut(pool string, volume string, volumeType string, volumeConfig api.StorageVolume) error {
 	if c.Remote.Public {
@@ -2968,3 +3447,111 @@ func (c *Client) StoragePoolVolumeTypeDelete(pool string, volume string, volumeT
 	_, err := c.delete(fmt.Sprintf("storage-pools/%s/volumes/%s/%s", pool, volumeType, volume), nil, api.SyncResponse)
 	return err
 }
+
+// /1.0/storage-pools/{pool}/volumes/{type}/{name}
+func (c *Client) StoragePoolVolumeRename(pool string, volume string, volumeType string, targetPool string, targetName string) (*api.Response, error) {
+	if c.Remote.Public {
+		return nil, fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	body := shared.Jmap{"name": targetName, "pool": targetPool}
+	return c.post(fmt.Sprintf("storage-pools/%s/volumes/%s/%s", pool, volumeType, volume), body, api.AsyncResponse)
+}
+
+// /1.0/storage-pools/{pool}/volumes/{type}
+func (c *Client) StoragePoolVolumeCopy(pool string, volume string, volumeType string, targetPool string, targetName string) (*api.Response, error) {
+	if c.Remote.Public {
+		return nil, fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	source := shared.Jmap{"type": "copy", "pool": pool, "name": volume}
+	body := shared.Jmap{"name": targetName, "type": volumeType, "source": source}
+	return c.post(fmt.Sprintf("storage-pools/%s/volumes/%s", targetPool, volumeType), body, api.AsyncResponse)
+}
+
+// /1.0/storage-pools/{pool}/volumes/{type}/{name}
+func (c *Client) StoragePoolVolumeGetMigrationSourceWS(pool string, volume string, volumeType string) (*api.Response, error) {
+	if c.Remote.Public {
+		return nil, fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	body := shared.Jmap{"migration": true}
+	return c.post(fmt.Sprintf("storage-pools/%s/volumes/%s/%s", pool, volumeType, volume), body, api.AsyncResponse)
+}
+
+// /1.0/storage-pools/{pool}/volumes/{type}
+func (c *Client) StoragePoolVolumeMigrateFrom(pool string, volume string, volumeType string, operation string, certificate string, sourceSecrets map[string]string) (*api.Response, error) {
+	if c.Remote.Public {
+		return nil, fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	source := shared.Jmap{
+		"type":        "migration",
+		"mode":        "pull",
+		"operation":   operation,
+		"certificate": certificate,
+		"secrets":     sourceSecrets,
+	}
+
+	body := shared.Jmap{"name": volume, "type": volumeType, "source": source}
+	return c.post(fmt.Sprintf("storage-pools/%s/volumes/%s", pool, volumeType), body, api.AsyncResponse)
+}
+
+// /1.0/storage-pools/{pool}/volumes/{type}/{name}
+func (c *Client) StoragePoolVolumeRestore(pool string, volume string, volumeType string, snapshotName string) error {
+	if c.Remote.Public {
+		return fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	body := shared.Jmap{"name": volume, "restore": snapshotName}
+	_, err := c.put(fmt.Sprintf("storage-pools/%s/volumes/%s/%s", pool, volumeType, volume), body, api.SyncResponse)
+	return err
+}
+
+// /1.0/storage-pools/{pool}/volumes/{type}/{name}/snapshots
+func (c *Client) StoragePoolVolumeSnapshotCreate(pool string, volume string, volumeType string, snapshotName string) (*api.Response, error) {
+	if c.Remote.Public {
+		return nil, fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	body := shared.Jmap{"name": snapshotName}
+	return c.post(fmt.Sprintf("storage-pools/%s/volumes/%s/%s/snapshots", pool, volumeType, volume), body, api.AsyncResponse)
+}
+
+// /1.0/storage-pools/{pool}/volumes/{type}/{name}/snapshots
+func (c *Client) StoragePoolVolumeSnapshotsList(pool string, volume string, volumeType string) ([]api.StorageVolumeSnapshot, error) {
+	if c.Remote.Public {
+		return nil, fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	resp, err := c.get(fmt.Sprintf("storage-pools/%s/volumes/%s/%s/snapshots?recursion=1", pool, volumeType, volume))
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := []api.StorageVolumeSnapshot{}
+	if err := resp.MetadataAsStruct(&snapshots); err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+// /1.0/storage-pools/{pool}/volumes/{type}/{name}/snapshots/{snapshot}
+func (c *Client) StoragePoolVolumeSnapshotRename(pool string, volume string, volumeType string, snapshotName string, newName string) (*api.Response, error) {
+	if c.Remote.Public {
+		return nil, fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	body := shared.Jmap{"name": newName}
+	return c.post(fmt.Sprintf("storage-pools/%s/volumes/%s/%s/snapshots/%s", pool, volumeType, volume, snapshotName), body, api.AsyncResponse)
+}
+
+// /1.0/storage-pools/{pool}/volumes/{type}/{name}/snapshots/{snapshot}
+func (c *Client) StoragePoolVolumeSnapshotDelete(pool string, volume string, volumeType string, snapshotName string) (*api.Response, error) {
+	if c.Remote.Public {
+		return nil, fmt.Errorf("This function isn't supported by public remotes.")
+	}
+
+	return c.delete(fmt.Sprintf("storage-pools/%s/volumes/%s/%s/snapshots/%s", pool, volumeType, volume, snapshotName), nil, api.AsyncResponse)
+}
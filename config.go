@@ -29,6 +29,11 @@ type Config struct {
 	// Command line aliases for `lxc`
 	Aliases map[string]string `yaml:"aliases"`
 
+	// Projects maps remote names to the project used on them when it
+	// isn't the default one. It's kept separate from the remotes so
+	// that it also applies to the static ones.
+	Projects map[string]string `yaml:"projects,omitempty"`
+
 	// This is the path to the config directory, so the client can find
 	// previously stored server certs, give good error messages, and save
 	// new server certs, etc.
@@ -43,6 +48,7 @@ type RemoteConfig struct {
 	Addr     string `yaml:"addr"`
 	Public   bool   `yaml:"public"`
 	Protocol string `yaml:"protocol,omitempty"`
+	Project  string `yaml:"-"`
 	Static   bool   `yaml:"-"`
 }
 
@@ -111,6 +117,16 @@ func LoadConfig(path string) (*Config, error) {
 		c.Remotes[k] = v
 	}
 
+	for k, project := range c.Projects {
+		rc, ok := c.Remotes[k]
+		if !ok {
+			continue
+		}
+
+		rc.Project = project
+		c.Remotes[k] = rc
+	}
+
 	// NOTE: Remove this once we only see a small fraction of non-simplestreams users
 	// Upgrade users to the "simplestreams" protocol
 	images, ok := c.Remotes["images"]
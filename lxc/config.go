@@ -22,7 +22,10 @@ import (
 )
 
 type configCmd struct {
-	expanded bool
+	expanded   bool
+	restricted bool
+	containers string
+	metrics    bool
 }
 
 func (c *configCmd) showByDefault() bool {
@@ -31,6 +34,9 @@ func (c *configCmd) showByDefault() bool {
 
 func (c *configCmd) flags() {
 	gnuflag.BoolVar(&c.expanded, "expanded", false, i18n.G("Show the expanded configuration"))
+	gnuflag.BoolVar(&c.restricted, "restricted", false, i18n.G("Restrict the certificate to the containers given with --containers"))
+	gnuflag.StringVar(&c.containers, "containers", "", i18n.G("Comma separated list of containers a restricted certificate may access"))
+	gnuflag.BoolVar(&c.metrics, "metrics", false, i18n.G("Only allow the certificate to read metrics"))
 }
 
 func (c *configCmd) configEditHelp() string {
@@ -77,6 +83,10 @@ lxc config edit [<remote>:][container]                                        Ed
 
 lxc config trust list [<remote>:]                                             List all trusted certs.
 lxc config trust add [<remote>:] <certfile.crt>                               Add certfile.crt to trusted hosts.
+lxc config trust add [<remote>:] <certfile.crt> --restricted --containers=<container>[,<container>...]
+    Add certfile.crt to trusted hosts, only granting it access to the given containers.
+lxc config trust add [<remote>:] <certfile.crt> --metrics
+    Add certfile.crt to trusted hosts, only granting it access to the metrics.
 lxc config trust remove [<remote>:] [hostname|fingerprint]                    Remove the cert from trusted hosts.
 
 Examples:
@@ -301,6 +311,19 @@ func (c *configCmd) run(config *lxd.Config, args []string) error {
 			}
 
 			name, _ := shared.SplitExt(fname)
+			if c.metrics {
+				return d.CertificateAddMetrics(cert, name)
+			}
+
+			if c.restricted || c.containers != "" {
+				containers := []string{}
+				if c.containers != "" {
+					containers = strings.Split(c.containers, ",")
+				}
+
+				return d.CertificateAddRestricted(cert, name, containers)
+			}
+
 			return d.CertificateAdd(cert, name)
 		case "remove":
 			var remote string
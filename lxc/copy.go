@@ -15,6 +15,7 @@ type copyCmd struct {
 	profArgs profileList
 	confArgs configList
 	ephem    bool
+	refresh  bool
 }
 
 func (c *copyCmd) showByDefault() bool {
@@ -25,7 +26,11 @@ func (c *copyCmd) usage() string {
 	return i18n.G(
 		`Copy containers within or in between LXD instances.
 
-lxc copy [<remote>:]<source>[/<snapshot>] [[<remote>:]<destination>] [--ephemeral|e] [--profile|-p <profile>...] [--config|-c <key=value>...]`)
+lxc copy [<remote>:]<source>[/<snapshot>] [[<remote>:]<destination>] [--ephemeral|e] [--profile|-p <profile>...] [--config|-c <key=value>...] [--refresh]
+
+When --refresh is passed and the destination container already exists, only
+the snapshots it's missing and the changes since its newest snapshot are
+transferred.`)
 }
 
 func (c *copyCmd) flags() {
@@ -35,6 +40,7 @@ func (c *copyCmd) flags() {
 	gnuflag.Var(&c.profArgs, "p", i18n.G("Profile to apply to the new container"))
 	gnuflag.BoolVar(&c.ephem, "ephemeral", false, i18n.G("Ephemeral container"))
 	gnuflag.BoolVar(&c.ephem, "e", false, i18n.G("Ephemeral container"))
+	gnuflag.BoolVar(&c.refresh, "refresh", false, i18n.G("Update the destination container if it already exists"))
 }
 
 func (c *copyCmd) copyContainer(config *lxd.Config, sourceResource string, destResource string, keepVolatile bool, ephemeral int) error {
@@ -49,6 +55,20 @@ func (c *copyCmd) copyContainer(config *lxd.Config, sourceResource string, destR
 		destName = sourceName
 	}
 
+	if c.refresh {
+		if destName == "" {
+			return fmt.Errorf(i18n.G("--refresh requires a destination container name"))
+		}
+
+		if shared.IsSnapshot(sourceName) {
+			return fmt.Errorf(i18n.G("--refresh can only be used with containers"))
+		}
+
+		if sourceRemote == destRemote && sourceName == destName {
+			return fmt.Errorf(i18n.G("can't copy to the same container name"))
+		}
+	}
+
 	source, err := lxd.NewClient(config, sourceRemote)
 	if err != nil {
 		return err
@@ -110,8 +130,10 @@ func (c *copyCmd) copyContainer(config *lxd.Config, sourceResource string, destR
 		}
 	}
 
-	// Do a local copy if the remotes are the same, otherwise do a migration
-	if sourceRemote == destRemote {
+	// Do a local copy if the remotes are the same, otherwise do a
+	// migration. Refreshing always goes through migration so that only
+	// what the destination is missing gets transferred.
+	if sourceRemote == destRemote && !c.refresh {
 		if sourceName == destName {
 			return fmt.Errorf(i18n.G("can't copy to the same container name"))
 		}
@@ -211,7 +233,7 @@ func (c *copyCmd) copyContainer(config *lxd.Config, sourceResource string, destR
 		var migration *api.Response
 
 		sourceWSUrl := "https://" + addr + sourceWSResponse.Operation
-		migration, err = dest.MigrateFrom(destName, sourceWSUrl, source.Certificate, secrets, status.Architecture, status.Config, status.Devices, status.Profiles, baseImage, ephemeral == 1, false, source, sourceWSResponse.Operation)
+		migration, err = dest.MigrateFrom(destName, sourceWSUrl, source.Certificate, secrets, status.Architecture, status.Config, status.Devices, status.Profiles, baseImage, ephemeral == 1, false, source, sourceWSResponse.Operation, c.refresh)
 		if err != nil {
 			continue
 		}
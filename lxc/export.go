@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/lxc/lxd"
+	"github.com/lxc/lxd/shared/gnuflag"
+	"github.com/lxc/lxd/shared/i18n"
+)
+
+type exportCmd struct {
+	containerOnly    bool
+	optimizedStorage bool
+}
+
+func (c *exportCmd) showByDefault() bool {
+	return true
+}
+
+func (c *exportCmd) usage() string {
+	return i18n.G(
+		`Export a container as a backup tarball.
+
+lxc export [<remote>:]<container> [target] [--container-only] [--optimized-storage]
+
+The backup includes the container's snapshots unless --container-only is passed.
+With --optimized-storage, the storage driver's native format is used (btrfs and
+zfs only), such a backup can only be imported on a pool of the same type.
+
+The target defaults to "<container>.backup" in the current directory.
+
+Example:
+    lxc export u1 backup0.tar.gz`)
+}
+
+func (c *exportCmd) flags() {
+	gnuflag.BoolVar(&c.containerOnly, "container-only", false, i18n.G("Whether or not to only backup the container (without snapshots)"))
+	gnuflag.BoolVar(&c.optimizedStorage, "optimized-storage", false, i18n.G("Use storage driver optimized format (can only be restored on a similar pool)"))
+}
+
+func (c *exportCmd) run(config *lxd.Config, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errArgs
+	}
+
+	remote, name := config.ParseRemoteAndContainer(args[0])
+	d, err := lxd.NewClient(config, remote)
+	if err != nil {
+		return err
+	}
+
+	target := fmt.Sprintf("%s.backup", name)
+	if len(args) == 2 {
+		target = args[1]
+	}
+
+	// Create the backup, using a temporary name
+	backupName := "lxc-export"
+	resp, err := d.CreateContainerBackup(name, backupName, c.containerOnly, c.optimizedStorage)
+	if err != nil {
+		return err
+	}
+
+	err = d.WaitForSuccess(resp.Operation)
+	if err != nil {
+		return err
+	}
+
+	// Always remove the backup from the server, even if the download fails
+	defer func() {
+		resp, err := d.DeleteContainerBackup(name, backupName)
+		if err == nil {
+			d.WaitForSuccess(resp.Operation)
+		}
+	}()
+
+	_, err = d.ExportContainerBackup(name, backupName, target)
+	return err
+}
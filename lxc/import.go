@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/lxc/lxd"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/gnuflag"
+	"github.com/lxc/lxd/shared/i18n"
+)
+
+type importCmd struct {
+	storagePool string
+}
+
+func (c *importCmd) showByDefault() bool {
+	return true
+}
+
+func (c *importCmd) usage() string {
+	return i18n.G(
+		`Import a container from a backup tarball.
+
+lxc import [<remote>:] <backup file> [--storage|-s <pool>]
+
+The container is created with the name it had when it was exported. Unless
+a storage pool is given, the original one is used if it exists.
+
+Example:
+    lxc import backup0.tar.gz`)
+}
+
+func (c *importCmd) flags() {
+	gnuflag.StringVar(&c.storagePool, "storage", "", i18n.G("Storage pool name"))
+	gnuflag.StringVar(&c.storagePool, "s", "", i18n.G("Storage pool name"))
+}
+
+func (c *importCmd) run(config *lxd.Config, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errArgs
+	}
+
+	remote := config.DefaultRemote
+	backupFile := args[0]
+	if len(args) == 2 {
+		remote, _ = config.ParseRemoteAndContainer(args[0])
+		backupFile = args[1]
+	}
+
+	d, err := lxd.NewClient(config, remote)
+	if err != nil {
+		return err
+	}
+
+	progress := ProgressRenderer{Format: i18n.G("Importing container: %s")}
+	handler := func(percent int64, speed int64) {
+		progress.Update(fmt.Sprintf("%d%% (%s/s)", percent, shared.GetByteSizeString(speed, 2)))
+	}
+
+	resp, err := d.ImportContainer(backupFile, c.storagePool, handler)
+	progress.Done("")
+	if err != nil {
+		return err
+	}
+
+	return d.WaitForSuccess(resp.Operation)
+}
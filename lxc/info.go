@@ -3,18 +3,21 @@ package main
 import (
 	"fmt"
 	"io/ioutil"
+	"sort"
 	"strings"
 
 	"gopkg.in/yaml.v2"
 
 	"github.com/lxc/lxd"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/gnuflag"
 	"github.com/lxc/lxd/shared/i18n"
 )
 
 type infoCmd struct {
-	showLog bool
+	showLog   bool
+	resources bool
 }
 
 func (c *infoCmd) showByDefault() bool {
@@ -29,11 +32,12 @@ For a container:
     lxc info [<remote:>]<container> [--show-log]
 
 For a server:
-    lxc info [<remote:>]`)
+    lxc info [<remote:>] [--resources]`)
 }
 
 func (c *infoCmd) flags() {
 	gnuflag.BoolVar(&c.showLog, "show-log", false, i18n.G("Show the container's last 100 log lines?"))
+	gnuflag.BoolVar(&c.resources, "resources", false, i18n.G("Show the resources available to the server"))
 }
 
 func (c *infoCmd) run(config *lxd.Config, args []string) error {
@@ -51,6 +55,10 @@ func (c *infoCmd) run(config *lxd.Config, args []string) error {
 	}
 
 	if cName == "" {
+		if c.resources {
+			return c.remoteResources(d)
+		}
+
 		return c.remoteInfo(d)
 	} else {
 		return c.containerInfo(d, cName, c.showLog)
@@ -73,6 +81,65 @@ func (c *infoCmd) remoteInfo(d *lxd.Client) error {
 	return nil
 }
 
+func (c *infoCmd) remoteResources(d *lxd.Client) error {
+	res, err := d.ServerResources()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(i18n.G("CPU:"))
+	for _, socket := range res.CPU.Sockets {
+		fmt.Printf("  "+i18n.G("Socket %d:")+"\n", socket.Socket)
+		if socket.Vendor != "" {
+			fmt.Printf("    "+i18n.G("Vendor: %s")+"\n", socket.Vendor)
+		}
+
+		if socket.Name != "" {
+			fmt.Printf("    "+i18n.G("Name: %s")+"\n", socket.Name)
+		}
+
+		fmt.Printf("    "+i18n.G("Cores: %d")+"\n", socket.Cores)
+		fmt.Printf("    "+i18n.G("Threads: %d")+"\n", socket.Threads)
+
+		if socket.Frequency > 0 {
+			if socket.FrequencyTurbo > 0 {
+				fmt.Printf("    "+i18n.G("Frequency: %d Mhz (max: %d Mhz)")+"\n", socket.Frequency, socket.FrequencyTurbo)
+			} else {
+				fmt.Printf("    "+i18n.G("Frequency: %d Mhz")+"\n", socket.Frequency)
+			}
+		}
+	}
+	fmt.Printf("  "+i18n.G("Total threads: %d")+"\n", res.CPU.Total)
+
+	fmt.Println(i18n.G("Memory:"))
+	fmt.Printf("  "+i18n.G("Used: %s")+"\n", shared.GetByteSizeString(int64(res.Memory.Used), 2))
+	fmt.Printf("  "+i18n.G("Total: %s")+"\n", shared.GetByteSizeString(int64(res.Memory.Total), 2))
+
+	if len(res.StoragePools) > 0 {
+		names := []string{}
+		for name := range res.StoragePools {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Println(i18n.G("Storage pools:"))
+		for _, name := range names {
+			pool := res.StoragePools[name]
+
+			fmt.Printf("  %s:\n", name)
+			fmt.Printf("    "+i18n.G("Space used: %s")+"\n", shared.GetByteSizeString(int64(pool.Space.Used), 2))
+			fmt.Printf("    "+i18n.G("Space total: %s")+"\n", shared.GetByteSizeString(int64(pool.Space.Total), 2))
+
+			if pool.Inodes.Total > 0 {
+				fmt.Printf("    "+i18n.G("Inodes used: %d")+"\n", pool.Inodes.Used)
+				fmt.Printf("    "+i18n.G("Inodes total: %d")+"\n", pool.Inodes.Total)
+			}
+		}
+	}
+
+	return nil
+}
+
 func (c *infoCmd) containerInfo(d *lxd.Client, name string, showLog bool) error {
 	ct, err := d.ContainerInfo(name)
 	if err != nil {
@@ -144,6 +211,29 @@ func (c *infoCmd) containerInfo(d *lxd.Client, name string, showLog bool) error
 			fmt.Printf(diskInfo)
 		}
 
+		// Disk I/O
+		diskIOInfo := ""
+		if cs.Disk != nil {
+			for entry, disk := range cs.Disk {
+				if disk.Counters == (api.ContainerStateDiskCounters{}) {
+					continue
+				}
+
+				diskIOInfo += fmt.Sprintf("    %s:\n", entry)
+				diskIOInfo += fmt.Sprintf("      %s: %s\n", i18n.G("Bytes read"), shared.GetByteSizeString(disk.Counters.BytesRead, 2))
+				diskIOInfo += fmt.Sprintf("      %s: %s\n", i18n.G("Bytes written"), shared.GetByteSizeString(disk.Counters.BytesWritten, 2))
+				diskIOInfo += fmt.Sprintf("      %s: %d\n", i18n.G("Read operations"), disk.Counters.OpsRead)
+				diskIOInfo += fmt.Sprintf("      %s: %d\n", i18n.G("Write operations"), disk.Counters.OpsWritten)
+				diskIOInfo += fmt.Sprintf("      %s: %s/s\n", i18n.G("Read throughput"), shared.GetByteSizeString(disk.Rates.BytesRead, 2))
+				diskIOInfo += fmt.Sprintf("      %s: %s/s\n", i18n.G("Write throughput"), shared.GetByteSizeString(disk.Rates.BytesWritten, 2))
+			}
+		}
+
+		if diskIOInfo != "" {
+			fmt.Println(fmt.Sprintf("  %s", i18n.G("Disk I/O:")))
+			fmt.Printf(diskIOInfo)
+		}
+
 		// CPU usage
 		cpuInfo := ""
 		if cs.CPU.Usage != 0 {
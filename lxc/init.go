@@ -60,11 +60,12 @@ func (f *profileList) Set(value string) error {
 var initRequestedEmptyProfiles bool
 
 type initCmd struct {
-	profArgs    profileList
-	confArgs    configList
-	ephem       bool
-	network     string
-	storagePool string
+	profArgs     profileList
+	confArgs     configList
+	ephem        bool
+	network      string
+	storagePool  string
+	instanceType string
 }
 
 func (c *initCmd) showByDefault() bool {
@@ -75,13 +76,17 @@ func (c *initCmd) usage() string {
 	return i18n.G(
 		`Initialize a container from a particular image.
 
-lxc init [<remote>:]<image> [<remote>:][<name>] [--ephemeral|-e] [--profile|-p <profile>...] [--config|-c <key=value>...] [--network|-n <network>] [--storage|-s <pool>]
+lxc init [<remote>:]<image> [<remote>:][<name>] [--ephemeral|-e] [--profile|-p <profile>...] [--config|-c <key=value>...] [--network|-n <network>] [--storage|-s <pool>] [--type|-t <instance type>]
 
 Initializes a container using the specified image and name.
 
 Not specifying -p will result in the default profile.
 Specifying "-p" with no argument will result in no profile.
 
+The instance type can be given either as a preset name, optionally
+prefixed by its cloud (e.g. "t2.micro" or "aws:t2.micro"), or as
+"c<CPU>-m<RAM in GB>" (e.g. "c2-m4").
+
 Example:
     lxc init ubuntu:16.04 u1`)
 }
@@ -144,6 +149,8 @@ func (c *initCmd) flags() {
 	gnuflag.StringVar(&c.network, "n", "", i18n.G("Network name"))
 	gnuflag.StringVar(&c.storagePool, "storage", "", i18n.G("Storage pool name"))
 	gnuflag.StringVar(&c.storagePool, "s", "", i18n.G("Storage pool name"))
+	gnuflag.StringVar(&c.instanceType, "type", "", i18n.G("Instance type"))
+	gnuflag.StringVar(&c.instanceType, "t", "", i18n.G("Instance type"))
 }
 
 func (c *initCmd) run(config *lxd.Config, args []string) error {
@@ -214,9 +221,9 @@ func (c *initCmd) run(config *lxd.Config, args []string) error {
 	}
 
 	if !initRequestedEmptyProfiles && len(profiles) == 0 {
-		resp, err = d.Init(name, iremote, image, nil, configMap, devicesMap, c.ephem)
+		resp, err = d.InitWithInstanceType(name, iremote, image, nil, configMap, devicesMap, c.ephem, c.instanceType)
 	} else {
-		resp, err = d.Init(name, iremote, image, &profiles, configMap, devicesMap, c.ephem)
+		resp, err = d.InitWithInstanceType(name, iremote, image, &profiles, configMap, devicesMap, c.ephem, c.instanceType)
 	}
 	if err != nil {
 		return err
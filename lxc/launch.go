@@ -23,13 +23,17 @@ func (c *launchCmd) usage() string {
 	return i18n.G(
 		`Launch a container from a particular image.
 
-lxc launch [<remote>:]<image> [<remote>:][<name>] [--ephemeral|-e] [--profile|-p <profile>...] [--config|-c <key=value>...] [--network|-n <network>] [--storage|-s <pool>]
+lxc launch [<remote>:]<image> [<remote>:][<name>] [--ephemeral|-e] [--profile|-p <profile>...] [--config|-c <key=value>...] [--network|-n <network>] [--storage|-s <pool>] [--type|-t <instance type>]
 
 Launches a container using the specified image and name.
 
 Not specifying -p will result in the default profile.
 Specifying "-p" with no argument will result in no profile.
 
+The instance type can be given either as a preset name, optionally
+prefixed by its cloud (e.g. "t2.micro" or "aws:t2.micro"), or as
+"c<CPU>-m<RAM in GB>" (e.g. "c2-m4").
+
 Example:
     lxc launch ubuntu:16.04 u1`)
 }
@@ -99,9 +103,9 @@ func (c *launchCmd) run(config *lxd.Config, args []string) error {
 	}
 
 	if !initRequestedEmptyProfiles && len(profiles) == 0 {
-		resp, err = d.Init(name, iremote, image, nil, configMap, devicesMap, c.init.ephem)
+		resp, err = d.InitWithInstanceType(name, iremote, image, nil, configMap, devicesMap, c.init.ephem, c.init.instanceType)
 	} else {
-		resp, err = d.Init(name, iremote, image, &profiles, configMap, devicesMap, c.init.ephem)
+		resp, err = d.InitWithInstanceType(name, iremote, image, &profiles, configMap, devicesMap, c.init.ephem, c.init.instanceType)
 	}
 	if err != nil {
 		return err
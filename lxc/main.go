@@ -163,10 +163,12 @@ var commands = map[string]command{
 	"copy":    &copyCmd{},
 	"delete":  &deleteCmd{},
 	"exec":    &execCmd{},
+	"export":  &exportCmd{},
 	"file":    &fileCmd{},
 	"finger":  &fingerCmd{},
 	"help":    &helpCmd{},
 	"image":   &imageCmd{},
+	"import":  &importCmd{},
 	"info":    &infoCmd{},
 	"init":    &initCmd{},
 	"launch":  &launchCmd{},
@@ -181,6 +183,7 @@ var commands = map[string]command{
 		additionalHelp: i18n.G("The opposite of `lxc pause` is `lxc start`."),
 	},
 	"profile": &profileCmd{},
+	"project": &projectCmd{},
 	"publish": &publishCmd{},
 	"remote":  &remoteCmd{},
 	"restart": &actionCmd{
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"fmt"
+
 	"github.com/lxc/lxd"
+	"github.com/lxc/lxd/shared/gnuflag"
 	"github.com/lxc/lxd/shared/i18n"
 )
 
 type moveCmd struct {
+	storagePool string
 }
 
 func (c *moveCmd) showByDefault() bool {
@@ -16,17 +20,23 @@ func (c *moveCmd) usage() string {
 	return i18n.G(
 		`Move containers within or in between lxd instances.
 
-lxc move [<remote>:]<source container> [<remote>:][<destination container>]
+lxc move [<remote>:]<source container> [<remote>:][<destination container>] [--storage|-s <pool>]
     Move a container between two hosts, renaming it if destination name differs.
 
 lxc move <old name> <new name>
     Rename a local container.
 
 lxc move <container>/<old snapshot name> <container>/<new snapshot name>
-    Rename a snapshot.`)
+    Rename a snapshot.
+
+lxc move <container> <container> --storage <pool>
+    Move a stopped container and its snapshots to another storage pool.`)
 }
 
-func (c *moveCmd) flags() {}
+func (c *moveCmd) flags() {
+	gnuflag.StringVar(&c.storagePool, "storage", "", i18n.G("Storage pool name"))
+	gnuflag.StringVar(&c.storagePool, "s", "", i18n.G("Storage pool name"))
+}
 
 func (c *moveCmd) run(config *lxd.Config, args []string) error {
 	if len(args) != 2 {
@@ -47,6 +57,19 @@ func (c *moveCmd) run(config *lxd.Config, args []string) error {
 			return err
 		}
 
+		if c.storagePool != "" {
+			if destName != "" && destName != sourceName {
+				return fmt.Errorf(i18n.G("Containers can't be renamed while being moved to another storage pool"))
+			}
+
+			move, err := source.MoveToPool(sourceName, c.storagePool)
+			if err != nil {
+				return err
+			}
+
+			return source.WaitForSuccess(move.Operation)
+		}
+
 		rename, err := source.Rename(sourceName, destName)
 		if err != nil {
 			return err
@@ -55,6 +78,10 @@ func (c *moveCmd) run(config *lxd.Config, args []string) error {
 		return source.WaitForSuccess(rename.Operation)
 	}
 
+	if c.storagePool != "" {
+		return fmt.Errorf(i18n.G("Moving to another storage pool is only supported within the same remote"))
+	}
+
 	cpy := copyCmd{}
 
 	// A move is just a copy followed by a delete; however, we want to
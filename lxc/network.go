@@ -45,11 +45,36 @@ func (c *networkCmd) networkEditHelp() string {
 ### Note that only the configuration can be changed.`)
 }
 
+func (c *networkCmd) networkACLEditHelp() string {
+	return i18n.G(
+		`### This is a yaml representation of the network ACL.
+### Any line starting with a '# will be ignored.
+###
+### A network ACL consists of ingress and egress rules, applied in order.
+### Traffic not matching any rule of a direction having rules is rejected.
+###
+### An example would look like:
+### name: web
+### description: Web servers
+### ingress:
+### - action: allow
+###   protocol: tcp
+###   destination_port: 80,443
+### - action: allow
+###   source: 10.0.0.0/8
+###   protocol: icmp4
+### egress: []
+###
+### Note that the name is shown but cannot be changed`)
+}
+
 func (c *networkCmd) usage() string {
 	return i18n.G(
 		`Manage networks.
 
 lxc network list [<remote>:]                              List available networks.
+lxc network list-leases [<remote>:]<network>              List the DHCP leases of a network.
+lxc network info [<remote>:]<network>                     Show the state and traffic counters of a network.
 lxc network show [<remote>:]<network>                     Show details of a network.
 lxc network create [<remote>:]<network> [key=value...]    Create a network.
 lxc network get [<remote>:]<network> <key>                Get network configuration.
@@ -65,7 +90,16 @@ lxc network attach [<remote>:]<network> <container> [device name] [interface nam
 lxc network attach-profile [<remote>:]<network> <profile> [device name] [interface name]
 
 lxc network detach [<remote>:]<network> <container> [device name]
-lxc network detach-profile [<remote>:]<network> <container> [device name]`)
+lxc network detach-profile [<remote>:]<network> <container> [device name]
+
+lxc network acl list [<remote>:]                          List available network ACLs.
+lxc network acl show [<remote>:]<acl>                     Show details of a network ACL.
+lxc network acl create [<remote>:]<acl>                   Create a network ACL.
+lxc network acl edit [<remote>:]<acl>                     Edit a network ACL.
+lxc network acl rename [<remote>:]<acl> <new-name>        Rename a network ACL.
+lxc network acl delete [<remote>:]<acl>                   Delete a network ACL.
+
+Network ACLs are applied by setting security.acls on networks or nic devices.`)
 }
 
 func (c *networkCmd) flags() {}
@@ -79,6 +113,10 @@ func (c *networkCmd) run(config *lxd.Config, args []string) error {
 		return c.doNetworkList(config, args)
 	}
 
+	if args[0] == "acl" {
+		return c.doNetworkACL(config, args[1:])
+	}
+
 	if len(args) < 2 {
 		return errArgs
 	}
@@ -106,6 +144,10 @@ func (c *networkCmd) run(config *lxd.Config, args []string) error {
 		return c.doNetworkEdit(client, network)
 	case "get":
 		return c.doNetworkGet(client, network, args[2:])
+	case "info":
+		return c.doNetworkInfo(client, network)
+	case "list-leases":
+		return c.doNetworkListLeases(client, network)
 	case "set":
 		return c.doNetworkSet(client, network, args[2:])
 	case "unset":
@@ -389,6 +431,55 @@ func (c *networkCmd) doNetworkGet(client *lxd.Client, name string, args []string
 	return nil
 }
 
+func (c *networkCmd) doNetworkInfo(client *lxd.Client, name string) error {
+	state, err := client.NetworkState(name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(i18n.G("Name: %s")+"\n", name)
+	c.printNetworkState(state, "")
+
+	if len(state.Tunnels) > 0 {
+		tunnels := []string{}
+		for tunnel := range state.Tunnels {
+			tunnels = append(tunnels, tunnel)
+		}
+		sort.Strings(tunnels)
+
+		fmt.Println(i18n.G("Tunnels:"))
+		for _, tunnel := range tunnels {
+			tunnelState := state.Tunnels[tunnel]
+			fmt.Printf("  %s:\n", tunnel)
+			c.printNetworkState(&tunnelState, "    ")
+		}
+	}
+
+	return nil
+}
+
+func (c *networkCmd) printNetworkState(state *api.NetworkState, indent string) {
+	fmt.Printf(indent+i18n.G("State: %s")+"\n", state.State)
+	fmt.Printf(indent+i18n.G("Type: %s")+"\n", state.Type)
+	fmt.Printf(indent+i18n.G("MTU: %d")+"\n", state.Mtu)
+	if state.Hwaddr != "" {
+		fmt.Printf(indent+i18n.G("MAC address: %s")+"\n", state.Hwaddr)
+	}
+
+	if len(state.Addresses) > 0 {
+		fmt.Println(indent + i18n.G("Addresses:"))
+		for _, addr := range state.Addresses {
+			fmt.Printf("%s  %s\t%s/%s (%s)\n", indent, addr.Family, addr.Address, addr.Netmask, addr.Scope)
+		}
+	}
+
+	fmt.Println(indent + i18n.G("Network usage:"))
+	fmt.Printf("%s  %s: %s\n", indent, i18n.G("Bytes received"), shared.GetByteSizeString(state.Counters.BytesReceived, 2))
+	fmt.Printf("%s  %s: %s\n", indent, i18n.G("Bytes sent"), shared.GetByteSizeString(state.Counters.BytesSent, 2))
+	fmt.Printf("%s  %s: %d\n", indent, i18n.G("Packets received"), state.Counters.PacketsReceived)
+	fmt.Printf("%s  %s: %d\n", indent, i18n.G("Packets sent"), state.Counters.PacketsSent)
+}
+
 func (c *networkCmd) doNetworkList(config *lxd.Config, args []string) error {
 	var remote string
 	if len(args) > 1 {
@@ -442,6 +533,33 @@ func (c *networkCmd) doNetworkList(config *lxd.Config, args []string) error {
 	return nil
 }
 
+func (c *networkCmd) doNetworkListLeases(client *lxd.Client, name string) error {
+	leases, err := client.NetworkLeases(name)
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	for _, lease := range leases {
+		data = append(data, []string{lease.Hostname, lease.Hwaddr, lease.Address, strings.ToUpper(lease.Type)})
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetRowLine(true)
+	table.SetHeader([]string{
+		i18n.G("HOSTNAME"),
+		i18n.G("MAC ADDRESS"),
+		i18n.G("IP ADDRESS"),
+		i18n.G("TYPE")})
+	sort.Sort(byName(data))
+	table.AppendBulk(data)
+	table.Render()
+
+	return nil
+}
+
 func (c *networkCmd) doNetworkSet(client *lxd.Client, name string, args []string) error {
 	// we shifted @args so so it should read "<key> [<value>]"
 	if len(args) < 1 {
@@ -495,3 +613,175 @@ func (c *networkCmd) doNetworkShow(client *lxd.Client, name string) error {
 
 	return nil
 }
+
+func (c *networkCmd) doNetworkACL(config *lxd.Config, args []string) error {
+	if len(args) < 1 {
+		return errArgs
+	}
+
+	if args[0] == "list" {
+		return c.doNetworkACLList(config, args)
+	}
+
+	if len(args) < 2 {
+		return errArgs
+	}
+
+	remote, acl := config.ParseRemoteAndContainer(args[1])
+	client, err := lxd.NewClient(config, remote)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "create":
+		err := client.NetworkACLCreate(acl, api.NetworkACLPut{})
+		if err == nil {
+			fmt.Printf(i18n.G("Network ACL %s created")+"\n", acl)
+		}
+
+		return err
+	case "delete":
+		err := client.NetworkACLDelete(acl)
+		if err == nil {
+			fmt.Printf(i18n.G("Network ACL %s deleted")+"\n", acl)
+		}
+
+		return err
+	case "edit":
+		return c.doNetworkACLEdit(client, acl)
+	case "rename":
+		if len(args) != 3 {
+			return errArgs
+		}
+
+		err := client.NetworkACLRename(acl, args[2])
+		if err == nil {
+			fmt.Printf(i18n.G("Network ACL %s renamed to %s")+"\n", acl, args[2])
+		}
+
+		return err
+	case "show":
+		info, err := client.NetworkACLGet(acl)
+		if err != nil {
+			return err
+		}
+
+		data, err := yaml.Marshal(info)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s", data)
+		return nil
+	default:
+		return errArgs
+	}
+}
+
+func (c *networkCmd) doNetworkACLEdit(client *lxd.Client, name string) error {
+	// If stdin isn't a terminal, read text from it
+	if !termios.IsTerminal(int(syscall.Stdin)) {
+		contents, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		newdata := api.NetworkACLPut{}
+		err = yaml.Unmarshal(contents, &newdata)
+		if err != nil {
+			return err
+		}
+		return client.NetworkACLPut(name, newdata)
+	}
+
+	// Extract the current value
+	acl, err := client.NetworkACLGet(name)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&acl)
+	if err != nil {
+		return err
+	}
+
+	// Spawn the editor
+	content, err := shared.TextEditor("", []byte(c.networkACLEditHelp()+"\n\n"+string(data)))
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Parse the text received from the editor
+		newdata := api.NetworkACLPut{}
+		err = yaml.Unmarshal(content, &newdata)
+		if err == nil {
+			err = client.NetworkACLPut(name, newdata)
+		}
+
+		// Respawn the editor
+		if err != nil {
+			fmt.Fprintf(os.Stderr, i18n.G("Config parsing error: %s")+"\n", err)
+			fmt.Println(i18n.G("Press enter to open the editor again"))
+
+			_, err := os.Stdin.Read(make([]byte, 1))
+			if err != nil {
+				return err
+			}
+
+			content, err = shared.TextEditor("", content)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		break
+	}
+	return nil
+}
+
+func (c *networkCmd) doNetworkACLList(config *lxd.Config, args []string) error {
+	var remote string
+	if len(args) > 1 {
+		var name string
+		remote, name = config.ParseRemoteAndContainer(args[1])
+		if name != "" {
+			return fmt.Errorf(i18n.G("Cannot provide container name to list"))
+		}
+	} else {
+		remote = config.DefaultRemote
+	}
+
+	client, err := lxd.NewClient(config, remote)
+	if err != nil {
+		return err
+	}
+
+	acls, err := client.ListNetworkACLs()
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	for _, acl := range acls {
+		strUsedBy := fmt.Sprintf("%d", len(acl.UsedBy))
+		data = append(data, []string{acl.Name, acl.Description, fmt.Sprintf("%d", len(acl.Ingress)), fmt.Sprintf("%d", len(acl.Egress)), strUsedBy})
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetRowLine(true)
+	table.SetHeader([]string{
+		i18n.G("NAME"),
+		i18n.G("DESCRIPTION"),
+		i18n.G("INGRESS RULES"),
+		i18n.G("EGRESS RULES"),
+		i18n.G("USED BY")})
+	sort.Sort(byName(data))
+	table.AppendBulk(data)
+	table.Render()
+
+	return nil
+}
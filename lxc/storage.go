@@ -15,6 +15,7 @@ import (
 	"github.com/lxc/lxd"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/gnuflag"
 	"github.com/lxc/lxd/shared/i18n"
 	"github.com/lxc/lxd/shared/termios"
 )
@@ -46,6 +47,7 @@ func (a byNameAndType) Less(i, j int) bool {
 }
 
 type storageCmd struct {
+	dryRun bool
 }
 
 func (c *storageCmd) showByDefault() bool {
@@ -94,6 +96,7 @@ lxc storage get [<remote>:]<pool> <key>                         Get storage pool
 lxc storage set [<remote>:]<pool> <key> <value>                 Set storage pool configuration.
 lxc storage unset [<remote>:]<pool> <key>                       Unset storage pool configuration.
 lxc storage delete [<remote>:]<pool>                            Delete a storage pool.
+lxc storage gc [<remote>:]<pool> [--dry-run]                    Remove unused image volumes from a storage pool.
 lxc storage edit [<remote>:]<pool>
     Edit storage pool, either by launching external editor or reading STDIN.
     Example: lxc storage edit [<remote>:]<pool> # launch editor
@@ -117,6 +120,14 @@ lxc storage volume attach-profile [<remote:>]<pool> <volume> <profile> [device n
 lxc storage volume detach [<remote>:]<pool> <volume> <container> [device name]
 lxc storage volume detach-profile [<remote:>]<pool> <volume> <profile> [device name]
 
+lxc storage volume rename [<remote>:]<pool> <old name> <new name>             Rename a storage volume.
+lxc storage volume copy [<remote>:]<pool>/<volume> [<remote>:]<pool>/<volume>  Copy a storage volume.
+lxc storage volume move [<remote>:]<pool>/<volume> [<remote>:]<pool>/<volume>  Move a storage volume.
+
+lxc storage volume snapshot [<remote>:]<pool> <volume> [<snapshot>]    Create a snapshot of a storage volume.
+lxc storage volume restore [<remote>:]<pool> <volume> <snapshot>       Restore a storage volume from a snapshot.
+lxc storage volume delete [<remote>:]<pool> <volume>/<snapshot>        Delete a snapshot of a storage volume.
+
 
 Unless specified through a prefix, all volume operations affect "custom" (user created) volumes.
 
@@ -126,10 +137,19 @@ To show the properties of a custom volume called "data" in the "default" pool:
 
 To show the properties of the filesystem for a container called "data" in the "default" pool:
     lxc storage volume show default container/data
+
+To snapshot a custom volume called "data" in the "default" pool and restore it later on:
+    lxc storage volume snapshot default data snap0
+    lxc storage volume restore default data snap0
+
+To copy a custom volume called "data" in the "default" pool to the "pool1" pool on the remote "host2":
+    lxc storage volume copy default/data host2:pool1/data
 `)
 }
 
-func (c *storageCmd) flags() {}
+func (c *storageCmd) flags() {
+	gnuflag.BoolVar(&c.dryRun, "dry-run", false, i18n.G("Only list what would be removed"))
+}
 
 func (c *storageCmd) run(config *lxd.Config, args []string) error {
 	if len(args) < 1 {
@@ -152,6 +172,11 @@ func (c *storageCmd) run(config *lxd.Config, args []string) error {
 
 	if args[0] == "volume" {
 		switch args[1] {
+		case "copy", "move":
+			if len(args) != 4 {
+				return errArgs
+			}
+			return c.doStoragePoolVolumeCopy(config, args[2], args[3], args[1] == "move")
 		case "attach":
 			if len(args) < 5 {
 				return errArgs
@@ -214,6 +239,20 @@ func (c *storageCmd) run(config *lxd.Config, args []string) error {
 			}
 			pool := args[2]
 			return c.doStoragePoolVolumesList(config, remote, pool, args)
+		case "rename":
+			if len(args) != 5 {
+				return errArgs
+			}
+			pool := args[2]
+			volume := args[3]
+			return c.doStoragePoolVolumeRename(client, pool, volume, args[4])
+		case "restore":
+			if len(args) != 5 {
+				return errArgs
+			}
+			pool := args[2]
+			volume := args[3]
+			return c.doStoragePoolVolumeRestore(client, pool, volume, args[4])
 		case "set":
 			if len(args) < 4 {
 				return errArgs
@@ -235,6 +274,17 @@ func (c *storageCmd) run(config *lxd.Config, args []string) error {
 			pool := args[2]
 			volume := args[3]
 			return c.doStoragePoolVolumeShow(client, pool, volume)
+		case "snapshot":
+			if len(args) < 4 || len(args) > 5 {
+				return errArgs
+			}
+			pool := args[2]
+			volume := args[3]
+			snapshot := ""
+			if len(args) == 5 {
+				snapshot = args[4]
+			}
+			return c.doStoragePoolVolumeSnapshot(client, pool, volume, snapshot)
 		default:
 			return errArgs
 		}
@@ -251,6 +301,8 @@ func (c *storageCmd) run(config *lxd.Config, args []string) error {
 			return c.doStoragePoolDelete(client, pool)
 		case "edit":
 			return c.doStoragePoolEdit(client, pool)
+		case "gc":
+			return c.doStoragePoolGC(client, pool)
 		case "get":
 			if len(args) < 2 {
 				return errArgs
@@ -285,6 +337,12 @@ func (c *storageCmd) parseVolume(name string) (string, string) {
 		return fields[0], defaultType
 	}
 
+	// Anything that isn't a volume type prefix is a snapshot of a custom
+	// volume (<volume>/<snapshot>).
+	if !shared.StringInSlice(fields[0], []string{"custom", "image", "container"}) {
+		return name, defaultType
+	}
+
 	return fields[1], fields[0]
 }
 
@@ -477,6 +535,28 @@ func (c *storageCmd) doStoragePoolDelete(client *lxd.Client, name string) error
 	return err
 }
 
+func (c *storageCmd) doStoragePoolGC(client *lxd.Client, name string) error {
+	res, err := client.StoragePoolGC(name, c.dryRun)
+	if err != nil {
+		return err
+	}
+
+	if len(res.Volumes) == 0 {
+		fmt.Printf(i18n.G("No unused image volumes found on storage pool %s")+"\n", name)
+		return nil
+	}
+
+	for _, volume := range res.Volumes {
+		if res.Reclaimed {
+			fmt.Printf(i18n.G("Removed %s")+"\n", volume)
+		} else {
+			fmt.Printf(i18n.G("Would remove %s")+"\n", volume)
+		}
+	}
+
+	return nil
+}
+
 func (c *storageCmd) doStoragePoolEdit(client *lxd.Client, name string) error {
 	// If stdin isn't a terminal, read text from it
 	if !termios.IsTerminal(int(syscall.Stdin)) {
@@ -703,6 +783,22 @@ func (c *storageCmd) doStoragePoolVolumeCreate(client *lxd.Client, pool string,
 
 func (c *storageCmd) doStoragePoolVolumeDelete(client *lxd.Client, pool string, volume string) error {
 	volName, volType := c.parseVolume(volume)
+
+	if shared.IsSnapshot(volName) {
+		fields := strings.SplitN(volName, shared.SnapshotDelimiter, 2)
+		resp, err := client.StoragePoolVolumeSnapshotDelete(pool, fields[0], volType, fields[1])
+		if err != nil {
+			return err
+		}
+
+		err = client.WaitForSuccess(resp.Operation)
+		if err == nil {
+			fmt.Printf(i18n.G("Storage volume snapshot %s deleted")+"\n", volume)
+		}
+
+		return err
+	}
+
 	err := client.StoragePoolVolumeTypeDelete(pool, volName, volType)
 	if err == nil {
 		fmt.Printf(i18n.G("Storage volume %s deleted")+"\n", volume)
@@ -711,6 +807,137 @@ func (c *storageCmd) doStoragePoolVolumeDelete(client *lxd.Client, pool string,
 	return err
 }
 
+func (c *storageCmd) doStoragePoolVolumeRename(client *lxd.Client, pool string, volume string, newName string) error {
+	volName, volType := c.parseVolume(volume)
+	resp, err := client.StoragePoolVolumeRename(pool, volName, volType, pool, newName)
+	if err != nil {
+		return err
+	}
+
+	err = client.WaitForSuccess(resp.Operation)
+	if err == nil {
+		fmt.Printf(i18n.G("Renamed storage volume from \"%s\" to \"%s\"")+"\n", volume, newName)
+	}
+
+	return err
+}
+
+func (c *storageCmd) doStoragePoolVolumeSnapshot(client *lxd.Client, pool string, volume string, snapshot string) error {
+	volName, volType := c.parseVolume(volume)
+	resp, err := client.StoragePoolVolumeSnapshotCreate(pool, volName, volType, snapshot)
+	if err != nil {
+		return err
+	}
+
+	return client.WaitForSuccess(resp.Operation)
+}
+
+func (c *storageCmd) doStoragePoolVolumeRestore(client *lxd.Client, pool string, volume string, snapshot string) error {
+	volName, volType := c.parseVolume(volume)
+	return client.StoragePoolVolumeRestore(pool, volName, volType, snapshot)
+}
+
+func (c *storageCmd) doStoragePoolVolumeCopy(config *lxd.Config, source string, target string, move bool) error {
+	sourceRemote, sourceName := config.ParseRemoteAndContainer(source)
+	targetRemote, targetName := config.ParseRemoteAndContainer(target)
+
+	sourceFields := strings.SplitN(sourceName, "/", 2)
+	targetFields := strings.SplitN(targetName, "/", 2)
+	if len(sourceFields) != 2 || len(targetFields) != 2 {
+		return errArgs
+	}
+
+	sourcePool, sourceVolume := sourceFields[0], sourceFields[1]
+	targetPool, targetVolume := targetFields[0], targetFields[1]
+
+	sourceClient, err := lxd.NewClient(config, sourceRemote)
+	if err != nil {
+		return err
+	}
+
+	// Copies and moves on the same remote are handled by the server.
+	if sourceRemote == targetRemote {
+		var resp *api.Response
+		if move {
+			resp, err = sourceClient.StoragePoolVolumeRename(sourcePool, sourceVolume, "custom", targetPool, targetVolume)
+		} else {
+			resp, err = sourceClient.StoragePoolVolumeCopy(sourcePool, sourceVolume, "custom", targetPool, targetVolume)
+		}
+		if err != nil {
+			return err
+		}
+
+		err = sourceClient.WaitForSuccess(resp.Operation)
+		if err != nil {
+			return err
+		}
+	} else {
+		targetClient, err := lxd.NewClient(config, targetRemote)
+		if err != nil {
+			return err
+		}
+
+		sourceWSResponse, err := sourceClient.StoragePoolVolumeGetMigrationSourceWS(sourcePool, sourceVolume, "custom")
+		if err != nil {
+			return err
+		}
+
+		secrets := map[string]string{}
+		op, err := sourceWSResponse.MetadataAsOperation()
+		if err != nil {
+			return err
+		}
+
+		for k, v := range op.Metadata {
+			secrets[k] = v.(string)
+		}
+
+		addresses, err := sourceClient.Addresses()
+		if err != nil {
+			return err
+		}
+
+		// Try all the addresses of the source until one of them works.
+		for _, addr := range addresses {
+			var migration *api.Response
+
+			sourceWSUrl := "https://" + addr + sourceWSResponse.Operation
+			migration, err = targetClient.StoragePoolVolumeMigrateFrom(targetPool, targetVolume, "custom", sourceWSUrl, sourceClient.Certificate, secrets)
+			if err != nil {
+				continue
+			}
+
+			if err = targetClient.WaitForSuccess(migration.Operation); err != nil {
+				continue
+			}
+
+			if err = sourceClient.WaitForSuccess(sourceWSResponse.Operation); err != nil {
+				return err
+			}
+
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if move {
+			err = sourceClient.StoragePoolVolumeTypeDelete(sourcePool, sourceVolume, "custom")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if move {
+		fmt.Printf(i18n.G("Storage volume moved successfully!") + "\n")
+	} else {
+		fmt.Printf(i18n.G("Storage volume copied successfully!") + "\n")
+	}
+
+	return nil
+}
+
 func (c *storageCmd) doStoragePoolVolumeGet(client *lxd.Client, pool string, volume string, args []string) error {
 	// we shifted @args so so it should read "<key>"
 	if len(args) != 2 {
@@ -26,6 +26,9 @@ var api10 = []Command{
 	containerSnapshotsCmd,
 	containerSnapshotCmd,
 	containerExecCmd,
+	containerBackupsCmd,
+	containerBackupCmd,
+	containerBackupExportCmd,
 	aliasCmd,
 	aliasesCmd,
 	eventsCmd,
@@ -39,16 +42,30 @@ var api10 = []Command{
 	operationWebsocket,
 	networksCmd,
 	networkCmd,
+	networkLeasesCmd,
+	networkStateCmd,
+	networkACLsCmd,
+	networkACLCmd,
 	api10Cmd,
 	certificatesCmd,
 	certificateFingerprintCmd,
 	profilesCmd,
 	profileCmd,
+	projectsCmd,
+	projectCmd,
 	storagePoolsCmd,
 	storagePoolCmd,
+	storagePoolGCCmd,
 	storagePoolVolumesCmd,
 	storagePoolVolumesTypeCmd,
+	storagePoolVolumeSnapshotsTypeCmd,
+	storagePoolVolumeSnapshotTypeCmd,
+	storagePoolVolumeTypeStateCmd,
 	storagePoolVolumeTypeCmd,
+	metricsCmd,
+	api10ResourcesCmd,
+	storagePoolResourcesCmd,
+	storageCheckCmd,
 }
 
 func api10Get(d *Daemon, r *http.Request) Response {
@@ -93,6 +110,32 @@ func api10Get(d *Daemon, r *http.Request) Response {
 			"file_delete",
 			"file_append",
 			"network_dhcp_expiry",
+			"storage_api_volume_snapshots",
+			"storage_api_volume_copy",
+			"operations_persistence",
+			"snapshot_scheduling",
+			"container_backup",
+			"proxy",
+			"projects",
+			"certificate_restrictions",
+			"metrics",
+			"container_instance_type",
+			"resources",
+			"storage_ceph",
+			"storage_lvm_migration",
+			"container_incremental_copy",
+			"storage_volume_state",
+			"storage_pool_resize",
+			"container_storage_move",
+			"container_disk_io",
+			"storage_volume_attachments",
+			"storage_pool_gc",
+			"network_leases",
+			"network_state",
+			"network_dns",
+			"network_acl",
+			"nic_vlan",
+			"storage_check",
 		},
 		APIStatus:  "stable",
 		APIVersion: version.APIVersion,
@@ -107,6 +150,12 @@ func api10Get(d *Daemon, r *http.Request) Response {
 
 	srv.Auth = "trusted"
 
+	// Restricted clients don't get to see the host details
+	restricted, _ := d.clientRestriction(r)
+	if restricted {
+		return SyncResponseETag(true, srv, nil)
+	}
+
 	/*
 	 * Based on: https://groups.google.com/forum/#!topic/golang-nuts/Jel8Bb-YwX8
 	 * there is really no better way to do this, which is
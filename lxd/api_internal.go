@@ -22,6 +22,7 @@ var apiInternal = []Command{
 	internalShutdownCmd,
 	internalContainerOnStartCmd,
 	internalContainerOnStopCmd,
+	internalContainerOnNetworkUpCmd,
 	internalContainersCmd,
 }
 
@@ -97,10 +98,37 @@ func internalContainerOnStop(d *Daemon, r *http.Request) Response {
 	return EmptySyncResponse
 }
 
+func internalContainerOnNetworkUp(d *Daemon, r *http.Request) Response {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return SmartError(err)
+	}
+
+	device := r.FormValue("device")
+	hostName := r.FormValue("host_name")
+	if device == "" || hostName == "" {
+		return BadRequest(fmt.Errorf("A device and host interface name must be provided"))
+	}
+
+	c, err := containerLoadById(d, id)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	err = c.OnNetworkUp(device, hostName)
+	if err != nil {
+		shared.Log.Error("network up hook failed", log.Ctx{"container": c.Name(), "device": device, "err": err})
+		return SmartError(err)
+	}
+
+	return EmptySyncResponse
+}
+
 var internalShutdownCmd = Command{name: "shutdown", put: internalShutdown}
 var internalReadyCmd = Command{name: "ready", put: internalReady, get: internalWaitReady}
 var internalContainerOnStartCmd = Command{name: "containers/{id}/onstart", get: internalContainerOnStart}
 var internalContainerOnStopCmd = Command{name: "containers/{id}/onstop", get: internalContainerOnStop}
+var internalContainerOnNetworkUpCmd = Command{name: "containers/{id}/onnetwork-up", get: internalContainerOnNetworkUp}
 
 func slurpBackupFile(path string) (*backupFile, error) {
 	data, err := ioutil.ReadFile(path)
@@ -173,6 +201,21 @@ func internalImport(d *Daemon, r *http.Request) Response {
 				return InternalError(err)
 			}
 			defer tryUnmount(containerLvmPath, 0)
+		case "ceph":
+			clusterName := pool.Config["ceph.cluster_name"]
+			osdPoolName := pool.Config["ceph.osd.pool_name"]
+			userName := pool.Config["ceph.user.name"]
+			devPath, err := cephRBDVolumeMap(clusterName, osdPoolName, name, storagePoolVolumeApiEndpointContainers, "", userName)
+			if err != nil {
+				return InternalError(err)
+			}
+			defer cephRBDVolumeUnmap(clusterName, osdPoolName, name, storagePoolVolumeApiEndpointContainers, "", userName)
+
+			err = tryMount(devPath, containerMntPoint, "", 0, "")
+			if err != nil {
+				return InternalError(err)
+			}
+			defer tryUnmount(containerMntPoint, 0)
 		case "dir":
 			// noop: There is nothing to mount.
 		case "btrfs":
@@ -8,9 +8,11 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
 
+	"github.com/lxc/lxd/lxd/types"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/version"
@@ -30,18 +32,17 @@ func certificatesGet(d *Daemon, r *http.Request) Response {
 			resp := api.Certificate{}
 			resp.Fingerprint = baseCert.Fingerprint
 			resp.Certificate = baseCert.Certificate
-			if baseCert.Type == 1 {
-				resp.Type = "client"
-			} else {
-				resp.Type = "unknown"
-			}
+			resp.Name = baseCert.Name
+			resp.Restricted = baseCert.Restricted
+			resp.Containers = baseCert.Containers
+			resp.Type = certificateTypeName(baseCert.Type)
 			certResponses = append(certResponses, resp)
 		}
 		return SyncResponse(true, certResponses)
 	}
 
 	body := []string{}
-	for _, cert := range d.clientCerts {
+	for _, cert := range append(d.clientCerts, d.metricsCerts...) {
 		fingerprint := fmt.Sprintf("/%s/certificates/%s", version.APIVersion, shared.CertFingerprint(&cert))
 		body = append(body, fingerprint)
 	}
@@ -49,8 +50,35 @@ func certificatesGet(d *Daemon, r *http.Request) Response {
 	return SyncResponse(true, body)
 }
 
+// certificateTypeName returns the API name of a certificate type stored in
+// the database.
+func certificateTypeName(certType int) string {
+	switch certType {
+	case certificateTypeClient:
+		return "client"
+	case certificateTypeMetrics:
+		return "metrics"
+	}
+
+	return "unknown"
+}
+
+// certificateTypeID returns the database value for an API certificate type.
+func certificateTypeID(name string) (int, error) {
+	switch name {
+	case "client":
+		return certificateTypeClient, nil
+	case "metrics":
+		return certificateTypeMetrics, nil
+	}
+
+	return -1, fmt.Errorf("Unknown request type %s", name)
+}
+
 func readSavedClientCAList(d *Daemon) {
 	d.clientCerts = []x509.Certificate{}
+	d.metricsCerts = []x509.Certificate{}
+	d.clientRestrictions = map[string][]string{}
 
 	dbCerts, err := dbCertsGet(d.db)
 	if err != nil {
@@ -70,15 +98,204 @@ func readSavedClientCAList(d *Daemon) {
 			shared.LogInfof("Error reading certificate for %s: %s", dbCert.Name, err)
 			continue
 		}
+
+		// Metrics certificates only grant access to /1.0/metrics
+		if dbCert.Type == certificateTypeMetrics {
+			d.metricsCerts = append(d.metricsCerts, *cert)
+			continue
+		}
+
 		d.clientCerts = append(d.clientCerts, *cert)
+
+		if dbCert.Restricted {
+			d.clientRestrictions[dbCert.Fingerprint] = dbCert.Containers
+		}
+	}
+}
+
+// clientRestriction returns whether the request comes from a client using a
+// restricted certificate and if so, the containers it may access.
+func (d *Daemon) clientRestriction(r *http.Request) (bool, []string) {
+	if r.RemoteAddr == "@" || r.TLS == nil {
+		return false, nil
+	}
+
+	for i := range r.TLS.PeerCertificates {
+		containers, ok := d.clientRestrictions[shared.CertFingerprint(r.TLS.PeerCertificates[i])]
+		if ok {
+			return true, containers
+		}
+	}
+
+	return false, nil
+}
+
+// isAllowedRestricted checks that a request coming from a restricted client
+// only touches the containers that client was granted access to. Listing and
+// creating containers is checked by the handlers themselves.
+func (d *Daemon) isAllowedRestricted(r *http.Request, apiVersion string, c Command) bool {
+	restricted, containers := d.clientRestriction(r)
+	if !restricted {
+		return true
+	}
+
+	// Restricted clients are confined to the default project
+	if apiVersion != version.APIVersion || projectParam(r) != projectDefault {
+		return false
+	}
+
+	switch c.name {
+	case "", "images", "images/{fingerprint}", "images/{fingerprint}/export", "images/aliases", "images/aliases/{name:.*}":
+		return r.Method == "GET"
+	case "containers":
+		return r.Method == "GET" || r.Method == "POST"
+	case "operations/{id}/websocket":
+		// Protected by the operation secret
+		return true
+	case "operations/{id}", "operations/{id}/wait":
+		op, err := operationGet(mux.Vars(r)["id"])
+		if err != nil {
+			return false
+		}
+
+		if len(op.resources["containers"]) == 0 {
+			return false
+		}
+
+		for _, name := range op.resources["containers"] {
+			if !shared.StringInSlice(name, containers) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if strings.HasPrefix(c.name, "containers/{name}") {
+		return shared.StringInSlice(mux.Vars(r)["name"], containers)
+	}
+
+	return false
+}
+
+// restrictedConfigKey returns whether a restricted client may set the given
+// container configuration key.
+func restrictedConfigKey(key string, value string) bool {
+	for _, prefix := range []string{"boot.", "environment.", "image.", "limits.", "snapshots.", "user."} {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	switch key {
+	case "security.nesting", "security.idmap.isolated":
+		return true
+	case "security.privileged":
+		return !shared.IsTrue(value)
+	}
+
+	return false
+}
+
+// restrictedDevice returns an error if a restricted client may not add the
+// given device.
+func restrictedDevice(name string, m types.Device) error {
+	switch m["type"] {
+	case "none":
+		return nil
+	case "nic":
+		if !shared.StringInSlice(m["nictype"], []string{"bridged", "p2p"}) {
+			return fmt.Errorf("Restricted clients can't add '%s' nics (%s)", m["nictype"], name)
+		}
+
+		return nil
+	case "disk":
+		if m["path"] != "/" || m["source"] != "" {
+			return fmt.Errorf("Restricted clients can't add disks other than the root disk (%s)", name)
+		}
+
+		return nil
 	}
+
+	return fmt.Errorf("Restricted clients can't add '%s' devices (%s)", m["type"], name)
 }
 
-func saveCert(d *Daemon, host string, cert *x509.Certificate) error {
+// restrictedCheckContainer rejects container settings which would let a
+// restricted client reach outside of its containers. The settings are
+// checked once expanded with the given profiles, only what differs from the
+// current container (if any) being subject to the check.
+func restrictedCheckContainer(d *Daemon, c container, config map[string]string, devices types.Devices, profiles []string) error {
+	// Apply the profiles
+	expandedConfig := map[string]string{}
+	expandedDevices := types.Devices{}
+	for _, name := range profiles {
+		profileConfig, err := dbProfileConfig(d.db, projectDefault, name)
+		if err != nil {
+			return fmt.Errorf("Failed to load profile '%s': %s", name, err)
+		}
+
+		for k, v := range profileConfig {
+			expandedConfig[k] = v
+		}
+
+		profileDevices, err := dbDevices(d.db, projectDefault, name, true)
+		if err != nil {
+			return fmt.Errorf("Failed to load profile '%s': %s", name, err)
+		}
+
+		for k, v := range profileDevices {
+			expandedDevices[k] = v
+		}
+	}
+
+	for k, v := range config {
+		expandedConfig[k] = v
+	}
+
+	for k, v := range devices {
+		expandedDevices[k] = v
+	}
+
+	// Settings which are already in effect are left alone
+	oldConfig := map[string]string{}
+	oldDevices := types.Devices{}
+	if c != nil {
+		oldConfig = c.ExpandedConfig()
+		oldDevices = c.ExpandedDevices()
+	}
+
+	for k, v := range expandedConfig {
+		oldValue, ok := oldConfig[k]
+		if ok && oldValue == v {
+			continue
+		}
+
+		if !restrictedConfigKey(k, v) {
+			return fmt.Errorf("Restricted clients can't set '%s'", k)
+		}
+	}
+
+	for name, m := range expandedDevices {
+		if oldDevices.Contains(name, m) {
+			continue
+		}
+
+		err := restrictedDevice(name, m)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func saveCert(d *Daemon, host string, cert *x509.Certificate, certType int, restricted bool, containers []string) error {
 	baseCert := new(dbCertInfo)
 	baseCert.Fingerprint = shared.CertFingerprint(cert)
-	baseCert.Type = 1
+	baseCert.Type = certType
 	baseCert.Name = host
+	baseCert.Restricted = restricted
+	baseCert.Containers = containers
 	baseCert.Certificate = string(
 		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}),
 	)
@@ -98,8 +315,9 @@ func certificatesPost(d *Daemon, r *http.Request) Response {
 		return Forbidden
 	}
 
-	if req.Type != "client" {
-		return BadRequest(fmt.Errorf("Unknown request type %s", req.Type))
+	certType, err := certificateTypeID(req.Type)
+	if err != nil {
+		return BadRequest(err)
 	}
 
 	// Extract the certificate
@@ -133,18 +351,29 @@ func certificatesPost(d *Daemon, r *http.Request) Response {
 	}
 
 	fingerprint := shared.CertFingerprint(cert)
-	for _, existingCert := range d.clientCerts {
+	for _, existingCert := range append(d.clientCerts, d.metricsCerts...) {
 		if fingerprint == shared.CertFingerprint(&existingCert) {
 			return BadRequest(fmt.Errorf("Certificate already in trust store"))
 		}
 	}
 
-	err := saveCert(d, name, cert)
+	// Only trusted clients get to restrict certificates, anything added
+	// through the trust password has full access.
+	restricted := false
+	containers := []string{}
+	if d.isTrustedClient(r) && certType == certificateTypeClient {
+		restricted = req.Restricted
+		if req.Containers != nil {
+			containers = req.Containers
+		}
+	}
+
+	err = saveCert(d, name, cert, certType, restricted, containers)
 	if err != nil {
 		return SmartError(err)
 	}
 
-	d.clientCerts = append(d.clientCerts, *cert)
+	readSavedClientCAList(d)
 
 	return SyncResponseLocation(true, nil, fmt.Sprintf("/%s/certificates/%s", version.APIVersion, fingerprint))
 }
@@ -173,11 +402,9 @@ func doCertificateGet(d *Daemon, fingerprint string) (api.Certificate, error) {
 	resp.Fingerprint = dbCertInfo.Fingerprint
 	resp.Certificate = dbCertInfo.Certificate
 	resp.Name = dbCertInfo.Name
-	if dbCertInfo.Type == 1 {
-		resp.Type = "client"
-	} else {
-		resp.Type = "unknown"
-	}
+	resp.Restricted = dbCertInfo.Restricted
+	resp.Containers = dbCertInfo.Containers
+	resp.Type = certificateTypeName(dbCertInfo.Type)
 
 	return resp, nil
 }
@@ -236,19 +463,52 @@ func certificateFingerprintPatch(d *Daemon, r *http.Request) Response {
 		req.Type = value
 	}
 
+	// Get restricted
+	restricted, err := reqRaw.GetBool("restricted")
+	if err == nil {
+		req.Restricted = restricted
+	}
+
+	// Get containers
+	rawContainers, ok := reqRaw["containers"].([]interface{})
+	if ok {
+		req.Containers = []string{}
+		for _, entry := range rawContainers {
+			name, ok := entry.(string)
+			if !ok {
+				return BadRequest(fmt.Errorf("Invalid container name: %v", entry))
+			}
+
+			req.Containers = append(req.Containers, name)
+		}
+	}
+
 	return doCertificateUpdate(d, fingerprint, req.Writable())
 }
 
 func doCertificateUpdate(d *Daemon, fingerprint string, req api.CertificatePut) Response {
-	if req.Type != "client" {
-		return BadRequest(fmt.Errorf("Unknown request type %s", req.Type))
+	certType, err := certificateTypeID(req.Type)
+	if err != nil {
+		return BadRequest(err)
 	}
 
-	err := dbCertUpdate(d.db, fingerprint, req.Name, 1)
+	if req.Containers == nil {
+		req.Containers = []string{}
+	}
+
+	// Restrictions only apply to regular clients
+	if certType != certificateTypeClient {
+		req.Restricted = false
+		req.Containers = []string{}
+	}
+
+	err = dbCertUpdate(d.db, fingerprint, req.Name, certType, req.Restricted, req.Containers)
 	if err != nil {
 		return InternalError(err)
 	}
 
+	readSavedClientCAList(d)
+
 	return EmptySyncResponse
 }
 
@@ -113,6 +113,12 @@ func containerValidDeviceConfigKey(t, k string) bool {
 			return true
 		case "security.mac_filtering":
 			return true
+		case "security.acls":
+			return true
+		case "vlan":
+			return true
+		case "vlan.tagged":
+			return true
 		default:
 			return false
 		}
@@ -177,6 +183,15 @@ func containerValidDeviceConfigKey(t, k string) bool {
 		default:
 			return false
 		}
+	case "proxy":
+		switch k {
+		case "listen":
+			return true
+		case "connect":
+			return true
+		default:
+			return false
+		}
 	case "none":
 		return false
 	default:
@@ -268,7 +283,7 @@ func containerValidDevices(devices types.Devices, profile bool, expanded bool) e
 			return fmt.Errorf("Missing device type for device '%s'", name)
 		}
 
-		if !shared.StringInSlice(m["type"], []string{"none", "nic", "disk", "unix-char", "unix-block", "usb", "gpu"}) {
+		if !shared.StringInSlice(m["type"], []string{"none", "nic", "disk", "unix-char", "unix-block", "usb", "gpu", "proxy"}) {
 			return fmt.Errorf("Invalid device type for device '%s'", name)
 		}
 
@@ -290,6 +305,39 @@ func containerValidDevices(devices types.Devices, profile bool, expanded bool) e
 			if shared.StringInSlice(m["nictype"], []string{"bridged", "physical", "macvlan"}) && m["parent"] == "" {
 				return fmt.Errorf("Missing parent for %s type nic.", m["nictype"])
 			}
+
+			if m["security.acls"] != "" {
+				if m["nictype"] != "bridged" {
+					return fmt.Errorf("Network ACLs are only supported on bridged nics.")
+				}
+
+				err := networkACLValidNames(m["security.acls"])
+				if err != nil {
+					return err
+				}
+			}
+
+			if m["vlan"] != "" {
+				if !shared.StringInSlice(m["nictype"], []string{"bridged", "macvlan"}) {
+					return fmt.Errorf("VLANs are only supported on bridged and macvlan nics.")
+				}
+
+				err := networkValidVLAN(m["vlan"])
+				if err != nil {
+					return err
+				}
+			}
+
+			if m["vlan.tagged"] != "" {
+				if m["nictype"] != "bridged" {
+					return fmt.Errorf("Tagged VLANs are only supported on bridged nics.")
+				}
+
+				err := networkValidVLANList(m["vlan.tagged"])
+				if err != nil {
+					return err
+				}
+			}
 		} else if m["type"] == "disk" {
 			if !expanded && !shared.StringInSlice(m["path"], diskDevicePaths) {
 				diskDevicePaths = append(diskDevicePaths, m["path"])
@@ -356,6 +404,28 @@ func containerValidDevices(devices types.Devices, profile bool, expanded bool) e
 		} else if m["type"] == "gpu" {
 			// Probably no checks needed, since we allow users to
 			// pass in all GPUs.
+		} else if m["type"] == "proxy" {
+			if m["listen"] == "" || m["connect"] == "" {
+				return fmt.Errorf("Proxy device entry is missing the required \"listen\" or \"connect\" property.")
+			}
+
+			listenProto, listenAddr, err := deviceProxyParseAddr(m["listen"])
+			if err != nil {
+				return err
+			}
+
+			if listenProto == "unix" && !strings.HasPrefix(listenAddr, "@") {
+				return fmt.Errorf("Proxy devices can only listen on abstract unix sockets (\"unix:@<name>\").")
+			}
+
+			connectProto, _, err := deviceProxyParseAddr(m["connect"])
+			if err != nil {
+				return err
+			}
+
+			if (listenProto == "udp") != (connectProto == "udp") {
+				return fmt.Errorf("Proxy devices can't forward between datagram (udp) and stream (tcp, unix) sockets.")
+			}
 		} else if m["type"] == "none" {
 			continue
 		} else {
@@ -455,10 +525,12 @@ type container interface {
 	// Hooks
 	OnStart() error
 	OnStop(target string) error
+	OnNetworkUp(deviceName string, hostName string) error
 
 	// Properties
 	Id() int
 	Name() string
+	Project() string
 	Architecture() int
 	CreationDate() time.Time
 	LastUsedDate() time.Time
@@ -532,8 +604,14 @@ func containerCreateEmptySnapshot(d *Daemon, args containerArgs) (container, err
 }
 
 func containerCreateFromImage(d *Daemon, args containerArgs, hash string) (container, error) {
+	containerProject, _ := projectSplitName(args.Name)
+	project, err := imageProject(d.db, containerProject)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get the image properties
-	_, img, err := dbImageGet(d.db, hash, false, false)
+	_, img, err := dbImageGet(d.db, project, hash, false, false)
 	if err != nil {
 		return nil, err
 	}
@@ -679,7 +757,8 @@ func containerCreateInternal(d *Daemon, args containerArgs) (container, error) {
 
 	// Validate container name
 	if args.Ctype == cTypeRegular {
-		err := containerValidName(args.Name)
+		_, name := projectSplitName(args.Name)
+		err := containerValidName(name)
 		if err != nil {
 			return nil, err
 		}
@@ -704,7 +783,13 @@ func containerCreateInternal(d *Daemon, args containerArgs) (container, error) {
 	}
 
 	// Validate profiles
-	profiles, err := dbProfiles(d.db)
+	project, _ := projectSplitName(args.Name)
+	profilesProject, err := profileProject(d.db, project)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles, err := dbProfiles(d.db, profilesProject)
 	if err != nil {
 		return nil, err
 	}
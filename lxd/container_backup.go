@@ -0,0 +1,666 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxd/types"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/osarch"
+)
+
+/*
+ * A backup is a (compressed) tarball with the following layout:
+ *
+ *  backup/index.yaml            Description of the backup (backupIndex)
+ *  backup/backup.yaml           Container and snapshots configuration
+ *  backup/container/            Container directory (rootfs, templates, ...)
+ *  backup/snapshots/<name>/     Snapshot directories
+ *
+ * For optimized backups, the container and snapshot directories are
+ * replaced by backup/container.bin and backup/snapshots/<name>.bin which
+ * hold the output of the storage driver (btrfs send or zfs send).
+ */
+type backupIndex struct {
+	Name      string   `yaml:"name"`
+	Backend   string   `yaml:"backend"`
+	Pool      string   `yaml:"pool"`
+	Optimized bool     `yaml:"optimized"`
+	Snapshots []string `yaml:"snapshots,omitempty"`
+}
+
+func backupPath(containerName string, backupName string) string {
+	return shared.VarPath("backups", containerName, backupName)
+}
+
+func storageSupportsOptimizedBackups(sType storageType) bool {
+	return sType == storageTypeBtrfs || sType == storageTypeZfs
+}
+
+func backupCreate(d *Daemon, c container, backup api.ContainerBackup) error {
+	// Prepare a working directory
+	tmpPath, err := ioutil.TempDir(shared.VarPath("backups"), "lxd_backup_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpPath)
+
+	backupDir := filepath.Join(tmpPath, "backup")
+	err = os.MkdirAll(backupDir, 0700)
+	if err != nil {
+		return err
+	}
+
+	// Don't unmount the container if someone else mounted it
+	storagePool, err := c.StoragePool()
+	if err != nil {
+		return err
+	}
+
+	mountedBefore := shared.IsMountPoint(getContainerMountPoint(storagePool, c.Name()))
+	err = c.StorageStart()
+	if err != nil {
+		return err
+	}
+	if !mountedBefore {
+		defer c.StorageStop()
+	}
+
+	// Make sure the backup.yaml file is up to date
+	err = writeBackupFile(c)
+	if err != nil {
+		return err
+	}
+
+	err = shared.FileCopy(filepath.Join(c.Path(), "backup.yaml"), filepath.Join(backupDir, "backup.yaml"))
+	if err != nil {
+		return err
+	}
+
+	snapshots := []container{}
+	if !backup.ContainerOnly {
+		snapshots, err = c.Snapshots()
+		if err != nil {
+			return err
+		}
+	}
+
+	// The backup doesn't record the project so it can be imported anywhere
+	_, name := projectSplitName(c.Name())
+
+	index := backupIndex{
+		Name:      name,
+		Backend:   c.Storage().GetStorageTypeName(),
+		Pool:      storagePool,
+		Optimized: backup.OptimizedStorage && storageSupportsOptimizedBackups(c.Storage().GetStorageType()),
+	}
+
+	for _, snap := range snapshots {
+		fields := strings.SplitN(snap.Name(), shared.SnapshotDelimiter, 2)
+		index.Snapshots = append(index.Snapshots, fields[1])
+	}
+
+	if index.Optimized {
+		err = c.Storage().ContainerBackupDump(c, snapshots, backupDir)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, snap := range snapshots {
+			fields := strings.SplitN(snap.Name(), shared.SnapshotDelimiter, 2)
+
+			err := snap.StorageStart()
+			if err != nil {
+				return err
+			}
+
+			output, err := storageRsyncCopy(snap.Path(), filepath.Join(backupDir, "snapshots", fields[1]))
+			snap.StorageStop()
+			if err != nil {
+				return fmt.Errorf("Failed to copy snapshot \"%s\": %s", snap.Name(), output)
+			}
+		}
+
+		output, err := storageRsyncCopy(c.Path(), filepath.Join(backupDir, "container"))
+		if err != nil {
+			return fmt.Errorf("Failed to copy container \"%s\": %s", c.Name(), output)
+		}
+	}
+
+	data, err := yaml.Marshal(&index)
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(filepath.Join(backupDir, "index.yaml"), data, 0600)
+	if err != nil {
+		return err
+	}
+
+	// Create the tarball
+	target := backupPath(c.Name(), backup.Name)
+	err = os.MkdirAll(filepath.Dir(target), 0700)
+	if err != nil {
+		return err
+	}
+
+	tarball := filepath.Join(tmpPath, "backup.tar")
+	output, err := exec.Command("tar", "-cpf", tarball, "--numeric-owner", "--xattrs", "-C", tmpPath, "backup").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Failed to create the backup tarball: %s", output)
+	}
+
+	compress := daemonConfig["images.compression_algorithm"].Get()
+	if compress != "none" {
+		compressed, err := compressFile(tarball, compress)
+		if err != nil {
+			return err
+		}
+
+		tarball = compressed
+	}
+
+	return os.Rename(tarball, target)
+}
+
+// backupReadIndex extracts the index of a backup tarball.
+func backupReadIndex(path string) (*backupIndex, error) {
+	extractArgs, extension, err := detectCompression(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(extension, ".tar") {
+		return nil, fmt.Errorf("Unsupported backup format: %s", extension)
+	}
+
+	args := append(extractArgs, path, "-O", "backup/index.yaml")
+	data, err := exec.Command("tar", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the backup index: %v", err)
+	}
+
+	index := backupIndex{}
+	err = yaml.Unmarshal(data, &index)
+	if err != nil {
+		return nil, err
+	}
+
+	return &index, nil
+}
+
+// backupContainerArgs turns a container or snapshot from a backup.yaml file
+// into arguments suitable to recreate it, on the given storage pool.
+func backupContainerArgs(name string, arch string, config map[string]string, devices types.Devices, pool string) (containerArgs, error) {
+	args := containerArgs{Name: name}
+
+	archID, err := osarch.ArchitectureId(arch)
+	if err != nil {
+		return args, err
+	}
+	args.Architecture = archID
+
+	// Drop the volatile keys, except for the ones describing the on-disk
+	// state of the container
+	args.BaseImage = config["volatile.base_image"]
+	for k := range config {
+		if strings.HasPrefix(k, "volatile.") && k != "volatile.last_state.idmap" {
+			delete(config, k)
+		}
+	}
+	args.Config = config
+
+	if devices == nil {
+		devices = types.Devices{}
+	}
+
+	// Move the root disk to the target pool
+	if pool != "" {
+		rootDevName, _, err := containerGetRootDiskDevice(devices)
+		if err != nil {
+			devices["root"] = types.Device{"type": "disk", "path": "/", "pool": pool}
+		} else {
+			devices[rootDevName]["pool"] = pool
+		}
+	}
+	args.Devices = devices
+
+	return args, nil
+}
+
+func backupLoad(d *Daemon, path string, index *backupIndex, pool string) error {
+	// Unpack the backup
+	tmpPath, err := ioutil.TempDir(shared.VarPath("backups"), "lxd_restore_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpPath)
+
+	sType := storageTypeDir
+	if pool != "" {
+		_, poolInfo, err := dbStoragePoolGet(d.db, pool)
+		if err != nil {
+			return err
+		}
+
+		sType, err = storageStringToType(poolInfo.Driver)
+		if err != nil {
+			return err
+		}
+
+		if index.Optimized && poolInfo.Driver != index.Backend {
+			return fmt.Errorf("Optimized backups can only be restored on a \"%s\" storage pool", index.Backend)
+		}
+	}
+
+	err = unpack(d, path, tmpPath, sType)
+	if err != nil {
+		return err
+	}
+
+	backupDir := filepath.Join(tmpPath, "backup")
+	sf, err := slurpBackupFile(filepath.Join(backupDir, "backup.yaml"))
+	if err != nil {
+		return err
+	}
+
+	// Create the container
+	args, err := backupContainerArgs(index.Name, sf.Container.Architecture, sf.Container.Config, sf.Container.Devices, pool)
+	if err != nil {
+		return err
+	}
+	args.Ctype = cTypeRegular
+	args.CreationDate = sf.Container.CreatedAt
+	args.LastUsedDate = sf.Container.LastUsedAt
+	args.Ephemeral = sf.Container.Ephemeral
+	args.Profiles = sf.Container.Profiles
+	args.Stateful = sf.Container.Stateful
+
+	c, err := containerCreateAsEmpty(d, args)
+	if err != nil {
+		return err
+	}
+
+	success := false
+	defer func() {
+		if !success {
+			c.Delete()
+		}
+	}()
+
+	if index.Optimized && c.Storage().GetStorageTypeName() != index.Backend {
+		return fmt.Errorf("Optimized backups can only be restored on a \"%s\" storage pool", index.Backend)
+	}
+
+	snapshotsArgs := []containerArgs{}
+	for _, snap := range sf.Snapshots {
+		fields := strings.SplitN(snap.Name, shared.SnapshotDelimiter, 2)
+		if len(fields) != 2 || !shared.StringInSlice(fields[1], index.Snapshots) {
+			continue
+		}
+
+		snapArgs, err := backupContainerArgs(c.Name()+shared.SnapshotDelimiter+fields[1], snap.Architecture, snap.Config, snap.Devices, pool)
+		if err != nil {
+			return err
+		}
+		snapArgs.Ctype = cTypeSnapshot
+		snapArgs.CreationDate = snap.CreationDate
+		snapArgs.LastUsedDate = snap.LastUsedDate
+		snapArgs.Ephemeral = snap.Ephemeral
+		snapArgs.Profiles = snap.Profiles
+		snapArgs.Stateful = snap.Stateful
+
+		snapshotsArgs = append(snapshotsArgs, snapArgs)
+	}
+
+	if index.Optimized {
+		snapshots := []container{}
+		for _, snapArgs := range snapshotsArgs {
+			snap, err := containerCreateInternal(d, snapArgs)
+			if err != nil {
+				return err
+			}
+
+			snapshots = append(snapshots, snap)
+		}
+
+		err = c.Storage().ContainerBackupLoad(c, snapshots, backupDir)
+		if err != nil {
+			return err
+		}
+
+		success = true
+		return nil
+	}
+
+	err = c.StorageStart()
+	if err != nil {
+		return err
+	}
+	defer c.StorageStop()
+
+	isDirBackend := c.Storage().GetStorageType() == storageTypeDir
+	for _, snapArgs := range snapshotsArgs {
+		fields := strings.SplitN(snapArgs.Name, shared.SnapshotDelimiter, 2)
+		snapshotDir := filepath.Join(backupDir, "snapshots", fields[1])
+
+		if isDirBackend {
+			snap, err := containerCreateEmptySnapshot(d, snapArgs)
+			if err != nil {
+				return err
+			}
+
+			output, err := storageRsyncCopy(snapshotDir, shared.AddSlash(snap.Path()))
+			if err != nil {
+				return fmt.Errorf("Failed to restore snapshot \"%s\": %s", snap.Name(), output)
+			}
+
+			continue
+		}
+
+		output, err := storageRsyncCopy(snapshotDir, shared.AddSlash(c.Path()))
+		if err != nil {
+			return fmt.Errorf("Failed to restore snapshot \"%s\": %s", snapArgs.Name, output)
+		}
+
+		_, err = containerCreateAsSnapshot(d, snapArgs, c)
+		if err != nil {
+			return err
+		}
+	}
+
+	output, err := storageRsyncCopy(filepath.Join(backupDir, "container"), shared.AddSlash(c.Path()))
+	if err != nil {
+		return fmt.Errorf("Failed to restore container \"%s\": %s", c.Name(), output)
+	}
+
+	success = true
+	return nil
+}
+
+func createFromBackup(d *Daemon, project string, data io.Reader, pool string) Response {
+	// Write the data to a temporary file
+	f, err := ioutil.TempFile(shared.VarPath("backups"), "lxd_backup_")
+	if err != nil {
+		return InternalError(err)
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, data)
+	if err != nil {
+		os.Remove(f.Name())
+		return InternalError(err)
+	}
+
+	index, err := backupReadIndex(f.Name())
+	if err != nil {
+		os.Remove(f.Name())
+		return BadRequest(err)
+	}
+	index.Name = projectPrefix(project, index.Name)
+
+	_, err = dbContainerId(d.db, index.Name)
+	if err == nil {
+		os.Remove(f.Name())
+		return BadRequest(fmt.Errorf("Container '%s' already exists", index.Name))
+	}
+
+	// Use the original pool if it exists and none was provided
+	if pool == "" {
+		_, _, err := dbStoragePoolGet(d.db, index.Pool)
+		if err == nil {
+			pool = index.Pool
+		}
+	}
+
+	run := func(op *operation) error {
+		defer os.Remove(f.Name())
+		return backupLoad(d, f.Name(), index, pool)
+	}
+
+	resources := map[string][]string{}
+	resources["containers"] = []string{index.Name}
+
+	op, err := operationCreate(operationClassTask, resources, nil, run, nil, nil)
+	if err != nil {
+		os.Remove(f.Name())
+		return InternalError(err)
+	}
+
+	return OperationResponse(op)
+}
+
+func containerBackupsGet(d *Daemon, r *http.Request) Response {
+	name := projectPrefix(projectParam(r), mux.Vars(r)["name"])
+
+	recursion, err := strconv.Atoi(r.FormValue("recursion"))
+	if err != nil {
+		recursion = 0
+	}
+
+	c, err := containerLoadByName(d, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	backups, err := dbContainerBackupsList(d.db, c.Id())
+	if err != nil {
+		return SmartError(err)
+	}
+
+	resultString := []string{}
+	resultMap := []*api.ContainerBackup{}
+
+	for _, backupName := range backups {
+		if recursion == 0 {
+			url := projectContainerURL(name, "backups", backupName)
+			resultString = append(resultString, url)
+			continue
+		}
+
+		_, backup, err := dbContainerBackupGet(d.db, c.Id(), backupName)
+		if err != nil {
+			continue
+		}
+
+		resultMap = append(resultMap, backup)
+	}
+
+	if recursion == 0 {
+		return SyncResponse(true, resultString)
+	}
+
+	return SyncResponse(true, resultMap)
+}
+
+func containerBackupsPost(d *Daemon, r *http.Request) Response {
+	name := projectPrefix(projectParam(r), mux.Vars(r)["name"])
+
+	c, err := containerLoadByName(d, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	req := api.ContainerBackupsPost{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	backups, err := dbContainerBackupsList(d.db, c.Id())
+	if err != nil {
+		return SmartError(err)
+	}
+
+	if req.Name == "" {
+		// come up with a name
+		for i := 0; ; i++ {
+			req.Name = fmt.Sprintf("backup%d", i)
+			if !shared.StringInSlice(req.Name, backups) {
+				break
+			}
+		}
+	}
+
+	if strings.Contains(req.Name, "/") {
+		return BadRequest(fmt.Errorf("Backup names may not contain slashes"))
+	}
+
+	if shared.StringInSlice(req.Name, backups) {
+		return BadRequest(fmt.Errorf("Backup '%s' already exists", req.Name))
+	}
+
+	backup := api.ContainerBackup{
+		Name:             req.Name,
+		CreationDate:     time.Now().UTC(),
+		ContainerOnly:    req.ContainerOnly,
+		OptimizedStorage: req.OptimizedStorage,
+	}
+
+	run := func(op *operation) error {
+		err := backupCreate(d, c, backup)
+		if err != nil {
+			os.Remove(backupPath(c.Name(), backup.Name))
+			return err
+		}
+
+		return dbContainerBackupCreate(d.db, c.Id(), backup)
+	}
+
+	resources := map[string][]string{}
+	resources["containers"] = []string{name}
+
+	op, err := operationCreate(operationClassTask, resources, nil, run, nil, nil)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return OperationResponse(op)
+}
+
+func containerBackupHandler(d *Daemon, r *http.Request) Response {
+	name := projectPrefix(projectParam(r), mux.Vars(r)["name"])
+	backupName := mux.Vars(r)["backupName"]
+
+	c, err := containerLoadByName(d, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	id, backup, err := dbContainerBackupGet(d.db, c.Id(), backupName)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	switch r.Method {
+	case "GET":
+		return SyncResponse(true, backup)
+	case "POST":
+		return containerBackupPost(d, r, c, id, backup)
+	case "DELETE":
+		return containerBackupDelete(d, c, id, backup)
+	default:
+		return NotFound
+	}
+}
+
+func containerBackupPost(d *Daemon, r *http.Request, c container, id int, backup *api.ContainerBackup) Response {
+	req := api.ContainerBackupPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	if req.Name == "" || strings.Contains(req.Name, "/") {
+		return BadRequest(fmt.Errorf("Invalid backup name '%s'", req.Name))
+	}
+
+	_, _, err = dbContainerBackupGet(d.db, c.Id(), req.Name)
+	if err == nil {
+		return Conflict
+	}
+
+	rename := func(op *operation) error {
+		err := os.Rename(backupPath(c.Name(), backup.Name), backupPath(c.Name(), req.Name))
+		if err != nil {
+			return err
+		}
+
+		return dbContainerBackupRename(d.db, id, req.Name)
+	}
+
+	resources := map[string][]string{}
+	resources["containers"] = []string{c.Name()}
+
+	op, err := operationCreate(operationClassTask, resources, nil, rename, nil, nil)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return OperationResponse(op)
+}
+
+func containerBackupDelete(d *Daemon, c container, id int, backup *api.ContainerBackup) Response {
+	remove := func(op *operation) error {
+		err := os.Remove(backupPath(c.Name(), backup.Name))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return dbContainerBackupRemove(d.db, id)
+	}
+
+	resources := map[string][]string{}
+	resources["containers"] = []string{c.Name()}
+
+	op, err := operationCreate(operationClassTask, resources, nil, remove, nil, nil)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return OperationResponse(op)
+}
+
+func containerBackupExportGet(d *Daemon, r *http.Request) Response {
+	name := projectPrefix(projectParam(r), mux.Vars(r)["name"])
+	backupName := mux.Vars(r)["backupName"]
+
+	c, err := containerLoadByName(d, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	_, backup, err := dbContainerBackupGet(d.db, c.Id(), backupName)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	path := backupPath(c.Name(), backup.Name)
+	_, ext, err := detectCompression(path)
+	if err != nil {
+		ext = ""
+	}
+
+	// Don't leak the project prefix into the file name
+	_, containerName := projectSplitName(c.Name())
+
+	files := make([]fileResponseEntry, 1)
+	files[0].identifier = "backup"
+	files[0].path = path
+	files[0].filename = fmt.Sprintf("%s-%s%s", containerName, backup.Name, ext)
+
+	return FileResponse(r, files, nil, false)
+}
@@ -17,7 +17,6 @@ import (
 
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
-	"github.com/lxc/lxd/shared/version"
 
 	log "gopkg.in/inconshreveable/log15.v2"
 )
@@ -315,7 +314,7 @@ func (s *execWs) Do(op *operation) error {
 }
 
 func containerExecPost(d *Daemon, r *http.Request) Response {
-	name := mux.Vars(r)["name"]
+	name := projectPrefix(projectParam(r), mux.Vars(r)["name"])
 	c, err := containerLoadByName(d, name)
 	if err != nil {
 		return SmartError(err)
@@ -453,8 +452,8 @@ func containerExecPost(d *Daemon, r *http.Request) Response {
 			// Update metadata with the right URLs
 			metadata["return"] = cmdResult
 			metadata["output"] = shared.Jmap{
-				"1": fmt.Sprintf("/%s/containers/%s/logs/%s", version.APIVersion, c.Name(), filepath.Base(stdout.Name())),
-				"2": fmt.Sprintf("/%s/containers/%s/logs/%s", version.APIVersion, c.Name(), filepath.Base(stderr.Name())),
+				"1": projectContainerURL(c.Name(), "logs", filepath.Base(stdout.Name())),
+				"2": projectContainerURL(c.Name(), "logs", filepath.Base(stderr.Name())),
 			}
 		} else {
 			cmdResult, _, cmdErr = c.Exec(post.Command, env, nil, nil, nil, true)
@@ -7,7 +7,7 @@ import (
 )
 
 func containerGet(d *Daemon, r *http.Request) Response {
-	name := mux.Vars(r)["name"]
+	name := projectPrefix(projectParam(r), mux.Vars(r)["name"])
 	c, err := containerLoadByName(d, name)
 	if err != nil {
 		return SmartError(err)
@@ -10,7 +10,6 @@ import (
 	"github.com/gorilla/mux"
 
 	"github.com/lxc/lxd/shared"
-	"github.com/lxc/lxd/shared/version"
 )
 
 func containerLogsGet(d *Daemon, r *http.Request) Response {
@@ -27,6 +26,8 @@ func containerLogsGet(d *Daemon, r *http.Request) Response {
 		return BadRequest(err)
 	}
 
+	name = projectPrefix(projectParam(r), name)
+
 	result := []string{}
 
 	dents, err := ioutil.ReadDir(shared.LogPath(name))
@@ -39,7 +40,7 @@ func containerLogsGet(d *Daemon, r *http.Request) Response {
 			continue
 		}
 
-		result = append(result, fmt.Sprintf("/%s/containers/%s/logs/%s", version.APIVersion, name, f.Name()))
+		result = append(result, projectContainerURL(name, "logs", f.Name()))
 	}
 
 	return SyncResponse(true, result)
@@ -68,6 +69,7 @@ func containerLogGet(d *Daemon, r *http.Request) Response {
 	if err := containerValidName(name); err != nil {
 		return BadRequest(err)
 	}
+	name = projectPrefix(projectParam(r), name)
 
 	if !validLogFileName(file) {
 		return BadRequest(fmt.Errorf("log file name %s not valid", file))
@@ -88,6 +90,7 @@ func containerLogDelete(d *Daemon, r *http.Request) Response {
 	if err := containerValidName(name); err != nil {
 		return BadRequest(err)
 	}
+	name = projectPrefix(projectParam(r), name)
 
 	if !validLogFileName(file) {
 		return BadRequest(fmt.Errorf("log file name %s not valid", file))
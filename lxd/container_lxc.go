@@ -8,6 +8,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net"
+	"net/url"
 	"os"
 	"os/exec"
 	"path"
@@ -98,6 +99,25 @@ func (op *lxcContainerOperation) Done(err error) {
 var lxcContainerOperationsLock sync.Mutex
 var lxcContainerOperations map[int]*lxcContainerOperation = make(map[int]*lxcContainerOperation)
 
+// Block I/O counters sampled for each disk device of each container. A new
+// sample is only taken once the latest one is at least lxcDiskSampleInterval
+// old, so that the rates computed between the two last samples don't depend
+// on how often the container state gets queried.
+type lxcDiskSample struct {
+	time     time.Time
+	counters api.ContainerStateDiskCounters
+}
+
+type lxcDiskSamplePair struct {
+	previous lxcDiskSample
+	latest   lxcDiskSample
+}
+
+var lxcDiskSampleInterval = 10 * time.Second
+
+var lxcDiskSamplesLock sync.Mutex
+var lxcDiskSamples map[int]map[string]*lxcDiskSamplePair = make(map[int]map[string]*lxcDiskSamplePair)
+
 // Helper functions
 func lxcSetConfigItem(c *lxc.Container, key string, value string) error {
 	if c == nil {
@@ -905,7 +925,8 @@ func (c *containerLXC) initLXC() error {
 	}
 
 	// Setup the hostname
-	err = lxcSetConfigItem(cc, "lxc.utsname", c.Name())
+	_, hostname := projectSplitName(c.Name())
+	err = lxcSetConfigItem(cc, "lxc.utsname", hostname)
 	if err != nil {
 		return err
 	}
@@ -1235,8 +1256,9 @@ func (c *containerLXC) initLXC() error {
 			vethName := ""
 			if m["host_name"] != "" {
 				vethName = m["host_name"]
-			} else if shared.IsTrue(m["security.mac_filtering"]) {
-				// We need a known device name for MAC filtering
+			} else if shared.IsTrue(m["security.mac_filtering"]) || m["security.acls"] != "" {
+				// We need a known device name for MAC filtering and
+				// network ACLs
 				vethName = deviceNextVeth()
 			}
 
@@ -1247,6 +1269,17 @@ func (c *containerLXC) initLXC() error {
 				}
 			}
 
+			// VLANs are applied by the network up hook, once LXC has
+			// attached the host side interface to the bridge. The
+			// device name is escaped as LXC runs the hook through
+			// a shell.
+			if m["nictype"] == "bridged" && (m["vlan"] != "" || m["vlan.tagged"] != "") {
+				err = lxcSetConfigItem(cc, "lxc.network.script.up", fmt.Sprintf("%s callhook %s %d network-up %s", execPath, shared.VarPath(""), c.id, url.QueryEscape(k)))
+				if err != nil {
+					return err
+				}
+			}
+
 			// MAC address
 			if m["hwaddr"] != "" {
 				err = lxcSetConfigItem(cc, "lxc.network.hwaddr", m["hwaddr"])
@@ -1393,9 +1426,14 @@ func (c *containerLXC) initStorage() error {
 func (c *containerLXC) expandConfig() error {
 	config := map[string]string{}
 
+	project, err := profileProject(c.daemon.db, c.Project())
+	if err != nil {
+		return err
+	}
+
 	// Apply all the profiles
 	for _, name := range c.profiles {
-		profileConfig, err := dbProfileConfig(c.daemon.db, name)
+		profileConfig, err := dbProfileConfig(c.daemon.db, project, name)
 		if err != nil {
 			return err
 		}
@@ -1417,9 +1455,14 @@ func (c *containerLXC) expandConfig() error {
 func (c *containerLXC) expandDevices() error {
 	devices := types.Devices{}
 
+	project, err := profileProject(c.daemon.db, c.Project())
+	if err != nil {
+		return err
+	}
+
 	// Apply all the profiles
 	for _, p := range c.profiles {
-		profileDevices, err := dbDevices(c.daemon.db, p, true)
+		profileDevices, err := dbDevices(c.daemon.db, project, p, true)
 		if err != nil {
 			return err
 		}
@@ -1707,7 +1750,14 @@ func (c *containerLXC) startCommon() (string, error) {
 				diskDevices[k] = m
 			}
 		} else if m["type"] == "nic" {
-			if m["nictype"] == "bridged" && shared.IsTrue(m["security.mac_filtering"]) {
+			if m["nictype"] == "macvlan" && m["vlan"] != "" {
+				_, err = networkVLANDevice(m["parent"], m["vlan"])
+				if err != nil {
+					return "", err
+				}
+			}
+
+			if m["nictype"] == "bridged" && (shared.IsTrue(m["security.mac_filtering"]) || m["security.acls"] != "") {
 				m, err = c.fillNetworkDevice(k, m)
 				if err != nil {
 					return "", err
@@ -1739,9 +1789,20 @@ func (c *containerLXC) startCommon() (string, error) {
 					return "", fmt.Errorf("Failed to find device name for mac_filtering")
 				}
 
-				err = c.createNetworkFilter(vethName, m["parent"], m["hwaddr"])
-				if err != nil {
-					return "", err
+				if shared.IsTrue(m["security.mac_filtering"]) {
+					err = c.createNetworkFilter(vethName, m["parent"], m["hwaddr"])
+					if err != nil {
+						return "", err
+					}
+				}
+
+				// The rules match on the interface name, so can
+				// be set before the interface gets created
+				if m["security.acls"] != "" {
+					err = networkACLsApplyNic(c.daemon, c.name, k, vethName, m["security.acls"])
+					if err != nil {
+						return "", err
+					}
 				}
 			}
 		}
@@ -1917,6 +1978,11 @@ func (c *containerLXC) Start(stateful bool) error {
 			return err
 		}
 
+		// Start the proxy devices
+		if err := c.startProxyDevices(); err != nil {
+			shared.LogError("Failed to start proxy devices", log.Ctx{"container": c.name, "err": err})
+		}
+
 		shared.LogInfo("Started container", ctxMap)
 
 		return err
@@ -1988,6 +2054,11 @@ func (c *containerLXC) Start(stateful bool) error {
 			err, lxcLog)
 	}
 
+	// Start the proxy devices
+	if err := c.startProxyDevices(); err != nil {
+		shared.LogError("Failed to start proxy devices", log.Ctx{"container": c.name, "err": err})
+	}
+
 	shared.LogInfo("Started container", ctxMap)
 
 	return nil
@@ -2223,6 +2294,23 @@ func (c *containerLXC) Shutdown(timeout time.Duration) error {
 	return nil
 }
 
+// OnNetworkUp is called by LXC once the host side interface of a bridged nic
+// has been created and attached to its bridge, failing the start of the
+// container on error.
+func (c *containerLXC) OnNetworkUp(deviceName string, hostName string) error {
+	m, ok := c.expandedDevices[deviceName]
+	if !ok || m["type"] != "nic" || m["nictype"] != "bridged" {
+		return fmt.Errorf("Unknown bridged nic device: %s", deviceName)
+	}
+
+	err := networkSetVLAN(m["parent"], hostName, m["vlan"], m["vlan.tagged"])
+	if err != nil {
+		return fmt.Errorf("Failed to apply VLAN configuration to \"%s\": %s", deviceName, err)
+	}
+
+	return nil
+}
+
 func (c *containerLXC) OnStop(target string) error {
 	// Validate target
 	if !shared.StringInSlice(target, []string{"stop", "reboot"}) {
@@ -2291,6 +2379,12 @@ func (c *containerLXC) OnStop(target string) error {
 			shared.LogError("Unable to remove disk devices", log.Ctx{"container": c.Name(), "err": err})
 		}
 
+		// Stop all the proxy devices
+		err = c.removeProxyDevices()
+		if err != nil {
+			shared.LogError("Unable to remove proxy devices", log.Ctx{"container": c.Name(), "err": err})
+		}
+
 		// Clean all network filters
 		err = c.removeNetworkFilters()
 		if err != nil {
@@ -2432,6 +2526,9 @@ func (c *containerLXC) Render() (interface{}, interface{}, error) {
 	// Prepare the ETag
 	etag := []interface{}{c.architecture, c.localConfig, c.localDevices, c.ephemeral, c.profiles}
 
+	// The project is only part of the internal name
+	_, name := projectSplitName(c.name)
+
 	if c.IsSnapshot() {
 		return &api.ContainerSnapshot{
 			Architecture:    architectureName,
@@ -2442,7 +2539,7 @@ func (c *containerLXC) Render() (interface{}, interface{}, error) {
 			ExpandedConfig:  c.expandedConfig,
 			ExpandedDevices: c.expandedDevices,
 			LastUsedDate:    c.lastUsedDate,
-			Name:            c.name,
+			Name:            name,
 			Profiles:        c.profiles,
 			Stateful:        c.stateful,
 		}, etag, nil
@@ -2457,7 +2554,7 @@ func (c *containerLXC) Render() (interface{}, interface{}, error) {
 		ct := api.Container{
 			ExpandedConfig:  c.expandedConfig,
 			ExpandedDevices: c.expandedDevices,
-			Name:            c.name,
+			Name:            name,
 			Status:          statusCode.String(),
 			StatusCode:      statusCode,
 			Stateful:        c.stateful,
@@ -2633,6 +2730,11 @@ func (c *containerLXC) Restore(sourceContainer container) error {
 }
 
 func (c *containerLXC) cleanup() {
+	// Forget about the block I/O samples
+	lxcDiskSamplesLock.Lock()
+	delete(lxcDiskSamples, c.id)
+	lxcDiskSamplesLock.Unlock()
+
 	// Unmount any leftovers
 	c.removeUnixDevices()
 	c.removeDiskDevices()
@@ -2678,6 +2780,9 @@ func (c *containerLXC) Delete() error {
 		// Clean things up
 		c.cleanup()
 
+		// Remove the container's backups
+		os.RemoveAll(shared.VarPath("backups", c.Name()))
+
 		// Delete the container from disk
 		if shared.PathExists(c.Path()) && c.storage != nil {
 			if err := c.storage.ContainerDelete(c); err != nil {
@@ -2744,7 +2849,8 @@ func (c *containerLXC) Rename(newName string) error {
 	}
 
 	// Sanity checks
-	if !c.IsSnapshot() && !shared.ValidHostname(newName) {
+	_, newBaseName := projectSplitName(newName)
+	if !c.IsSnapshot() && !shared.ValidHostname(newBaseName) {
 		return fmt.Errorf("Invalid container name")
 	}
 
@@ -2778,6 +2884,15 @@ func (c *containerLXC) Rename(newName string) error {
 			shared.LogError("Failed renaming container", ctxMap)
 			return err
 		}
+
+		// Rename the backups path
+		if shared.PathExists(shared.VarPath("backups", oldName)) {
+			err := os.Rename(shared.VarPath("backups", oldName), shared.VarPath("backups", newName))
+			if err != nil {
+				shared.LogError("Failed renaming container", ctxMap)
+				return err
+			}
+		}
 	}
 
 	// Rename the database entry
@@ -2989,7 +3104,12 @@ func (c *containerLXC) Update(args containerArgs, userRequested bool) error {
 	}
 
 	// Validate the new profiles
-	profiles, err := dbProfiles(c.daemon.db)
+	profilesProject, err := profileProject(c.daemon.db, c.Project())
+	if err != nil {
+		return err
+	}
+
+	profiles, err := dbProfiles(c.daemon.db, profilesProject)
 	if err != nil {
 		return err
 	}
@@ -3498,6 +3618,11 @@ func (c *containerLXC) Update(args containerArgs, userRequested bool) error {
 				if err != nil {
 					return err
 				}
+			} else if m["type"] == "proxy" {
+				err = c.removeProxyDevice(k)
+				if err != nil {
+					return err
+				}
 			} else if m["type"] == "usb" {
 				if usbs == nil {
 					usbs, err = deviceLoadUsb()
@@ -3590,6 +3715,11 @@ func (c *containerLXC) Update(args containerArgs, userRequested bool) error {
 				if err != nil {
 					return err
 				}
+			} else if m["type"] == "proxy" {
+				err = c.insertProxyDevice(k, m)
+				if err != nil {
+					return err
+				}
 			} else if m["type"] == "usb" {
 				if usbs == nil {
 					usbs, err = deviceLoadUsb()
@@ -3674,6 +3804,20 @@ func (c *containerLXC) Update(args containerArgs, userRequested bool) error {
 				if err != nil {
 					return err
 				}
+
+				// Refresh the network ACLs
+				err = c.setNetworkACLs(k, m)
+				if err != nil {
+					return err
+				}
+
+				// Refresh the VLAN configuration
+				if m["nictype"] == "bridged" {
+					err = c.setNetworkVLAN(k, m)
+					if err != nil {
+						return err
+					}
+				}
 			}
 		}
 
@@ -3726,7 +3870,7 @@ func (c *containerLXC) Update(args containerArgs, userRequested bool) error {
 		return err
 	}
 
-	err = dbContainerProfilesInsert(tx, c.id, args.Profiles)
+	err = dbContainerProfilesInsert(tx, c.id, profilesProject, args.Profiles)
 	if err != nil {
 		tx.Rollback()
 		return err
@@ -4786,9 +4930,124 @@ func (c *containerLXC) diskState() map[string]api.ContainerStateDisk {
 		disk[name] = api.ContainerStateDisk{Usage: usage}
 	}
 
+	// Block I/O counters and rates
+	counters := c.diskCounters()
+
+	lxcDiskSamplesLock.Lock()
+	defer lxcDiskSamplesLock.Unlock()
+
+	now := time.Now()
+	samples := lxcDiskSamples[c.id]
+	lxcDiskSamples[c.id] = map[string]*lxcDiskSamplePair{}
+
+	for name, current := range counters {
+		state := disk[name]
+		state.Counters = current
+
+		pair, ok := samples[name]
+		if !ok {
+			pair = &lxcDiskSamplePair{latest: lxcDiskSample{time: now, counters: current}}
+		} else if now.Sub(pair.latest.time) >= lxcDiskSampleInterval {
+			pair.previous = pair.latest
+			pair.latest = lxcDiskSample{time: now, counters: current}
+		}
+
+		// Counters going backwards mean the block devices changed, start over
+		if current.BytesRead < pair.latest.counters.BytesRead || current.BytesWritten < pair.latest.counters.BytesWritten {
+			pair = &lxcDiskSamplePair{latest: lxcDiskSample{time: now, counters: current}}
+		}
+
+		previous := pair.previous
+		latest := pair.latest
+		elapsed := latest.time.Sub(previous.time).Seconds()
+		if !previous.time.IsZero() && elapsed > 0 && latest.counters.BytesRead >= previous.counters.BytesRead && latest.counters.BytesWritten >= previous.counters.BytesWritten {
+			state.Rates = api.ContainerStateDiskCounters{
+				BytesRead:    int64(float64(latest.counters.BytesRead-previous.counters.BytesRead) / elapsed),
+				BytesWritten: int64(float64(latest.counters.BytesWritten-previous.counters.BytesWritten) / elapsed),
+				OpsRead:      int64(float64(latest.counters.OpsRead-previous.counters.OpsRead) / elapsed),
+				OpsWritten:   int64(float64(latest.counters.OpsWritten-previous.counters.OpsWritten) / elapsed),
+			}
+		}
+
+		disk[name] = state
+		lxcDiskSamples[c.id][name] = pair
+	}
+
 	return disk
 }
 
+// diskCounters returns the blkio counters of the block devices backing each
+// disk device of the container. Disk devices sharing a block device report
+// the same counters.
+func (c *containerLXC) diskCounters() map[string]api.ContainerStateDiskCounters {
+	counters := map[string]api.ContainerStateDiskCounters{}
+
+	if !cgBlkioController {
+		return counters
+	}
+
+	value, err := c.CGroupGet("blkio.throttle.io_service_bytes")
+	if err != nil {
+		return counters
+	}
+	serviceBytes := deviceParseBlkioStats(value)
+
+	value, err = c.CGroupGet("blkio.throttle.io_serviced")
+	if err != nil {
+		return counters
+	}
+	serviced := deviceParseBlkioStats(value)
+
+	validBlocks, err := deviceGetValidBlocks()
+	if err != nil {
+		return counters
+	}
+
+	for _, name := range c.expandedDevices.DeviceNames() {
+		d := c.expandedDevices[name]
+		if d["type"] != "disk" {
+			continue
+		}
+
+		// Resolve the path backing the disk device
+		source := d["source"]
+		if source == "" {
+			source = c.RootfsPath()
+		} else if d["pool"] != "" {
+			source = getStoragePoolVolumeMountPoint(d["pool"], source)
+		}
+
+		if !shared.PathExists(source) {
+			continue
+		}
+
+		blocks, err := deviceGetParentBlocks(source)
+		if err != nil {
+			continue
+		}
+
+		// Partitions of the same disk resolve to the same block device
+		seen := []string{}
+		device := api.ContainerStateDiskCounters{}
+		for _, block := range blocks {
+			block = deviceGetWholeBlock(block, validBlocks)
+			if block == "" || shared.StringInSlice(block, seen) {
+				continue
+			}
+			seen = append(seen, block)
+
+			device.BytesRead += serviceBytes[block][0]
+			device.BytesWritten += serviceBytes[block][1]
+			device.OpsRead += serviced[block][0]
+			device.OpsWritten += serviced[block][1]
+		}
+
+		counters[name] = device
+	}
+
+	return counters
+}
+
 func (c *containerLXC) memoryState() api.ContainerStateMemory {
 	memory := api.ContainerStateMemory{}
 
@@ -5484,6 +5743,137 @@ func (c *containerLXC) removeUnixDevices() error {
 	return nil
 }
 
+// Proxy device handling
+func (c *containerLXC) proxyPidPath(name string) string {
+	return filepath.Join(c.DevicesPath(), fmt.Sprintf("proxy.%s", strings.Replace(name, "/", "-", -1)))
+}
+
+func (c *containerLXC) insertProxyDevice(name string, m types.Device) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("Can't insert a proxy device into a stopped container")
+	}
+
+	// Setup the listening socket on the host
+	listener, err := deviceProxyListen(m["listen"])
+	if err != nil {
+		return fmt.Errorf("Failed to listen on %s: %s", m["listen"], err)
+	}
+	defer listener.Close()
+
+	logFile, err := os.OpenFile(filepath.Join(c.LogPath(), fmt.Sprintf("proxy.%s.log", name)), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	// Spawn the forwarder
+	cmd := exec.Command(
+		execPath,
+		"forkproxy",
+		fmt.Sprintf("%d", c.InitPID()),
+		m["listen"],
+		m["connect"])
+	cmd.ExtraFiles = []*os.File{listener}
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+
+	err = cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	// Reap the process once it's done
+	go cmd.Wait()
+
+	// Record the PID so it can be stopped later
+	err = os.MkdirAll(c.DevicesPath(), 0711)
+	if err != nil {
+		cmd.Process.Kill()
+		return err
+	}
+
+	err = ioutil.WriteFile(c.proxyPidPath(name), []byte(fmt.Sprintf("%d", cmd.Process.Pid)), 0600)
+	if err != nil {
+		cmd.Process.Kill()
+		return err
+	}
+
+	return nil
+}
+
+func (c *containerLXC) removeProxyDevice(name string) error {
+	return c.killProxy(c.proxyPidPath(name))
+}
+
+func (c *containerLXC) killProxy(pidPath string) error {
+	content, err := ioutil.ReadFile(pidPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+	defer os.Remove(pidPath)
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return err
+	}
+
+	// Make sure the PID wasn't recycled
+	cmdline, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil || !strings.Contains(string(cmdline), "forkproxy") {
+		return nil
+	}
+
+	return syscall.Kill(pid, syscall.SIGTERM)
+}
+
+func (c *containerLXC) startProxyDevices() error {
+	for _, name := range c.expandedDevices.DeviceNames() {
+		m := c.expandedDevices[name]
+		if m["type"] != "proxy" {
+			continue
+		}
+
+		err := c.insertProxyDevice(name, m)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *containerLXC) removeProxyDevices() error {
+	// Check that we indeed have devices to remove
+	if !shared.PathExists(c.DevicesPath()) {
+		return nil
+	}
+
+	// Load the directory listing
+	dents, err := ioutil.ReadDir(c.DevicesPath())
+	if err != nil {
+		return err
+	}
+
+	// Go through all the proxy devices
+	for _, f := range dents {
+		if !strings.HasPrefix(f.Name(), "proxy.") {
+			continue
+		}
+
+		pidPath := filepath.Join(c.DevicesPath(), f.Name())
+		err := c.killProxy(pidPath)
+		if err != nil {
+			shared.LogError("failed stopping proxy device", log.Ctx{"err": err, "path": pidPath})
+		}
+	}
+
+	return nil
+}
+
 // Network device handling
 func (c *containerLXC) createNetworkDevice(name string, m types.Device) (string, error) {
 	var dev, n1 string
@@ -5522,6 +5912,15 @@ func (c *containerLXC) createNetworkDevice(name string, m types.Device) (string,
 			if shared.PathExists(fmt.Sprintf("/proc/sys/net/ipv6/conf/%s/disable_ipv6", n1)) {
 				ioutil.WriteFile(fmt.Sprintf("/proc/sys/net/ipv6/conf/%s/disable_ipv6", n1), []byte("1"), 0644)
 			}
+
+			// Configure the VLANs of the bridge port
+			if m["vlan"] != "" || m["vlan.tagged"] != "" {
+				err = networkSetVLAN(m["parent"], n1, m["vlan"], m["vlan.tagged"])
+				if err != nil {
+					deviceRemoveInterface(n2)
+					return "", fmt.Errorf("Failed to configure the VLANs: %s", err)
+				}
+			}
 		}
 
 		dev = n2
@@ -5567,6 +5966,15 @@ func (c *containerLXC) createNetworkDevice(name string, m types.Device) (string,
 		}
 	}
 
+	// Set the network ACLs on the host side interface
+	if m["nictype"] == "bridged" && m["security.acls"] != "" {
+		err = networkACLsApplyNic(c.daemon, c.name, name, n1, m["security.acls"])
+		if err != nil {
+			deviceRemoveInterface(dev)
+			return "", err
+		}
+	}
+
 	return dev, nil
 }
 
@@ -5714,6 +6122,11 @@ func (c *containerLXC) fillNetworkDevice(name string, m types.Device) (types.Dev
 		newDevice["name"] = volatileName
 	}
 
+	// Macvlan VLANs go through a VLAN interface on top of the parent
+	if m["nictype"] == "macvlan" && m["vlan"] != "" {
+		newDevice["parent"] = fmt.Sprintf("%s.%s", m["parent"], m["vlan"])
+	}
+
 	return newDevice, nil
 }
 
@@ -5776,11 +6189,78 @@ func (c *containerLXC) removeNetworkFilters() error {
 		if err != nil {
 			return err
 		}
+
+		err = networkACLsClearNic(c.name, k)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// setNetworkVLAN applies the VLAN configuration of a bridged nic to its
+// host side interface.
+func (c *containerLXC) setNetworkVLAN(name string, m types.Device) error {
+	// Load the go-lxc struct
+	err := c.initLXC()
+	if err != nil {
+		return err
+	}
+
+	// Check that the container is running
+	if !c.IsRunning() {
+		return fmt.Errorf("Can't set VLANs on stopped container")
+	}
+
+	// Fill in some fields from volatile
+	m, err = c.fillNetworkDevice(name, m)
+	if err != nil {
+		return err
+	}
+
+	// Look for the host side interface name
+	veth := c.getHostInterface(m["name"])
+	if veth == "" {
+		return fmt.Errorf("LXC doesn't know about this device and the host_name property isn't set, can't find host side veth name")
+	}
+
+	return networkSetVLAN(m["parent"], veth, m["vlan"], m["vlan.tagged"])
+}
+
+// setNetworkACLs applies the network ACLs of a bridged nic to its host side
+// interface, replacing any previously applied rules.
+func (c *containerLXC) setNetworkACLs(name string, m types.Device) error {
+	if m["security.acls"] == "" {
+		return networkACLsClearNic(c.name, name)
+	}
+
+	// Load the go-lxc struct
+	err := c.initLXC()
+	if err != nil {
+		return err
+	}
+
+	// Check that the container is running
+	if !c.IsRunning() {
+		return fmt.Errorf("Can't set network ACLs on stopped container")
+	}
+
+	// Fill in some fields from volatile
+	m, err = c.fillNetworkDevice(name, m)
+	if err != nil {
+		return err
+	}
+
+	// Look for the host side interface name
+	veth := c.getHostInterface(m["name"])
+	if veth == "" {
+		return fmt.Errorf("LXC doesn't know about this device and the host_name property isn't set, can't find host side veth name")
+	}
+
+	return networkACLsApplyNic(c.daemon, c.name, name, veth, m["security.acls"])
+}
+
 func (c *containerLXC) insertNetworkDevice(name string, m types.Device) error {
 	// Load the go-lxc struct
 	err := c.initLXC()
@@ -5788,6 +6268,14 @@ func (c *containerLXC) insertNetworkDevice(name string, m types.Device) error {
 		return nil
 	}
 
+	// Create the VLAN interface used as the macvlan parent
+	if m["nictype"] == "macvlan" && m["vlan"] != "" {
+		_, err = networkVLANDevice(m["parent"], m["vlan"])
+		if err != nil {
+			return err
+		}
+	}
+
 	// Fill in some fields from volatile
 	m, err = c.fillNetworkDevice(name, m)
 	if err != nil {
@@ -5867,6 +6355,11 @@ func (c *containerLXC) removeNetworkDevice(name string, m types.Device) error {
 		if err != nil {
 			return err
 		}
+
+		err = networkACLsClearNic(c.name, name)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -5920,6 +6413,9 @@ func (c *containerLXC) createDiskDevice(name string, m types.Device) (string, er
 			volumeTypeName = storagePoolVolumeTypeNameCustom
 			fallthrough
 		case storagePoolVolumeTypeNameCustom:
+			// Custom volumes are looked up within the project of
+			// the container.
+			volumeName = projectPrefix(c.Project(), volumeName)
 			srcPath = shared.VarPath("storage-pools", m["pool"], volumeTypeName, volumeName)
 		case storagePoolVolumeTypeNameImage:
 			return "", fmt.Errorf("Using image storage volumes is not supported.")
@@ -5937,12 +6433,18 @@ func (c *containerLXC) createDiskDevice(name string, m types.Device) (string, er
 				volumeTypeName,
 				m["pool"], err)
 		} else if err == nil {
+			err = storagePoolVolumeAttach(c.daemon, c, m["pool"], volumeName, volumeType, name)
+			if err != nil {
+				return "", err
+			}
+
 			_, err = s.StoragePoolVolumeMount()
 			if err != nil {
 				msg := fmt.Sprintf("Could not mount storage volume \"%s\" of type \"%s\" on storage pool \"%s\": %s.",
 					volumeName,
 					volumeTypeName,
 					m["pool"], err)
+				dbStorageVolumeAttachmentRemove(c.daemon.db, c.id, name)
 				if !isOptional {
 					shared.LogErrorf(msg)
 					return "", err
@@ -6068,6 +6570,14 @@ func (c *containerLXC) removeDiskDevice(name string, m types.Device) error {
 		return fmt.Errorf("Can't remove device from stopped container")
 	}
 
+	// Forget about the storage volume attachment
+	if m["pool"] != "" {
+		err := dbStorageVolumeAttachmentRemove(c.daemon.db, c.id, name)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Figure out the paths
 	tgtPath := strings.TrimPrefix(m["path"], "/")
 	devName := fmt.Sprintf("disk.%s", strings.Replace(tgtPath, "/", "-", -1))
@@ -6097,6 +6607,12 @@ func (c *containerLXC) removeDiskDevice(name string, m types.Device) error {
 }
 
 func (c *containerLXC) removeDiskDevices() error {
+	// Forget about all the storage volume attachments
+	err := dbStorageVolumeAttachmentRemove(c.daemon.db, c.id, "")
+	if err != nil {
+		return err
+	}
+
 	// Check that we indeed have devices to remove
 	if !shared.PathExists(c.DevicesPath()) {
 		return nil
@@ -6134,31 +6650,11 @@ func (c *containerLXC) getDiskLimits() (map[string]deviceBlockLimit, error) {
 	result := map[string]deviceBlockLimit{}
 
 	// Build a list of all valid block devices
-	validBlocks := []string{}
-
-	dents, err := ioutil.ReadDir("/sys/class/block/")
+	validBlocks, err := deviceGetValidBlocks()
 	if err != nil {
 		return nil, err
 	}
 
-	for _, f := range dents {
-		fPath := filepath.Join("/sys/class/block/", f.Name())
-		if shared.PathExists(fmt.Sprintf("%s/partition", fPath)) {
-			continue
-		}
-
-		if !shared.PathExists(fmt.Sprintf("%s/dev", fPath)) {
-			continue
-		}
-
-		block, err := ioutil.ReadFile(fmt.Sprintf("%s/dev", fPath))
-		if err != nil {
-			return nil, err
-		}
-
-		validBlocks = append(validBlocks, strings.TrimSuffix(string(block), "\n"))
-	}
-
 	// Process all the limits
 	blockLimits := map[string][]deviceBlockLimit{}
 	for _, k := range c.expandedDevices.DeviceNames() {
@@ -6203,20 +6699,7 @@ func (c *containerLXC) getDiskLimits() (map[string]deviceBlockLimit, error) {
 
 		device := deviceBlockLimit{readBps: readBps, readIops: readIops, writeBps: writeBps, writeIops: writeIops}
 		for _, block := range blocks {
-			blockStr := ""
-
-			if shared.StringInSlice(block, validBlocks) {
-				// Straightforward entry (full block device)
-				blockStr = block
-			} else {
-				// Attempt to deal with a partition (guess its parent)
-				fields := strings.SplitN(block, ":", 2)
-				fields[1] = "0"
-				if shared.StringInSlice(fmt.Sprintf("%s:%s", fields[0], fields[1]), validBlocks) {
-					blockStr = fmt.Sprintf("%s:%s", fields[0], fields[1])
-				}
-			}
-
+			blockStr := deviceGetWholeBlock(block, validBlocks)
 			if blockStr == "" {
 				return nil, fmt.Errorf("Block device doesn't support quotas: %s", block)
 			}
@@ -6587,6 +7070,11 @@ func (c *containerLXC) Name() string {
 	return c.name
 }
 
+func (c *containerLXC) Project() string {
+	project, _ := projectSplitName(c.name)
+	return project
+}
+
 func (c *containerLXC) Profiles() []string {
 	return c.profiles
 }
@@ -16,7 +16,7 @@ import (
 
 func containerPatch(d *Daemon, r *http.Request) Response {
 	// Get the container
-	name := mux.Vars(r)["name"]
+	name := projectPrefix(projectParam(r), mux.Vars(r)["name"])
 	c, err := containerLoadByName(d, name)
 	if err != nil {
 		return NotFound
@@ -98,6 +98,14 @@ func containerPatch(d *Daemon, r *http.Request) Response {
 		}
 	}
 
+	restricted, _ := d.clientRestriction(r)
+	if restricted {
+		err = restrictedCheckContainer(d, c, req.Config, req.Devices, req.Profiles)
+		if err != nil {
+			return BadRequest(err)
+		}
+	}
+
 	// Update container configuration
 	args := containerArgs{
 		Architecture: architecture,
@@ -2,16 +2,24 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"os"
+	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/pborman/uuid"
 
+	"github.com/lxc/lxd/lxd/types"
+	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+
+	log "gopkg.in/inconshreveable/log15.v2"
 )
 
 func containerPost(d *Daemon, r *http.Request) Response {
-	name := mux.Vars(r)["name"]
+	name := projectPrefix(projectParam(r), mux.Vars(r)["name"])
 	c, err := containerLoadByName(d, name)
 	if err != nil {
 		return SmartError(err)
@@ -44,14 +52,32 @@ func containerPost(d *Daemon, r *http.Request) Response {
 		return OperationResponse(op)
 	}
 
+	if body.Pool != "" {
+		return containerPostPool(d, c, body)
+	}
+
+	// Check the name before it's tied to the project
+	err = containerValidName(body.Name)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	// Restricted clients may only rename to a name they were granted
+	restricted, allowed := d.clientRestriction(r)
+	if restricted && !shared.StringInSlice(body.Name, allowed) {
+		return Forbidden
+	}
+
+	newName := projectPrefix(projectParam(r), body.Name)
+
 	// Check that the name isn't already in use
-	id, _ := dbContainerId(d.db, body.Name)
+	id, _ := dbContainerId(d.db, newName)
 	if id > 0 {
 		return Conflict
 	}
 
 	run := func(*operation) error {
-		return c.Rename(body.Name)
+		return c.Rename(newName)
 	}
 
 	resources := map[string][]string{}
@@ -64,3 +90,285 @@ func containerPost(d *Daemon, r *http.Request) Response {
 
 	return OperationResponse(op)
 }
+
+func containerPostPool(d *Daemon, c container, body api.ContainerPost) Response {
+	_, baseName := projectSplitName(c.Name())
+	if body.Name != "" && body.Name != baseName {
+		return BadRequest(fmt.Errorf("Containers can't be renamed while being moved to another storage pool"))
+	}
+
+	_, err := dbStoragePoolGetID(d.db, body.Pool)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	_, poolName := c.Storage().GetContainerPoolInfo()
+	if body.Pool == poolName {
+		return BadRequest(fmt.Errorf("The container is already on storage pool \"%s\"", body.Pool))
+	}
+
+	if c.IsRunning() {
+		return BadRequest(fmt.Errorf("Containers must be stopped to be moved to another storage pool"))
+	}
+
+	run := func(op *operation) error {
+		return containerMoveToPool(d, c, body.Pool, op)
+	}
+
+	resources := map[string][]string{}
+	resources["containers"] = []string{c.Name()}
+
+	op, err := operationCreate(operationClassTask, resources, nil, run, nil, nil)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return OperationResponse(op)
+}
+
+// containerMoveDevices returns the local devices of a container or snapshot
+// with the root disk device pointed at the given storage pool.
+func containerMoveDevices(c container, pool string) (types.Devices, error) {
+	rootName, _, err := containerGetRootDiskDevice(c.ExpandedDevices())
+	if err != nil {
+		return nil, err
+	}
+
+	devices := types.Devices{}
+	for name, dev := range c.LocalDevices() {
+		devices[name] = types.Device{}
+		for k, v := range dev {
+			devices[name][k] = v
+		}
+	}
+
+	_, ok := devices[rootName]
+	if !ok {
+		devices[rootName] = types.Device{"type": "disk", "path": "/"}
+	}
+	devices[rootName]["pool"] = pool
+
+	return devices, nil
+}
+
+// containerMoveToPool moves a stopped container and its snapshots to another
+// storage pool of the same host. A temporary copy is made on the target pool
+// through the storage migration drivers, after which the database is switched
+// over to the copy and the original storage volumes are removed.
+func containerMoveToPool(d *Daemon, c container, pool string, op *operation) error {
+	name := c.Name()
+	project, _ := projectSplitName(name)
+	tmpName := projectPrefix(project, fmt.Sprintf("lxd-move-%s", strings.Replace(uuid.NewRandom().String(), "-", "", -1)[:8]))
+
+	snapshots, err := c.Snapshots()
+	if err != nil {
+		return err
+	}
+
+	// Work out the new local devices of the container and its snapshots
+	devices := map[string]types.Devices{}
+	for _, ct := range append([]container{c}, snapshots...) {
+		devices[ct.Name()], err = containerMoveDevices(ct, pool)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Create the copy on the target storage pool
+	config := map[string]string{}
+	for k, v := range c.LocalConfig() {
+		if strings.HasPrefix(k, "volatile.") && k != "volatile.base_image" && k != "volatile.last_state.idmap" {
+			continue
+		}
+		config[k] = v
+	}
+
+	args := containerArgs{
+		Architecture: c.Architecture(),
+		BaseImage:    config["volatile.base_image"],
+		Config:       config,
+		Ctype:        cTypeRegular,
+		Devices:      devices[name],
+		Ephemeral:    c.IsEphemeral(),
+		Name:         tmpName,
+		Profiles:     c.Profiles(),
+		Stateful:     c.IsStateful(),
+	}
+
+	tmp, err := containerCreateAsEmpty(d, args)
+	if err != nil {
+		return err
+	}
+
+	snapshotArgs := []*Snapshot{}
+	for _, snap := range snapshots {
+		args := snapshotToProtobuf(snap)
+
+		args.LocalDevices = []*Device{}
+		for devName, dev := range devices[snap.Name()] {
+			devName := devName
+			props := []*Config{}
+			for k, v := range dev {
+				k := k
+				v := v
+				props = append(props, &Config{Key: &k, Value: &v})
+			}
+
+			args.LocalDevices = append(args.LocalDevices, &Device{Name: &devName, Config: props})
+		}
+
+		snapshotArgs = append(snapshotArgs, args)
+	}
+
+	err = containerMoveStorage(c, tmp, snapshotArgs, op)
+	if err != nil {
+		tmp.Delete()
+		return err
+	}
+
+	snapshotNames, err := dbContainerGetSnapshots(d.db, name)
+	if err != nil {
+		tmp.Delete()
+		return err
+	}
+
+	oldStorage := c.Storage()
+	oldPoolID, _ := oldStorage.GetContainerPoolInfo()
+	newPoolID, _ := tmp.Storage().GetContainerPoolInfo()
+
+	// Move the symlinks of the original container out of the way so that
+	// the copy can be renamed, they're put back on failure.
+	symlinks := map[string]string{}
+	for _, path := range []string{shared.VarPath("containers", name), shared.VarPath("snapshots", name)} {
+		target, err := os.Readlink(path)
+		if err != nil {
+			continue
+		}
+
+		err = os.Remove(path)
+		if err != nil {
+			containerMoveRestoreSymlinks(symlinks)
+			tmp.Delete()
+			return err
+		}
+
+		symlinks[path] = target
+	}
+
+	// Switch the database over to the copy, only committing once the copy
+	// was given the name of the container. From here on, the copy is kept
+	// around on failure as it may be the only complete one left.
+	tx, err := dbBegin(d.db)
+	if err != nil {
+		containerMoveRestoreSymlinks(symlinks)
+		tmp.Delete()
+		return err
+	}
+
+	err = dbContainerPoolSwitch(tx, name, snapshotNames, tmpName, oldPoolID, newPoolID, devices)
+	if err != nil {
+		tx.Rollback()
+		containerMoveRestoreSymlinks(symlinks)
+		tmp.Delete()
+		return err
+	}
+
+	err = tmp.Storage().ContainerRename(tmp, name)
+	if err != nil {
+		tx.Rollback()
+		containerMoveRestoreSymlinks(symlinks)
+		return fmt.Errorf("Failed to rename the copy, it was kept as \"%s\": %s", tmpName, err)
+	}
+
+	err = txCommit(tx)
+	if err != nil {
+		return fmt.Errorf("Failed to switch the database over, the copy was kept on pool \"%s\" under the name of the container: %s", pool, err)
+	}
+
+	// Only now remove the original storage volumes
+	for _, snap := range snapshots {
+		err = oldStorage.ContainerSnapshotDelete(snap)
+		if err != nil {
+			shared.LogWarn("Failed to remove the original snapshot storage volume", log.Ctx{"snapshot": snap.Name(), "err": err})
+		}
+	}
+
+	err = oldStorage.ContainerDelete(c)
+	if err != nil {
+		shared.LogWarn("Failed to remove the original container storage volume", log.Ctx{"container": name, "err": err})
+	}
+
+	// Removing the original volumes also removed the symlinks, which now
+	// belong to the copy
+	err = os.Symlink(getContainerMountPoint(pool, name), shared.VarPath("containers", name))
+	if err != nil && !os.IsExist(err) {
+		return err
+	}
+
+	if shared.PathExists(getSnapshotMountPoint(pool, name)) {
+		err = os.Symlink(getSnapshotMountPoint(pool, name), shared.VarPath("snapshots", name))
+		if err != nil && !os.IsExist(err) {
+			return err
+		}
+	}
+
+	os.RemoveAll(shared.LogPath(tmpName))
+
+	c, err = containerLoadByName(d, name)
+	if err != nil {
+		return err
+	}
+
+	return writeBackupFile(c)
+}
+
+// containerMoveRestoreSymlinks puts back the symlinks of a container which
+// was being moved to another storage pool.
+func containerMoveRestoreSymlinks(symlinks map[string]string) {
+	for path, target := range symlinks {
+		os.Remove(path)
+		os.Symlink(target, path)
+	}
+}
+
+// containerMoveStorage transfers the storage volumes of a container and its
+// snapshots to the given target container through a local migration.
+func containerMoveStorage(c container, target container, snapshots []*Snapshot, op *operation) error {
+	driver, err := c.Storage().MigrationSource(c)
+	sink := target.Storage().MigrationSink
+	if err != nil || c.Storage().MigrationType() != target.Storage().MigrationType() {
+		driver, err = rsyncMigrationSource(c)
+		if err != nil {
+			return err
+		}
+
+		sink = rsyncMigrationSink
+	}
+	defer driver.Cleanup()
+
+	srcIdmap, err := c.LastIdmapSet()
+	if err != nil {
+		return err
+	}
+
+	srcConn, dstConn, err := migrationLocalConns()
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+	defer dstConn.Close()
+
+	sinkErr := make(chan error, 1)
+	go func() {
+		sinkErr <- sink(false, target, snapshots, dstConn, srcIdmap, op, false)
+	}()
+
+	err = driver.SendWhileRunning(srcConn, op)
+	if err != nil {
+		srcConn.Close()
+		<-sinkErr
+		return err
+	}
+
+	return <-sinkErr
+}
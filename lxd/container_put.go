@@ -21,7 +21,7 @@ import (
  */
 func containerPut(d *Daemon, r *http.Request) Response {
 	// Get the container
-	name := mux.Vars(r)["name"]
+	name := projectPrefix(projectParam(r), mux.Vars(r)["name"])
 	c, err := containerLoadByName(d, name)
 	if err != nil {
 		return NotFound
@@ -39,6 +39,14 @@ func containerPut(d *Daemon, r *http.Request) Response {
 		return BadRequest(err)
 	}
 
+	restricted, _ := d.clientRestriction(r)
+	if restricted {
+		err = restrictedCheckContainer(d, c, configRaw.Config, configRaw.Devices, configRaw.Profiles)
+		if err != nil {
+			return BadRequest(err)
+		}
+	}
+
 	architecture, err := osarch.ArchitectureId(configRaw.Architecture)
 	if err != nil {
 		architecture = 0
@@ -6,12 +6,13 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	log "gopkg.in/inconshreveable/log15.v2"
 
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
-	"github.com/lxc/lxd/shared/version"
 )
 
 func containerSnapshotsGet(d *Daemon, r *http.Request) Response {
@@ -21,7 +22,7 @@ func containerSnapshotsGet(d *Daemon, r *http.Request) Response {
 		recursion = 0
 	}
 
-	cname := mux.Vars(r)["name"]
+	cname := projectPrefix(projectParam(r), mux.Vars(r)["name"])
 	c, err := containerLoadByName(d, cname)
 	if err != nil {
 		return SmartError(err)
@@ -38,7 +39,7 @@ func containerSnapshotsGet(d *Daemon, r *http.Request) Response {
 	for _, snap := range snaps {
 		snapName := strings.SplitN(snap.Name(), shared.SnapshotDelimiter, 2)[1]
 		if recursion == 0 {
-			url := fmt.Sprintf("/%s/containers/%s/snapshots/%s", version.APIVersion, cname, snapName)
+			url := projectContainerURL(cname, "snapshots", snapName)
 			resultString = append(resultString, url)
 		} else {
 			render, _, err := snap.Render()
@@ -61,10 +62,13 @@ func containerSnapshotsGet(d *Daemon, r *http.Request) Response {
  * Note, the code below doesn't deal with snapshots of snapshots.
  * To do that, we'll need to weed out based on # slashes in names
  */
-func nextSnapshot(d *Daemon, name string) int {
-	base := name + shared.SnapshotDelimiter + "snap"
+// nextSnapshot returns the next free index for a snapshot of the given
+// container named after pattern (which must contain a single "%d").
+func nextSnapshot(d *Daemon, name string, pattern string) int {
+	prefix, suffix := snapshotPatternSplit(pattern)
+	base := name + shared.SnapshotDelimiter + prefix
 	length := len(base)
-	q := fmt.Sprintf("SELECT MAX(name) FROM containers WHERE type=? AND SUBSTR(name,1,?)=?")
+	q := fmt.Sprintf("SELECT name FROM containers WHERE type=? AND SUBSTR(name,1,?)=?")
 	var numstr string
 	inargs := []interface{}{cTypeSnapshot, length, base}
 	outfmt := []interface{}{numstr}
@@ -76,13 +80,8 @@ func nextSnapshot(d *Daemon, name string) int {
 
 	for _, r := range results {
 		numstr = r[0].(string)
-		if len(numstr) <= length {
-			continue
-		}
-		substr := numstr[length:]
-		var num int
-		count, err := fmt.Sscanf(substr, "%d", &num)
-		if err != nil || count != 1 {
+		num, ok := snapshotPatternIndex(numstr[length:], suffix)
+		if !ok {
 			continue
 		}
 		if num >= max {
@@ -93,8 +92,52 @@ func nextSnapshot(d *Daemon, name string) int {
 	return max
 }
 
+// snapshotPatternSplit returns the literal text before and after the "%d" of
+// a snapshot name pattern, with "%%" turned back into "%".
+func snapshotPatternSplit(pattern string) (string, string) {
+	prefix := ""
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' || i+1 >= len(pattern) {
+			prefix += string(pattern[i])
+			continue
+		}
+
+		if pattern[i+1] == 'd' {
+			suffix := strings.Replace(pattern[i+2:], "%%", "%", -1)
+			return prefix, suffix
+		}
+
+		// "%%"
+		prefix += "%"
+		i++
+	}
+
+	return prefix, ""
+}
+
+// snapshotPatternIndex returns the index found at the start of the given
+// snapshot name, once stripped of the prefix of its pattern, when the rest of
+// the name matches the suffix of the pattern.
+func snapshotPatternIndex(name string, suffix string) (int, bool) {
+	if !strings.HasSuffix(name, suffix) {
+		return 0, false
+	}
+
+	numstr := strings.TrimSuffix(name, suffix)
+	if numstr == "" || strings.Trim(numstr, "0123456789") != "" {
+		return 0, false
+	}
+
+	num, err := strconv.Atoi(numstr)
+	if err != nil {
+		return 0, false
+	}
+
+	return num, true
+}
+
 func containerSnapshotsPost(d *Daemon, r *http.Request) Response {
-	name := mux.Vars(r)["name"]
+	name := projectPrefix(projectParam(r), mux.Vars(r)["name"])
 
 	/*
 	 * snapshot is a three step operation:
@@ -131,33 +174,12 @@ func containerSnapshotsPost(d *Daemon, r *http.Request) Response {
 
 	if req.Name == "" {
 		// come up with a name
-		i := nextSnapshot(d, name)
+		i := nextSnapshot(d, name, "snap%d")
 		req.Name = fmt.Sprintf("snap%d", i)
 	}
 
-	fullName := name +
-		shared.SnapshotDelimiter +
-		req.Name
-
 	snapshot := func(op *operation) error {
-		args := containerArgs{
-			Name:         fullName,
-			Ctype:        cTypeSnapshot,
-			Config:       c.LocalConfig(),
-			Profiles:     c.Profiles(),
-			Ephemeral:    c.IsEphemeral(),
-			BaseImage:    c.ExpandedConfig()["volatile.base_image"],
-			Architecture: c.Architecture(),
-			Devices:      c.LocalDevices(),
-			Stateful:     req.Stateful,
-		}
-
-		_, err := containerCreateAsSnapshot(d, args, c)
-		if err != nil {
-			return err
-		}
-
-		return nil
+		return containerSnapshotCreate(d, c, req.Name, req.Stateful)
 	}
 
 	resources := map[string][]string{}
@@ -171,8 +193,29 @@ func containerSnapshotsPost(d *Daemon, r *http.Request) Response {
 	return OperationResponse(op)
 }
 
+func containerSnapshotCreate(d *Daemon, c container, snapshotName string, stateful bool) error {
+	args := containerArgs{
+		Name:         c.Name() + shared.SnapshotDelimiter + snapshotName,
+		Ctype:        cTypeSnapshot,
+		Config:       c.LocalConfig(),
+		Profiles:     c.Profiles(),
+		Ephemeral:    c.IsEphemeral(),
+		BaseImage:    c.ExpandedConfig()["volatile.base_image"],
+		Architecture: c.Architecture(),
+		Devices:      c.LocalDevices(),
+		Stateful:     stateful,
+	}
+
+	_, err := containerCreateAsSnapshot(d, args, c)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func snapshotHandler(d *Daemon, r *http.Request) Response {
-	containerName := mux.Vars(r)["name"]
+	containerName := projectPrefix(projectParam(r), mux.Vars(r)["name"])
 	snapshotName := mux.Vars(r)["snapshotName"]
 
 	sc, err := containerLoadByName(
@@ -272,3 +315,161 @@ func snapshotDelete(sc container, name string) Response {
 
 	return OperationResponse(op)
 }
+
+func autoCreateContainerSnapshots(d *Daemon, now time.Time) {
+	containers, err := dbContainersList(d.db, cTypeRegular)
+	if err != nil {
+		shared.LogError("Unable to retrieve the list of containers", log.Ctx{"err": err})
+		return
+	}
+
+	for _, name := range containers {
+		c, err := containerLoadByName(d, name)
+		if err != nil {
+			shared.LogError("Error loading container", log.Ctx{"err": err, "container": name})
+			continue
+		}
+
+		config := c.ExpandedConfig()
+		if config["snapshots.schedule"] == "" {
+			continue
+		}
+
+		schedule, err := shared.ParseCronSchedule(config["snapshots.schedule"])
+		if err != nil {
+			shared.LogError("Invalid snapshot schedule", log.Ctx{"err": err, "container": name})
+			continue
+		}
+
+		if !schedule.Matches(now) {
+			continue
+		}
+
+		pattern := config["snapshots.pattern"]
+		if pattern == "" {
+			pattern = "snap%d"
+		}
+
+		snapshotName := fmt.Sprintf(pattern, nextSnapshot(d, name, pattern))
+
+		snapshot := func(op *operation) error {
+			// Don't unmount the container if someone else mounted it
+			storagePool, err := c.StoragePool()
+			if err != nil {
+				return err
+			}
+
+			mountedBefore := shared.IsMountPoint(getContainerMountPoint(storagePool, c.Name()))
+			err = c.StorageStart()
+			if err != nil {
+				return err
+			}
+			if !mountedBefore {
+				defer c.StorageStop()
+			}
+
+			return containerSnapshotCreate(d, c, snapshotName, false)
+		}
+
+		resources := map[string][]string{}
+		resources["containers"] = []string{name}
+
+		op, err := operationCreate(operationClassTask, resources, nil, snapshot, nil, nil)
+		if err != nil {
+			shared.LogError("Failed to create scheduled snapshot", log.Ctx{"err": err, "container": name})
+			continue
+		}
+
+		shared.LogDebug("Creating scheduled snapshot", log.Ctx{"container": name, "snapshot": snapshotName})
+
+		chanRun, err := op.Run()
+		if err != nil {
+			shared.LogError("Failed to create scheduled snapshot", log.Ctx{"err": err, "container": name})
+			continue
+		}
+
+		err = <-chanRun
+		if err != nil {
+			shared.LogError("Failed to create scheduled snapshot", log.Ctx{"err": err, "container": name})
+		}
+	}
+}
+
+func pruneExpiredContainerSnapshots(d *Daemon, now time.Time) {
+	containers, err := dbContainersList(d.db, cTypeRegular)
+	if err != nil {
+		shared.LogError("Unable to retrieve the list of containers", log.Ctx{"err": err})
+		return
+	}
+
+	for _, name := range containers {
+		c, err := containerLoadByName(d, name)
+		if err != nil {
+			shared.LogError("Error loading container", log.Ctx{"err": err, "container": name})
+			continue
+		}
+
+		config := c.ExpandedConfig()
+		if config["snapshots.expiry"] == "" {
+			continue
+		}
+
+		pattern := config["snapshots.pattern"]
+		if pattern == "" {
+			pattern = "snap%d"
+		}
+
+		snapshots, err := c.Snapshots()
+		if err != nil {
+			shared.LogError("Unable to retrieve the list of snapshots", log.Ctx{"err": err, "container": name})
+			continue
+		}
+
+		for _, sc := range snapshots {
+			// Only consider the snapshots following the naming pattern
+			// so that manually created ones are left alone.
+			fields := strings.SplitN(sc.Name(), shared.SnapshotDelimiter, 2)
+			var num int
+			count, err := fmt.Sscanf(fields[1], pattern, &num)
+			if err != nil || count != 1 || fmt.Sprintf(pattern, num) != fields[1] {
+				continue
+			}
+
+			expiry, err := shared.GetSnapshotExpiry(sc.CreationDate(), config["snapshots.expiry"])
+			if err != nil {
+				shared.LogError("Invalid snapshot expiry", log.Ctx{"err": err, "container": name})
+				break
+			}
+
+			if expiry.After(now) {
+				continue
+			}
+
+			remove := func(op *operation) error {
+				return sc.Delete()
+			}
+
+			resources := map[string][]string{}
+			resources["containers"] = []string{sc.Name()}
+
+			op, err := operationCreate(operationClassTask, resources, nil, remove, nil, nil)
+			if err != nil {
+				shared.LogError("Failed to delete expired snapshot", log.Ctx{"err": err, "snapshot": sc.Name()})
+				continue
+			}
+
+			shared.LogDebug("Deleting expired snapshot", log.Ctx{"snapshot": sc.Name()})
+
+			chanRun, err := op.Run()
+			if err != nil {
+				shared.LogError("Failed to delete expired snapshot", log.Ctx{"err": err, "snapshot": sc.Name()})
+				continue
+			}
+
+			err = <-chanRun
+			if err != nil {
+				shared.LogError("Failed to delete expired snapshot", log.Ctx{"err": err, "snapshot": sc.Name()})
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func Test_snapshot_pattern_split(t *testing.T) {
+	tests := []struct {
+		pattern string
+		prefix  string
+		suffix  string
+	}{
+		{"snap%d", "snap", ""},
+		{"%d", "", ""},
+		{"auto-%d-daily", "auto-", "-daily"},
+		{"100%%-%d", "100%-", ""},
+		{"snap%d-%%", "snap", "-%"},
+		{"a%%d%d", "a%d", ""},
+	}
+
+	for _, test := range tests {
+		prefix, suffix := snapshotPatternSplit(test.pattern)
+		if prefix != test.prefix || suffix != test.suffix {
+			t.Errorf("%s: expected (%q, %q), got (%q, %q)", test.pattern, test.prefix, test.suffix, prefix, suffix)
+		}
+	}
+}
+
+func Test_snapshot_pattern_index(t *testing.T) {
+	pattern := "100%%-%d-%%"
+	prefix, suffix := snapshotPatternSplit(pattern)
+
+	// Names generated from the pattern are found again.
+	for _, i := range []int{0, 9, 10, 123} {
+		name := fmt.Sprintf(pattern, i)
+		if name[:len(prefix)] != prefix {
+			t.Errorf("%s: doesn't start with %q", name, prefix)
+			continue
+		}
+
+		num, ok := snapshotPatternIndex(name[len(prefix):], suffix)
+		if !ok || num != i {
+			t.Errorf("%s: expected %d, got %d (%v)", name, i, num, ok)
+		}
+	}
+
+	// Other names are ignored.
+	for _, name := range []string{"", "-%", "x-%", "1-", "1-%x", "-1-%"} {
+		_, ok := snapshotPatternIndex(name, suffix)
+		if ok {
+			t.Errorf("%q: unexpectedly matched", name)
+		}
+	}
+}
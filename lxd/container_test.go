@@ -35,6 +35,7 @@ func (suite *lxdTestSuite) TestContainer_ProfilesMulti() {
 	// Create an unprivileged profile
 	_, err := dbProfileCreate(
 		suite.d.db,
+		"default",
 		"unprivileged",
 		"unprivileged",
 		map[string]string{"security.privileged": "true"},
@@ -42,7 +43,7 @@ func (suite *lxdTestSuite) TestContainer_ProfilesMulti() {
 
 	suite.Req.Nil(err, "Failed to create the unprivileged profile.")
 	defer func() {
-		dbProfileDelete(suite.d.db, "unprivileged")
+		dbProfileDelete(suite.d.db, "default", "unprivileged")
 	}()
 
 	args := containerArgs{
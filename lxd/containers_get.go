@@ -12,7 +12,7 @@ import (
 
 func containersGet(d *Daemon, r *http.Request) Response {
 	for i := 0; i < 100; i++ {
-		result, err := doContainersGet(d, d.isRecursionRequest(r))
+		result, err := doContainersGet(d, r, projectParam(r), d.isRecursionRequest(r))
 		if err == nil {
 			return SyncResponse(true, result)
 		}
@@ -30,7 +30,10 @@ func containersGet(d *Daemon, r *http.Request) Response {
 	return InternalError(fmt.Errorf("DB is locked"))
 }
 
-func doContainersGet(d *Daemon, recursion bool) (interface{}, error) {
+func doContainersGet(d *Daemon, r *http.Request, project string, recursion bool) (interface{}, error) {
+	// Restricted clients only get to see their own containers
+	restricted, allowed := d.clientRestriction(r)
+
 	result, err := dbContainersList(d.db, cTypeRegular)
 	if err != nil {
 		return nil, err
@@ -43,14 +46,23 @@ func doContainersGet(d *Daemon, recursion bool) (interface{}, error) {
 	}
 
 	for _, container := range result {
+		containerProject, name := projectSplitName(container)
+		if containerProject != project {
+			continue
+		}
+
+		if restricted && !shared.StringInSlice(name, allowed) {
+			continue
+		}
+
 		if !recursion {
-			url := fmt.Sprintf("/%s/containers/%s", version.APIVersion, container)
+			url := fmt.Sprintf("/%s/containers/%s%s", version.APIVersion, name, projectQuery(project))
 			resultString = append(resultString, url)
 		} else {
 			c, err := doContainerGet(d, container)
 			if err != nil {
 				c = &api.Container{
-					Name:       container,
+					Name:       name,
 					Status:     api.Error.String(),
 					StatusCode: api.Error}
 			}
@@ -23,13 +23,19 @@ func createFromImage(d *Daemon, req *api.ContainersPost) Response {
 	var hash string
 	var err error
 
+	containerProject, _ := projectSplitName(req.Name)
+	project, err := imageProject(d.db, containerProject)
+	if err != nil {
+		return SmartError(err)
+	}
+
 	if req.Source.Fingerprint != "" {
 		hash = req.Source.Fingerprint
 	} else if req.Source.Alias != "" {
 		if req.Source.Server != "" {
 			hash = req.Source.Alias
 		} else {
-			_, alias, err := dbImageAliasGet(d.db, req.Source.Alias, true)
+			_, alias, err := dbImageAliasGet(d.db, project, req.Source.Alias, true)
 			if err != nil {
 				return InternalError(err)
 			}
@@ -41,7 +47,7 @@ func createFromImage(d *Daemon, req *api.ContainersPost) Response {
 			return BadRequest(fmt.Errorf("Property match is only supported for local images"))
 		}
 
-		hashes, err := dbImagesGet(d.db, false)
+		hashes, err := dbImagesGet(d.db, project, false)
 		if err != nil {
 			return InternalError(err)
 		}
@@ -49,7 +55,7 @@ func createFromImage(d *Daemon, req *api.ContainersPost) Response {
 		var image *api.Image
 
 		for _, hash := range hashes {
-			_, img, err := dbImageGet(d.db, hash, false, true)
+			_, img, err := dbImageGet(d.db, project, hash, false, true)
 			if err != nil {
 				continue
 			}
@@ -99,14 +105,14 @@ func createFromImage(d *Daemon, req *api.ContainersPost) Response {
 			}
 
 			hash, err = d.ImageDownload(
-				op, req.Source.Server, req.Source.Protocol, req.Source.Certificate, req.Source.Secret,
+				op, project, req.Source.Server, req.Source.Protocol, req.Source.Certificate, req.Source.Secret,
 				hash, true, daemonConfig["images.auto_update_cached"].GetBool(), "")
 			if err != nil {
 				return err
 			}
 		}
 
-		_, imgInfo, err := dbImageGet(d.db, hash, false, false)
+		_, imgInfo, err := dbImageGet(d.db, project, hash, false, false)
 		if err != nil {
 			return err
 		}
@@ -172,6 +178,17 @@ func createFromMigration(d *Daemon, req *api.ContainersPost) Response {
 		return NotImplemented
 	}
 
+	containerProject, _ := projectSplitName(req.Name)
+	project, err := imageProject(d.db, containerProject)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	profilesProject, err := profileProject(d.db, containerProject)
+	if err != nil {
+		return SmartError(err)
+	}
+
 	var c container
 
 	// Parse the architecture name
@@ -192,6 +209,25 @@ func createFromMigration(d *Daemon, req *api.ContainersPost) Response {
 		Profiles:     req.Profiles,
 	}
 
+	// When refreshing, the existing container is updated in place
+	// rather than created from scratch.
+	refresh := false
+	if req.Source.Refresh {
+		_, err := dbContainerId(d.db, req.Name)
+		if err == nil {
+			c, err = containerLoadByName(d, req.Name)
+			if err != nil {
+				return SmartError(err)
+			}
+
+			if c.IsRunning() {
+				return BadRequest(fmt.Errorf("Cannot refresh a running container"))
+			}
+
+			refresh = true
+		}
+	}
+
 	// Grab the container's root device if one is specified
 	storagePool := ""
 	storagePoolProfile := ""
@@ -212,7 +248,7 @@ func createFromMigration(d *Daemon, req *api.ContainersPost) Response {
 	// If we don't have a valid pool yet, look through profiles
 	if storagePool == "" {
 		for _, pName := range req.Profiles {
-			_, p, err := dbProfileGet(d.db, pName)
+			_, p, err := dbProfileGet(d.db, profilesProject, pName)
 			if err != nil {
 				return InternalError(err)
 			}
@@ -269,63 +305,84 @@ func createFromMigration(d *Daemon, req *api.ContainersPost) Response {
 		args.Devices[localRootDiskDeviceKey]["pool"] = storagePool
 	}
 
-	/* Only create a container from an image if we're going to
-	 * rsync over the top of it. In the case of a better file
-	 * transfer mechanism, let's just use that.
-	 *
-	 * TODO: we could invent some negotiation here, where if the
-	 * source and sink both have the same image, we can clone from
-	 * it, but we have to know before sending the snapshot that
-	 * we're sending the whole thing or just a delta from the
-	 * image, so one extra negotiation round trip is needed. An
-	 * alternative is to move actual container object to a later
-	 * point and just negotiate it over the migration control
-	 * socket. Anyway, it'll happen later :)
-	 */
-	_, _, err = dbImageGet(d.db, req.Source.BaseImage, false, true)
-	if err != nil {
-		c, err = containerCreateAsEmpty(d, args)
-		if err != nil {
-			return InternalError(err)
-		}
-	} else {
-		// Retrieve the future storage pool
-		cM, err := containerLXCLoad(d, args)
-		if err != nil {
-			return InternalError(err)
+	if refresh {
+		// Keep the container on the storage pool it's already on.
+		_, containerPool := c.Storage().GetContainerPoolInfo()
+		rootDiskDeviceKey, _, _ := containerGetRootDiskDevice(args.Devices)
+		if rootDiskDeviceKey != "" {
+			args.Devices[rootDiskDeviceKey]["pool"] = containerPool
 		}
 
-		_, rootDiskDevice, err := containerGetRootDiskDevice(cM.ExpandedDevices())
+		err = c.Update(args, false)
 		if err != nil {
-			return InternalError(err)
-		}
-
-		if rootDiskDevice["pool"] == "" {
-			return BadRequest(fmt.Errorf("The container's root device is missing the pool property."))
+			return SmartError(err)
 		}
+	} else {
+		/* Only create a container from an image if we're going to
+		 * rsync over the top of it. In the case of a better file
+		 * transfer mechanism, let's just use that.
+		 *
+		 * TODO: we could invent some negotiation here, where if the
+		 * source and sink both have the same image, we can clone from
+		 * it, but we have to know before sending the snapshot that
+		 * we're sending the whole thing or just a delta from the
+		 * image, so one extra negotiation round trip is needed. An
+		 * alternative is to move actual container object to a later
+		 * point and just negotiate it over the migration control
+		 * socket. Anyway, it'll happen later :)
+		 */
+		_, _, err = dbImageGet(d.db, project, req.Source.BaseImage, false, true)
+		if err != nil {
+			c, err = containerCreateAsEmpty(d, args)
+			if err != nil {
+				return InternalError(err)
+			}
+		} else {
+			// Retrieve the future storage pool
+			cM, err := containerLXCLoad(d, args)
+			if err != nil {
+				return InternalError(err)
+			}
 
-		storagePool = rootDiskDevice["pool"]
+			_, rootDiskDevice, err := containerGetRootDiskDevice(cM.ExpandedDevices())
+			if err != nil {
+				return InternalError(err)
+			}
 
-		ps, err := storagePoolInit(d, storagePool)
-		if err != nil {
-			return InternalError(err)
-		}
+			if rootDiskDevice["pool"] == "" {
+				return BadRequest(fmt.Errorf("The container's root device is missing the pool property."))
+			}
 
-		err = ps.StoragePoolCheck()
-		if err != nil {
-			return InternalError(err)
-		}
+			storagePool = rootDiskDevice["pool"]
 
-		if ps.MigrationType() == MigrationFSType_RSYNC {
-			c, err = containerCreateFromImage(d, args, req.Source.BaseImage)
+			ps, err := storagePoolInit(d, storagePool)
 			if err != nil {
 				return InternalError(err)
 			}
-		} else {
-			c, err = containerCreateAsEmpty(d, args)
+
+			err = ps.StoragePoolCheck()
 			if err != nil {
 				return InternalError(err)
 			}
+
+			if ps.MigrationType() == MigrationFSType_RSYNC {
+				c, err = containerCreateFromImage(d, args, req.Source.BaseImage)
+				if err != nil {
+					return InternalError(err)
+				}
+			} else {
+				c, err = containerCreateAsEmpty(d, args)
+				if err != nil {
+					return InternalError(err)
+				}
+			}
+		}
+	}
+
+	// Only get rid of the container on failure if we created it.
+	cleanup := func() {
+		if !refresh {
+			c.Delete()
 		}
 	}
 
@@ -333,20 +390,20 @@ func createFromMigration(d *Daemon, req *api.ContainersPost) Response {
 	if req.Source.Certificate != "" {
 		certBlock, _ := pem.Decode([]byte(req.Source.Certificate))
 		if certBlock == nil {
-			c.Delete()
+			cleanup()
 			return InternalError(fmt.Errorf("Invalid certificate"))
 		}
 
 		cert, err = x509.ParseCertificate(certBlock.Bytes)
 		if err != nil {
-			c.Delete()
+			cleanup()
 			return InternalError(err)
 		}
 	}
 
 	config, err := shared.GetTLSConfig("", "", "", cert)
 	if err != nil {
-		c.Delete()
+		cleanup()
 		return InternalError(err)
 	}
 
@@ -364,11 +421,12 @@ func createFromMigration(d *Daemon, req *api.ContainersPost) Response {
 		Secrets:   req.Source.Websockets,
 		Push:      push,
 		Live:      req.Source.Live,
+		Refresh:   refresh,
 	}
 
 	sink, err := NewMigrationSink(&migrationArgs)
 	if err != nil {
-		c.Delete()
+		cleanup()
 		return InternalError(err)
 	}
 
@@ -377,13 +435,13 @@ func createFromMigration(d *Daemon, req *api.ContainersPost) Response {
 		err = sink.Do(op)
 		if err != nil {
 			shared.LogError("Error during migration sink", log.Ctx{"err": err})
-			c.Delete()
+			cleanup()
 			return fmt.Errorf("Error transferring container data: %s", err)
 		}
 
 		err = c.TemplateApply("copy")
 		if err != nil {
-			c.Delete()
+			cleanup()
 			return err
 		}
 
@@ -496,6 +554,17 @@ func createFromCopy(d *Daemon, req *api.ContainersPost) Response {
 func containersPost(d *Daemon, r *http.Request) Response {
 	shared.LogDebugf("Responding to container create")
 
+	restricted, allowed := d.clientRestriction(r)
+
+	// Import from a backup tarball
+	if r.Header.Get("Content-Type") == "application/octet-stream" {
+		if restricted {
+			return Forbidden
+		}
+
+		return createFromBackup(d, projectParam(r), r.Body, r.Header.Get("X-LXD-pool"))
+	}
+
 	req := api.ContainersPost{}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		return BadRequest(err)
@@ -507,6 +576,8 @@ func containersPost(d *Daemon, r *http.Request) Response {
 		return BadRequest(fmt.Errorf("No storage pool found. Please create a new storage pool."))
 	}
 
+	project := projectParam(r)
+
 	if req.Name == "" {
 		cs, err := dbContainersList(d.db, cTypeRegular)
 		if err != nil {
@@ -517,7 +588,7 @@ func containersPost(d *Daemon, r *http.Request) Response {
 		for {
 			i++
 			req.Name = strings.ToLower(petname.Generate(2, "-"))
-			if !shared.StringInSlice(req.Name, cs) {
+			if !shared.StringInSlice(projectPrefix(project, req.Name), cs) {
 				break
 			}
 
@@ -536,10 +607,68 @@ func containersPost(d *Daemon, r *http.Request) Response {
 		req.Config = map[string]string{}
 	}
 
+	// Expand the instance type, explicit limits take precedence
+	if req.InstanceType != "" {
+		limits, err := instanceParseType(req.InstanceType)
+		if err != nil {
+			return BadRequest(err)
+		}
+
+		for k, v := range limits {
+			_, ok := req.Config[k]
+			if !ok {
+				req.Config[k] = v
+			}
+		}
+	}
+
 	if strings.Contains(req.Name, shared.SnapshotDelimiter) {
 		return BadRequest(fmt.Errorf("Invalid container name: '%s' is reserved for snapshots", shared.SnapshotDelimiter))
 	}
 
+	// Check the name before it's tied to the project
+	err = containerValidName(req.Name)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	// Restricted clients may only create the containers they were granted
+	if restricted {
+		if !shared.StringInSlice(req.Name, allowed) {
+			return Forbidden
+		}
+
+		if req.Source.Type == "copy" && !shared.StringInSlice(req.Source.Source, allowed) {
+			return Forbidden
+		}
+
+		// Copies start from the config of their source
+		var source container
+		if req.Source.Type == "copy" {
+			source, err = containerLoadByName(d, projectPrefix(project, req.Source.Source))
+			if err != nil {
+				return SmartError(err)
+			}
+		}
+
+		profiles := req.Profiles
+		if profiles == nil && source != nil {
+			profiles = source.Profiles()
+		} else if profiles == nil {
+			profiles = []string{"default"}
+		}
+
+		err = restrictedCheckContainer(d, source, req.Config, req.Devices, profiles)
+		if err != nil {
+			return BadRequest(err)
+		}
+	}
+
+	req.Name = projectPrefix(project, req.Name)
+	if req.Source.Type == "copy" && req.Source.Source != "" {
+		req.Source.Source = projectPrefix(project, req.Source.Source)
+	}
+
 	switch req.Source.Type {
 	case "image":
 		return createFromImage(d, &req)
@@ -69,6 +69,8 @@ type Daemon struct {
 	architectures       []int
 	BackingFs           string
 	clientCerts         []x509.Certificate
+	clientRestrictions  map[string][]string
+	metricsCerts        []x509.Certificate
 	db                  *sql.DB
 	group               string
 	IdmapSet            *shared.IdmapSet
@@ -85,6 +87,8 @@ type Daemon struct {
 
 	devlxd *net.UnixListener
 
+	dns *dnsServer
+
 	MockMode  bool
 	SetupMode bool
 
@@ -98,6 +102,7 @@ type Command struct {
 	name          string
 	untrustedGet  bool
 	untrustedPost bool
+	metricsGet    bool
 	get           func(d *Daemon, r *http.Request) Response
 	put           func(d *Daemon, r *http.Request) Response
 	post          func(d *Daemon, r *http.Request) Response
@@ -240,6 +245,24 @@ func (d *Daemon) isTrustedClient(r *http.Request) bool {
 	return false
 }
 
+// isMetricsClient returns whether the request comes from a client using a
+// certificate which is only trusted to read metrics.
+func (d *Daemon) isMetricsClient(r *http.Request) bool {
+	if r.TLS == nil {
+		return false
+	}
+
+	for i := range r.TLS.PeerCertificates {
+		for _, cert := range d.metricsCerts {
+			if bytes.Compare(r.TLS.PeerCertificates[i].Raw, cert.Raw) == 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 func isJSONRequest(r *http.Request) bool {
 	for k, vs := range r.Header {
 		if strings.ToLower(k) == "content-type" &&
@@ -285,6 +308,10 @@ func (d *Daemon) createCmd(version string, c Command) {
 			shared.LogDebug(
 				"allowing untrusted POST",
 				log.Ctx{"url": r.URL.RequestURI(), "ip": r.RemoteAddr})
+		} else if r.Method == "GET" && c.metricsGet && d.isMetricsClient(r) {
+			shared.LogDebug(
+				"allowing metrics GET",
+				log.Ctx{"url": r.URL.RequestURI(), "ip": r.RemoteAddr})
 		} else {
 			shared.LogWarn(
 				"rejecting request from untrusted client",
@@ -293,6 +320,25 @@ func (d *Daemon) createCmd(version string, c Command) {
 			return
 		}
 
+		// Restricted clients may only access their own containers
+		if !d.isAllowedRestricted(r, version, c) {
+			shared.LogWarn(
+				"rejecting request from restricted client",
+				log.Ctx{"method": r.Method, "url": r.URL.RequestURI(), "ip": r.RemoteAddr})
+			Forbidden.Render(w)
+			return
+		}
+
+		// Reject requests targeting a project which doesn't exist
+		project := r.URL.Query().Get("project")
+		if project != "" {
+			_, err := dbProjectID(d.db, project)
+			if err != nil {
+				SmartError(err).Render(w)
+				return
+			}
+		}
+
 		if debug && r.Method != "GET" && isJSONRequest(r) {
 			newBody := &bytes.Buffer{}
 			captured := &bytes.Buffer{}
@@ -544,6 +590,33 @@ func (d *Daemon) UpdateHTTPsPort(newAddress string) error {
 	return nil
 }
 
+func (d *Daemon) UpdateDNSAddress(newAddress string) error {
+	oldAddress := daemonConfig["core.dns_address"].Get()
+
+	if oldAddress == newAddress {
+		return nil
+	}
+
+	// Start the new server first so that the old one keeps running if
+	// the new address can't be bound.
+	var dns *dnsServer
+	if newAddress != "" {
+		var err error
+		dns, err = dnsServerStart(newAddress, func() (*dnsRecords, error) { return networkDNSRecords(d) })
+		if err != nil {
+			return fmt.Errorf("cannot listen on dns socket: %v", err)
+		}
+	}
+
+	if d.dns != nil {
+		d.dns.Stop()
+	}
+
+	d.dns = dns
+
+	return nil
+}
+
 func haveMacAdmin() bool {
 	c, err := capability.NewPid(0)
 	if err != nil {
@@ -755,6 +828,9 @@ func (d *Daemon) Init() error {
 	if err := os.MkdirAll(shared.CachePath(), 0700); err != nil {
 		return err
 	}
+	if err := os.MkdirAll(shared.VarPath("backups"), 0700); err != nil {
+		return err
+	}
 	if err := os.MkdirAll(shared.VarPath("containers"), 0711); err != nil {
 		return err
 	}
@@ -843,6 +919,12 @@ func (d *Daemon) Init() error {
 		return err
 	}
 
+	/* Restore the operations from the database */
+	err = operationsInit(d.db)
+	if err != nil {
+		return err
+	}
+
 	if !d.MockMode {
 		/* Read the storage pools */
 		err = d.SetupStorageDriver()
@@ -885,6 +967,19 @@ func (d *Daemon) Init() error {
 		}
 	}()
 
+	/* Operation expiry */
+	go func() {
+		t := time.NewTicker(time.Hour)
+		for {
+			err := operationsExpire(daemonConfig["core.operations_expiry"].GetInt64())
+			if err != nil {
+				shared.LogError("Failed to expire operations", log.Ctx{"err": err})
+			}
+
+			<-t.C
+		}
+	}()
+
 	/* set the initial proxy function based on config values in the DB */
 	d.proxy = shared.ProxyFromConfig(
 		daemonConfig["core.proxy_https"].Get(),
@@ -1070,6 +1165,17 @@ func (d *Daemon) Init() error {
 		}
 	}
 
+	dnsAddr := daemonConfig["core.dns_address"].Get()
+	if dnsAddr != "" {
+		dns, err := dnsServerStart(dnsAddr, func() (*dnsRecords, error) { return networkDNSRecords(d) })
+		if err != nil {
+			shared.LogError("cannot listen on dns socket, skipping...", log.Ctx{"err": err})
+		} else {
+			shared.LogInfo("Started DNS server", log.Ctx{"address": dnsAddr})
+			d.dns = dns
+		}
+	}
+
 	// Bind the REST API
 	shared.LogInfof("REST API daemon:")
 	if d.UnixSocket != nil {
@@ -1144,6 +1250,25 @@ func (d *Daemon) Ready() error {
 		}
 	}()
 
+	/* Scheduled container snapshots */
+	go func() {
+		for {
+			// Run at the start of every minute
+			now := time.Now()
+			time.Sleep(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+
+			now = time.Now().Truncate(time.Minute)
+			autoCreateContainerSnapshots(d, now)
+			pruneExpiredContainerSnapshots(d, now)
+		}
+	}()
+
+	/* Forget about volumes attached to containers which are gone */
+	err := storageVolumeAttachmentsPrune(d)
+	if err != nil {
+		shared.LogWarnf("Failed to prune storage volume attachments: %s", err)
+	}
+
 	/* Restore containers */
 	containersRestart(d)
 
@@ -1210,6 +1335,11 @@ func (d *Daemon) Stop() error {
 		}
 	}
 
+	if d.dns != nil {
+		shared.LogInfof("Stopping DNS server")
+		d.dns.Stop()
+	}
+
 	shared.LogInfof("Stopping /dev/lxd handler")
 	d.devlxd.Close()
 	shared.LogInfof("Stopped /dev/lxd handler")
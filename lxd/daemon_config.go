@@ -173,11 +173,13 @@ func (k *daemonConfigKey) GetInt64() int64 {
 func daemonConfigInit(db *sql.DB) error {
 	// Set all the keys
 	daemonConfig = map[string]*daemonConfigKey{
+		"core.dns_address":               {valueType: "string", setter: daemonConfigSetDNSAddress},
 		"core.https_address":             {valueType: "string", setter: daemonConfigSetAddress},
 		"core.https_allowed_headers":     {valueType: "string"},
 		"core.https_allowed_methods":     {valueType: "string"},
 		"core.https_allowed_origin":      {valueType: "string"},
 		"core.https_allowed_credentials": {valueType: "bool"},
+		"core.operations_expiry":         {valueType: "int", defaultValue: "24"},
 		"core.proxy_http":                {valueType: "string", setter: daemonConfigSetProxy},
 		"core.proxy_https":               {valueType: "string", setter: daemonConfigSetProxy},
 		"core.proxy_ignore_hosts":        {valueType: "string", setter: daemonConfigSetProxy},
@@ -271,6 +273,16 @@ func daemonConfigSetAddress(d *Daemon, key string, value string) (string, error)
 	return value, nil
 }
 
+func daemonConfigSetDNSAddress(d *Daemon, key string, value string) (string, error) {
+	// Update the current dns address
+	err := d.UpdateDNSAddress(value)
+	if err != nil {
+		return "", err
+	}
+
+	return value, nil
+}
+
 func daemonConfigSetProxy(d *Daemon, key string, value string) (string, error) {
 	// Get the current config
 	config := map[string]string{}
@@ -86,7 +86,7 @@ func imageLoadStreamCache(d *Daemon) error {
 
 // ImageDownload checks if we have that Image Fingerprint else
 // downloads the image from a remote server.
-func (d *Daemon) ImageDownload(op *operation, server string, protocol string, certificate string, secret string, alias string, forContainer bool, autoUpdate bool, storagePool string) (string, error) {
+func (d *Daemon) ImageDownload(op *operation, project string, server string, protocol string, certificate string, secret string, alias string, forContainer bool, autoUpdate bool, storagePool string) (string, error) {
 	var err error
 	var ss *simplestreams.SimpleStreams
 	var ctxMap log.Ctx
@@ -184,8 +184,14 @@ func (d *Daemon) ImageDownload(op *operation, server string, protocol string, ce
 		}
 	}
 
+	// Images already known to another project only need recording in
+	// this one.
+	_, imgInfo, err := dbImageGet(d.db, project, fp, false, false)
+	if err != nil {
+		imgInfo, err = imageCopyToProject(d, fp, project)
+	}
+
 	// Check if the image already exists on any storage pool.
-	_, imgInfo, err := dbImageGet(d.db, fp, false, false)
 	if err == nil {
 		shared.LogDebug("Image already exists in the db", log.Ctx{"image": fp})
 
@@ -242,7 +248,13 @@ func (d *Daemon) ImageDownload(op *operation, server string, protocol string, ce
 			shared.LogWarnf("Value transmitted over image lock semaphore?")
 		}
 
-		if _, _, err := dbImageGet(d.db, fp, false, true); err != nil {
+		// The download may have happened on behalf of another project
+		_, _, err := dbImageGet(d.db, project, fp, false, true)
+		if err != nil {
+			_, err = imageCopyToProject(d, fp, project)
+		}
+
+		if err != nil {
 			shared.LogError(
 				"Previous download didn't succeed",
 				log.Ctx{"image": fp})
@@ -371,13 +383,13 @@ func (d *Daemon) ImageDownload(op *operation, server string, protocol string, ce
 			}
 		}
 
-		_, err = imageBuildFromInfo(d, info)
+		_, err = imageBuildFromInfo(d, project, info)
 		if err != nil {
 			return "", err
 		}
 
 		if alias != fp {
-			id, _, err := dbImageGet(d.db, fp, false, true)
+			id, _, err := dbImageGet(d.db, project, fp, false, true)
 			if err != nil {
 				return "", err
 			}
@@ -546,7 +558,7 @@ func (d *Daemon) ImageDownload(op *operation, server string, protocol string, ce
 		}
 	}
 
-	_, err = imageBuildFromInfo(d, &info)
+	_, err = imageBuildFromInfo(d, project, &info)
 	if err != nil {
 		shared.LogError(
 			"Failed to create image",
@@ -556,7 +568,7 @@ func (d *Daemon) ImageDownload(op *operation, server string, protocol string, ce
 	}
 
 	if alias != fp {
-		id, _, err := dbImageGet(d.db, fp, false, true)
+		id, _, err := dbImageGet(d.db, project, fp, false, true)
 		if err != nil {
 			return "", err
 		}
@@ -33,8 +33,16 @@ CREATE TABLE IF NOT EXISTS certificates (
     type INTEGER NOT NULL,
     name VARCHAR(255) NOT NULL,
     certificate TEXT NOT NULL,
+    restricted INTEGER NOT NULL DEFAULT 0,
     UNIQUE (fingerprint)
 );
+CREATE TABLE IF NOT EXISTS certificates_containers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    certificate_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    FOREIGN KEY (certificate_id) REFERENCES certificates (id) ON DELETE CASCADE,
+    UNIQUE (certificate_id, name)
+);
 CREATE TABLE IF NOT EXISTS config (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
     key VARCHAR(255) NOT NULL,
@@ -44,13 +52,25 @@ CREATE TABLE IF NOT EXISTS config (
 CREATE TABLE IF NOT EXISTS containers (
     id INTEGER primary key AUTOINCREMENT NOT NULL,
     name VARCHAR(255) NOT NULL,
+    project_id INTEGER NOT NULL DEFAULT 1,
     architecture INTEGER NOT NULL,
     type INTEGER NOT NULL,
     ephemeral INTEGER NOT NULL DEFAULT 0,
     stateful INTEGER NOT NULL DEFAULT 0,
     creation_date DATETIME,
     last_use_date DATETIME,
-    UNIQUE (name)
+    UNIQUE (name),
+    FOREIGN KEY (project_id) REFERENCES projects (id)
+);
+CREATE TABLE IF NOT EXISTS containers_backups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    container_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    creation_date DATETIME,
+    container_only INTEGER NOT NULL DEFAULT 0,
+    optimized_storage INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (container_id) REFERENCES containers (id) ON DELETE CASCADE,
+    UNIQUE (container_id, name)
 );
 CREATE TABLE IF NOT EXISTS containers_config (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
@@ -98,15 +118,19 @@ CREATE TABLE IF NOT EXISTS images (
     expiry_date DATETIME,
     upload_date DATETIME NOT NULL,
     last_use_date DATETIME,
-    UNIQUE (fingerprint)
+    project_id INTEGER NOT NULL DEFAULT 1,
+    UNIQUE (fingerprint, project_id),
+    FOREIGN KEY (project_id) REFERENCES projects (id)
 );
 CREATE TABLE IF NOT EXISTS images_aliases (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
     name VARCHAR(255) NOT NULL,
     image_id INTEGER NOT NULL,
     description VARCHAR(255),
+    project_id INTEGER NOT NULL DEFAULT 1,
     FOREIGN KEY (image_id) REFERENCES images (id) ON DELETE CASCADE,
-    UNIQUE (name)
+    FOREIGN KEY (project_id) REFERENCES projects (id),
+    UNIQUE (name, project_id)
 );
 CREATE TABLE IF NOT EXISTS images_properties (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
@@ -138,6 +162,26 @@ CREATE TABLE IF NOT EXISTS networks_config (
     UNIQUE (network_id, key),
     FOREIGN KEY (network_id) REFERENCES networks (id) ON DELETE CASCADE
 );
+CREATE TABLE IF NOT EXISTS networks_acls (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    ingress TEXT NOT NULL,
+    egress TEXT NOT NULL,
+    UNIQUE (name)
+);
+CREATE TABLE IF NOT EXISTS operations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    uuid VARCHAR(36) NOT NULL,
+    class INTEGER NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    status INTEGER NOT NULL,
+    resources TEXT,
+    metadata TEXT,
+    err TEXT,
+    UNIQUE (uuid)
+);
 CREATE TABLE IF NOT EXISTS patches (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
     name VARCHAR(255) NOT NULL,
@@ -148,7 +192,9 @@ CREATE TABLE IF NOT EXISTS profiles (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
     name VARCHAR(255) NOT NULL,
     description TEXT,
-    UNIQUE (name)
+    project_id INTEGER NOT NULL DEFAULT 1,
+    UNIQUE (name, project_id),
+    FOREIGN KEY (project_id) REFERENCES projects (id)
 );
 CREATE TABLE IF NOT EXISTS profiles_config (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
@@ -174,6 +220,20 @@ CREATE TABLE IF NOT EXISTS profiles_devices_config (
     UNIQUE (profile_device_id, key),
     FOREIGN KEY (profile_device_id) REFERENCES profiles_devices (id) ON DELETE CASCADE
 );
+CREATE TABLE IF NOT EXISTS projects (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    UNIQUE (name)
+);
+CREATE TABLE IF NOT EXISTS projects_config (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    project_id INTEGER NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    value TEXT,
+    UNIQUE (project_id, key),
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
+);
 CREATE TABLE IF NOT EXISTS schema (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
     version INTEGER NOT NULL,
@@ -199,8 +259,10 @@ CREATE TABLE IF NOT EXISTS storage_volumes (
     name VARCHAR(255) NOT NULL,
     storage_pool_id INTEGER NOT NULL,
     type INTEGER NOT NULL,
+    project_id INTEGER NOT NULL DEFAULT 1,
     UNIQUE (storage_pool_id, name, type),
-    FOREIGN KEY (storage_pool_id) REFERENCES storage_pools (id) ON DELETE CASCADE
+    FOREIGN KEY (storage_pool_id) REFERENCES storage_pools (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects (id)
 );
 CREATE TABLE IF NOT EXISTS storage_volumes_config (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
@@ -209,6 +271,15 @@ CREATE TABLE IF NOT EXISTS storage_volumes_config (
     value TEXT,
     UNIQUE (storage_volume_id, key),
     FOREIGN KEY (storage_volume_id) REFERENCES storage_volumes (id) ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS storage_volumes_attachments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    storage_volume_id INTEGER NOT NULL,
+    container_id INTEGER NOT NULL,
+    device VARCHAR(255) NOT NULL,
+    UNIQUE (container_id, device),
+    FOREIGN KEY (storage_volume_id) REFERENCES storage_volumes (id) ON DELETE CASCADE,
+    FOREIGN KEY (container_id) REFERENCES containers (id) ON DELETE CASCADE
 );`
 
 func enableForeignKeys(conn *sqlite3.SQLiteConn) error {
@@ -245,7 +316,12 @@ func createDb(db *sql.DB) (err error) {
 		dbPatchesMarkApplied(db, p.name)
 	}
 
-	err = dbProfileCreateDefault(db)
+	err = dbProjectCreateDefault(db)
+	if err != nil {
+		return err
+	}
+
+	err = dbProfileCreateDefault(db, projectDefault)
 	if err != nil {
 		return err
 	}
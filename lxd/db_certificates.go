@@ -6,6 +6,12 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// Certificate types as stored in the database.
+const (
+	certificateTypeClient  = 1
+	certificateTypeMetrics = 2
+)
+
 // dbCertInfo is here to pass the certificates content
 // from the database around
 type dbCertInfo struct {
@@ -14,35 +20,83 @@ type dbCertInfo struct {
 	Type        int
 	Name        string
 	Certificate string
+	Restricted  bool
+	Containers  []string
 }
 
 // dbCertsGet returns all certificates from the DB as CertBaseInfo objects.
 func dbCertsGet(db *sql.DB) (certs []*dbCertInfo, err error) {
 	rows, err := dbQuery(
 		db,
-		"SELECT id, fingerprint, type, name, certificate FROM certificates",
+		"SELECT id, fingerprint, type, name, certificate, restricted FROM certificates",
 	)
 	if err != nil {
 		return certs, err
 	}
 
-	defer rows.Close()
-
 	for rows.Next() {
 		cert := new(dbCertInfo)
+		restricted := 0
 		rows.Scan(
 			&cert.ID,
 			&cert.Fingerprint,
 			&cert.Type,
 			&cert.Name,
 			&cert.Certificate,
+			&restricted,
 		)
+		cert.Restricted = restricted == 1
 		certs = append(certs, cert)
 	}
+	rows.Close()
+
+	for _, cert := range certs {
+		cert.Containers, err = dbCertContainersGet(db, cert.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	return certs, nil
 }
 
+// dbCertContainersGet returns the names of the containers a restricted
+// certificate is allowed to access.
+func dbCertContainersGet(db *sql.DB, id int) ([]string, error) {
+	var name string
+	query := "SELECT name FROM certificates_containers WHERE certificate_id=?"
+	inargs := []interface{}{id}
+	outfmt := []interface{}{name}
+
+	results, err := dbQueryScan(db, query, inargs, outfmt)
+	if err != nil {
+		return nil, err
+	}
+
+	containers := []string{}
+	for _, r := range results {
+		containers = append(containers, r[0].(string))
+	}
+
+	return containers, nil
+}
+
+func dbCertContainersSet(tx *sql.Tx, id int64, containers []string) error {
+	_, err := tx.Exec("DELETE FROM certificates_containers WHERE certificate_id=?", id)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range containers {
+		_, err = tx.Exec("INSERT INTO certificates_containers (certificate_id, name) VALUES (?, ?)", id, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // dbCertGet gets an CertBaseInfo object from the database.
 // The argument fingerprint will be queried with a LIKE query, means you can
 // pass a shortform and will get the full fingerprint.
@@ -50,6 +104,7 @@ func dbCertsGet(db *sql.DB) (certs []*dbCertInfo, err error) {
 // enforced by a UNIQUE constraint in the schema.
 func dbCertGet(db *sql.DB, fingerprint string) (cert *dbCertInfo, err error) {
 	cert = new(dbCertInfo)
+	restricted := 0
 
 	inargs := []interface{}{fingerprint + "%"}
 	outfmt := []interface{}{
@@ -58,11 +113,12 @@ func dbCertGet(db *sql.DB, fingerprint string) (cert *dbCertInfo, err error) {
 		&cert.Type,
 		&cert.Name,
 		&cert.Certificate,
+		&restricted,
 	}
 
 	query := `
 		SELECT
-			id, fingerprint, type, name, certificate
+			id, fingerprint, type, name, certificate, restricted
 		FROM
 			certificates
 		WHERE fingerprint LIKE ?`
@@ -70,6 +126,12 @@ func dbCertGet(db *sql.DB, fingerprint string) (cert *dbCertInfo, err error) {
 	if err = dbQueryRowScan(db, query, inargs, outfmt); err != nil {
 		return nil, err
 	}
+	cert.Restricted = restricted == 1
+
+	cert.Containers, err = dbCertContainersGet(db, cert.ID)
+	if err != nil {
+		return nil, err
+	}
 
 	return cert, err
 }
@@ -86,25 +148,45 @@ func dbCertSave(db *sql.DB, cert *dbCertInfo) error {
 				fingerprint,
 				type,
 				name,
-				certificate
-			) VALUES (?, ?, ?, ?)`,
+				certificate,
+				restricted
+			) VALUES (?, ?, ?, ?, ?)`,
 	)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 	defer stmt.Close()
-	_, err = stmt.Exec(
+
+	restricted := 0
+	if cert.Restricted {
+		restricted = 1
+	}
+
+	result, err := stmt.Exec(
 		cert.Fingerprint,
 		cert.Type,
 		cert.Name,
 		cert.Certificate,
+		restricted,
 	)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
+	id, err := result.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = dbCertContainersSet(tx, id, cert.Containers)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	return txCommit(tx)
 }
 
@@ -118,13 +200,29 @@ func dbCertDelete(db *sql.DB, fingerprint string) error {
 	return nil
 }
 
-func dbCertUpdate(db *sql.DB, fingerprint string, certName string, certType int) error {
+func dbCertUpdate(db *sql.DB, fingerprint string, certName string, certType int, restricted bool, containers []string) error {
+	cert, err := dbCertGet(db, fingerprint)
+	if err != nil {
+		return err
+	}
+
 	tx, err := dbBegin(db)
 	if err != nil {
 		return err
 	}
 
-	_, err = tx.Exec("UPDATE certificates SET name=?, type=? WHERE fingerprint=?", certName, certType, fingerprint)
+	restrictedInt := 0
+	if restricted {
+		restrictedInt = 1
+	}
+
+	_, err = tx.Exec("UPDATE certificates SET name=?, type=?, restricted=? WHERE fingerprint=?", certName, certType, restrictedInt, fingerprint)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = dbCertContainersSet(tx, int64(cert.ID), containers)
 	if err != nil {
 		tx.Rollback()
 		return err
@@ -7,6 +7,7 @@ import (
 
 	"github.com/lxc/lxd/lxd/types"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 
 	log "gopkg.in/inconshreveable/log15.v2"
 )
@@ -98,7 +99,7 @@ func dbContainerGet(db *sql.DB, name string) (containerArgs, error) {
 
 	/* get container_devices */
 	args.Devices = types.Devices{}
-	newdevs, err := dbDevices(db, name, false)
+	newdevs, err := dbDevices(db, "", name, false)
 	if err != nil {
 		return args, err
 	}
@@ -116,6 +117,12 @@ func dbContainerCreate(db *sql.DB, args containerArgs) (int, error) {
 		return 0, DbErrAlreadyDefined
 	}
 
+	project, _ := projectSplitName(args.Name)
+	profilesProject, err := profileProject(db, project)
+	if err != nil {
+		return 0, err
+	}
+
 	tx, err := dbBegin(db)
 	if err != nil {
 		return 0, err
@@ -134,14 +141,14 @@ func dbContainerCreate(db *sql.DB, args containerArgs) (int, error) {
 	args.CreationDate = time.Now().UTC()
 	args.LastUsedDate = time.Unix(0, 0).UTC()
 
-	str := fmt.Sprintf("INSERT INTO containers (name, architecture, type, ephemeral, creation_date, last_use_date, stateful) VALUES (?, ?, ?, ?, ?, ?, ?)")
+	str := fmt.Sprintf("INSERT INTO containers (name, architecture, type, ephemeral, creation_date, last_use_date, stateful, project_id) VALUES (?, ?, ?, ?, ?, ?, ?, (SELECT id FROM projects WHERE name=?))")
 	stmt, err := tx.Prepare(str)
 	if err != nil {
 		tx.Rollback()
 		return 0, err
 	}
 	defer stmt.Close()
-	result, err := stmt.Exec(args.Name, args.Architecture, args.Ctype, ephemInt, args.CreationDate.Unix(), args.LastUsedDate.Unix(), statefulInt)
+	result, err := stmt.Exec(args.Name, args.Architecture, args.Ctype, ephemInt, args.CreationDate.Unix(), args.LastUsedDate.Unix(), statefulInt, project)
 	if err != nil {
 		tx.Rollback()
 		return 0, err
@@ -159,7 +166,7 @@ func dbContainerCreate(db *sql.DB, args containerArgs) (int, error) {
 		return 0, err
 	}
 
-	if err := dbContainerProfilesInsert(tx, id, args.Profiles); err != nil {
+	if err := dbContainerProfilesInsert(tx, id, profilesProject, args.Profiles); err != nil {
 		tx.Rollback()
 		return 0, err
 	}
@@ -237,17 +244,18 @@ func dbContainerSetStateful(db *sql.DB, id int, stateful bool) error {
 	return err
 }
 
-func dbContainerProfilesInsert(tx *sql.Tx, id int, profiles []string) error {
+func dbContainerProfilesInsert(tx *sql.Tx, id int, project string, profiles []string) error {
 	applyOrder := 1
 	str := `INSERT INTO containers_profiles (container_id, profile_id, apply_order) VALUES
-		(?, (SELECT id FROM profiles WHERE name=?), ?);`
+		(?, (SELECT profiles.id FROM profiles JOIN projects ON profiles.project_id=projects.id
+		     WHERE projects.name=? AND profiles.name=?), ?);`
 	stmt, err := tx.Prepare(str)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 	for _, p := range profiles {
-		_, err = stmt.Exec(id, p, applyOrder)
+		_, err = stmt.Exec(id, project, p, applyOrder)
 		if err != nil {
 			shared.LogDebugf("Error adding profile %s to container: %s",
 				p, err)
@@ -395,6 +403,65 @@ func dbContainerRename(db *sql.DB, oldName string, newName string) error {
 	return txCommit(tx)
 }
 
+// dbContainerPoolSwitch moves a container and its snapshots over to the
+// storage volumes of a temporary copy made on another storage pool. The
+// storage volumes of the copy take over the names of the original ones, the
+// records of the copy are removed and the local devices of the container and
+// its snapshots are replaced by the given ones. The caller is responsible
+// for committing or rolling back the transaction.
+func dbContainerPoolSwitch(tx *sql.Tx, name string, snapshots []string, tmpName string, oldPoolID int64, newPoolID int64, devices map[string]types.Devices) error {
+	for _, volume := range append([]string{name}, snapshots...) {
+		tmpVolume := tmpName + volume[len(name):]
+
+		_, err := tx.Exec("DELETE FROM storage_volumes WHERE name=? AND storage_pool_id=? AND type=?", volume, oldPoolID, storagePoolVolumeTypeContainer)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("DELETE FROM containers WHERE name=?", tmpVolume)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("UPDATE storage_volumes SET name=? WHERE name=? AND storage_pool_id=? AND type=?", volume, tmpVolume, newPoolID, storagePoolVolumeTypeContainer)
+		if err != nil {
+			return err
+		}
+
+		volumeDevices, ok := devices[volume]
+		if !ok {
+			continue
+		}
+
+		id := -1
+		err = tx.QueryRow("SELECT id FROM containers WHERE name=?", volume).Scan(&id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`DELETE FROM containers_devices_config WHERE id IN
+			(SELECT containers_devices_config.id
+			 FROM containers_devices_config JOIN containers_devices
+			 ON containers_devices_config.container_device_id=containers_devices.id
+			 WHERE containers_devices.container_id=?)`, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("DELETE FROM containers_devices WHERE container_id=?", id)
+		if err != nil {
+			return err
+		}
+
+		err = dbDevicesAdd(tx, "container", int64(id), volumeDevices)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func dbContainerUpdate(tx *sql.Tx, id int, architecture int, ephemeral bool) error {
 	str := fmt.Sprintf("UPDATE containers SET architecture=?, ephemeral=? WHERE id=?")
 	stmt, err := tx.Prepare(str)
@@ -464,3 +531,73 @@ WHERE storage_volumes.name=? AND storage_volumes.type=?`
 
 	return poolName, nil
 }
+
+// Get the names of all the backups of a given container.
+func dbContainerBackupsList(db *sql.DB, containerID int) ([]string, error) {
+	q := "SELECT name FROM containers_backups WHERE container_id=? ORDER BY creation_date"
+	inargs := []interface{}{containerID}
+	var name string
+	outfmt := []interface{}{name}
+	dbResults, err := dbQueryScan(db, q, inargs, outfmt)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []string{}
+	for _, r := range dbResults {
+		result = append(result, r[0].(string))
+	}
+
+	return result, nil
+}
+
+func dbContainerBackupGet(db *sql.DB, containerID int, name string) (int, *api.ContainerBackup, error) {
+	id := -1
+	containerOnly := 0
+	optimizedStorage := 0
+
+	backup := api.ContainerBackup{Name: name}
+
+	q := "SELECT id, creation_date, container_only, optimized_storage FROM containers_backups WHERE container_id=? AND name=?"
+	arg1 := []interface{}{containerID, name}
+	arg2 := []interface{}{&id, &backup.CreationDate, &containerOnly, &optimizedStorage}
+	err := dbQueryRowScan(db, q, arg1, arg2)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, nil, NoSuchObjectError
+		}
+
+		return -1, nil, err
+	}
+
+	backup.ContainerOnly = containerOnly == 1
+	backup.OptimizedStorage = optimizedStorage == 1
+
+	return id, &backup, nil
+}
+
+func dbContainerBackupCreate(db *sql.DB, containerID int, backup api.ContainerBackup) error {
+	containerOnly := 0
+	if backup.ContainerOnly {
+		containerOnly = 1
+	}
+
+	optimizedStorage := 0
+	if backup.OptimizedStorage {
+		optimizedStorage = 1
+	}
+
+	_, err := dbExec(db, "INSERT INTO containers_backups (container_id, name, creation_date, container_only, optimized_storage) VALUES (?, ?, ?, ?, ?)",
+		containerID, backup.Name, backup.CreationDate.UTC(), containerOnly, optimizedStorage)
+	return err
+}
+
+func dbContainerBackupRename(db *sql.DB, id int, newName string) error {
+	_, err := dbExec(db, "UPDATE containers_backups SET name=? WHERE id=?", newName, id)
+	return err
+}
+
+func dbContainerBackupRemove(db *sql.DB, id int) error {
+	_, err := dbExec(db, "DELETE FROM containers_backups WHERE id=?", id)
+	return err
+}
@@ -17,14 +17,14 @@ var dbImageSourceProtocol = map[int]string{
 	2: "simplestreams",
 }
 
-func dbImagesGet(db *sql.DB, public bool) ([]string, error) {
-	q := "SELECT fingerprint FROM images"
+func dbImagesGet(db *sql.DB, project string, public bool) ([]string, error) {
+	q := "SELECT fingerprint FROM images WHERE project_id=(SELECT id FROM projects WHERE name=?)"
 	if public == true {
-		q = "SELECT fingerprint FROM images WHERE public=1"
+		q += " AND public=1"
 	}
 
 	var fp string
-	inargs := []interface{}{}
+	inargs := []interface{}{project}
 	outfmt := []interface{}{fp}
 	dbResults, err := dbQueryScan(db, q, inargs, outfmt)
 	if err != nil {
@@ -39,11 +39,26 @@ func dbImagesGet(db *sql.DB, public bool) ([]string, error) {
 	return results, nil
 }
 
-func dbImagesGetExpired(db *sql.DB, expiry int64) ([]string, error) {
-	q := `SELECT fingerprint FROM images WHERE cached=1 AND creation_date<=strftime('%s', date('now', '-` + fmt.Sprintf("%d", expiry) + ` day'))`
+// dbImagesCacheSize returns the number and total size of the images which
+// were cached when creating containers.
+func dbImagesCacheSize(db *sql.DB) (int64, int64, error) {
+	var count int64
+	var size int64
+
+	q := "SELECT COUNT(*), COALESCE(SUM(size), 0) FROM images WHERE cached=1"
+	err := dbQueryRowScan(db, q, []interface{}{}, []interface{}{&count, &size})
+	if err != nil {
+		return -1, -1, err
+	}
+
+	return count, size, nil
+}
+
+func dbImagesGetExpired(db *sql.DB, project string, expiry int64) ([]string, error) {
+	q := `SELECT fingerprint FROM images WHERE project_id=(SELECT id FROM projects WHERE name=?) AND cached=1 AND creation_date<=strftime('%s', date('now', '-` + fmt.Sprintf("%d", expiry) + ` day'))`
 
 	var fp string
-	inargs := []interface{}{}
+	inargs := []interface{}{project}
 	outfmt := []interface{}{fp}
 	dbResults, err := dbQueryScan(db, q, inargs, outfmt)
 	if err != nil {
@@ -108,9 +123,9 @@ func dbImageSourceGet(db *sql.DB, imageId int) (int, api.ImageSource, error) {
 // dbImageGet gets an ImageBaseInfo object from the database.
 // The argument fingerprint will be queried with a LIKE query, means you can
 // pass a shortform and will get the full fingerprint.
-// There can never be more than one image with a given fingerprint, as it is
-// enforced by a UNIQUE constraint in the schema.
-func dbImageGet(db *sql.DB, fingerprint string, public bool, strictMatching bool) (int, *api.Image, error) {
+// There can never be more than one image with a given fingerprint in a
+// project, as it is enforced by a UNIQUE constraint in the schema.
+func dbImageGet(db *sql.DB, project string, fingerprint string, public bool, strictMatching bool) (int, *api.Image, error) {
 	var err error
 	var create, expire, used, upload *time.Time // These hold the db-returned times
 
@@ -128,23 +143,23 @@ func dbImageGet(db *sql.DB, fingerprint string, public bool, strictMatching bool
 
 	var inargs []interface{}
 	if strictMatching {
-		inargs = []interface{}{fingerprint}
+		inargs = []interface{}{project, fingerprint}
 		query = `
         SELECT
             id, fingerprint, filename, size, cached, public, auto_update, architecture,
             creation_date, expiry_date, last_use_date, upload_date
         FROM
             images
-        WHERE fingerprint = ?`
+        WHERE project_id = (SELECT id FROM projects WHERE name = ?) AND fingerprint = ?`
 	} else {
-		inargs = []interface{}{fingerprint + "%"}
+		inargs = []interface{}{project, fingerprint + "%"}
 		query = `
         SELECT
             id, fingerprint, filename, size, cached, public, auto_update, architecture,
             creation_date, expiry_date, last_use_date, upload_date
         FROM
             images
-        WHERE fingerprint LIKE ?`
+        WHERE project_id = (SELECT id FROM projects WHERE name = ?) AND fingerprint LIKE ?`
 	}
 
 	if public {
@@ -158,7 +173,7 @@ func dbImageGet(db *sql.DB, fingerprint string, public bool, strictMatching bool
 
 	// Validate we only have a single match
 	if !strictMatching {
-		query = "SELECT COUNT(id) FROM images WHERE fingerprint LIKE ?"
+		query = "SELECT COUNT(id) FROM images WHERE project_id = (SELECT id FROM projects WHERE name = ?) AND fingerprint LIKE ?"
 		count := 0
 		outfmt := []interface{}{&count}
 
@@ -251,12 +266,34 @@ func dbImageDelete(db *sql.DB, id int) error {
 	return nil
 }
 
-func dbImageAliasGet(db *sql.DB, name string, isTrustedClient bool) (int, api.ImageAliasesEntry, error) {
+// dbImageProjects returns the names of the projects which have a copy of the
+// image with the given fingerprint.
+func dbImageProjects(db *sql.DB, fingerprint string) ([]string, error) {
+	q := "SELECT projects.name FROM images JOIN projects ON images.project_id=projects.id WHERE images.fingerprint=?"
+
+	var name string
+	inargs := []interface{}{fingerprint}
+	outfmt := []interface{}{name}
+	dbResults, err := dbQueryScan(db, q, inargs, outfmt)
+	if err != nil {
+		return []string{}, err
+	}
+
+	results := []string{}
+	for _, r := range dbResults {
+		results = append(results, r[0].(string))
+	}
+
+	return results, nil
+}
+
+func dbImageAliasGet(db *sql.DB, project string, name string, isTrustedClient bool) (int, api.ImageAliasesEntry, error) {
 	q := `SELECT images_aliases.id, images.fingerprint, images_aliases.description
 			 FROM images_aliases
 			 INNER JOIN images
 			 ON images_aliases.image_id=images.id
-			 WHERE images_aliases.name=?`
+			 WHERE images_aliases.project_id=(SELECT id FROM projects WHERE name=?)
+			 AND images_aliases.name=?`
 	if !isTrustedClient {
 		q = q + ` AND images.public=1`
 	}
@@ -265,7 +302,7 @@ func dbImageAliasGet(db *sql.DB, name string, isTrustedClient bool) (int, api.Im
 	id := -1
 	entry := api.ImageAliasesEntry{}
 
-	arg1 := []interface{}{name}
+	arg1 := []interface{}{project, name}
 	arg2 := []interface{}{&id, &fingerprint, &description}
 	err := dbQueryRowScan(db, q, arg1, arg2)
 	if err != nil {
@@ -288,8 +325,8 @@ func dbImageAliasRename(db *sql.DB, id int, name string) error {
 	return err
 }
 
-func dbImageAliasDelete(db *sql.DB, name string) error {
-	_, err := dbExec(db, "DELETE FROM images_aliases WHERE name=?", name)
+func dbImageAliasDelete(db *sql.DB, project string, name string) error {
+	_, err := dbExec(db, "DELETE FROM images_aliases WHERE project_id=(SELECT id FROM projects WHERE name=?) AND name=?", project, name)
 	return err
 }
 
@@ -299,9 +336,9 @@ func dbImageAliasesMove(db *sql.DB, source int, destination int) error {
 }
 
 // Insert an alias ento the database.
-func dbImageAliasAdd(db *sql.DB, name string, imageID int, desc string) error {
-	stmt := `INSERT INTO images_aliases (name, image_id, description) values (?, ?, ?)`
-	_, err := dbExec(db, stmt, name, imageID, desc)
+func dbImageAliasAdd(db *sql.DB, project string, name string, imageID int, desc string) error {
+	stmt := `INSERT INTO images_aliases (name, image_id, description, project_id) values (?, ?, ?, (SELECT id FROM projects WHERE name=?))`
+	_, err := dbExec(db, stmt, name, imageID, desc, project)
 	return err
 }
 
@@ -380,7 +417,7 @@ func dbImageUpdate(db *sql.DB, id int, fname string, sz int64, public bool, auto
 	return nil
 }
 
-func dbImageInsert(db *sql.DB, fp string, fname string, sz int64, public bool, autoUpdate bool, architecture string, createdAt time.Time, expiresAt time.Time, properties map[string]string) error {
+func dbImageInsert(db *sql.DB, project string, fp string, fname string, sz int64, public bool, autoUpdate bool, architecture string, createdAt time.Time, expiresAt time.Time, properties map[string]string) error {
 	arch, err := osarch.ArchitectureId(architecture)
 	if err != nil {
 		arch = 0
@@ -401,14 +438,14 @@ func dbImageInsert(db *sql.DB, fp string, fname string, sz int64, public bool, a
 		autoUpdateInt = 1
 	}
 
-	stmt, err := tx.Prepare(`INSERT INTO images (fingerprint, filename, size, public, auto_update, architecture, creation_date, expiry_date, upload_date) VALUES (?, ?, ?, ?, ?, ?, ?, ?, strftime("%s"))`)
+	stmt, err := tx.Prepare(`INSERT INTO images (fingerprint, filename, size, public, auto_update, architecture, creation_date, expiry_date, upload_date, project_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, strftime("%s"), (SELECT id FROM projects WHERE name=?))`)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 	defer stmt.Close()
 
-	result, err := stmt.Exec(fp, fname, sz, publicInt, autoUpdateInt, arch, createdAt, expiresAt)
+	result, err := stmt.Exec(fp, fname, sz, publicInt, autoUpdateInt, arch, createdAt, expiresAt, project)
 	if err != nil {
 		tx.Rollback()
 		return err
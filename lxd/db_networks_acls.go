@@ -0,0 +1,174 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+func dbNetworkACLs(db *sql.DB) ([]string, error) {
+	q := "SELECT name FROM networks_acls ORDER BY name"
+	inargs := []interface{}{}
+	var name string
+	outfmt := []interface{}{name}
+	result, err := dbQueryScan(db, q, inargs, outfmt)
+	if err != nil {
+		return []string{}, err
+	}
+
+	response := []string{}
+	for _, r := range result {
+		response = append(response, r[0].(string))
+	}
+
+	return response, nil
+}
+
+func dbNetworkACLGet(db *sql.DB, name string) (int64, *api.NetworkACL, error) {
+	id := int64(-1)
+	description := sql.NullString{}
+	ingress := ""
+	egress := ""
+
+	q := "SELECT id, description, ingress, egress FROM networks_acls WHERE name=?"
+	arg1 := []interface{}{name}
+	arg2 := []interface{}{&id, &description, &ingress, &egress}
+	err := dbQueryRowScan(db, q, arg1, arg2)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	acl := api.NetworkACL{
+		Name:   name,
+		UsedBy: []string{},
+	}
+	acl.Description = description.String
+
+	err = json.Unmarshal([]byte(ingress), &acl.Ingress)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	err = json.Unmarshal([]byte(egress), &acl.Egress)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	return id, &acl, nil
+}
+
+// dbNetworkACLRules returns the JSON encoded ingress and egress rules of an
+// ACL, as stored in the database.
+func dbNetworkACLRules(acl api.NetworkACLPut) (string, string, error) {
+	rules := [][]api.NetworkACLRule{acl.Ingress, acl.Egress}
+	encoded := []string{}
+
+	for _, list := range rules {
+		if list == nil {
+			list = []api.NetworkACLRule{}
+		}
+
+		data, err := json.Marshal(list)
+		if err != nil {
+			return "", "", err
+		}
+
+		encoded = append(encoded, string(data))
+	}
+
+	return encoded[0], encoded[1], nil
+}
+
+func dbNetworkACLCreate(db *sql.DB, name string, acl api.NetworkACLPut) (int64, error) {
+	ingress, egress, err := dbNetworkACLRules(acl)
+	if err != nil {
+		return -1, err
+	}
+
+	result, err := dbExec(db, "INSERT INTO networks_acls (name, description, ingress, egress) VALUES (?, ?, ?, ?)", name, acl.Description, ingress, egress)
+	if err != nil {
+		return -1, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return -1, err
+	}
+
+	return id, nil
+}
+
+func dbNetworkACLUpdate(db *sql.DB, name string, acl api.NetworkACLPut) error {
+	id, _, err := dbNetworkACLGet(db, name)
+	if err != nil {
+		return err
+	}
+
+	ingress, egress, err := dbNetworkACLRules(acl)
+	if err != nil {
+		return err
+	}
+
+	_, err = dbExec(db, "UPDATE networks_acls SET description=?, ingress=?, egress=? WHERE id=?", acl.Description, ingress, egress, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func dbNetworkACLRename(db *sql.DB, oldName string, newName string) error {
+	id, _, err := dbNetworkACLGet(db, oldName)
+	if err != nil {
+		return err
+	}
+
+	_, err = dbExec(db, "UPDATE networks_acls SET name=? WHERE id=?", newName, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func dbNetworkACLDelete(db *sql.DB, name string) error {
+	id, _, err := dbNetworkACLGet(db, name)
+	if err != nil {
+		return err
+	}
+
+	_, err = dbExec(db, "DELETE FROM networks_acls WHERE id=?", id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// dbNetworkACLProfiles returns the names and projects of the profiles with
+// devices referencing network ACLs, along with the referenced ACLs.
+func dbNetworkACLProfiles(db *sql.DB) ([][]string, error) {
+	q := `
+SELECT profiles.name, projects.name, profiles_devices_config.value
+    FROM profiles_devices_config
+    JOIN profiles_devices ON profiles_devices_config.profile_device_id=profiles_devices.id
+    JOIN profiles ON profiles_devices.profile_id=profiles.id
+    JOIN projects ON profiles.project_id=projects.id
+    WHERE profiles_devices_config.key="security.acls"`
+	var name, project, value string
+	outfmt := []interface{}{name, project, value}
+	result, err := dbQueryScan(db, q, []interface{}{}, outfmt)
+	if err != nil {
+		return nil, err
+	}
+
+	response := [][]string{}
+	for _, r := range result {
+		response = append(response, []string{r[0].(string), r[1].(string), r[2].(string)})
+	}
+
+	return response, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// dbOperation is the database representation of a background operation.
+type dbOperation struct {
+	uuid      string
+	class     operationClass
+	createdAt time.Time
+	updatedAt time.Time
+	status    api.StatusCode
+	resources map[string][]string
+	metadata  map[string]interface{}
+	err       string
+}
+
+func dbOperationSave(db *sql.DB, op dbOperation) error {
+	resources, err := json.Marshal(op.resources)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := json.Marshal(op.metadata)
+	if err != nil {
+		return err
+	}
+
+	stmt := `INSERT OR REPLACE INTO operations (uuid, class, created_at, updated_at, status, resources, metadata, err) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = dbExec(db, stmt, op.uuid, int(op.class), op.createdAt.UTC(), op.updatedAt.UTC(), int(op.status), string(resources), string(metadata), op.err)
+	return err
+}
+
+func dbOperationGet(db *sql.DB, uuid string) (*dbOperation, error) {
+	var class, status int
+	var resources, metadata string
+
+	op := dbOperation{uuid: uuid}
+
+	q := "SELECT class, created_at, updated_at, status, resources, metadata, err FROM operations WHERE uuid=?"
+	arg1 := []interface{}{uuid}
+	arg2 := []interface{}{&class, &op.createdAt, &op.updatedAt, &status, &resources, &metadata, &op.err}
+	err := dbQueryRowScan(db, q, arg1, arg2)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, NoSuchObjectError
+		}
+
+		return nil, err
+	}
+
+	op.class = operationClass(class)
+	op.status = api.StatusCode(status)
+
+	err = json.Unmarshal([]byte(resources), &op.resources)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal([]byte(metadata), &op.metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return &op, nil
+}
+
+func dbOperations(db *sql.DB) ([]string, error) {
+	q := "SELECT uuid FROM operations"
+	inargs := []interface{}{}
+	var uuid string
+	outfmt := []interface{}{uuid}
+	result, err := dbQueryScan(db, q, inargs, outfmt)
+	if err != nil {
+		return []string{}, err
+	}
+
+	response := []string{}
+	for _, r := range result {
+		response = append(response, r[0].(string))
+	}
+
+	return response, nil
+}
+
+// dbOperationsInterrupt marks all the operations which didn't reach a final
+// state as failed. This is used on startup to report the operations which
+// were interrupted by the daemon going away.
+func dbOperationsInterrupt(db *sql.DB, errMsg string) error {
+	stmt := `UPDATE operations SET status=?, err=?, updated_at=? WHERE status IN (?, ?, ?)`
+	_, err := dbExec(db, stmt, int(api.Failure), errMsg, time.Now().UTC(), int(api.Pending), int(api.Running), int(api.Cancelling))
+	return err
+}
+
+// dbOperationsExpire removes the finished operations which haven't been
+// updated since the given time.
+func dbOperationsExpire(db *sql.DB, before time.Time) error {
+	stmt := `DELETE FROM operations WHERE updated_at<? AND status NOT IN (?, ?, ?)`
+	_, err := dbExec(db, stmt, before.UTC(), int(api.Pending), int(api.Running), int(api.Cancelling))
+	return err
+}
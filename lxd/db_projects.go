@@ -0,0 +1,277 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
+)
+
+func dbProjects(db *sql.DB) ([]string, error) {
+	q := "SELECT name FROM projects"
+	inargs := []interface{}{}
+	var name string
+	outfmt := []interface{}{name}
+	result, err := dbQueryScan(db, q, inargs, outfmt)
+	if err != nil {
+		return []string{}, err
+	}
+
+	response := []string{}
+	for _, r := range result {
+		response = append(response, r[0].(string))
+	}
+
+	return response, nil
+}
+
+func dbProjectGet(db *sql.DB, name string) (int64, *api.Project, error) {
+	id := int64(-1)
+	description := sql.NullString{}
+
+	q := "SELECT id, description FROM projects WHERE name=?"
+	arg1 := []interface{}{name}
+	arg2 := []interface{}{&id, &description}
+	err := dbQueryRowScan(db, q, arg1, arg2)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, nil, NoSuchObjectError
+		}
+
+		return -1, nil, err
+	}
+
+	config, err := dbProjectConfigGet(db, id)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	usedBy, err := dbProjectUsedBy(db, name)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	project := api.Project{
+		Name:   name,
+		UsedBy: usedBy,
+	}
+	project.Config = config
+	project.Description = description.String
+
+	return id, &project, nil
+}
+
+func dbProjectConfigGet(db *sql.DB, id int64) (map[string]string, error) {
+	var key, value string
+	query := "SELECT key, value FROM projects_config WHERE project_id=?"
+	inargs := []interface{}{id}
+	outfmt := []interface{}{key, value}
+	results, err := dbQueryScan(db, query, inargs, outfmt)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get project '%d'", id)
+	}
+
+	config := map[string]string{}
+	for _, r := range results {
+		key = r[0].(string)
+		value = r[1].(string)
+
+		config[key] = value
+	}
+
+	return config, nil
+}
+
+// dbProjectUsedBy returns the URLs of all the containers, images, profiles
+// and custom storage volumes which belong to the project.
+func dbProjectUsedBy(db *sql.DB, name string) ([]string, error) {
+	usedBy := []string{}
+
+	// Containers and custom volumes are stored under their project
+	// prefixed name
+	queries := []struct {
+		query    string
+		format   string
+		prefixed bool
+	}{
+		{"SELECT containers.name FROM containers JOIN projects ON containers.project_id=projects.id WHERE projects.name=? AND containers.type=0", "/%s/containers/%s", true},
+		{"SELECT images.fingerprint FROM images JOIN projects ON images.project_id=projects.id WHERE projects.name=?", "/%s/images/%s", false},
+		{"SELECT profiles.name FROM profiles JOIN projects ON profiles.project_id=projects.id WHERE projects.name=?", "/%s/profiles/%s", false},
+		{fmt.Sprintf("SELECT storage_volumes.name, storage_pools.name FROM storage_volumes JOIN storage_pools ON storage_volumes.storage_pool_id=storage_pools.id JOIN projects ON storage_volumes.project_id=projects.id WHERE projects.name=? AND storage_volumes.type=%d AND storage_volumes.name NOT LIKE '%%/%%'", storagePoolVolumeTypeCustom), "/%s/storage-pools/%[3]s/volumes/custom/%[2]s", true},
+	}
+
+	for _, q := range queries {
+		var entry, pool string
+		outfmt := []interface{}{entry}
+		if strings.Contains(q.query, "storage_pools.name") {
+			outfmt = append(outfmt, pool)
+		}
+
+		results, err := dbQueryScan(db, q.query, []interface{}{name}, outfmt)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range results {
+			entry = r[0].(string)
+			if q.prefixed && name != projectDefault {
+				entry = strings.TrimPrefix(entry, name+"_")
+			}
+
+			args := []interface{}{version.APIVersion, entry}
+			if len(r) > 1 {
+				args = append(args, r[1].(string))
+			}
+
+			usedBy = append(usedBy, fmt.Sprintf(q.format, args...)+projectQuery(name))
+		}
+	}
+
+	return usedBy, nil
+}
+
+func dbProjectCreate(db *sql.DB, name string, description string, config map[string]string) (int64, error) {
+	tx, err := dbBegin(db)
+	if err != nil {
+		return -1, err
+	}
+
+	result, err := tx.Exec("INSERT INTO projects (name, description) VALUES (?, ?)", name, description)
+	if err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	err = dbProjectConfigAdd(tx, id, config)
+	if err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	err = txCommit(tx)
+	if err != nil {
+		return -1, err
+	}
+
+	return id, nil
+}
+
+func dbProjectCreateDefault(db *sql.DB) error {
+	id, _, _ := dbProjectGet(db, projectDefault)
+
+	if id != -1 {
+		// default project already exists
+		return nil
+	}
+
+	config := map[string]string{
+		"features.images":   "true",
+		"features.profiles": "true",
+	}
+
+	_, err := dbProjectCreate(db, projectDefault, "Default LXD project", config)
+	return err
+}
+
+func dbProjectUpdate(db *sql.DB, id int64, description string, config map[string]string) error {
+	tx, err := dbBegin(db)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE projects SET description=? WHERE id=?", description, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM projects_config WHERE project_id=?", id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = dbProjectConfigAdd(tx, id, config)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return txCommit(tx)
+}
+
+func dbProjectConfigAdd(tx *sql.Tx, id int64, config map[string]string) error {
+	str := "INSERT INTO projects_config (project_id, key, value) VALUES(?, ?, ?)"
+	stmt, err := tx.Prepare(str)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for k, v := range config {
+		if v == "" {
+			continue
+		}
+
+		_, err = stmt.Exec(id, k, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func dbProjectRename(db *sql.DB, name string, newName string) error {
+	_, err := dbExec(db, "UPDATE projects SET name=? WHERE name=?", newName, name)
+	return err
+}
+
+// dbProjectDelete removes the project along with its own default profile, the
+// caller is expected to have checked that nothing else is left in it.
+func dbProjectDelete(db *sql.DB, name string) error {
+	tx, err := dbBegin(db)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM profiles WHERE project_id=(SELECT id FROM projects WHERE name=?)", name)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM projects WHERE name=?", name)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return txCommit(tx)
+}
+
+func dbProjectID(db *sql.DB, name string) (int64, error) {
+	id := int64(-1)
+
+	q := "SELECT id FROM projects WHERE name=?"
+	err := dbQueryRowScan(db, q, []interface{}{name}, []interface{}{&id})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, NoSuchObjectError
+		}
+
+		return -1, err
+	}
+
+	return id, nil
+}
@@ -319,6 +319,57 @@ func dbStoragePoolVolumesGetType(db *sql.DB, volumeType int, poolID int64) ([]st
 	return response, nil
 }
 
+// Get the names of all storage volumes of a given volume type attached to a
+// given storage pool which belong to the given project.
+func dbStoragePoolVolumesGetTypeProject(db *sql.DB, project string, volumeType int, poolID int64) ([]string, error) {
+	var volumeName string
+	query := `SELECT storage_volumes.name FROM storage_volumes
+JOIN projects ON projects.id=storage_volumes.project_id
+WHERE storage_volumes.storage_pool_id=? AND storage_volumes.type=? AND projects.name=?`
+	inargs := []interface{}{poolID, volumeType, project}
+	outargs := []interface{}{volumeName}
+
+	result, err := dbQueryScan(db, query, inargs, outargs)
+	if err != nil {
+		return []string{}, err
+	}
+
+	response := []string{}
+	for _, r := range result {
+		response = append(response, r[0].(string))
+	}
+
+	return response, nil
+}
+
+// Move a storage volume into the given project.
+func dbStoragePoolVolumeProjectSet(db *sql.DB, volumeID int64, project string) error {
+	_, err := dbExec(db, "UPDATE storage_volumes SET project_id=(SELECT id FROM projects WHERE name=?) WHERE id=?", project, volumeID)
+	return err
+}
+
+// Get the names of all snapshots of a given storage volume. The returned names
+// are of the form "<volume>/<snapshot>".
+func dbStoragePoolVolumeSnapshotsGetType(db *sql.DB, volumeName string, volumeType int, poolID int64) ([]string, error) {
+	var snapshotName string
+	prefix := volumeName + shared.SnapshotDelimiter
+	query := "SELECT name FROM storage_volumes WHERE storage_pool_id=? AND type=? AND SUBSTR(name, 1, ?)=?"
+	inargs := []interface{}{poolID, volumeType, len(prefix), prefix}
+	outargs := []interface{}{snapshotName}
+
+	result, err := dbQueryScan(db, query, inargs, outargs)
+	if err != nil {
+		return []string{}, err
+	}
+
+	response := []string{}
+	for _, r := range result {
+		response = append(response, r[0].(string))
+	}
+
+	return response, nil
+}
+
 // Get a single storage volume attached to a given storage pool of a given type.
 func dbStoragePoolVolumeGetType(db *sql.DB, volumeName string, volumeType int, poolID int64) (int64, *api.StorageVolume, error) {
 	volumeID, err := dbStoragePoolVolumeGetTypeID(db, volumeName, volumeType, poolID)
@@ -4,6 +4,8 @@ import (
 	"database/sql"
 
 	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/lxc/lxd/shared/api"
 )
 
 // Get config of a storage volume.
@@ -59,3 +61,72 @@ func dbStorageVolumeConfigClear(tx *sql.Tx, volumeID int64) error {
 
 	return nil
 }
+
+// Record that a storage volume is attached to a running container through the
+// given disk device.
+func dbStorageVolumeAttachmentAdd(db *sql.DB, volumeID int64, containerID int, device string) error {
+	_, err := dbExec(db, "INSERT OR REPLACE INTO storage_volumes_attachments (storage_volume_id, container_id, device) VALUES (?, ?, ?)", volumeID, containerID, device)
+	return err
+}
+
+// Remove the storage volume attachments of a container, either for the given
+// disk device or for all of them if device is empty.
+func dbStorageVolumeAttachmentRemove(db *sql.DB, containerID int, device string) error {
+	if device == "" {
+		_, err := dbExec(db, "DELETE FROM storage_volumes_attachments WHERE container_id=?", containerID)
+		return err
+	}
+
+	_, err := dbExec(db, "DELETE FROM storage_volumes_attachments WHERE container_id=? AND device=?", containerID, device)
+	return err
+}
+
+// Get the names of all the containers with storage volumes attached.
+func dbStorageVolumeAttachmentContainers(db *sql.DB) ([]string, error) {
+	var name string
+	query := `SELECT DISTINCT containers.name
+FROM storage_volumes_attachments
+JOIN containers ON containers.id=storage_volumes_attachments.container_id`
+	inargs := []interface{}{}
+	outargs := []interface{}{name}
+
+	results, err := dbQueryScan(db, query, inargs, outargs)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, r := range results {
+		names = append(names, r[0].(string))
+	}
+
+	return names, nil
+}
+
+// Get the containers a storage volume is attached to along with the disk
+// devices it is attached through.
+func dbStorageVolumeAttachmentsGet(db *sql.DB, volumeID int64) ([]api.StorageVolumeAttachment, error) {
+	var name, device string
+	query := `SELECT containers.name, storage_volumes_attachments.device
+FROM storage_volumes_attachments
+JOIN containers ON containers.id=storage_volumes_attachments.container_id
+WHERE storage_volumes_attachments.storage_volume_id=?
+ORDER BY containers.name, storage_volumes_attachments.device`
+	inargs := []interface{}{volumeID}
+	outargs := []interface{}{name, device}
+
+	results, err := dbQueryScan(db, query, inargs, outargs)
+	if err != nil {
+		return nil, err
+	}
+
+	attachments := []api.StorageVolumeAttachment{}
+	for _, r := range results {
+		attachments = append(attachments, api.StorageVolumeAttachment{
+			Container: r[0].(string),
+			Device:    r[1].(string),
+		})
+	}
+
+	return attachments, nil
+}
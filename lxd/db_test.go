@@ -422,7 +422,7 @@ func Test_dbImageGet_finds_image_for_fingerprint(t *testing.T) {
 	db = createTestDb(t)
 	defer db.Close()
 
-	_, result, err = dbImageGet(db, "fingerprint", false, false)
+	_, result, err = dbImageGet(db, "default", "fingerprint", false, false)
 
 	if err != nil {
 		t.Fatal(err)
@@ -456,7 +456,7 @@ func Test_dbImageGet_for_missing_fingerprint(t *testing.T) {
 	db = createTestDb(t)
 	defer db.Close()
 
-	_, _, err = dbImageGet(db, "unknown", false, false)
+	_, _, err = dbImageGet(db, "default", "unknown", false, false)
 
 	if err != sql.ErrNoRows {
 		t.Fatal("Wrong err type returned")
@@ -471,7 +471,7 @@ func Test_dbImageAliasGet_alias_exists(t *testing.T) {
 	db = createTestDb(t)
 	defer db.Close()
 
-	_, alias, err := dbImageAliasGet(db, "somealias", true)
+	_, alias, err := dbImageAliasGet(db, "default", "somealias", true)
 	result = alias.Target
 
 	if err != nil {
@@ -491,7 +491,7 @@ func Test_dbImageAliasGet_alias_does_not_exists(t *testing.T) {
 	db = createTestDb(t)
 	defer db.Close()
 
-	_, _, err = dbImageAliasGet(db, "whatever", true)
+	_, _, err = dbImageAliasGet(db, "default", "whatever", true)
 
 	if err != NoSuchObjectError {
 		t.Fatal("Error should be NoSuchObjectError")
@@ -506,12 +506,12 @@ func Test_dbImageAliasAdd(t *testing.T) {
 	db = createTestDb(t)
 	defer db.Close()
 
-	err = dbImageAliasAdd(db, "Chaosphere", 1, "Someone will like the name")
+	err = dbImageAliasAdd(db, "default", "Chaosphere", 1, "Someone will like the name")
 	if err != nil {
 		t.Fatal("Error inserting Image alias.")
 	}
 
-	_, alias, err := dbImageAliasGet(db, "Chaosphere", true)
+	_, alias, err := dbImageAliasGet(db, "default", "Chaosphere", true)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -558,7 +558,7 @@ func Test_dbProfileConfig(t *testing.T) {
 
 	_, err = db.Exec("INSERT INTO profiles_config (profile_id, key, value) VALUES (3, 'something', 'something else');")
 
-	result, err = dbProfileConfig(db, "theprofile")
+	result, err = dbProfileConfig(db, "default", "theprofile")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -604,7 +604,7 @@ func Test_dbDevices_profiles(t *testing.T) {
 	db = createTestDb(t)
 	defer db.Close()
 
-	result, err = dbDevices(db, "theprofile", true)
+	result, err = dbDevices(db, "default", "theprofile", true)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -630,7 +630,7 @@ func Test_dbDevices_containers(t *testing.T) {
 	db = createTestDb(t)
 	defer db.Close()
 
-	result, err = dbDevices(db, "thename", false)
+	result, err = dbDevices(db, "", "thename", false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -645,3 +645,59 @@ func Test_dbDevices_containers(t *testing.T) {
 	}
 
 }
+
+func Test_dbOperationsInterrupt(t *testing.T) {
+	var db *sql.DB
+	var err error
+
+	db = createTestDb(t)
+	defer db.Close()
+
+	op := dbOperation{
+		uuid:      "theuuid",
+		class:     operationClassTask,
+		createdAt: time.Now(),
+		updatedAt: time.Now(),
+		status:    api.Running,
+		resources: map[string][]string{"containers": {"thename"}},
+		metadata:  map[string]interface{}{"thekey": "thevalue"},
+	}
+
+	err = dbOperationSave(db, op)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = dbOperationsInterrupt(db, "interrupted")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := dbOperationGet(db, "theuuid")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.status != api.Failure {
+		t.Errorf("Interrupted operation wasn't marked as failed: %s", result.status)
+	}
+
+	if result.err != "interrupted" {
+		t.Errorf("Mismatching error for interrupted operation: %s", result.err)
+	}
+
+	if result.resources["containers"][0] != "thename" || result.metadata["thekey"] != "thevalue" {
+		t.Errorf("Operation resources or metadata weren't restored properly")
+	}
+
+	// The operation is final now, so it gets expired
+	err = dbOperationsExpire(db, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = dbOperationGet(db, "theuuid")
+	if err != NoSuchObjectError {
+		t.Errorf("Expired operation is still in the database: %v", err)
+	}
+}
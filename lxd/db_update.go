@@ -69,6 +69,12 @@ var dbUpdates = []dbUpdate{
 	{version: 33, run: dbUpdateFromV32},
 	{version: 34, run: dbUpdateFromV33},
 	{version: 35, run: dbUpdateFromV34},
+	{version: 36, run: dbUpdateFromV35},
+	{version: 37, run: dbUpdateFromV36},
+	{version: 38, run: dbUpdateFromV37},
+	{version: 39, run: dbUpdateFromV38},
+	{version: 40, run: dbUpdateFromV39},
+	{version: 41, run: dbUpdateFromV40},
 }
 
 type dbUpdate struct {
@@ -125,6 +131,194 @@ func dbUpdatesApplyAll(d *Daemon) error {
 }
 
 // Schema updates begin here
+func dbUpdateFromV40(currentVersion int, version int, d *Daemon) error {
+	stmt := `
+CREATE TABLE IF NOT EXISTS networks_acls (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    ingress TEXT NOT NULL,
+    egress TEXT NOT NULL,
+    UNIQUE (name)
+);`
+	_, err := d.db.Exec(stmt)
+	return err
+}
+
+func dbUpdateFromV39(currentVersion int, version int, d *Daemon) error {
+	stmt := `
+CREATE TABLE IF NOT EXISTS storage_volumes_attachments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    storage_volume_id INTEGER NOT NULL,
+    container_id INTEGER NOT NULL,
+    device VARCHAR(255) NOT NULL,
+    UNIQUE (container_id, device),
+    FOREIGN KEY (storage_volume_id) REFERENCES storage_volumes (id) ON DELETE CASCADE,
+    FOREIGN KEY (container_id) REFERENCES containers (id) ON DELETE CASCADE
+);`
+	_, err := d.db.Exec(stmt)
+	return err
+}
+
+func dbUpdateFromV38(currentVersion int, version int, d *Daemon) error {
+	stmt := `
+ALTER TABLE certificates ADD COLUMN restricted INTEGER NOT NULL DEFAULT 0;
+CREATE TABLE IF NOT EXISTS certificates_containers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    certificate_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    FOREIGN KEY (certificate_id) REFERENCES certificates (id) ON DELETE CASCADE,
+    UNIQUE (certificate_id, name)
+);`
+	_, err := d.db.Exec(stmt)
+	return err
+}
+
+func dbUpdateFromV37(currentVersion int, version int, d *Daemon) error {
+	stmt := `
+PRAGMA foreign_keys=OFF; -- So that integrity doesn't get in the way for now
+
+CREATE TABLE projects (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    UNIQUE (name)
+);
+CREATE TABLE projects_config (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    project_id INTEGER NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    value TEXT,
+    UNIQUE (project_id, key),
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
+);
+
+INSERT INTO projects (id, name, description) VALUES (1, 'default', 'Default LXD project');
+INSERT INTO projects_config (project_id, key, value) VALUES (1, 'features.images', 'true');
+INSERT INTO projects_config (project_id, key, value) VALUES (1, 'features.profiles', 'true');
+
+CREATE TABLE tmp (
+    id INTEGER primary key AUTOINCREMENT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    project_id INTEGER NOT NULL DEFAULT 1,
+    architecture INTEGER NOT NULL,
+    type INTEGER NOT NULL,
+    ephemeral INTEGER NOT NULL DEFAULT 0,
+    stateful INTEGER NOT NULL DEFAULT 0,
+    creation_date DATETIME,
+    last_use_date DATETIME,
+    UNIQUE (name),
+    FOREIGN KEY (project_id) REFERENCES projects (id)
+);
+INSERT INTO tmp (id, name, architecture, type, ephemeral, stateful, creation_date, last_use_date)
+    SELECT id, name, architecture, type, ephemeral, stateful, creation_date, last_use_date FROM containers;
+DROP TABLE containers;
+ALTER TABLE tmp RENAME TO containers;
+
+CREATE TABLE tmp (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    cached INTEGER NOT NULL DEFAULT 0,
+    fingerprint VARCHAR(255) NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    size INTEGER NOT NULL,
+    public INTEGER NOT NULL DEFAULT 0,
+    auto_update INTEGER NOT NULL DEFAULT 0,
+    architecture INTEGER NOT NULL,
+    creation_date DATETIME,
+    expiry_date DATETIME,
+    upload_date DATETIME NOT NULL,
+    last_use_date DATETIME,
+    project_id INTEGER NOT NULL DEFAULT 1,
+    UNIQUE (fingerprint, project_id),
+    FOREIGN KEY (project_id) REFERENCES projects (id)
+);
+INSERT INTO tmp (id, cached, fingerprint, filename, size, public, auto_update, architecture, creation_date, expiry_date, upload_date, last_use_date)
+    SELECT id, cached, fingerprint, filename, size, public, auto_update, architecture, creation_date, expiry_date, upload_date, last_use_date FROM images;
+DROP TABLE images;
+ALTER TABLE tmp RENAME TO images;
+
+CREATE TABLE tmp (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    image_id INTEGER NOT NULL,
+    description VARCHAR(255),
+    project_id INTEGER NOT NULL DEFAULT 1,
+    FOREIGN KEY (image_id) REFERENCES images (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects (id),
+    UNIQUE (name, project_id)
+);
+INSERT INTO tmp (id, name, image_id, description)
+    SELECT id, name, image_id, description FROM images_aliases;
+DROP TABLE images_aliases;
+ALTER TABLE tmp RENAME TO images_aliases;
+
+CREATE TABLE tmp (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    project_id INTEGER NOT NULL DEFAULT 1,
+    UNIQUE (name, project_id),
+    FOREIGN KEY (project_id) REFERENCES projects (id)
+);
+INSERT INTO tmp (id, name, description)
+    SELECT id, name, description FROM profiles;
+DROP TABLE profiles;
+ALTER TABLE tmp RENAME TO profiles;
+
+CREATE TABLE tmp (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    storage_pool_id INTEGER NOT NULL,
+    type INTEGER NOT NULL,
+    project_id INTEGER NOT NULL DEFAULT 1,
+    UNIQUE (storage_pool_id, name, type),
+    FOREIGN KEY (storage_pool_id) REFERENCES storage_pools (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects (id)
+);
+INSERT INTO tmp (id, name, storage_pool_id, type)
+    SELECT id, name, storage_pool_id, type FROM storage_volumes;
+DROP TABLE storage_volumes;
+ALTER TABLE tmp RENAME TO storage_volumes;
+
+PRAGMA foreign_keys=ON; -- Make sure we turn integrity checks back on.`
+	_, err := d.db.Exec(stmt)
+	return err
+}
+
+func dbUpdateFromV36(currentVersion int, version int, d *Daemon) error {
+	stmt := `
+CREATE TABLE IF NOT EXISTS containers_backups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    container_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    creation_date DATETIME,
+    container_only INTEGER NOT NULL DEFAULT 0,
+    optimized_storage INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (container_id) REFERENCES containers (id) ON DELETE CASCADE,
+    UNIQUE (container_id, name)
+);`
+	_, err := d.db.Exec(stmt)
+	return err
+}
+
+func dbUpdateFromV35(currentVersion int, version int, d *Daemon) error {
+	stmt := `
+CREATE TABLE IF NOT EXISTS operations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    uuid VARCHAR(36) NOT NULL,
+    class INTEGER NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    status INTEGER NOT NULL,
+    resources TEXT,
+    metadata TEXT,
+    err TEXT,
+    UNIQUE (uuid)
+);`
+	_, err := d.db.Exec(stmt)
+	return err
+}
+
 func dbUpdateFromV34(currentVersion int, version int, d *Daemon) error {
 	stmt := `
 CREATE TABLE IF NOT EXISTS storage_pools (
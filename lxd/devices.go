@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"math/big"
+	"net"
 	"os"
 	"os/exec"
 	"path"
@@ -1012,6 +1013,91 @@ func deviceTotalMemory() (int64, error) {
 	return -1, fmt.Errorf("Couldn't find MemTotal")
 }
 
+// deviceGetValidBlocks returns the major:minor of all the whole block devices
+// of the system, partitions excluded.
+func deviceGetValidBlocks() ([]string, error) {
+	validBlocks := []string{}
+
+	dents, err := ioutil.ReadDir("/sys/class/block/")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range dents {
+		fPath := filepath.Join("/sys/class/block/", f.Name())
+		if shared.PathExists(fmt.Sprintf("%s/partition", fPath)) {
+			continue
+		}
+
+		if !shared.PathExists(fmt.Sprintf("%s/dev", fPath)) {
+			continue
+		}
+
+		block, err := ioutil.ReadFile(fmt.Sprintf("%s/dev", fPath))
+		if err != nil {
+			return nil, err
+		}
+
+		validBlocks = append(validBlocks, strings.TrimSuffix(string(block), "\n"))
+	}
+
+	return validBlocks, nil
+}
+
+// deviceGetWholeBlock returns the whole block device (major:minor) the given
+// block device belongs to, or an empty string if it can't be found.
+func deviceGetWholeBlock(block string, validBlocks []string) string {
+	if shared.StringInSlice(block, validBlocks) {
+		// Straightforward entry (full block device)
+		return block
+	}
+
+	// Attempt to deal with a partition (guess its parent)
+	fields := strings.SplitN(block, ":", 2)
+	if len(fields) != 2 {
+		return ""
+	}
+
+	parent := fmt.Sprintf("%s:0", fields[0])
+	if shared.StringInSlice(parent, validBlocks) {
+		return parent
+	}
+
+	return ""
+}
+
+// deviceParseBlkioStats parses the content of a blkio cgroup statistics file
+// (e.g. blkio.throttle.io_service_bytes) into the read and write counters of
+// each block device (major:minor).
+func deviceParseBlkioStats(content string) map[string][2]int64 {
+	stats := map[string][2]int64{}
+
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+
+		value, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		counters := stats[fields[0]]
+		switch fields[1] {
+		case "Read":
+			counters[0] += value
+		case "Write":
+			counters[1] += value
+		default:
+			continue
+		}
+		stats[fields[0]] = counters
+	}
+
+	return stats
+}
+
 func deviceGetParentBlocks(path string) ([]string, error) {
 	var devices []string
 	var device []string
@@ -1270,3 +1356,78 @@ func deviceLoadUsb() ([]usbDevice, error) {
 
 	return result, nil
 }
+
+// deviceProxyParseAddr splits a proxy device address ("tcp:<host>:<port>",
+// "udp:<host>:<port>" or "unix:<path>") into its protocol and address.
+func deviceProxyParseAddr(addr string) (string, string, error) {
+	fields := strings.SplitN(addr, ":", 2)
+	if len(fields) != 2 || fields[1] == "" {
+		return "", "", fmt.Errorf("Invalid proxy address: %s", addr)
+	}
+
+	switch fields[0] {
+	case "tcp", "udp":
+		_, _, err := net.SplitHostPort(fields[1])
+		if err != nil {
+			return "", "", fmt.Errorf("Invalid proxy address %s: %s", addr, err)
+		}
+	case "unix":
+	default:
+		return "", "", fmt.Errorf("Unsupported proxy protocol: %s", fields[0])
+	}
+
+	return fields[0], fields[1], nil
+}
+
+// deviceProxyListen sets up the host side of a proxy device and returns the
+// listening socket so that it can be handed over to forkproxy.
+func deviceProxyListen(addr string) (*os.File, error) {
+	proto, address, err := deviceProxyParseAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	switch proto {
+	case "tcp":
+		listener, err := net.Listen(proto, address)
+		if err != nil {
+			return nil, err
+		}
+		defer listener.Close()
+
+		return listener.(*net.TCPListener).File()
+	case "udp":
+		conn, err := net.ListenPacket(proto, address)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+
+		return conn.(*net.UDPConn).File()
+	}
+
+	// Only abstract sockets may be created on the host, anything else
+	// would let the device config create files anywhere on the host
+	if !strings.HasPrefix(address, "@") {
+		return nil, fmt.Errorf("Only abstract unix sockets can be used on the host: %s", addr)
+	}
+
+	// Unix sockets are set up by hand as closing a net.UnixListener
+	// removes the socket from the filesystem
+	fd, err := syscall.Socket(syscall.AF_UNIX, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	err = syscall.Bind(fd, &syscall.SockaddrUnix{Name: address})
+	if err == nil {
+		err = syscall.Listen(fd, syscall.SOMAXCONN)
+	}
+
+	if err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	return os.NewFile(uintptr(fd), address), nil
+}
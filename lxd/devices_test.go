@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+)
+
+func Test_device_parse_blkio_stats(t *testing.T) {
+	content := `8:0 Read 4096
+8:0 Write 8192
+8:0 Sync 12288
+8:0 Async 0
+8:0 Total 12288
+253:1 Read 512
+253:1 Write 0
+253:1 Total 512
+Total 12800`
+
+	stats := deviceParseBlkioStats(content)
+	if len(stats) != 2 {
+		t.Fatalf("Unexpected devices: %v", stats)
+	}
+
+	if stats["8:0"] != [2]int64{4096, 8192} {
+		t.Fatalf("Unexpected counters for 8:0: %v", stats["8:0"])
+	}
+
+	if stats["253:1"] != [2]int64{512, 0} {
+		t.Fatalf("Unexpected counters for 253:1: %v", stats["253:1"])
+	}
+}
+
+func Test_device_get_whole_block(t *testing.T) {
+	validBlocks := []string{"8:0", "253:1"}
+
+	tests := map[string]string{
+		"8:0":   "8:0",
+		"8:2":   "8:0",
+		"253:1": "253:1",
+		"7:3":   "",
+	}
+
+	for block, expected := range tests {
+		result := deviceGetWholeBlock(block, validBlocks)
+		if result != expected {
+			t.Fatalf("Unexpected whole block for %s: %s", block, result)
+		}
+	}
+}
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lxc/lxd/shared"
+)
+
+// A minimal authoritative DNS responder, serving the names of the containers
+// attached to LXD managed networks on a host address. Only what's needed for
+// that is implemented: a single question per query, A, AAAA, PTR and SOA
+// records, over UDP and TCP.
+
+// TTL of the records served, the records are also rebuilt at most that often.
+const dnsTTL = 5
+
+const (
+	dnsTypeA    uint16 = 1
+	dnsTypeSOA  uint16 = 6
+	dnsTypePTR  uint16 = 12
+	dnsTypeAAAA uint16 = 28
+	dnsTypeANY  uint16 = 255
+
+	dnsClassIN  uint16 = 1
+	dnsClassANY uint16 = 255
+)
+
+const (
+	dnsRcodeSuccess        byte = 0
+	dnsRcodeFormatError    byte = 1
+	dnsRcodeNameError      byte = 3
+	dnsRcodeNotImplemented byte = 4
+	dnsRcodeRefused        byte = 5
+)
+
+// dnsRecords holds the zones served and their content. All names are fully
+// qualified and lower case.
+type dnsRecords struct {
+	serial  uint32
+	zones   []string
+	forward map[string][]net.IP
+	reverse map[string]string
+}
+
+func newDNSRecords() *dnsRecords {
+	return &dnsRecords{
+		serial:  uint32(time.Now().Unix()),
+		zones:   []string{},
+		forward: map[string][]net.IP{},
+		reverse: map[string]string{},
+	}
+}
+
+// addZone serves the given zone, if not already done.
+func (r *dnsRecords) addZone(zone string) {
+	zone = dnsFqdn(zone)
+	if !shared.StringInSlice(zone, r.zones) {
+		r.zones = append(r.zones, zone)
+	}
+}
+
+// addReverseZone serves the reverse zone covering the given subnet. The zone
+// is rounded down to the closest octet (IPv4) or nibble (IPv6) boundary.
+func (r *dnsRecords) addReverseZone(subnet *net.IPNet) {
+	ones, _ := subnet.Mask.Size()
+
+	labels := []string{}
+	if ip := subnet.IP.To4(); ip != nil {
+		for i := 0; i < ones/8; i++ {
+			labels = append([]string{fmt.Sprintf("%d", ip[i])}, labels...)
+		}
+
+		labels = append(labels, "in-addr", "arpa")
+	} else {
+		nibbles := fmt.Sprintf("%x", []byte(subnet.IP.To16()))
+		for i := 0; i < ones/4; i++ {
+			labels = append([]string{nibbles[i : i+1]}, labels...)
+		}
+
+		labels = append(labels, "ip6", "arpa")
+	}
+
+	r.addZone(strings.Join(labels, "."))
+}
+
+// addHost adds the forward and reverse records of a host.
+func (r *dnsRecords) addHost(name string, ip net.IP) {
+	name = dnsFqdn(name)
+
+	for _, existing := range r.forward[name] {
+		if existing.Equal(ip) {
+			return
+		}
+	}
+
+	r.forward[name] = append(r.forward[name], ip)
+	r.reverse[dnsReverseName(ip)] = name
+}
+
+// zone returns the most specific zone served containing name, if any.
+func (r *dnsRecords) zone(name string) string {
+	match := ""
+	for _, zone := range r.zones {
+		if name != zone && !strings.HasSuffix(name, "."+zone) {
+			continue
+		}
+
+		if len(zone) > len(match) {
+			match = zone
+		}
+	}
+
+	return match
+}
+
+func dnsFqdn(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, ".")) + "."
+}
+
+// dnsReverseName returns the PTR record name of an address.
+func dnsReverseName(ip net.IP) string {
+	labels := []string{}
+	if ip4 := ip.To4(); ip4 != nil {
+		for i := 3; i >= 0; i-- {
+			labels = append(labels, fmt.Sprintf("%d", ip4[i]))
+		}
+
+		return strings.Join(labels, ".") + ".in-addr.arpa."
+	}
+
+	nibbles := fmt.Sprintf("%x", []byte(ip.To16()))
+	for i := len(nibbles) - 1; i >= 0; i-- {
+		labels = append(labels, nibbles[i:i+1])
+	}
+
+	return strings.Join(labels, ".") + ".ip6.arpa."
+}
+
+// dnsPackName returns the wire format of a domain name, without compression.
+func dnsPackName(name string) []byte {
+	buf := []byte{}
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" {
+			continue
+		}
+
+		buf = append(buf, byte(len(label)))
+		buf = append(buf, label...)
+	}
+
+	return append(buf, 0)
+}
+
+// dnsUnpackName reads an uncompressed domain name from a message, returning
+// it along with the offset following it.
+func dnsUnpackName(msg []byte, offset int) (string, int, error) {
+	labels := []string{}
+	for {
+		if offset >= len(msg) {
+			return "", 0, fmt.Errorf("Truncated name")
+		}
+
+		length := int(msg[offset])
+		offset++
+		if length == 0 {
+			break
+		}
+
+		// Questions are never compressed
+		if length > 63 || offset+length > len(msg) {
+			return "", 0, fmt.Errorf("Invalid label")
+		}
+
+		labels = append(labels, string(msg[offset:offset+length]))
+		offset += length
+	}
+
+	return strings.Join(labels, ".") + ".", offset, nil
+}
+
+// dnsRecord returns the wire format of a resource record.
+func dnsRecord(name []byte, rrType uint16, data []byte) []byte {
+	buf := make([]byte, 10)
+	binary.BigEndian.PutUint16(buf[0:], rrType)
+	binary.BigEndian.PutUint16(buf[2:], dnsClassIN)
+	binary.BigEndian.PutUint32(buf[4:], dnsTTL)
+	binary.BigEndian.PutUint16(buf[8:], uint16(len(data)))
+
+	record := append([]byte{}, name...)
+	record = append(record, buf...)
+	return append(record, data...)
+}
+
+// dnsSOA returns the SOA record of a zone.
+func (r *dnsRecords) dnsSOA(zone string) []byte {
+	data := dnsPackName(zone)
+	data = append(data, dnsPackName("hostmaster."+zone)...)
+
+	timers := make([]byte, 20)
+	binary.BigEndian.PutUint32(timers[0:], r.serial)
+	binary.BigEndian.PutUint32(timers[4:], 3600)
+	binary.BigEndian.PutUint32(timers[8:], 600)
+	binary.BigEndian.PutUint32(timers[12:], 86400)
+	binary.BigEndian.PutUint32(timers[16:], dnsTTL)
+
+	return dnsRecord(dnsPackName(zone), dnsTypeSOA, append(data, timers...))
+}
+
+// dnsAnswer builds the response to a query. A nil response means that the
+// query should be ignored.
+func dnsAnswer(query []byte, records *dnsRecords, maxSize int) []byte {
+	if len(query) < 12 || query[2]&0x80 != 0 {
+		return nil
+	}
+
+	opcode := (query[2] >> 3) & 0x0f
+	header := make([]byte, 12)
+	copy(header[0:2], query[0:2])
+	header[2] = 0x80 | opcode<<3 | query[2]&0x01
+
+	reply := func(rcode byte, question []byte, answers [][]byte, authority [][]byte) []byte {
+		header[3] = rcode
+		qdcount := 0
+		if question != nil {
+			qdcount = 1
+		}
+
+		binary.BigEndian.PutUint16(header[4:], uint16(qdcount))
+		binary.BigEndian.PutUint16(header[6:], uint16(len(answers)))
+		binary.BigEndian.PutUint16(header[8:], uint16(len(authority)))
+
+		msg := append([]byte{}, header...)
+		msg = append(msg, question...)
+		for _, record := range append(answers, authority...) {
+			msg = append(msg, record...)
+		}
+
+		// Let the client retry over TCP
+		if len(msg) > maxSize {
+			header[2] |= 0x02
+			binary.BigEndian.PutUint16(header[6:], 0)
+			binary.BigEndian.PutUint16(header[8:], 0)
+			msg = append(append([]byte{}, header...), question...)
+		}
+
+		return msg
+	}
+
+	if opcode != 0 {
+		return reply(dnsRcodeNotImplemented, nil, nil, nil)
+	}
+
+	if binary.BigEndian.Uint16(query[4:]) != 1 {
+		return reply(dnsRcodeFormatError, nil, nil, nil)
+	}
+
+	name, offset, err := dnsUnpackName(query, 12)
+	if err != nil || offset+4 > len(query) {
+		return reply(dnsRcodeFormatError, nil, nil, nil)
+	}
+
+	question := query[12 : offset+4]
+	qtype := binary.BigEndian.Uint16(query[offset:])
+	qclass := binary.BigEndian.Uint16(query[offset+2:])
+
+	name = strings.ToLower(name)
+	zone := records.zone(name)
+	if zone == "" || (qclass != dnsClassIN && qclass != dnsClassANY) {
+		return reply(dnsRcodeRefused, question, nil, nil)
+	}
+
+	// We're authoritative for everything below our zones
+	header[2] |= 0x04
+
+	// Answers point back to the name in the question
+	pointer := []byte{0xc0, 12}
+
+	answers := [][]byte{}
+	ips, hasForward := records.forward[name]
+	for _, ip := range ips {
+		if ip.To4() != nil && (qtype == dnsTypeA || qtype == dnsTypeANY) {
+			answers = append(answers, dnsRecord(pointer, dnsTypeA, ip.To4()))
+		} else if ip.To4() == nil && (qtype == dnsTypeAAAA || qtype == dnsTypeANY) {
+			answers = append(answers, dnsRecord(pointer, dnsTypeAAAA, ip.To16()))
+		}
+	}
+
+	target, hasReverse := records.reverse[name]
+	if hasReverse && (qtype == dnsTypePTR || qtype == dnsTypeANY) {
+		answers = append(answers, dnsRecord(pointer, dnsTypePTR, dnsPackName(target)))
+	}
+
+	if name == zone && (qtype == dnsTypeSOA || qtype == dnsTypeANY) {
+		answers = append(answers, records.dnsSOA(zone))
+	}
+
+	if len(answers) > 0 {
+		return reply(dnsRcodeSuccess, question, answers, nil)
+	}
+
+	// The SOA lets resolvers cache the negative answer
+	rcode := dnsRcodeNameError
+	if hasForward || hasReverse || name == zone || records.hasChildren(name) {
+		rcode = dnsRcodeSuccess
+	}
+
+	return reply(rcode, question, nil, [][]byte{records.dnsSOA(zone)})
+}
+
+// hasChildren tells whether any record exists below name, in which case name
+// exists even if it doesn't have records of its own.
+func (r *dnsRecords) hasChildren(name string) bool {
+	for record := range r.forward {
+		if strings.HasSuffix(record, "."+name) {
+			return true
+		}
+	}
+
+	for record := range r.reverse {
+		if strings.HasSuffix(record, "."+name) {
+			return true
+		}
+	}
+
+	for _, zone := range r.zones {
+		if strings.HasSuffix(zone, "."+name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dnsServer answers queries over UDP and TCP on a single address.
+type dnsServer struct {
+	udp  net.PacketConn
+	tcp  net.Listener
+	load func() (*dnsRecords, error)
+
+	recordsLock sync.Mutex
+	records     *dnsRecords
+	recordsTime time.Time
+}
+
+// dnsServerStart starts serving the records returned by load on address,
+// port 53 being used when none is given.
+func dnsServerStart(address string, load func() (*dnsRecords, error)) (*dnsServer, error) {
+	_, _, err := net.SplitHostPort(address)
+	if err != nil {
+		address = net.JoinHostPort(strings.Trim(address, "[]"), "53")
+	}
+
+	s := &dnsServer{load: load}
+
+	s.udp, err = net.ListenPacket("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	s.tcp, err = net.Listen("tcp", address)
+	if err != nil {
+		s.udp.Close()
+		return nil, err
+	}
+
+	go s.serveUDP()
+	go s.serveTCP()
+
+	return s, nil
+}
+
+// Stop closes the sockets of the server.
+func (s *dnsServer) Stop() {
+	s.udp.Close()
+	s.tcp.Close()
+}
+
+// getRecords returns the records to serve, rebuilding them when stale.
+func (s *dnsServer) getRecords() *dnsRecords {
+	s.recordsLock.Lock()
+	defer s.recordsLock.Unlock()
+
+	if s.records != nil && time.Since(s.recordsTime) < dnsTTL*time.Second {
+		return s.records
+	}
+
+	records, err := s.load()
+	if err != nil {
+		shared.LogErrorf("Failed to load the DNS records: %v", err)
+		if s.records != nil {
+			return s.records
+		}
+
+		return newDNSRecords()
+	}
+
+	s.records = records
+	s.recordsTime = time.Now()
+	return s.records
+}
+
+func (s *dnsServer) serveUDP() {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := s.udp.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		response := dnsAnswer(buf[:n], s.getRecords(), 512)
+		if response != nil {
+			s.udp.WriteTo(response, addr)
+		}
+	}
+}
+
+func (s *dnsServer) serveTCP() {
+	for {
+		conn, err := s.tcp.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.serveTCPConn(conn)
+	}
+}
+
+// serveTCPConn answers the queries of a TCP connection, each of them being
+// prefixed with its length.
+func (s *dnsServer) serveTCPConn(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+		length := make([]byte, 2)
+		_, err := io.ReadFull(conn, length)
+		if err != nil {
+			return
+		}
+
+		query := make([]byte, binary.BigEndian.Uint16(length))
+		_, err = io.ReadFull(conn, query)
+		if err != nil {
+			return
+		}
+
+		response := dnsAnswer(query, s.getRecords(), 65535)
+		if response == nil {
+			return
+		}
+
+		binary.BigEndian.PutUint16(length, uint16(len(response)))
+		_, err = conn.Write(append(length, response...))
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+// dnsTestQuery returns the wire format of a query for name.
+func dnsTestQuery(name string, qtype uint16) []byte {
+	query := []byte{0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0}
+	query = append(query, dnsPackName(name)...)
+
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint16(buf[0:], qtype)
+	binary.BigEndian.PutUint16(buf[2:], dnsClassIN)
+	return append(query, buf...)
+}
+
+func dnsTestRecords() *dnsRecords {
+	records := newDNSRecords()
+	records.addZone("lxd")
+
+	_, subnet, _ := net.ParseCIDR("10.0.3.1/24")
+	records.addReverseZone(subnet)
+	_, subnet, _ = net.ParseCIDR("fd42:1234::1/64")
+	records.addReverseZone(subnet)
+
+	records.addHost("c1.lxd", net.ParseIP("10.0.3.10"))
+	records.addHost("c1.lxd", net.ParseIP("fd42:1234::10"))
+	return records
+}
+
+func Test_dns_zones(t *testing.T) {
+	records := dnsTestRecords()
+
+	expected := []string{"lxd.", "3.0.10.in-addr.arpa.", "0.0.0.0.0.0.0.0.4.3.2.1.2.4.d.f.ip6.arpa."}
+	if len(records.zones) != len(expected) {
+		t.Fatalf("Unexpected zones: %v", records.zones)
+	}
+
+	for i, zone := range expected {
+		if records.zones[i] != zone {
+			t.Fatalf("Unexpected zones: %v", records.zones)
+		}
+	}
+
+	if records.reverse["10.3.0.10.in-addr.arpa."] != "c1.lxd." {
+		t.Fatalf("Unexpected reverse records: %v", records.reverse)
+	}
+}
+
+func Test_dns_answer_forward(t *testing.T) {
+	response := dnsAnswer(dnsTestQuery("C1.lxd", dnsTypeA), dnsTestRecords(), 512)
+
+	if response[0] != 0x12 || response[1] != 0x34 {
+		t.Fatal("The query ID wasn't copied")
+	}
+
+	if response[2]&0x84 != 0x84 || response[3]&0x0f != dnsRcodeSuccess {
+		t.Fatalf("Unexpected flags: %x %x", response[2], response[3])
+	}
+
+	if binary.BigEndian.Uint16(response[6:]) != 1 {
+		t.Fatalf("Unexpected answer count: %d", binary.BigEndian.Uint16(response[6:]))
+	}
+
+	ip := net.IP(response[len(response)-4:])
+	if !ip.Equal(net.ParseIP("10.0.3.10")) {
+		t.Fatalf("Unexpected answer: %s", ip)
+	}
+}
+
+func Test_dns_answer_reverse(t *testing.T) {
+	response := dnsAnswer(dnsTestQuery("0.1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.4.3.2.1.2.4.d.f.ip6.arpa", dnsTypePTR), dnsTestRecords(), 512)
+
+	if binary.BigEndian.Uint16(response[6:]) != 1 {
+		t.Fatalf("Unexpected answer count: %d", binary.BigEndian.Uint16(response[6:]))
+	}
+
+	target := dnsPackName("c1.lxd")
+	if string(response[len(response)-len(target):]) != string(target) {
+		t.Fatalf("Unexpected answer: %v", response[len(response)-len(target):])
+	}
+}
+
+func Test_dns_answer_errors(t *testing.T) {
+	records := dnsTestRecords()
+
+	tests := []struct {
+		query []byte
+		rcode byte
+	}{
+		{dnsTestQuery("c2.lxd", dnsTypeA), dnsRcodeNameError},
+		{dnsTestQuery("c1.lxd", dnsTypePTR), dnsRcodeSuccess},
+		{dnsTestQuery("example.com", dnsTypeA), dnsRcodeRefused},
+		{dnsTestQuery("c1.lxd", dnsTypeA)[:14], dnsRcodeFormatError},
+	}
+
+	for _, test := range tests {
+		response := dnsAnswer(test.query, records, 512)
+		if response[3]&0x0f != test.rcode {
+			t.Fatalf("Unexpected rcode %d for %v", response[3]&0x0f, test.query)
+		}
+
+		if binary.BigEndian.Uint16(response[6:]) != 0 {
+			t.Fatalf("Unexpected answers for %v", test.query)
+		}
+	}
+
+	// Responses are never answered
+	query := dnsTestQuery("c1.lxd", dnsTypeA)
+	query[2] |= 0x80
+	if dnsAnswer(query, records, 512) != nil {
+		t.Fatal("A response got answered")
+	}
+}
+
+func Test_dns_answer_truncated(t *testing.T) {
+	records := dnsTestRecords()
+	for i := 0; i < 64; i++ {
+		records.addHost("c1.lxd", net.IPv4(10, 0, 3, byte(100+i)))
+	}
+
+	response := dnsAnswer(dnsTestQuery("c1.lxd", dnsTypeA), records, 512)
+	if response[2]&0x02 == 0 || binary.BigEndian.Uint16(response[6:]) != 0 {
+		t.Fatal("The response wasn't truncated")
+	}
+
+	response = dnsAnswer(dnsTestQuery("c1.lxd", dnsTypeA), records, 65535)
+	if binary.BigEndian.Uint16(response[6:]) != 65 {
+		t.Fatalf("Unexpected answer count: %d", binary.BigEndian.Uint16(response[6:]))
+	}
+}
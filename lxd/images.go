@@ -129,7 +129,7 @@ func unpack(d *Daemon, file string, path string, sType storageType) error {
 
 		// Check if we're running out of space
 		if int64(fs.Bfree) < int64(2*fs.Bsize) {
-			if sType == storageTypeLvm {
+			if sType == storageTypeLvm || sType == storageTypeCeph {
 				return fmt.Errorf("Unable to unpack image, run out of disk space (consider increasing your pool's volume.size).")
 			} else {
 				return fmt.Errorf("Unable to unpack image, run out of disk space.")
@@ -221,7 +221,7 @@ type imageMetadata struct {
  * This function takes a container or snapshot from the local image server and
  * exports it as an image.
  */
-func imgPostContInfo(d *Daemon, r *http.Request, req api.ImagesPost,
+func imgPostContInfo(d *Daemon, project string, r *http.Request, req api.ImagesPost,
 	builddir string) (info api.Image, err error) {
 
 	info.Properties = map[string]string{}
@@ -252,7 +252,7 @@ func imgPostContInfo(d *Daemon, r *http.Request, req api.ImagesPost,
 		info.Public = false
 	}
 
-	c, err := containerLoadByName(d, name)
+	c, err := containerLoadByName(d, projectPrefix(projectParam(r), name))
 	if err != nil {
 		return info, err
 	}
@@ -301,7 +301,7 @@ func imgPostContInfo(d *Daemon, r *http.Request, req api.ImagesPost,
 	}
 	info.Fingerprint = fmt.Sprintf("%x", sha256.Sum(nil))
 
-	_, _, err = dbImageGet(d.db, info.Fingerprint, false, true)
+	_, _, err = dbImageGet(d.db, project, info.Fingerprint, false, true)
 	if err == nil {
 		return info, fmt.Errorf("The image already exists: %s", info.Fingerprint)
 	}
@@ -319,7 +319,7 @@ func imgPostContInfo(d *Daemon, r *http.Request, req api.ImagesPost,
 	return info, nil
 }
 
-func imgPostRemoteInfo(d *Daemon, req api.ImagesPost, op *operation) error {
+func imgPostRemoteInfo(d *Daemon, project string, req api.ImagesPost, op *operation) error {
 	var err error
 	var hash string
 
@@ -331,12 +331,12 @@ func imgPostRemoteInfo(d *Daemon, req api.ImagesPost, op *operation) error {
 		return fmt.Errorf("must specify one of alias or fingerprint for init from image")
 	}
 
-	hash, err = d.ImageDownload(op, req.Source["server"], req.Source["protocol"], req.Source["certificate"], req.Source["secret"], hash, false, req.AutoUpdate, "")
+	hash, err = d.ImageDownload(op, project, req.Source["server"], req.Source["protocol"], req.Source["certificate"], req.Source["secret"], hash, false, req.AutoUpdate, "")
 	if err != nil {
 		return err
 	}
 
-	id, info, err := dbImageGet(d.db, hash, false, false)
+	id, info, err := dbImageGet(d.db, project, hash, false, false)
 	if err != nil {
 		return err
 	}
@@ -362,7 +362,7 @@ func imgPostRemoteInfo(d *Daemon, req api.ImagesPost, op *operation) error {
 	return nil
 }
 
-func imgPostURLInfo(d *Daemon, req api.ImagesPost, op *operation) error {
+func imgPostURLInfo(d *Daemon, project string, req api.ImagesPost, op *operation) error {
 	var err error
 
 	if req.Source["url"] == "" {
@@ -405,12 +405,12 @@ func imgPostURLInfo(d *Daemon, req api.ImagesPost, op *operation) error {
 	}
 
 	// Import the image
-	hash, err = d.ImageDownload(op, url, "direct", "", "", hash, false, req.AutoUpdate, "")
+	hash, err = d.ImageDownload(op, project, url, "direct", "", "", hash, false, req.AutoUpdate, "")
 	if err != nil {
 		return err
 	}
 
-	id, info, err := dbImageGet(d.db, hash, false, false)
+	id, info, err := dbImageGet(d.db, project, hash, false, false)
 	if err != nil {
 		return err
 	}
@@ -651,9 +651,49 @@ func imageCreateInPool(d *Daemon, info *api.Image, storagePool string) error {
 	return nil
 }
 
-func imageBuildFromInfo(d *Daemon, info *api.Image) (metadata map[string]string, err error) {
+// imageCopyToProject records an image which is already present in another
+// project into the given one.
+func imageCopyToProject(d *Daemon, fingerprint string, project string) (*api.Image, error) {
+	projects, err := dbImageProjects(d.db, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(projects) == 0 {
+		return nil, NoSuchObjectError
+	}
+
+	srcID, info, err := dbImageGet(d.db, projects[0], fingerprint, false, true)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = imageBuildFromInfo(d, project, info)
+	if err != nil {
+		return nil, err
+	}
+
+	_, source, err := dbImageSourceGet(d.db, srcID)
+	if err == nil {
+		id, _, err := dbImageGet(d.db, project, fingerprint, false, true)
+		if err != nil {
+			return nil, err
+		}
+
+		err = dbImageSourceInsert(d.db, id, source.Server, source.Protocol, source.Certificate, source.Alias)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, info, err = dbImageGet(d.db, project, fingerprint, false, true)
+	return info, err
+}
+
+func imageBuildFromInfo(d *Daemon, project string, info *api.Image) (metadata map[string]string, err error) {
 	err = dbImageInsert(
 		d.db,
+		project,
 		info.Fingerprint,
 		info.Filename,
 		info.Size,
@@ -675,7 +715,10 @@ func imageBuildFromInfo(d *Daemon, info *api.Image) (metadata map[string]string,
 }
 
 func imagesPost(d *Daemon, r *http.Request) Response {
-	var err error
+	project, err := imageProject(d.db, projectParam(r))
+	if err != nil {
+		return SmartError(err)
+	}
 
 	// create a directory under which we keep everything while building
 	builddir, err := ioutil.TempDir(shared.VarPath("images"), "lxd_build_")
@@ -734,7 +777,7 @@ func imagesPost(d *Daemon, r *http.Request) Response {
 
 		/* Processing image copy from remote */
 		if !imageUpload && req.Source["type"] == "image" {
-			err := imgPostRemoteInfo(d, req, op)
+			err := imgPostRemoteInfo(d, project, req, op)
 			if err != nil {
 				return err
 			}
@@ -743,7 +786,7 @@ func imagesPost(d *Daemon, r *http.Request) Response {
 
 		/* Processing image copy from URL */
 		if !imageUpload && req.Source["type"] == "url" {
-			err := imgPostURLInfo(d, req, op)
+			err := imgPostURLInfo(d, project, req, op)
 			if err != nil {
 				return err
 			}
@@ -759,7 +802,7 @@ func imagesPost(d *Daemon, r *http.Request) Response {
 		} else {
 			/* Processing image creation from container */
 			imagePublishLock.Lock()
-			info, err = imgPostContInfo(d, r, req, builddir)
+			info, err = imgPostContInfo(d, project, r, req, builddir)
 			if err != nil {
 				imagePublishLock.Unlock()
 				return err
@@ -767,7 +810,7 @@ func imagesPost(d *Daemon, r *http.Request) Response {
 			imagePublishLock.Unlock()
 		}
 
-		metadata, err := imageBuildFromInfo(d, &info)
+		metadata, err := imageBuildFromInfo(d, project, &info)
 		if err != nil {
 			return err
 		}
@@ -827,8 +870,8 @@ func getImageMetadata(fname string) (*imageMetadata, error) {
 	return &metadata, nil
 }
 
-func doImagesGet(d *Daemon, recursion bool, public bool) (interface{}, error) {
-	results, err := dbImagesGet(d.db, public)
+func doImagesGet(d *Daemon, project string, recursion bool, public bool) (interface{}, error) {
+	results, err := dbImagesGet(d.db, project, public)
 	if err != nil {
 		return []string{}, err
 	}
@@ -838,10 +881,10 @@ func doImagesGet(d *Daemon, recursion bool, public bool) (interface{}, error) {
 	i := 0
 	for _, name := range results {
 		if !recursion {
-			url := fmt.Sprintf("/%s/images/%s", version.APIVersion, name)
+			url := fmt.Sprintf("/%s/images/%s%s", version.APIVersion, name, projectQuery(project))
 			resultString[i] = url
 		} else {
-			image, response := doImageGet(d, name, public)
+			image, response := doImageGet(d, project, name, public)
 			if response != nil {
 				continue
 			}
@@ -859,9 +902,14 @@ func doImagesGet(d *Daemon, recursion bool, public bool) (interface{}, error) {
 }
 
 func imagesGet(d *Daemon, r *http.Request) Response {
+	project, err := imageProject(d.db, projectParam(r))
+	if err != nil {
+		return SmartError(err)
+	}
+
 	public := !d.isTrustedClient(r)
 
-	result, err := doImagesGet(d, d.isRecursionRequest(r), public)
+	result, err := doImagesGet(d, project, d.isRecursionRequest(r), public)
 	if err != nil {
 		return SmartError(err)
 	}
@@ -873,177 +921,136 @@ var imagesCmd = Command{name: "images", post: imagesPost, untrustedGet: true, ge
 func autoUpdateImages(d *Daemon) {
 	shared.LogInfof("Updating images")
 
-	images, err := dbImagesGet(d.db, false)
+	projects, err := dbProjects(d.db)
 	if err != nil {
-		shared.LogError("Unable to retrieve the list of images", log.Ctx{"err": err})
+		shared.LogError("Unable to retrieve the list of projects", log.Ctx{"err": err})
 		return
 	}
 
-	for _, fp := range images {
-		id, info, err := dbImageGet(d.db, fp, false, true)
-		if err != nil {
-			shared.LogError("Error loading image", log.Ctx{"err": err, "fp": fp})
-			continue
-		}
-
-		if !info.AutoUpdate {
-			continue
-		}
-
-		_, source, err := dbImageSourceGet(d.db, id)
+	for _, project := range projects {
+		images, err := dbImagesGet(d.db, project, false)
 		if err != nil {
+			shared.LogError("Unable to retrieve the list of images", log.Ctx{"err": err, "project": project})
 			continue
 		}
 
-		// Get the IDs of all storage pools on which a storage volume
-		// for the requested image currently exists.
-		poolIDs, err := dbImageGetPools(d.db, fp)
-		if err != nil {
-			continue
+		for _, fp := range images {
+			autoUpdateImage(d, project, fp)
 		}
+	}
 
-		// Translate the IDs to poolNames.
-		poolNames, err := dbImageGetPoolNamesFromIDs(d.db, poolIDs)
-		if err != nil {
-			continue
-		}
+	shared.LogInfof("Done updating images")
+}
 
-		// If no optimized pools at least update the base store
-		if len(poolNames) == 0 {
-			poolNames = append(poolNames, "")
-		}
+func autoUpdateImage(d *Daemon, project string, fp string) {
+	id, info, err := dbImageGet(d.db, project, fp, false, true)
+	if err != nil {
+		shared.LogError("Error loading image", log.Ctx{"err": err, "fp": fp, "project": project})
+		return
+	}
 
-		shared.LogDebug("Processing image", log.Ctx{"fp": fp, "server": source.Server, "protocol": source.Protocol, "alias": source.Alias})
+	if !info.AutoUpdate {
+		return
+	}
 
-		// Update the image on each pool where it currently exists.
-		var hash string
-		for _, poolName := range poolNames {
-			hash, err = d.ImageDownload(nil, source.Server, source.Protocol, "", "", source.Alias, false, true, poolName)
-			if hash == fp {
-				shared.LogDebug("Already up to date", log.Ctx{"fp": fp})
-				continue
-			} else if err != nil {
-				shared.LogError("Failed to update the image", log.Ctx{"err": err, "fp": fp})
-				continue
-			}
+	_, source, err := dbImageSourceGet(d.db, id)
+	if err != nil {
+		return
+	}
 
-			newId, _, err := dbImageGet(d.db, hash, false, true)
-			if err != nil {
-				shared.LogError("Error loading image", log.Ctx{"err": err, "fp": hash})
-				continue
-			}
+	// Get the IDs of all storage pools on which a storage volume
+	// for the requested image currently exists.
+	poolIDs, err := dbImageGetPools(d.db, fp)
+	if err != nil {
+		return
+	}
 
-			err = dbImageLastAccessUpdate(d.db, hash, info.LastUsedAt)
-			if err != nil {
-				shared.LogError("Error setting last use date", log.Ctx{"err": err, "fp": hash})
-				continue
-			}
+	// Translate the IDs to poolNames.
+	poolNames, err := dbImageGetPoolNamesFromIDs(d.db, poolIDs)
+	if err != nil {
+		return
+	}
 
-			err = dbImageAliasesMove(d.db, id, newId)
-			if err != nil {
-				shared.LogError("Error moving aliases", log.Ctx{"err": err, "fp": hash})
-				continue
-			}
+	// If no optimized pools at least update the base store
+	if len(poolNames) == 0 {
+		poolNames = append(poolNames, "")
+	}
 
-			err = doDeleteImageFromPool(d, fp, poolName)
-			if err != nil {
-				shared.LogError("Error deleting image", log.Ctx{"err": err, "fp": fp})
-			}
-		}
+	shared.LogDebug("Processing image", log.Ctx{"fp": fp, "server": source.Server, "protocol": source.Protocol, "alias": source.Alias, "project": project})
 
-		// Image didn't change, move on
+	// Update the image on each pool where it currently exists.
+	var hash string
+	for _, poolName := range poolNames {
+		hash, err = d.ImageDownload(nil, project, source.Server, source.Protocol, "", "", source.Alias, false, true, poolName)
 		if hash == fp {
+			shared.LogDebug("Already up to date", log.Ctx{"fp": fp})
+			continue
+		} else if err != nil {
+			shared.LogError("Failed to update the image", log.Ctx{"err": err, "fp": fp})
 			continue
 		}
 
-		// Remove main image file.
-		fname := shared.VarPath("images", fp)
-		if shared.PathExists(fname) {
-			err = os.Remove(fname)
-			if err != nil {
-				shared.LogDebugf("Error deleting image file %s: %s", fname, err)
-			}
+		newId, _, err := dbImageGet(d.db, project, hash, false, true)
+		if err != nil {
+			shared.LogError("Error loading image", log.Ctx{"err": err, "fp": hash})
+			continue
 		}
 
-		// Remove the rootfs file for the image.
-		fname = shared.VarPath("images", fp) + ".rootfs"
-		if shared.PathExists(fname) {
-			err = os.Remove(fname)
-			if err != nil {
-				shared.LogDebugf("Error deleting image file %s: %s", fname, err)
-			}
+		err = dbImageLastAccessUpdate(d.db, hash, info.LastUsedAt)
+		if err != nil {
+			shared.LogError("Error setting last use date", log.Ctx{"err": err, "fp": hash})
+			continue
 		}
 
-		// Remove the database entry for the image.
-		if err = dbImageDelete(d.db, id); err != nil {
-			shared.LogDebugf("Error deleting image from database %s: %s", fname, err)
+		err = dbImageAliasesMove(d.db, id, newId)
+		if err != nil {
+			shared.LogError("Error moving aliases", log.Ctx{"err": err, "fp": hash})
+			continue
 		}
 	}
 
-	shared.LogInfof("Done updating images")
+	// Image didn't change, move on
+	if hash == fp {
+		return
+	}
+
+	err = doDeleteImage(d, id, fp)
+	if err != nil {
+		shared.LogError("Error deleting image", log.Ctx{"err": err, "fp": fp})
+	}
 }
 
 func pruneExpiredImages(d *Daemon) {
 	shared.LogInfof("Pruning expired images")
 
-	// Get the list of expired images.
-	expiry := daemonConfig["images.remote_cache_expiry"].GetInt64()
-	images, err := dbImagesGetExpired(d.db, expiry)
+	projects, err := dbProjects(d.db)
 	if err != nil {
-		shared.LogError("Unable to retrieve the list of expired images", log.Ctx{"err": err})
+		shared.LogError("Unable to retrieve the list of projects", log.Ctx{"err": err})
 		return
 	}
 
-	// Delete them
-	for _, fp := range images {
-		// Get the IDs of all storage pools on which a storage volume
-		// for the requested image currently exists.
-		poolIDs, err := dbImageGetPools(d.db, fp)
-		if err != nil {
-			continue
-		}
-
-		// Translate the IDs to poolNames.
-		poolNames, err := dbImageGetPoolNamesFromIDs(d.db, poolIDs)
+	// Get the list of expired images.
+	expiry := daemonConfig["images.remote_cache_expiry"].GetInt64()
+	for _, project := range projects {
+		images, err := dbImagesGetExpired(d.db, project, expiry)
 		if err != nil {
+			shared.LogError("Unable to retrieve the list of expired images", log.Ctx{"err": err, "project": project})
 			continue
 		}
 
-		for _, pool := range poolNames {
-			err := doDeleteImageFromPool(d, fp, pool)
+		// Delete them
+		for _, fp := range images {
+			imgID, _, err := dbImageGet(d.db, project, fp, false, true)
 			if err != nil {
-				shared.LogDebugf("Error deleting image %s from storage pool %: %s", fp, pool, err)
+				shared.LogDebugf("Error retrieving image info %s: %s", fp, err)
 				continue
 			}
-		}
-
-		// Remove main image file.
-		fname := shared.VarPath("images", fp)
-		if shared.PathExists(fname) {
-			err = os.Remove(fname)
-			if err != nil {
-				shared.LogDebugf("Error deleting image file %s: %s", fname, err)
-			}
-		}
 
-		// Remove the rootfs file for the image.
-		fname = shared.VarPath("images", fp) + ".rootfs"
-		if shared.PathExists(fname) {
-			err = os.Remove(fname)
+			err = doDeleteImage(d, imgID, fp)
 			if err != nil {
-				shared.LogDebugf("Error deleting image file %s: %s", fname, err)
+				shared.LogDebugf("Error deleting image %s: %s", fp, err)
 			}
 		}
-
-		imgID, _, err := dbImageGet(d.db, fp, false, false)
-		if err != nil {
-			shared.LogDebugf("Error retrieving image info %s: %s", fp, err)
-		}
-
-		// Remove the database entry for the image.
-		if err = dbImageDelete(d.db, imgID); err != nil {
-			shared.LogDebugf("Error deleting image %s from database: %s", fp, err)
-		}
 	}
 
 	shared.LogInfof("Done pruning expired images")
@@ -1065,58 +1072,79 @@ func doDeleteImageFromPool(d *Daemon, fingerprint string, storagePool string) er
 	return nil
 }
 
-func imageDelete(d *Daemon, r *http.Request) Response {
-	fingerprint := mux.Vars(r)["fingerprint"]
+// doDeleteImage removes the database entry of an image. The image files and
+// storage volumes are shared between projects, so they only get removed
+// along with the last entry for the fingerprint.
+func doDeleteImage(d *Daemon, id int, fingerprint string) error {
+	err := dbImageDelete(d.db, id)
+	if err != nil {
+		return err
+	}
 
-	deleteFromAllPools := func() error {
-		// Use the fingerprint we received in a LIKE query and use the full
-		// fingerprint we receive from the database in all further queries.
-		imgID, imgInfo, err := dbImageGet(d.db, fingerprint, false, false)
-		if err != nil {
-			return err
-		}
+	projects, err := dbImageProjects(d.db, fingerprint)
+	if err != nil {
+		return err
+	}
 
-		poolIDs, err := dbImageGetPools(d.db, imgInfo.Fingerprint)
-		if err != nil {
-			return err
-		}
+	if len(projects) > 0 {
+		return nil
+	}
+
+	poolIDs, err := dbImageGetPools(d.db, fingerprint)
+	if err != nil {
+		return err
+	}
 
-		pools, err := dbImageGetPoolNamesFromIDs(d.db, poolIDs)
+	pools, err := dbImageGetPoolNamesFromIDs(d.db, poolIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, pool := range pools {
+		err := doDeleteImageFromPool(d, fingerprint, pool)
 		if err != nil {
 			return err
 		}
+	}
 
-		for _, pool := range pools {
-			err := doDeleteImageFromPool(d, imgInfo.Fingerprint, pool)
-			if err != nil {
-				return err
-			}
+	// Remove main image file.
+	fname := shared.VarPath("images", fingerprint)
+	if shared.PathExists(fname) {
+		err = os.Remove(fname)
+		if err != nil {
+			shared.LogDebugf("Error deleting image file %s: %s", fname, err)
 		}
+	}
 
-		// Remove main image file.
-		fname := shared.VarPath("images", imgInfo.Fingerprint)
-		if shared.PathExists(fname) {
-			err = os.Remove(fname)
-			if err != nil {
-				shared.LogDebugf("Error deleting image file %s: %s", fname, err)
-			}
+	// Remove the rootfs file for the image.
+	fname = shared.VarPath("images", fingerprint) + ".rootfs"
+	if shared.PathExists(fname) {
+		err = os.Remove(fname)
+		if err != nil {
+			shared.LogDebugf("Error deleting image file %s: %s", fname, err)
 		}
+	}
 
-		// Remove the rootfs file for the image.
-		fname = shared.VarPath("images", imgInfo.Fingerprint) + ".rootfs"
-		if shared.PathExists(fname) {
-			err = os.Remove(fname)
-			if err != nil {
-				shared.LogDebugf("Error deleting image file %s: %s", fname, err)
-			}
-		}
+	return nil
+}
 
-		// Remove the database entry for the image.
-		return dbImageDelete(d.db, imgID)
+func imageDelete(d *Daemon, r *http.Request) Response {
+	project, err := imageProject(d.db, projectParam(r))
+	if err != nil {
+		return SmartError(err)
 	}
 
+	fingerprint := mux.Vars(r)["fingerprint"]
+
 	rmimg := func(op *operation) error {
-		return deleteFromAllPools()
+		// Use the fingerprint we received in a LIKE query and use the full
+		// fingerprint we receive from the database in all further queries.
+		imgID, imgInfo, err := dbImageGet(d.db, project, fingerprint, false, false)
+		if err != nil {
+			return err
+		}
+
+		return doDeleteImage(d, imgID, imgInfo.Fingerprint)
 	}
 
 	resources := map[string][]string{}
@@ -1130,8 +1158,8 @@ func imageDelete(d *Daemon, r *http.Request) Response {
 	return OperationResponse(op)
 }
 
-func doImageGet(d *Daemon, fingerprint string, public bool) (*api.Image, Response) {
-	_, imgInfo, err := dbImageGet(d.db, fingerprint, public, false)
+func doImageGet(d *Daemon, project string, fingerprint string, public bool) (*api.Image, Response) {
+	_, imgInfo, err := dbImageGet(d.db, project, fingerprint, public, false)
 	if err != nil {
 		return nil, SmartError(err)
 	}
@@ -1170,6 +1198,11 @@ func imageValidSecret(fingerprint string, secret string) bool {
 }
 
 func imageGet(d *Daemon, r *http.Request) Response {
+	project, err := imageProject(d.db, projectParam(r))
+	if err != nil {
+		return SmartError(err)
+	}
+
 	fingerprint := mux.Vars(r)["fingerprint"]
 	public := !d.isTrustedClient(r)
 	secret := r.FormValue("secret")
@@ -1178,7 +1211,7 @@ func imageGet(d *Daemon, r *http.Request) Response {
 		public = false
 	}
 
-	info, response := doImageGet(d, fingerprint, public)
+	info, response := doImageGet(d, project, fingerprint, public)
 	if response != nil {
 		return response
 	}
@@ -1188,9 +1221,14 @@ func imageGet(d *Daemon, r *http.Request) Response {
 }
 
 func imagePut(d *Daemon, r *http.Request) Response {
+	project, err := imageProject(d.db, projectParam(r))
+	if err != nil {
+		return SmartError(err)
+	}
+
 	// Get current value
 	fingerprint := mux.Vars(r)["fingerprint"]
-	id, info, err := dbImageGet(d.db, fingerprint, false, false)
+	id, info, err := dbImageGet(d.db, project, fingerprint, false, false)
 	if err != nil {
 		return SmartError(err)
 	}
@@ -1216,9 +1254,14 @@ func imagePut(d *Daemon, r *http.Request) Response {
 }
 
 func imagePatch(d *Daemon, r *http.Request) Response {
+	project, err := imageProject(d.db, projectParam(r))
+	if err != nil {
+		return SmartError(err)
+	}
+
 	// Get current value
 	fingerprint := mux.Vars(r)["fingerprint"]
-	id, info, err := dbImageGet(d.db, fingerprint, false, false)
+	id, info, err := dbImageGet(d.db, project, fingerprint, false, false)
 	if err != nil {
 		return SmartError(err)
 	}
@@ -1284,6 +1327,11 @@ func imagePatch(d *Daemon, r *http.Request) Response {
 var imageCmd = Command{name: "images/{fingerprint}", untrustedGet: true, get: imageGet, put: imagePut, delete: imageDelete, patch: imagePatch}
 
 func aliasesPost(d *Daemon, r *http.Request) Response {
+	project, err := imageProject(d.db, projectParam(r))
+	if err != nil {
+		return SmartError(err)
+	}
+
 	req := api.ImageAliasesPost{}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		return BadRequest(err)
@@ -1294,17 +1342,17 @@ func aliasesPost(d *Daemon, r *http.Request) Response {
 	}
 
 	// This is just to see if the alias name already exists.
-	_, _, err := dbImageAliasGet(d.db, req.Name, true)
+	_, _, err = dbImageAliasGet(d.db, project, req.Name, true)
 	if err == nil {
 		return Conflict
 	}
 
-	id, _, err := dbImageGet(d.db, req.Target, false, false)
+	id, _, err := dbImageGet(d.db, project, req.Target, false, false)
 	if err != nil {
 		return SmartError(err)
 	}
 
-	err = dbImageAliasAdd(d.db, req.Name, id, req.Description)
+	err = dbImageAliasAdd(d.db, project, req.Name, id, req.Description)
 	if err != nil {
 		return InternalError(err)
 	}
@@ -1313,11 +1361,16 @@ func aliasesPost(d *Daemon, r *http.Request) Response {
 }
 
 func aliasesGet(d *Daemon, r *http.Request) Response {
+	project, err := imageProject(d.db, projectParam(r))
+	if err != nil {
+		return SmartError(err)
+	}
+
 	recursion := d.isRecursionRequest(r)
 
-	q := "SELECT name FROM images_aliases"
+	q := "SELECT name FROM images_aliases WHERE project_id=(SELECT id FROM projects WHERE name=?)"
 	var name string
-	inargs := []interface{}{}
+	inargs := []interface{}{project}
 	outfmt := []interface{}{name}
 	results, err := dbQueryScan(d.db, q, inargs, outfmt)
 	if err != nil {
@@ -1328,11 +1381,11 @@ func aliasesGet(d *Daemon, r *http.Request) Response {
 	for _, res := range results {
 		name = res[0].(string)
 		if !recursion {
-			url := fmt.Sprintf("/%s/images/aliases/%s", version.APIVersion, name)
+			url := fmt.Sprintf("/%s/images/aliases/%s%s", version.APIVersion, name, projectQuery(project))
 			responseStr = append(responseStr, url)
 
 		} else {
-			_, alias, err := dbImageAliasGet(d.db, name, d.isTrustedClient(r))
+			_, alias, err := dbImageAliasGet(d.db, project, name, d.isTrustedClient(r))
 			if err != nil {
 				continue
 			}
@@ -1348,9 +1401,14 @@ func aliasesGet(d *Daemon, r *http.Request) Response {
 }
 
 func aliasGet(d *Daemon, r *http.Request) Response {
+	project, err := imageProject(d.db, projectParam(r))
+	if err != nil {
+		return SmartError(err)
+	}
+
 	name := mux.Vars(r)["name"]
 
-	_, alias, err := dbImageAliasGet(d.db, name, d.isTrustedClient(r))
+	_, alias, err := dbImageAliasGet(d.db, project, name, d.isTrustedClient(r))
 	if err != nil {
 		return SmartError(err)
 	}
@@ -1359,13 +1417,18 @@ func aliasGet(d *Daemon, r *http.Request) Response {
 }
 
 func aliasDelete(d *Daemon, r *http.Request) Response {
+	project, err := imageProject(d.db, projectParam(r))
+	if err != nil {
+		return SmartError(err)
+	}
+
 	name := mux.Vars(r)["name"]
-	_, _, err := dbImageAliasGet(d.db, name, true)
+	_, _, err = dbImageAliasGet(d.db, project, name, true)
 	if err != nil {
 		return SmartError(err)
 	}
 
-	err = dbImageAliasDelete(d.db, name)
+	err = dbImageAliasDelete(d.db, project, name)
 	if err != nil {
 		return SmartError(err)
 	}
@@ -1374,9 +1437,14 @@ func aliasDelete(d *Daemon, r *http.Request) Response {
 }
 
 func aliasPut(d *Daemon, r *http.Request) Response {
+	project, err := imageProject(d.db, projectParam(r))
+	if err != nil {
+		return SmartError(err)
+	}
+
 	// Get current value
 	name := mux.Vars(r)["name"]
-	id, alias, err := dbImageAliasGet(d.db, name, true)
+	id, alias, err := dbImageAliasGet(d.db, project, name, true)
 	if err != nil {
 		return SmartError(err)
 	}
@@ -1396,7 +1464,7 @@ func aliasPut(d *Daemon, r *http.Request) Response {
 		return BadRequest(fmt.Errorf("The target field is required"))
 	}
 
-	imageId, _, err := dbImageGet(d.db, req.Target, false, false)
+	imageId, _, err := dbImageGet(d.db, project, req.Target, false, false)
 	if err != nil {
 		return SmartError(err)
 	}
@@ -1410,9 +1478,14 @@ func aliasPut(d *Daemon, r *http.Request) Response {
 }
 
 func aliasPatch(d *Daemon, r *http.Request) Response {
+	project, err := imageProject(d.db, projectParam(r))
+	if err != nil {
+		return SmartError(err)
+	}
+
 	// Get current value
 	name := mux.Vars(r)["name"]
-	id, alias, err := dbImageAliasGet(d.db, name, true)
+	id, alias, err := dbImageAliasGet(d.db, project, name, true)
 	if err != nil {
 		return SmartError(err)
 	}
@@ -1448,7 +1521,7 @@ func aliasPatch(d *Daemon, r *http.Request) Response {
 		alias.Description = description
 	}
 
-	imageId, _, err := dbImageGet(d.db, alias.Target, false, false)
+	imageId, _, err := dbImageGet(d.db, project, alias.Target, false, false)
 	if err != nil {
 		return SmartError(err)
 	}
@@ -1462,6 +1535,11 @@ func aliasPatch(d *Daemon, r *http.Request) Response {
 }
 
 func aliasPost(d *Daemon, r *http.Request) Response {
+	project, err := imageProject(d.db, projectParam(r))
+	if err != nil {
+		return SmartError(err)
+	}
+
 	name := mux.Vars(r)["name"]
 
 	req := api.ImageAliasesEntryPost{}
@@ -1470,12 +1548,12 @@ func aliasPost(d *Daemon, r *http.Request) Response {
 	}
 
 	// Check that the name isn't already in use
-	id, _, _ := dbImageAliasGet(d.db, req.Name, true)
+	id, _, _ := dbImageAliasGet(d.db, project, req.Name, true)
 	if id > 0 {
 		return Conflict
 	}
 
-	id, _, err := dbImageAliasGet(d.db, name, true)
+	id, _, err = dbImageAliasGet(d.db, project, name, true)
 	if err != nil {
 		return SmartError(err)
 	}
@@ -1489,6 +1567,11 @@ func aliasPost(d *Daemon, r *http.Request) Response {
 }
 
 func imageExport(d *Daemon, r *http.Request) Response {
+	project, err := imageProject(d.db, projectParam(r))
+	if err != nil {
+		return SmartError(err)
+	}
+
 	fingerprint := mux.Vars(r)["fingerprint"]
 
 	public := !d.isTrustedClient(r)
@@ -1498,7 +1581,7 @@ func imageExport(d *Daemon, r *http.Request) Response {
 		public = false
 	}
 
-	_, imgInfo, err := dbImageGet(d.db, fingerprint, public, false)
+	_, imgInfo, err := dbImageGet(d.db, project, fingerprint, public, false)
 	if err != nil {
 		return SmartError(err)
 	}
@@ -1543,8 +1626,13 @@ func imageExport(d *Daemon, r *http.Request) Response {
 }
 
 func imageSecret(d *Daemon, r *http.Request) Response {
+	project, err := imageProject(d.db, projectParam(r))
+	if err != nil {
+		return SmartError(err)
+	}
+
 	fingerprint := mux.Vars(r)["fingerprint"]
-	_, _, err := dbImageGet(d.db, fingerprint, false, false)
+	_, _, err = dbImageGet(d.db, project, fingerprint, false, false)
 	if err != nil {
 		return SmartError(err)
 	}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/shared"
+)
+
+// instanceType describes the resources of an instance type preset, with the
+// memory expressed in GB.
+type instanceType struct {
+	CPU    float64 `yaml:"cpu"`
+	Memory float64 `yaml:"mem"`
+}
+
+// instanceTypesDefault is the list of presets always available, grouped by
+// cloud. Additional presets can be defined in the instance-types.yaml file
+// found in the LXD directory, which uses the same layout.
+var instanceTypesDefault = map[string]map[string]instanceType{
+	"aws": {
+		"t2.nano":    {CPU: 1, Memory: 0.5},
+		"t2.micro":   {CPU: 1, Memory: 1},
+		"t2.small":   {CPU: 1, Memory: 2},
+		"t2.medium":  {CPU: 2, Memory: 4},
+		"t2.large":   {CPU: 2, Memory: 8},
+		"t2.xlarge":  {CPU: 4, Memory: 16},
+		"t2.2xlarge": {CPU: 8, Memory: 32},
+	},
+}
+
+func instanceTypesLoad() (map[string]map[string]instanceType, error) {
+	types := map[string]map[string]instanceType{}
+	for cloud, presets := range instanceTypesDefault {
+		types[cloud] = map[string]instanceType{}
+		for name, preset := range presets {
+			types[cloud][name] = preset
+		}
+	}
+
+	content, err := ioutil.ReadFile(shared.VarPath("instance-types.yaml"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return types, nil
+		}
+
+		return nil, err
+	}
+
+	local := map[string]map[string]instanceType{}
+	err = yaml.Unmarshal(content, &local)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse instance-types.yaml: %s", err)
+	}
+
+	for cloud, presets := range local {
+		_, ok := types[cloud]
+		if !ok {
+			types[cloud] = map[string]instanceType{}
+		}
+
+		for name, preset := range presets {
+			types[cloud][name] = preset
+		}
+	}
+
+	return types, nil
+}
+
+// instanceParseType turns an instance type into the limits it stands for. The
+// value is either "c<CPU>-m<RAM in GB>" or the name of a preset, optionally
+// prefixed by the name of its cloud ("<cloud>:<name>").
+func instanceParseType(value string) (map[string]string, error) {
+	preset, err := instanceTypeLookup(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if preset.CPU <= 0 || preset.Memory <= 0 {
+		return nil, fmt.Errorf("Invalid instance type: %s", value)
+	}
+
+	limits := map[string]string{}
+
+	// Fractional CPUs are turned into a hard CPU allowance, capping the
+	// container to that share of a single CPU
+	if preset.CPU < 1 {
+		quota := int(preset.CPU * 100)
+		if quota < 1 {
+			quota = 1
+		}
+
+		limits["limits.cpu.allowance"] = fmt.Sprintf("%dms/100ms", quota)
+	} else {
+		limits["limits.cpu"] = fmt.Sprintf("%d", int(math.Ceil(preset.CPU)))
+	}
+
+	limits["limits.memory"] = fmt.Sprintf("%dMB", int(preset.Memory*1024))
+
+	return limits, nil
+}
+
+func instanceTypeLookup(value string) (instanceType, error) {
+	// Explicit "c<CPU>-m<RAM>" type
+	if strings.HasPrefix(value, "c") && strings.Contains(value, "-m") {
+		fields := strings.SplitN(value[1:], "-m", 2)
+
+		cpu, errCPU := strconv.ParseFloat(fields[0], 64)
+		memory, errMemory := strconv.ParseFloat(fields[1], 64)
+		if errCPU == nil && errMemory == nil {
+			return instanceType{CPU: cpu, Memory: memory}, nil
+		}
+	}
+
+	types, err := instanceTypesLoad()
+	if err != nil {
+		return instanceType{}, err
+	}
+
+	cloud := ""
+	name := value
+	if strings.Contains(value, ":") {
+		fields := strings.SplitN(value, ":", 2)
+		cloud = fields[0]
+		name = fields[1]
+	}
+
+	if cloud != "" {
+		preset, ok := types[cloud][name]
+		if !ok {
+			return instanceType{}, fmt.Errorf("Unknown instance type: %s", value)
+		}
+
+		return preset, nil
+	}
+
+	// Look through the clouds in a stable order
+	clouds := []string{}
+	for cloud := range types {
+		clouds = append(clouds, cloud)
+	}
+	sort.Strings(clouds)
+
+	for _, cloud := range clouds {
+		preset, ok := types[cloud][name]
+		if ok {
+			return preset, nil
+		}
+	}
+
+	return instanceType{}, fmt.Errorf("Unknown instance type: %s", value)
+}
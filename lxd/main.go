@@ -23,6 +23,7 @@ var argMemProfile = gnuflag.String("memprofile", "", "")
 var argNetworkAddress = gnuflag.String("network-address", "", "")
 var argNetworkPort = gnuflag.Int64("network-port", -1, "")
 var argPrintGoroutinesEvery = gnuflag.Int("print-goroutines-every", -1, "")
+var argRepair = gnuflag.Bool("repair", false, "")
 var argStorageBackend = gnuflag.String("storage-backend", "", "")
 var argStorageCreateDevice = gnuflag.String("storage-create-device", "", "")
 var argStorageCreateLoop = gnuflag.Int64("storage-create-loop", -1, "")
@@ -76,6 +77,8 @@ func run() error {
 		fmt.Printf("        Wait until LXD is ready to handle requests\n")
 		fmt.Printf("    import <container name>\n")
 		fmt.Printf("        Import a pre-existing container from storage\n")
+		fmt.Printf("    storage-check [--repair]\n")
+		fmt.Printf("        Check that the storage pools match the database\n")
 
 		fmt.Printf("\n\nCommon options:\n")
 		fmt.Printf("    --debug\n")
@@ -127,6 +130,10 @@ func run() error {
 		fmt.Printf("    --timeout SECONDS\n")
 		fmt.Printf("        How long to wait before failing\n")
 
+		fmt.Printf("\nStorage-check options:\n")
+		fmt.Printf("    --repair\n")
+		fmt.Printf("        Fix the inconsistencies which are safe to fix\n")
+
 		fmt.Printf("\nWaitready options:\n")
 		fmt.Printf("    --timeout SECONDS\n")
 		fmt.Printf("        How long to wait before failing\n")
@@ -140,6 +147,8 @@ func run() error {
 		fmt.Printf("        Grab a file from a running container\n")
 		fmt.Printf("    forkmigrate\n")
 		fmt.Printf("        Restore a container after migration\n")
+		fmt.Printf("    forkproxy\n")
+		fmt.Printf("        Forward a host socket into a container\n")
 		fmt.Printf("    forkputfile\n")
 		fmt.Printf("        Push a file to a running container\n")
 		fmt.Printf("    forkstart\n")
@@ -194,7 +203,7 @@ func run() error {
 	// Process sub-commands
 	if len(os.Args) > 1 {
 		// "forkputfile", "forkgetfile", "forkmount" and "forkumount" are handled specially in nsexec.go
-		// "forkgetnet" is partially handled in nsexec.go (setns)
+		// "forkgetnet" and "forkproxy" are partially handled in nsexec.go (setns)
 		switch os.Args[1] {
 		// Main commands
 		case "activateifneeded":
@@ -213,12 +222,16 @@ func run() error {
 			return cmdWaitReady()
 		case "import":
 			return cmdImport(os.Args[1:])
+		case "storage-check":
+			return cmdStorageCheck()
 
 		// Internal commands
 		case "forkgetnet":
 			return cmdForkGetNet()
 		case "forkmigrate":
 			return cmdForkMigrate(os.Args[1:])
+		case "forkproxy":
+			return cmdForkProxy(os.Args[1:])
 		case "forkstart":
 			return cmdForkStart(os.Args[1:])
 		case "forkexec":
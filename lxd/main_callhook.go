@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"time"
 
@@ -40,6 +41,17 @@ func cmdCallHook(args []string) error {
 		url = fmt.Sprintf("%s?target=%s", url, target)
 	}
 
+	// LXC appends the container name, "net", "up", the interface
+	// type and the host side interface name to the network hook.
+	if state == "network-up" {
+		if len(args) < 6 {
+			return fmt.Errorf("Invalid arguments")
+		}
+
+		hostName := args[len(args)-1]
+		url = fmt.Sprintf("%s?device=%s&host_name=%s", url, args[4], neturl.QueryEscape(hostName))
+	}
+
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return err
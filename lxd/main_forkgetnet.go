@@ -97,28 +97,11 @@ func cmdForkGetNet() error {
 					family = "inet6"
 				}
 
-				scope := "global"
-				if strings.HasPrefix(fields[0], "127") {
-					scope = "local"
-				}
-
-				if fields[0] == "::1" {
-					scope = "local"
-				}
-
-				if strings.HasPrefix(fields[0], "169.254") {
-					scope = "link"
-				}
-
-				if strings.HasPrefix(fields[0], "fe80:") {
-					scope = "link"
-				}
-
 				address := api.ContainerStateNetworkAddress{}
 				address.Family = family
 				address.Address = fields[0]
 				address.Netmask = fields[1]
-				address.Scope = scope
+				address.Scope = networkAddressScope(fields[0])
 
 				network.Addresses = append(network.Addresses, address)
 			}
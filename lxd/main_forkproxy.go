@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+)
+
+// Forkproxy is called with:
+//
+//    lxd forkproxy <pid> <listen address> <connect address>
+//
+// with the listening socket (set up by LXD on the host) passed as fd 3. The
+// process is moved into the user, mount and network namespaces of <pid> by
+// nsexec.go, so that every connection made to the connect address happens
+// from within the container, unix socket paths included.
+func cmdForkProxy(args []string) error {
+	if len(args) != 4 {
+		return fmt.Errorf("Bad arguments %q", args)
+	}
+
+	listenProto, _, err := deviceProxyParseAddr(args[2])
+	if err != nil {
+		return err
+	}
+
+	connectProto, connectAddr, err := deviceProxyParseAddr(args[3])
+	if err != nil {
+		return err
+	}
+
+	file := os.NewFile(3, "listener")
+	defer file.Close()
+
+	if listenProto == "udp" {
+		conn, err := net.FilePacketConn(file)
+		if err != nil {
+			return err
+		}
+
+		return proxyPackets(conn, connectProto, connectAddr)
+	}
+
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return err
+	}
+
+	for {
+		src, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go proxyStream(src, connectProto, connectAddr)
+	}
+}
+
+func proxyStream(src net.Conn, proto string, addr string) {
+	defer src.Close()
+
+	dst, err := net.Dial(proto, addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: failed to connect to %s: %v\n", addr, err)
+		return
+	}
+	defer dst.Close()
+
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+
+	go func() {
+		io.Copy(dst, src)
+		dst.Close()
+		wg.Done()
+	}()
+
+	go func() {
+		io.Copy(src, dst)
+		src.Close()
+		wg.Done()
+	}()
+
+	wg.Wait()
+}
+
+// proxyPackets forwards datagrams, using a separate connection to the target
+// for every client so that replies can be sent back to the right one.
+func proxyPackets(listener net.PacketConn, proto string, addr string) error {
+	lock := sync.Mutex{}
+	clients := map[string]net.Conn{}
+
+	buf := make([]byte, 65536)
+	for {
+		n, clientAddr, err := listener.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+
+		lock.Lock()
+		dst, ok := clients[clientAddr.String()]
+		if !ok {
+			dst, err = net.Dial(proto, addr)
+			if err != nil {
+				lock.Unlock()
+				fmt.Fprintf(os.Stderr, "error: failed to connect to %s: %v\n", addr, err)
+				continue
+			}
+
+			clients[clientAddr.String()] = dst
+
+			go func(dst net.Conn, clientAddr net.Addr) {
+				reply := make([]byte, 65536)
+				for {
+					n, err := dst.Read(reply)
+					if err != nil {
+						break
+					}
+
+					listener.WriteTo(reply[:n], clientAddr)
+				}
+
+				lock.Lock()
+				delete(clients, clientAddr.String())
+				lock.Unlock()
+				dst.Close()
+			}(dst, clientAddr)
+		}
+		lock.Unlock()
+
+		dst.Write(buf[:n])
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/lxc/lxd"
+)
+
+func cmdStorageCheck() error {
+	c, err := lxd.NewClient(&lxd.DefaultConfig, "local")
+	if err != nil {
+		return err
+	}
+
+	res, err := c.StorageCheck(*argRepair)
+	if err != nil {
+		return err
+	}
+
+	left := 0
+	for _, issue := range res.Issues {
+		fmt.Println(storageCheckIssue(issue))
+		if !issue.Repaired {
+			left++
+		}
+	}
+
+	if left > 0 {
+		return fmt.Errorf("Found %d storage inconsistencies", left)
+	}
+
+	return nil
+}
@@ -71,7 +71,7 @@ func (suite *lxdTestSuite) SetupSuite() {
 	devicesMap := map[string]map[string]string{}
 	devicesMap["root"] = rootDev
 
-	defaultID, _, err := dbProfileGet(suite.d.db, "default")
+	defaultID, _, err := dbProfileGet(suite.d.db, "default", "default")
 	if err != nil {
 		os.Exit(1)
 	}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+
+	log "gopkg.in/inconshreveable/log15.v2"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// metricType is the OpenMetrics type of a metric family.
+type metricType string
+
+const (
+	metricTypeCounter metricType = "counter"
+	metricTypeGauge   metricType = "gauge"
+)
+
+type metricSample struct {
+	labels map[string]string
+	value  float64
+}
+
+type metricFamily struct {
+	name    string
+	help    string
+	kind    metricType
+	samples []metricSample
+}
+
+// metricSet holds the metric families in the order they were first added.
+type metricSet struct {
+	families []*metricFamily
+	index    map[string]*metricFamily
+}
+
+func newMetricSet() *metricSet {
+	return &metricSet{index: map[string]*metricFamily{}}
+}
+
+func (m *metricSet) add(name string, kind metricType, help string, labels map[string]string, value float64) {
+	family, ok := m.index[name]
+	if !ok {
+		family = &metricFamily{name: name, help: help, kind: kind}
+		m.index[name] = family
+		m.families = append(m.families, family)
+	}
+
+	family.samples = append(family.samples, metricSample{labels: labels, value: value})
+}
+
+// String renders the metrics using the OpenMetrics text format.
+func (m *metricSet) String() string {
+	var buf bytes.Buffer
+
+	for _, family := range m.families {
+		fmt.Fprintf(&buf, "# HELP %s %s\n", family.name, family.help)
+		fmt.Fprintf(&buf, "# TYPE %s %s\n", family.name, family.kind)
+
+		name := family.name
+		if family.kind == metricTypeCounter {
+			name += "_total"
+		}
+
+		for _, sample := range family.samples {
+			fmt.Fprintf(&buf, "%s%s %v\n", name, metricLabels(sample.labels), sample.value)
+		}
+	}
+
+	buf.WriteString("# EOF\n")
+	return buf.String()
+}
+
+func metricLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	keys := []string{}
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	entries := []string{}
+	for _, k := range keys {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(labels[k])
+		entries = append(entries, fmt.Sprintf(`%s="%s"`, k, value))
+	}
+
+	return fmt.Sprintf("{%s}", strings.Join(entries, ","))
+}
+
+func metricsGet(d *Daemon, r *http.Request) Response {
+	metrics := newMetricSet()
+
+	err := metricsContainers(d, metrics)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	err = metricsDaemon(d, metrics)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	return TextResponse("application/openmetrics-text; version=1.0.0; charset=utf-8", metrics.String())
+}
+
+func metricsContainers(d *Daemon, metrics *metricSet) error {
+	names, err := dbContainersList(d.db, cTypeRegular)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		c, err := containerLoadByName(d, name)
+		if err != nil {
+			shared.LogError("Failed to load container for metrics", log.Ctx{"container": name, "err": err})
+			continue
+		}
+
+		project, containerName := projectSplitName(name)
+		labels := map[string]string{"name": containerName, "project": project}
+
+		running := 0.0
+		if c.IsRunning() {
+			running = 1.0
+		}
+		metrics.add("lxd_container_running", metricTypeGauge, "Whether the container is running.", labels, running)
+
+		if !c.IsRunning() {
+			continue
+		}
+
+		state, err := c.RenderState()
+		if err != nil {
+			shared.LogError("Failed to get container state for metrics", log.Ctx{"container": name, "err": err})
+			continue
+		}
+
+		metricsContainerState(metrics, labels, state)
+	}
+
+	return nil
+}
+
+func metricsContainerState(metrics *metricSet, labels map[string]string, state *api.ContainerState) {
+	withDevice := func(device string) map[string]string {
+		result := map[string]string{"device": device}
+		for k, v := range labels {
+			result[k] = v
+		}
+
+		return result
+	}
+
+	if state.CPU.Usage >= 0 {
+		metrics.add("lxd_cpu_seconds", metricTypeCounter, "CPU time consumed by the container in seconds.", labels, float64(state.CPU.Usage)/1e9)
+	}
+
+	metrics.add("lxd_memory_usage_bytes", metricTypeGauge, "Memory used by the container.", labels, float64(state.Memory.Usage))
+	metrics.add("lxd_memory_usage_peak_bytes", metricTypeGauge, "Peak memory used by the container.", labels, float64(state.Memory.UsagePeak))
+	metrics.add("lxd_memory_swap_usage_bytes", metricTypeGauge, "Swap used by the container.", labels, float64(state.Memory.SwapUsage))
+	metrics.add("lxd_processes", metricTypeGauge, "Number of processes in the container.", labels, float64(state.Processes))
+
+	for device, disk := range state.Disk {
+		metrics.add("lxd_disk_usage_bytes", metricTypeGauge, "Disk space used by the container.", withDevice(device), float64(disk.Usage))
+	}
+
+	for device, network := range state.Network {
+		deviceLabels := withDevice(device)
+		metrics.add("lxd_network_receive_bytes", metricTypeCounter, "Bytes received by the network interface.", deviceLabels, float64(network.Counters.BytesReceived))
+		metrics.add("lxd_network_transmit_bytes", metricTypeCounter, "Bytes sent by the network interface.", deviceLabels, float64(network.Counters.BytesSent))
+		metrics.add("lxd_network_receive_packets", metricTypeCounter, "Packets received by the network interface.", deviceLabels, float64(network.Counters.PacketsReceived))
+		metrics.add("lxd_network_transmit_packets", metricTypeCounter, "Packets sent by the network interface.", deviceLabels, float64(network.Counters.PacketsSent))
+	}
+}
+
+func metricsDaemon(d *Daemon, metrics *metricSet) error {
+	// Operations, by class and status
+	counts := map[string]map[string]int{}
+
+	operationsLock.Lock()
+	for _, op := range operations {
+		class := op.class.String()
+		_, ok := counts[class]
+		if !ok {
+			counts[class] = map[string]int{}
+		}
+
+		counts[class][op.status.String()]++
+	}
+	operationsLock.Unlock()
+
+	classes := []string{}
+	for class := range counts {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+
+	for _, class := range classes {
+		statuses := []string{}
+		for status := range counts[class] {
+			statuses = append(statuses, status)
+		}
+		sort.Strings(statuses)
+
+		for _, status := range statuses {
+			labels := map[string]string{"class": class, "status": strings.ToLower(status)}
+			metrics.add("lxd_operations", metricTypeGauge, "Number of operations known to the daemon.", labels, float64(counts[class][status]))
+		}
+	}
+
+	// Daemon internals
+	metrics.add("lxd_goroutines", metricTypeGauge, "Number of goroutines in the daemon.", nil, float64(runtime.NumGoroutine()))
+
+	eventsLock.Lock()
+	listeners := len(eventListeners)
+	eventsLock.Unlock()
+	metrics.add("lxd_event_listeners", metricTypeGauge, "Number of connected event listeners.", nil, float64(listeners))
+
+	// Image cache
+	count, size, err := dbImagesCacheSize(d.db)
+	if err != nil {
+		return err
+	}
+
+	metrics.add("lxd_images_cached", metricTypeGauge, "Number of cached images.", nil, float64(count))
+	metrics.add("lxd_images_cached_bytes", metricTypeGauge, "Total size of the cached images.", nil, float64(size))
+
+	return nil
+}
+
+var metricsCmd = Command{name: "metrics", metricsGet: true, get: metricsGet}
@@ -8,6 +8,7 @@ package main
 import (
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -38,6 +39,9 @@ type migrationFields struct {
 	fsConn   *websocket.Conn
 
 	container container
+
+	// storage specific fields
+	storage storage
 }
 
 func (c *migrationFields) send(m proto.Message) error {
@@ -230,6 +234,42 @@ func (s *migrationSourceWs) Connect(op *operation, r *http.Request, w http.Respo
 	return nil
 }
 
+// migrationLocalConns returns both ends of a websocket connection over the
+// loopback interface, used to run a storage migration source and sink within
+// the same daemon.
+func migrationLocalConns() (*websocket.Conn, *websocket.Conn, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer listener.Close()
+
+	conns := make(chan *websocket.Conn, 1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := shared.WebsocketUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			conns <- nil
+			return
+		}
+
+		conns <- conn
+	})
+	go http.Serve(listener, handler)
+
+	dst, _, err := websocket.DefaultDialer.Dial(fmt.Sprintf("ws://%s/", listener.Addr().String()), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	src := <-conns
+	if src == nil {
+		dst.Close()
+		return nil, nil, fmt.Errorf("Failed to set up the local migration connection")
+	}
+
+	return src, dst, nil
+}
+
 func writeActionScript(directory string, operation string, secret string) error {
 	script := fmt.Sprintf(`#!/bin/sh -e
 if [ "$CRTOOLS_SCRIPT_ACTION" = "post-dump" ]; then
@@ -337,6 +377,9 @@ func (s *migrationSourceWs) Do(migrateOp *operation) error {
 		}
 	}
 
+	/* Refresh is set to let the sink know we can skip the snapshots it
+	 * already has, it's up to the sink to actually ask for it.
+	 */
 	myType := s.container.Storage().MigrationType()
 	header := MigrationHeader{
 		Fs:            &myType,
@@ -344,6 +387,7 @@ func (s *migrationSourceWs) Do(migrateOp *operation) error {
 		Idmap:         idmaps,
 		SnapshotNames: snapshotNames,
 		Snapshots:     snapshots,
+		Refresh:       proto.Bool(true),
 	}
 
 	if err := s.send(&header); err != nil {
@@ -368,6 +412,13 @@ func (s *migrationSourceWs) Do(migrateOp *operation) error {
 		driver, _ = rsyncMigrationSource(s.container)
 	}
 
+	/* The sink is refreshing an existing copy of the container and told
+	 * us which snapshots it's missing.
+	 */
+	if header.GetRefresh() {
+		driver.Refresh(header.SnapshotNames)
+	}
+
 	// All failure paths need to do a few things to correctly handle errors before returning.
 	// Unfortunately, handling errors is not well-suited to defer as the code depends on the
 	// status of driver and the error value.  The error value is especially tricky due to the
@@ -543,6 +594,7 @@ type migrationSink struct {
 	dialer       websocket.Dialer
 	allConnected chan bool
 	push         bool
+	refresh      bool
 }
 
 type MigrationSinkArgs struct {
@@ -552,14 +604,19 @@ type MigrationSinkArgs struct {
 	Secrets   map[string]string
 	Push      bool
 	Live      bool
+	Refresh   bool
+
+	// Storage specific fields
+	Storage storage
 }
 
 func NewMigrationSink(args *MigrationSinkArgs) (*migrationSink, error) {
 	sink := migrationSink{
-		src:    migrationFields{container: args.Container},
-		url:    args.Url,
-		dialer: args.Dialer,
-		push:   args.Push,
+		src:     migrationFields{container: args.Container},
+		url:     args.Url,
+		dialer:  args.Dialer,
+		push:    args.Push,
+		refresh: args.Refresh,
 	}
 
 	if sink.push {
@@ -750,6 +807,23 @@ func (c *migrationSink) Do(migrateOp *operation) error {
 		resp.Fs = &myType
 	}
 
+	if c.refresh {
+		if !header.GetRefresh() {
+			err := fmt.Errorf("The source server doesn't support refreshing containers")
+			controller(err)
+			return err
+		}
+
+		err := c.refreshSnapshots(&header)
+		if err != nil {
+			controller(err)
+			return err
+		}
+
+		resp.Refresh = proto.Bool(true)
+		resp.SnapshotNames = header.SnapshotNames
+	}
+
 	if err := sender(&resp); err != nil {
 		controller(err)
 		return err
@@ -801,7 +875,7 @@ func (c *migrationSink) Do(migrateOp *operation) error {
 				fsConn = c.src.fsConn
 			}
 
-			if err := mySink(live, c.src.container, snapshots, fsConn, srcIdmap, migrateOp); err != nil {
+			if err := mySink(live, c.src.container, snapshots, fsConn, srcIdmap, migrateOp, c.refresh); err != nil {
 				fsTransfer <- err
 				return
 			}
@@ -883,3 +957,64 @@ func (c *migrationSink) Do(migrateOp *operation) error {
 		}
 	}
 }
+
+/* refreshSnapshots trims the header received from the source down to the
+ * snapshots we actually need when refreshing an existing container. Our
+ * snapshots which don't match the source's history any more are deleted, so
+ * that the newest remaining one can be used as the base of an incremental
+ * transfer.
+ */
+func (c *migrationSink) refreshSnapshots(header *MigrationHeader) error {
+	targetSnapshots, err := c.src.container.Snapshots()
+	if err != nil {
+		return err
+	}
+
+	targetNames := []string{}
+	for _, snap := range targetSnapshots {
+		targetNames = append(targetNames, shared.ExtractSnapshotName(snap.Name()))
+	}
+
+	syncNames, deleteNames := migrationCompareSnapshots(header.SnapshotNames, targetNames)
+
+	for _, snap := range targetSnapshots {
+		if !shared.StringInSlice(shared.ExtractSnapshotName(snap.Name()), deleteNames) {
+			continue
+		}
+
+		err := snap.Delete()
+		if err != nil {
+			return err
+		}
+	}
+
+	snapshots := []*Snapshot{}
+	for _, snap := range header.Snapshots {
+		if shared.StringInSlice(snap.GetName(), syncNames) {
+			snapshots = append(snapshots, snap)
+		}
+	}
+
+	header.SnapshotNames = syncNames
+	header.Snapshots = snapshots
+
+	return nil
+}
+
+/* migrationCompareSnapshots compares the snapshots of the source with the
+ * ones of the target, both ordered from oldest to newest. It returns the
+ * source snapshots which need to be sent and the target snapshots which need
+ * to be deleted, everything from the first point where the two histories
+ * diverge.
+ */
+func migrationCompareSnapshots(sourceNames []string, targetNames []string) ([]string, []string) {
+	i := 0
+	for i < len(sourceNames) && i < len(targetNames) && sourceNames[i] == targetNames[i] {
+		i++
+	}
+
+	syncNames := append([]string{}, sourceNames[i:]...)
+	deleteNames := append([]string{}, targetNames[i:]...)
+
+	return syncNames, deleteNames
+}
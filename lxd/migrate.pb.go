@@ -31,17 +31,20 @@ const (
 	MigrationFSType_RSYNC MigrationFSType = 0
 	MigrationFSType_BTRFS MigrationFSType = 1
 	MigrationFSType_ZFS   MigrationFSType = 2
+	MigrationFSType_LVM   MigrationFSType = 3
 )
 
 var MigrationFSType_name = map[int32]string{
 	0: "RSYNC",
 	1: "BTRFS",
 	2: "ZFS",
+	3: "LVM",
 }
 var MigrationFSType_value = map[string]int32{
 	"RSYNC": 0,
 	"BTRFS": 1,
 	"ZFS":   2,
+	"LVM":   3,
 }
 
 func (x MigrationFSType) Enum() *MigrationFSType {
@@ -260,6 +263,7 @@ type MigrationHeader struct {
 	Idmap            []*IDMapType     `protobuf:"bytes,3,rep,name=idmap" json:"idmap,omitempty"`
 	SnapshotNames    []string         `protobuf:"bytes,4,rep,name=snapshotNames" json:"snapshotNames,omitempty"`
 	Snapshots        []*Snapshot      `protobuf:"bytes,5,rep,name=snapshots" json:"snapshots,omitempty"`
+	Refresh          *bool            `protobuf:"varint,6,opt,name=refresh" json:"refresh,omitempty"`
 	XXX_unrecognized []byte           `json:"-"`
 }
 
@@ -302,6 +306,13 @@ func (m *MigrationHeader) GetSnapshots() []*Snapshot {
 	return nil
 }
 
+func (m *MigrationHeader) GetRefresh() bool {
+	if m != nil && m.Refresh != nil {
+		return *m.Refresh
+	}
+	return false
+}
+
 type MigrationControl struct {
 	Success *bool `protobuf:"varint,1,req,name=success" json:"success,omitempty"`
 	// optional failure message if sending a failure
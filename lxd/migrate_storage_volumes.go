@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/lxc/lxd/shared"
+)
+
+// Custom storage volumes are always migrated using rsync. Only the volume
+// itself is transferred, its snapshots stay on the source.
+
+func NewStorageMigrationSource(storage storage) (*migrationSourceWs, error) {
+	ret := migrationSourceWs{migrationFields{storage: storage}, make(chan bool, 1)}
+
+	var err error
+	ret.controlSecret, err = shared.RandomCryptoString()
+	if err != nil {
+		return nil, err
+	}
+
+	ret.fsSecret, err = shared.RandomCryptoString()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ret, nil
+}
+
+func (s *migrationSourceWs) DoStorage(migrateOp *operation) error {
+	<-s.allConnected
+
+	myType := MigrationFSType_RSYNC
+	header := MigrationHeader{
+		Fs: &myType,
+	}
+
+	err := s.send(&header)
+	if err != nil {
+		s.sendControl(err)
+		return err
+	}
+
+	err = s.recv(&header)
+	if err != nil {
+		s.sendControl(err)
+		return err
+	}
+
+	if *header.Fs != myType {
+		err := fmt.Errorf("Storage volumes can only be migrated using rsync")
+		s.sendControl(err)
+		return err
+	}
+
+	ourMount, err := s.storage.StoragePoolVolumeMount()
+	if err != nil {
+		s.sendControl(err)
+		return err
+	}
+	if ourMount {
+		defer s.storage.StoragePoolVolumeUmount()
+	}
+
+	_, poolName := s.storage.GetContainerPoolInfo()
+	volumeName := s.storage.GetStoragePoolVolumeWritable().Name
+	volumeMntPoint := getStoragePoolVolumeMountPoint(poolName, volumeName)
+
+	wrapper := StorageProgressReader(migrateOp, "fs_progress", volumeName)
+	err = RsyncSend(shared.AddSlash(volumeMntPoint), s.fsConn, wrapper)
+	if err != nil {
+		s.sendControl(err)
+		return err
+	}
+
+	msg := MigrationControl{}
+	err = s.recv(&msg)
+	if err != nil {
+		s.disconnect()
+		return err
+	}
+
+	if !*msg.Success {
+		return fmt.Errorf(*msg.Message)
+	}
+
+	return nil
+}
+
+func NewStorageMigrationSink(args *MigrationSinkArgs) (*migrationSink, error) {
+	sink := migrationSink{
+		src:    migrationFields{storage: args.Storage},
+		url:    args.Url,
+		dialer: args.Dialer,
+		push:   args.Push,
+	}
+
+	if sink.push {
+		return nil, fmt.Errorf("Push mode migration isn't supported for storage volumes")
+	}
+
+	var ok bool
+	sink.src.controlSecret, ok = args.Secrets["control"]
+	if !ok {
+		return nil, fmt.Errorf("Missing control secret")
+	}
+
+	sink.src.fsSecret, ok = args.Secrets["fs"]
+	if !ok {
+		return nil, fmt.Errorf("Missing fs secret")
+	}
+
+	return &sink, nil
+}
+
+func (c *migrationSink) DoStorage(migrateOp *operation) error {
+	var err error
+
+	c.src.controlConn, err = c.connectWithSecret(c.src.controlSecret)
+	if err != nil {
+		return err
+	}
+	defer c.src.disconnect()
+
+	c.src.fsConn, err = c.connectWithSecret(c.src.fsSecret)
+	if err != nil {
+		c.src.sendControl(err)
+		return err
+	}
+
+	header := MigrationHeader{}
+	err = c.src.recv(&header)
+	if err != nil {
+		c.src.sendControl(err)
+		return err
+	}
+
+	myType := MigrationFSType_RSYNC
+	resp := MigrationHeader{
+		Fs: &myType,
+	}
+
+	err = c.src.send(&resp)
+	if err != nil {
+		c.src.sendControl(err)
+		return err
+	}
+
+	fsTransfer := make(chan error)
+	go func(fsConn *websocket.Conn) {
+		ourMount, err := c.src.storage.StoragePoolVolumeMount()
+		if err != nil {
+			fsTransfer <- err
+			return
+		}
+		if ourMount {
+			defer c.src.storage.StoragePoolVolumeUmount()
+		}
+
+		_, poolName := c.src.storage.GetContainerPoolInfo()
+		volumeName := c.src.storage.GetStoragePoolVolumeWritable().Name
+		volumeMntPoint := getStoragePoolVolumeMountPoint(poolName, volumeName)
+
+		wrapper := StorageProgressWriter(migrateOp, "fs_progress", volumeName)
+		fsTransfer <- RsyncRecv(shared.AddSlash(volumeMntPoint), fsConn, wrapper)
+	}(c.src.fsConn)
+
+	source := c.src.controlChannel()
+	for {
+		select {
+		case err = <-fsTransfer:
+			c.src.sendControl(err)
+			return err
+		case msg, ok := <-source:
+			if !ok {
+				c.src.disconnect()
+				return fmt.Errorf("Got error reading source")
+			}
+
+			if !*msg.Success {
+				c.src.disconnect()
+				return fmt.Errorf(*msg.Message)
+			}
+
+			// The source can only tell us it failed.
+			shared.LogDebugf("Unknown message %v from source", msg)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_migration_compare_snapshots(t *testing.T) {
+	tests := []struct {
+		source []string
+		target []string
+		sync   []string
+		delete []string
+	}{
+		{[]string{"snap0", "snap1"}, []string{}, []string{"snap0", "snap1"}, []string{}},
+		{[]string{"snap0", "snap1"}, []string{"snap0"}, []string{"snap1"}, []string{}},
+		{[]string{"snap0", "snap1"}, []string{"snap0", "snap1"}, []string{}, []string{}},
+		{[]string{"snap0"}, []string{"snap0", "snap1"}, []string{}, []string{"snap1"}},
+		{[]string{"snap0", "snap2"}, []string{"snap0", "snap1", "snap2"}, []string{"snap2"}, []string{"snap1", "snap2"}},
+	}
+
+	for _, test := range tests {
+		sync, remove := migrationCompareSnapshots(test.source, test.target)
+		if !reflect.DeepEqual(sync, test.sync) {
+			t.Fatalf("Unexpected snapshots to sync for %v -> %v: %v", test.source, test.target, sync)
+		}
+
+		if !reflect.DeepEqual(remove, test.delete) {
+			t.Fatalf("Unexpected snapshots to delete for %v -> %v: %v", test.source, test.target, remove)
+		}
+	}
+}
@@ -95,6 +95,11 @@ func networksPost(d *Daemon, r *http.Request) Response {
 		return BadRequest(err)
 	}
 
+	_, _, err = networkACLsLoad(d, req.Config["security.acls"])
+	if err != nil {
+		return BadRequest(err)
+	}
+
 	// Set some default values where needed
 	if req.Config["bridge.mode"] == "fan" {
 		if req.Config["fan.underlay_subnet"] == "" {
@@ -191,7 +196,7 @@ func doNetworkGet(d *Daemon, name string) (api.Network, error) {
 		}
 
 		if networkIsInUse(c, n.Name) {
-			n.UsedBy = append(n.UsedBy, fmt.Sprintf("/%s/containers/%s", version.APIVersion, ct))
+			n.UsedBy = append(n.UsedBy, projectContainerURL(ct))
 		}
 	}
 
@@ -360,6 +365,11 @@ func doNetworkUpdate(d *Daemon, name string, oldConfig map[string]string, newCon
 		return BadRequest(err)
 	}
 
+	_, _, err = networkACLsLoad(d, newConfig["security.acls"])
+	if err != nil {
+		return BadRequest(err)
+	}
+
 	// When switching to a fan bridge, auto-detect the underlay
 	if newConfig["bridge.mode"] == "fan" {
 		if newConfig["fan.underlay_subnet"] == "" {
@@ -383,6 +393,64 @@ func doNetworkUpdate(d *Daemon, name string, oldConfig map[string]string, newCon
 
 var networkCmd = Command{name: "networks/{name}", get: networkGet, delete: networkDelete, post: networkPost, put: networkPut, patch: networkPatch}
 
+func networkLeasesGet(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+
+	// Only managed networks have leases
+	_, _, err := dbNetworkGet(d.db, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	leases, err := networkLeases(d, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	return SyncResponse(true, leases)
+}
+
+var networkLeasesCmd = Command{name: "networks/{name}/leases", get: networkLeasesGet}
+
+func networkStateGet(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+
+	state, err := networkGetInterfaceState(name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	// Managed networks also report the interfaces they bring up
+	_, dbInfo, _ := dbNetworkGet(d.db, name)
+	if dbInfo != nil {
+		tunnels := []string{}
+		for _, tunnel := range networkGetTunnels(dbInfo.Config) {
+			tunnels = append(tunnels, fmt.Sprintf("%s-%s", name, tunnel))
+		}
+
+		if dbInfo.Config["bridge.mode"] == "fan" {
+			tunnels = append(tunnels, fmt.Sprintf("%s-fan", name))
+		}
+
+		for _, tunnel := range tunnels {
+			tunnelState, err := networkGetInterfaceState(tunnel)
+			if err != nil {
+				continue
+			}
+
+			if state.Tunnels == nil {
+				state.Tunnels = map[string]api.NetworkState{}
+			}
+
+			state.Tunnels[tunnel] = *tunnelState
+		}
+	}
+
+	return SyncResponse(true, state)
+}
+
+var networkStateCmd = Command{name: "networks/{name}/state", get: networkStateGet}
+
 // The network structs and functions
 func networkLoadByName(d *Daemon, name string) (*network, error) {
 	id, dbInfo, err := dbNetworkGet(d.db, name)
@@ -1127,6 +1195,12 @@ func (n *network) Start() error {
 		}
 	}
 
+	// Apply the network ACLs
+	err = networkACLsApplyNetwork(n)
+	if err != nil {
+		return err
+	}
+
 	// Kill any existing dnsmasq daemon for this network
 	err = networkKillDnsmasq(n.name, false)
 	if err != nil {
@@ -1233,6 +1307,11 @@ func (n *network) Stop() error {
 		return err
 	}
 
+	err = networkACLsClearNetwork(n.name)
+	if err != nil {
+		return err
+	}
+
 	// Kill any existing dnsmasq daemon for this network
 	err = networkKillDnsmasq(n.name, false)
 	if err != nil {
@@ -0,0 +1,869 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
+)
+
+// API endpoints
+func networkACLsGet(d *Daemon, r *http.Request) Response {
+	recursionStr := r.FormValue("recursion")
+	recursion, err := strconv.Atoi(recursionStr)
+	if err != nil {
+		recursion = 0
+	}
+
+	names, err := dbNetworkACLs(d.db)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	resultString := []string{}
+	resultMap := []api.NetworkACL{}
+	for _, name := range names {
+		if recursion == 0 {
+			resultString = append(resultString, fmt.Sprintf("/%s/network-acls/%s", version.APIVersion, name))
+		} else {
+			acl, err := doNetworkACLGet(d, name)
+			if err != nil {
+				continue
+			}
+			resultMap = append(resultMap, *acl)
+		}
+	}
+
+	if recursion == 0 {
+		return SyncResponse(true, resultString)
+	}
+
+	return SyncResponse(true, resultMap)
+}
+
+func networkACLsPost(d *Daemon, r *http.Request) Response {
+	req := api.NetworkACLsPost{}
+
+	// Parse the request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	// Sanity checks
+	if req.Name == "" {
+		return BadRequest(fmt.Errorf("No name provided"))
+	}
+
+	err = networkACLValidName(req.Name)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	err = networkACLValidate(req.NetworkACLPut)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	_, _, err = dbNetworkACLGet(d.db, req.Name)
+	if err == nil {
+		return BadRequest(fmt.Errorf("The network ACL already exists"))
+	}
+
+	// Create the database entry
+	_, err = dbNetworkACLCreate(d.db, req.Name, req.NetworkACLPut)
+	if err != nil {
+		return InternalError(fmt.Errorf("Error inserting %s into database: %s", req.Name, err))
+	}
+
+	return SyncResponseLocation(true, nil, fmt.Sprintf("/%s/network-acls/%s", version.APIVersion, req.Name))
+}
+
+var networkACLsCmd = Command{name: "network-acls", get: networkACLsGet, post: networkACLsPost}
+
+func networkACLGet(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+
+	acl, err := doNetworkACLGet(d, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	etag := []interface{}{acl.Name, acl.Description, acl.Ingress, acl.Egress}
+
+	return SyncResponseETag(true, acl, etag)
+}
+
+func doNetworkACLGet(d *Daemon, name string) (*api.NetworkACL, error) {
+	_, acl, err := dbNetworkACLGet(d.db, name)
+	if err != nil {
+		return nil, err
+	}
+
+	acl.UsedBy, err = networkACLUsedBy(d, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return acl, nil
+}
+
+func networkACLPut(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+
+	// Get the existing ACL
+	_, dbInfo, err := dbNetworkACLGet(d.db, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	// Validate the ETag
+	etag := []interface{}{dbInfo.Name, dbInfo.Description, dbInfo.Ingress, dbInfo.Egress}
+
+	err = etagCheck(r, etag)
+	if err != nil {
+		return PreconditionFailed(err)
+	}
+
+	req := api.NetworkACLPut{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return BadRequest(err)
+	}
+
+	return doNetworkACLUpdate(d, name, req)
+}
+
+func networkACLPatch(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+
+	// Get the existing ACL
+	_, dbInfo, err := dbNetworkACLGet(d.db, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	// Validate the ETag
+	etag := []interface{}{dbInfo.Name, dbInfo.Description, dbInfo.Ingress, dbInfo.Egress}
+
+	err = etagCheck(r, etag)
+	if err != nil {
+		return PreconditionFailed(err)
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	rdr1 := ioutil.NopCloser(bytes.NewBuffer(body))
+	rdr2 := ioutil.NopCloser(bytes.NewBuffer(body))
+
+	reqRaw := shared.Jmap{}
+	if err := json.NewDecoder(rdr1).Decode(&reqRaw); err != nil {
+		return BadRequest(err)
+	}
+
+	req := api.NetworkACLPut{}
+	if err := json.NewDecoder(rdr2).Decode(&req); err != nil {
+		return BadRequest(err)
+	}
+
+	// Keep whatever wasn't provided
+	_, err = reqRaw.GetString("description")
+	if err != nil {
+		req.Description = dbInfo.Description
+	}
+
+	if req.Ingress == nil {
+		req.Ingress = dbInfo.Ingress
+	}
+
+	if req.Egress == nil {
+		req.Egress = dbInfo.Egress
+	}
+
+	return doNetworkACLUpdate(d, name, req)
+}
+
+func doNetworkACLUpdate(d *Daemon, name string, req api.NetworkACLPut) Response {
+	err := networkACLValidate(req)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	err = dbNetworkACLUpdate(d.db, name, req)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	// Apply the new rules wherever the ACL is in use
+	err = networkACLsRefresh(d, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	return EmptySyncResponse
+}
+
+func networkACLPost(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+	req := api.NetworkACLPost{}
+
+	// Parse the request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	// Sanity checks
+	if req.Name == "" {
+		return BadRequest(fmt.Errorf("No name provided"))
+	}
+
+	err = networkACLValidName(req.Name)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	_, _, err = dbNetworkACLGet(d.db, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	_, _, err = dbNetworkACLGet(d.db, req.Name)
+	if err == nil {
+		return Conflict
+	}
+
+	// The references would otherwise have to be updated
+	usedBy, err := networkACLUsedBy(d, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	if len(usedBy) > 0 {
+		return BadRequest(fmt.Errorf("The network ACL is currently in use"))
+	}
+
+	err = dbNetworkACLRename(d.db, name, req.Name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	return SyncResponseLocation(true, nil, fmt.Sprintf("/%s/network-acls/%s", version.APIVersion, req.Name))
+}
+
+func networkACLDelete(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+
+	_, _, err := dbNetworkACLGet(d.db, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	usedBy, err := networkACLUsedBy(d, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	if len(usedBy) > 0 {
+		return BadRequest(fmt.Errorf("The network ACL is currently in use"))
+	}
+
+	err = dbNetworkACLDelete(d.db, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	return EmptySyncResponse
+}
+
+var networkACLCmd = Command{name: "network-acls/{name}", get: networkACLGet, delete: networkACLDelete, post: networkACLPost, put: networkACLPut, patch: networkACLPatch}
+
+// Validation
+func networkACLValidName(value string) error {
+	if len(value) > 63 {
+		return fmt.Errorf("Network ACL name is too long (maximum 63 characters)")
+	}
+
+	match, _ := regexp.MatchString("^[a-zA-Z0-9][-_.a-zA-Z0-9]*$", value)
+	if !match {
+		return fmt.Errorf("Network ACL name contains invalid characters")
+	}
+
+	return nil
+}
+
+// networkACLNames returns the ACL names listed in a "security.acls" value.
+func networkACLNames(value string) []string {
+	names := []string{}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// networkACLValidNames validates the syntax of a "security.acls" value.
+func networkACLValidNames(value string) error {
+	for _, name := range networkACLNames(value) {
+		err := networkACLValidName(name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func networkACLValidate(acl api.NetworkACLPut) error {
+	for _, rule := range append(acl.Ingress, acl.Egress...) {
+		err := networkACLValidRule(rule)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func networkACLValidRule(rule api.NetworkACLRule) error {
+	if !shared.StringInSlice(rule.Action, []string{"allow", "reject", "drop"}) {
+		return fmt.Errorf("Invalid rule action: \"%s\"", rule.Action)
+	}
+
+	if !shared.StringInSlice(rule.Protocol, []string{"", "tcp", "udp", "icmp4", "icmp6"}) {
+		return fmt.Errorf("Invalid rule protocol: \"%s\"", rule.Protocol)
+	}
+
+	if (rule.SourcePort != "" || rule.DestinationPort != "") && !shared.StringInSlice(rule.Protocol, []string{"tcp", "udp"}) {
+		return fmt.Errorf("Ports can only be used with the tcp and udp protocols")
+	}
+
+	for _, value := range []string{rule.Source, rule.Destination} {
+		for _, entry := range strings.Split(value, ",") {
+			entry = strings.TrimSpace(entry)
+			if value == "" {
+				continue
+			}
+
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				_, _, err := net.ParseCIDR(entry)
+				if err != nil {
+					return fmt.Errorf("Invalid address or subnet: \"%s\"", entry)
+				}
+			}
+
+			if networkACLFamily(entry) == "ipv4" && rule.Protocol == "icmp6" || networkACLFamily(entry) == "ipv6" && rule.Protocol == "icmp4" {
+				return fmt.Errorf("Address \"%s\" can't be used with protocol %s", entry, rule.Protocol)
+			}
+		}
+	}
+
+	for _, value := range []string{rule.SourcePort, rule.DestinationPort} {
+		for _, entry := range strings.Split(value, ",") {
+			entry = strings.TrimSpace(entry)
+			if value == "" {
+				continue
+			}
+
+			ports := strings.SplitN(entry, "-", 2)
+			for _, port := range ports {
+				err := networkValidPort(port)
+				if err != nil || port == "" {
+					return fmt.Errorf("Invalid port or port range: \"%s\"", entry)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// networkACLFamily returns the family of an address or subnet.
+func networkACLFamily(value string) string {
+	if strings.Contains(value, ":") {
+		return "ipv6"
+	}
+
+	return "ipv4"
+}
+
+// References
+func networkACLUsedBy(d *Daemon, name string) ([]string, error) {
+	usedBy := []string{}
+
+	networks, err := dbNetworks(d.db)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, netName := range networks {
+		_, network, err := dbNetworkGet(d.db, netName)
+		if err != nil {
+			return nil, err
+		}
+
+		if shared.StringInSlice(name, networkACLNames(network.Config["security.acls"])) {
+			usedBy = append(usedBy, fmt.Sprintf("/%s/networks/%s", version.APIVersion, netName))
+		}
+	}
+
+	profiles, err := dbNetworkACLProfiles(d.db)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, profile := range profiles {
+		url := fmt.Sprintf("/%s/profiles/%s%s", version.APIVersion, profile[0], projectQuery(profile[1]))
+		if shared.StringInSlice(name, networkACLNames(profile[2])) && !shared.StringInSlice(url, usedBy) {
+			usedBy = append(usedBy, url)
+		}
+	}
+
+	cts, err := dbContainersList(d.db, cTypeRegular)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ct := range cts {
+		c, err := containerLoadByName(d, ct)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range c.ExpandedDevices() {
+			if m["type"] == "nic" && shared.StringInSlice(name, networkACLNames(m["security.acls"])) {
+				usedBy = append(usedBy, projectContainerURL(ct))
+				break
+			}
+		}
+	}
+
+	return usedBy, nil
+}
+
+// networkACLsLoad returns the ingress and egress rules of the ACLs listed in
+// a "security.acls" value, in order.
+func networkACLsLoad(d *Daemon, value string) ([]api.NetworkACLRule, []api.NetworkACLRule, error) {
+	ingress := []api.NetworkACLRule{}
+	egress := []api.NetworkACLRule{}
+
+	for _, name := range networkACLNames(value) {
+		_, acl, err := dbNetworkACLGet(d.db, name)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, nil, fmt.Errorf("Network ACL \"%s\" doesn't exist", name)
+			}
+
+			return nil, nil, err
+		}
+
+		ingress = append(ingress, acl.Ingress...)
+		egress = append(egress, acl.Egress...)
+	}
+
+	return ingress, egress, nil
+}
+
+// Rules generation
+func networkACLAddresses(value string, family string) ([]string, bool) {
+	if value == "" {
+		return []string{""}, true
+	}
+
+	addresses := []string{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if networkACLFamily(entry) == family {
+			addresses = append(addresses, entry)
+		}
+	}
+
+	return addresses, len(addresses) > 0
+}
+
+func networkACLPorts(value string) []string {
+	if value == "" {
+		return []string{""}
+	}
+
+	ports := []string{}
+	for _, entry := range strings.Split(value, ",") {
+		ports = append(ports, strings.Replace(strings.TrimSpace(entry), "-", ":", 1))
+	}
+
+	return ports
+}
+
+// networkACLMatches returns the combinations of addresses and ports matched
+// by a rule for the given family, along with the protocol name to use.
+// Nothing is returned if the rule doesn't apply to the family.
+func networkACLMatches(family string, rule api.NetworkACLRule) ([][]string, string) {
+	protocol := rule.Protocol
+	if protocol == "icmp4" {
+		if family != "ipv4" {
+			return nil, ""
+		}
+
+		protocol = "icmp"
+	} else if protocol == "icmp6" {
+		if family != "ipv6" {
+			return nil, ""
+		}
+
+		protocol = "ipv6-icmp"
+	}
+
+	sources, ok := networkACLAddresses(rule.Source, family)
+	if !ok {
+		return nil, ""
+	}
+
+	destinations, ok := networkACLAddresses(rule.Destination, family)
+	if !ok {
+		return nil, ""
+	}
+
+	matches := [][]string{}
+	for _, source := range sources {
+		for _, destination := range destinations {
+			for _, sourcePort := range networkACLPorts(rule.SourcePort) {
+				for _, destinationPort := range networkACLPorts(rule.DestinationPort) {
+					matches = append(matches, []string{source, destination, sourcePort, destinationPort})
+				}
+			}
+		}
+	}
+
+	return matches, protocol
+}
+
+// networkACLIptablesRules returns the iptables arguments implementing a rule.
+func networkACLIptablesRules(family string, rule api.NetworkACLRule) [][]string {
+	target := map[string]string{"allow": "ACCEPT", "reject": "REJECT", "drop": "DROP"}[rule.Action]
+
+	matches, protocol := networkACLMatches(family, rule)
+
+	rules := [][]string{}
+	for _, match := range matches {
+		args := []string{}
+		for i, flag := range []string{"-s", "-d"} {
+			if match[i] != "" {
+				args = append(args, flag, match[i])
+			}
+		}
+
+		if protocol != "" {
+			args = append(args, "-p", protocol)
+		}
+
+		for i, flag := range []string{"--sport", "--dport"} {
+			if match[i+2] != "" {
+				args = append(args, flag, match[i+2])
+			}
+		}
+
+		rules = append(rules, append(args, "-j", target))
+	}
+
+	return rules
+}
+
+// networkACLEbtablesRules returns the ebtables arguments implementing a rule.
+// As ebtables can't reject packets, rejected packets are dropped. Allowed
+// packets go back to the calling chain so that other filters still apply.
+func networkACLEbtablesRules(family string, rule api.NetworkACLRule) [][]string {
+	target := "DROP"
+	if rule.Action == "allow" {
+		target = "RETURN"
+	}
+
+	protocol := "IPv4"
+	prefix := "--ip"
+	if family == "ipv6" {
+		protocol = "IPv6"
+		prefix = "--ip6"
+	}
+
+	matches, ipProtocol := networkACLMatches(family, rule)
+
+	rules := [][]string{}
+	for _, match := range matches {
+		args := []string{"-p", protocol}
+		for i, flag := range []string{"-src", "-dst"} {
+			if match[i] != "" {
+				args = append(args, prefix+flag, match[i])
+			}
+		}
+
+		if ipProtocol != "" {
+			args = append(args, prefix+"-proto", ipProtocol)
+		}
+
+		for i, flag := range []string{"-sport", "-dport"} {
+			if match[i+2] != "" {
+				args = append(args, prefix+flag, match[i+2])
+			}
+		}
+
+		rules = append(rules, append(args, "-j", target))
+	}
+
+	return rules
+}
+
+// Rules application
+func networkACLNetworkChain(network string, direction string) string {
+	return fmt.Sprintf("lxdacl-%s-%s", direction, network)
+}
+
+// networkACLsApplyNetwork sets up the iptables chains filtering the traffic
+// forwarded in (ingress) and out (egress) of a managed network. Established
+// connections are always allowed, while new ones not matching any rule get
+// rejected.
+func networkACLsApplyNetwork(n *network) error {
+	ingress, egress, err := networkACLsLoad(n.daemon, n.config["security.acls"])
+	if err != nil {
+		return err
+	}
+
+	directions := []struct {
+		name  string
+		rules []api.NetworkACLRule
+		match string
+	}{
+		{"in", ingress, "-o"},
+		{"out", egress, "-i"},
+	}
+
+	for _, family := range []string{"ipv4", "ipv6"} {
+		if family == "ipv6" && !shared.PathExists("/proc/sys/net/ipv6") {
+			continue
+		}
+
+		for _, direction := range directions {
+			chain := networkACLNetworkChain(n.name, direction.name)
+			if len(direction.rules) == 0 {
+				err := networkIptablesChainDelete(family, chain)
+				if err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			err := networkIptablesChainCreate(family, chain)
+			if err != nil {
+				return err
+			}
+
+			entries := [][]string{{"-m", "conntrack", "--ctstate", "ESTABLISHED,RELATED", "-j", "ACCEPT"}}
+			for _, rule := range direction.rules {
+				entries = append(entries, networkACLIptablesRules(family, rule)...)
+			}
+			entries = append(entries, []string{"-j", "REJECT"})
+
+			// Rules get prepended, so go through them backward
+			for i := len(entries) - 1; i >= 0; i-- {
+				err = networkIptablesPrepend(family, n.name, "", chain, entries[i]...)
+				if err != nil {
+					return err
+				}
+			}
+
+			err = networkIptablesPrepend(family, n.name, "", "FORWARD", direction.match, n.name, "-j", chain)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// networkACLsClearNetwork removes the ACL chains of a managed network.
+func networkACLsClearNetwork(name string) error {
+	for _, family := range []string{"ipv4", "ipv6"} {
+		for _, direction := range []string{"in", "out"} {
+			err := networkIptablesChainDelete(family, networkACLNetworkChain(name, direction))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// networkACLNicChain returns the name of the ebtables chain of a container
+// nic, which has to fit in 31 characters.
+func networkACLNicChain(container string, device string, direction string) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s/%s", container, device)))
+	return fmt.Sprintf("lxdacl-%x-%s", hash[:4], direction)
+}
+
+// networkACLsApplyNic sets up the ebtables chains filtering the traffic of
+// a bridged nic, given the host side interface name. The rules only apply to
+// IP traffic, DHCP and IPv6 neighbour discovery are always allowed. Unlike
+// the network ones, these rules are stateless.
+func networkACLsApplyNic(d *Daemon, container string, device string, veth string, acls string) error {
+	ingress, egress, err := networkACLsLoad(d, acls)
+	if err != nil {
+		return err
+	}
+
+	ndp := []string{"-p", "IPv6", "--ip6-proto", "ipv6-icmp", "--ip6-icmp-type", "133:137", "-j", "RETURN"}
+	directions := []struct {
+		name   string
+		rules  []api.NetworkACLRule
+		match  string
+		chains []string
+		always [][]string
+	}{
+		{"in", ingress, "-o", []string{"FORWARD", "OUTPUT"}, [][]string{
+			{"-p", "IPv4", "--ip-proto", "udp", "--ip-dport", "68", "-j", "RETURN"},
+			{"-p", "IPv6", "--ip6-proto", "udp", "--ip6-dport", "546", "-j", "RETURN"},
+			ndp}},
+		{"out", egress, "-i", []string{"FORWARD", "INPUT"}, [][]string{
+			{"-p", "IPv4", "--ip-proto", "udp", "--ip-dport", "67", "-j", "RETURN"},
+			{"-p", "IPv6", "--ip6-proto", "udp", "--ip6-dport", "547", "-j", "RETURN"},
+			ndp}},
+	}
+
+	for _, direction := range directions {
+		chain := networkACLNicChain(container, device, direction.name)
+
+		// Start over, ebtables can't check for existing rules
+		err := networkEbtablesChainDelete(chain)
+		if err != nil {
+			return err
+		}
+
+		if len(direction.rules) == 0 {
+			continue
+		}
+
+		err = networkEbtablesChainCreate(chain)
+		if err != nil {
+			return err
+		}
+
+		entries := direction.always
+		for _, rule := range direction.rules {
+			entries = append(entries, networkACLEbtablesRules("ipv4", rule)...)
+			entries = append(entries, networkACLEbtablesRules("ipv6", rule)...)
+		}
+		entries = append(entries, []string{"-p", "IPv4", "-j", "DROP"}, []string{"-p", "IPv6", "-j", "DROP"})
+
+		for _, entry := range entries {
+			err = shared.RunCommand("ebtables", append([]string{"-A", chain}, entry...)...)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, base := range direction.chains {
+			err = shared.RunCommand("ebtables", "-I", base, direction.match, veth, "-j", chain)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// networkACLsClearNic removes the ebtables chains of a container nic.
+func networkACLsClearNic(container string, device string) error {
+	// Without ebtables, no rules could have been set
+	_, err := exec.LookPath("ebtables")
+	if err != nil {
+		return nil
+	}
+
+	for _, direction := range []string{"in", "out"} {
+		err := networkEbtablesChainDelete(networkACLNicChain(container, device, direction))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// networkACLsRefresh applies the current rules of an ACL to the running
+// networks and containers referencing it.
+func networkACLsRefresh(d *Daemon, name string) error {
+	networks, err := dbNetworks(d.db)
+	if err != nil {
+		return err
+	}
+
+	for _, netName := range networks {
+		n, err := networkLoadByName(d, netName)
+		if err != nil {
+			return err
+		}
+
+		if !n.IsRunning() || !shared.StringInSlice(name, networkACLNames(n.config["security.acls"])) {
+			continue
+		}
+
+		err = networkACLsApplyNetwork(n)
+		if err != nil {
+			return err
+		}
+	}
+
+	cts, err := dbContainersList(d.db, cTypeRegular)
+	if err != nil {
+		return err
+	}
+
+	for _, ct := range cts {
+		c, err := containerLoadByName(d, ct)
+		if err != nil {
+			return err
+		}
+
+		if !c.IsRunning() {
+			continue
+		}
+
+		devices := c.ExpandedDevices()
+		for _, k := range devices.DeviceNames() {
+			m := devices[k]
+			if m["type"] != "nic" || !shared.StringInSlice(name, networkACLNames(m["security.acls"])) {
+				continue
+			}
+
+			err = c.(*containerLXC).setNetworkACLs(k, m)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
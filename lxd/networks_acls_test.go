@@ -0,0 +1,115 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+func Test_network_acl_valid_rule(t *testing.T) {
+	valid := []api.NetworkACLRule{
+		{Action: "allow"},
+		{Action: "reject", Protocol: "tcp", DestinationPort: "22,8000-8080"},
+		{Action: "drop", Source: "10.0.0.0/8, fd42::1", Protocol: "udp", SourcePort: "53"},
+		{Action: "allow", Destination: "192.168.1.1", Protocol: "icmp4"},
+	}
+
+	for _, rule := range valid {
+		err := networkACLValidRule(rule)
+		if err != nil {
+			t.Fatalf("Valid rule %v rejected: %s", rule, err)
+		}
+	}
+
+	invalid := []api.NetworkACLRule{
+		{Action: "accept"},
+		{Action: "allow", Protocol: "sctp"},
+		{Action: "allow", DestinationPort: "22"},
+		{Action: "allow", Protocol: "icmp4", DestinationPort: "22"},
+		{Action: "allow", Protocol: "tcp", DestinationPort: "22-"},
+		{Action: "allow", Protocol: "tcp", DestinationPort: "70000"},
+		{Action: "allow", Source: "10.0.0.0/33"},
+		{Action: "allow", Source: "fd42::/64", Protocol: "icmp4"},
+	}
+
+	for _, rule := range invalid {
+		err := networkACLValidRule(rule)
+		if err == nil {
+			t.Fatalf("Invalid rule %v accepted", rule)
+		}
+	}
+}
+
+func Test_network_acl_iptables_rules(t *testing.T) {
+	rule := api.NetworkACLRule{
+		Action:          "reject",
+		Source:          "10.0.0.0/8,fd42::/64",
+		Protocol:        "tcp",
+		DestinationPort: "22,8000-8080",
+	}
+
+	expected := [][]string{
+		{"-s", "10.0.0.0/8", "-p", "tcp", "--dport", "22", "-j", "REJECT"},
+		{"-s", "10.0.0.0/8", "-p", "tcp", "--dport", "8000:8080", "-j", "REJECT"},
+	}
+
+	rules := networkACLIptablesRules("ipv4", rule)
+	if !reflect.DeepEqual(rules, expected) {
+		t.Fatalf("Unexpected rules: %v", rules)
+	}
+
+	rules = networkACLIptablesRules("ipv6", rule)
+	if len(rules) != 2 || rules[0][1] != "fd42::/64" {
+		t.Fatalf("Unexpected rules: %v", rules)
+	}
+
+	// Rules limited to the other family don't apply
+	rule = api.NetworkACLRule{Action: "allow", Destination: "fd42::1"}
+	if len(networkACLIptablesRules("ipv4", rule)) != 0 {
+		t.Fatal("IPv6 rule applied to IPv4")
+	}
+
+	rule = api.NetworkACLRule{Action: "allow", Protocol: "icmp6"}
+	rules = networkACLIptablesRules("ipv6", rule)
+	if !reflect.DeepEqual(rules, [][]string{{"-p", "ipv6-icmp", "-j", "ACCEPT"}}) {
+		t.Fatalf("Unexpected rules: %v", rules)
+	}
+}
+
+func Test_network_acl_ebtables_rules(t *testing.T) {
+	rule := api.NetworkACLRule{
+		Action:      "allow",
+		Destination: "fd42::1",
+		Protocol:    "udp",
+		SourcePort:  "53",
+	}
+
+	if len(networkACLEbtablesRules("ipv4", rule)) != 0 {
+		t.Fatal("IPv6 rule applied to IPv4")
+	}
+
+	expected := [][]string{{"-p", "IPv6", "--ip6-dst", "fd42::1", "--ip6-proto", "udp", "--ip6-sport", "53", "-j", "RETURN"}}
+	rules := networkACLEbtablesRules("ipv6", rule)
+	if !reflect.DeepEqual(rules, expected) {
+		t.Fatalf("Unexpected rules: %v", rules)
+	}
+
+	// ebtables can't reject
+	rule = api.NetworkACLRule{Action: "reject"}
+	rules = networkACLEbtablesRules("ipv4", rule)
+	if !reflect.DeepEqual(rules, [][]string{{"-p", "IPv4", "-j", "DROP"}}) {
+		t.Fatalf("Unexpected rules: %v", rules)
+	}
+}
+
+func Test_network_acl_nic_chain(t *testing.T) {
+	chain := networkACLNicChain("c1", "eth0", "out")
+	if len(chain) > 31 {
+		t.Fatalf("Chain name too long: %s", chain)
+	}
+
+	if chain == networkACLNicChain("c1", "eth1", "out") || chain == networkACLNicChain("c1", "eth0", "in") {
+		t.Fatal("Chain names aren't unique")
+	}
+}
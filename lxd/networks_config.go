@@ -89,6 +89,8 @@ var networkConfigKeys = map[string]func(value string) error{
 	},
 
 	"raw.dnsmasq": shared.IsAny,
+
+	"security.acls": networkACLValidNames,
 }
 
 func networkValidateConfig(name string, config map[string]string) error {
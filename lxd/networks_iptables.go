@@ -81,3 +81,115 @@ func networkIptablesClear(protocol string, netName string, table string) error {
 
 	return nil
 }
+
+// networkIptablesChainCreate creates a user defined chain in the filter
+// table, flushing it if it already exists.
+func networkIptablesChainCreate(protocol string, chain string) error {
+	cmd := "iptables"
+	if protocol == "ipv6" {
+		cmd = "ip6tables"
+	}
+
+	if shared.RunCommand(cmd, "-w", "-N", chain) == nil {
+		return nil
+	}
+
+	return shared.RunCommand(cmd, "-w", "-F", chain)
+}
+
+// networkIptablesChainDelete removes a user defined chain from the filter
+// table along with any rule jumping to it.
+func networkIptablesChainDelete(protocol string, chain string) error {
+	// Detect kernels that lack IPv6 support
+	if !shared.PathExists("/proc/sys/net/ipv6") && protocol == "ipv6" {
+		return nil
+	}
+
+	cmd := "iptables"
+	if protocol == "ipv6" {
+		cmd = "ip6tables"
+	}
+
+	// Nothing to do if the chain doesn't exist
+	if shared.RunCommand(cmd, "-w", "-S", chain) != nil {
+		return nil
+	}
+
+	// List the rules
+	output, err := exec.Command(cmd, "-w", "-S").Output()
+	if err != nil {
+		return fmt.Errorf("Failed to list %s rules", protocol)
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "-A" || !strings.HasSuffix(line, fmt.Sprintf(" -j %s", chain)) {
+			continue
+		}
+
+		// Remove the jump
+		fields[0] = "-D"
+		err = shared.RunCommand("sh", "-c", fmt.Sprintf("%s -w %s", cmd, strings.Join(fields, " ")))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = shared.RunCommand(cmd, "-w", "-F", chain)
+	if err != nil {
+		return err
+	}
+
+	return shared.RunCommand(cmd, "-w", "-X", chain)
+}
+
+// networkEbtablesChainCreate creates a user defined ebtables chain, flushing
+// it if it already exists. Packets reaching the end of the chain go back to
+// the calling chain.
+func networkEbtablesChainCreate(chain string) error {
+	if shared.RunCommand("ebtables", "-N", chain, "-P", "RETURN") == nil {
+		return nil
+	}
+
+	return shared.RunCommand("ebtables", "-F", chain)
+}
+
+// networkEbtablesChainDelete removes a user defined ebtables chain along
+// with any rule jumping to it.
+func networkEbtablesChainDelete(chain string) error {
+	output, err := exec.Command("ebtables", "-L", "--Lx").Output()
+	if err != nil {
+		return fmt.Errorf("Failed to list ebtables rules")
+	}
+
+	found := false
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 5 && fields[3] == "-N" && fields[4] == chain {
+			found = true
+			continue
+		}
+
+		if len(fields) < 6 || fields[3] != "-A" || fields[len(fields)-2] != "-j" || fields[len(fields)-1] != chain {
+			continue
+		}
+
+		// Remove the jump
+		fields[3] = "-D"
+		err = shared.RunCommand(fields[0], fields[1:]...)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !found {
+		return nil
+	}
+
+	err = shared.RunCommand("ebtables", "-F", chain)
+	if err != nil {
+		return err
+	}
+
+	return shared.RunCommand("ebtables", "-X", chain)
+}
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 )
 
 func networkAutoAttach(d *Daemon, devName string) error {
@@ -71,6 +72,151 @@ func networkDetachInterface(netName string, devName string) error {
 	return nil
 }
 
+// networkVLANDevice returns the name of the VLAN interface carrying the given
+// VLAN on top of parent, creating it if needed.
+func networkVLANDevice(parent string, vlan string) (string, error) {
+	devName := fmt.Sprintf("%s.%s", parent, vlan)
+	if shared.PathExists(fmt.Sprintf("/sys/class/net/%s", devName)) {
+		return devName, nil
+	}
+
+	if !shared.PathExists(fmt.Sprintf("/sys/class/net/%s", parent)) {
+		return "", fmt.Errorf("Parent device '%s' doesn't exist", parent)
+	}
+
+	err := shared.RunCommand("ip", "link", "add", "link", parent, "name", devName, "type", "vlan", "id", vlan)
+	if err != nil {
+		return "", err
+	}
+
+	err = shared.RunCommand("ip", "link", "set", "dev", devName, "up")
+	if err != nil {
+		return "", err
+	}
+
+	return devName, nil
+}
+
+// networkBridgeVLANs parses the output of "bridge vlan show dev <name>" and
+// returns the VLANs configured on the port.
+func networkBridgeVLANs(output string, devName string) []string {
+	vlans := []string{}
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && fields[0] == devName {
+			fields = fields[1:]
+		}
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		_, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+
+		vlans = append(vlans, fields[0])
+	}
+
+	return vlans
+}
+
+// networkSetVLAN configures the VLANs of a port attached to a native or
+// openvswitch bridge. Untagged traffic goes to the vlan VLAN (or to the
+// bridge default when empty) and tagged traffic is only allowed for the
+// VLANs listed in tagged.
+func networkSetVLAN(netName string, devName string, vlan string, tagged string) error {
+	tags := []string{}
+	for _, tag := range strings.Split(tagged, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	if !shared.PathExists(fmt.Sprintf("/sys/class/net/%s/bridge", netName)) {
+		err := shared.RunCommand("ovs-vsctl", "clear", "port", devName, "tag", "trunks", "vlan_mode")
+		if err != nil {
+			return err
+		}
+
+		if vlan != "" && len(tags) > 0 {
+			return shared.RunCommand("ovs-vsctl", "set", "port", devName, fmt.Sprintf("tag=%s", vlan), fmt.Sprintf("trunks=%s", strings.Join(append([]string{vlan}, tags...), ",")), "vlan_mode=native-untagged")
+		} else if vlan != "" {
+			return shared.RunCommand("ovs-vsctl", "set", "port", devName, fmt.Sprintf("tag=%s", vlan), "vlan_mode=access")
+		} else if len(tags) > 0 {
+			// Untagged traffic is seen as VLAN 0 on trunk ports
+			return shared.RunCommand("ovs-vsctl", "set", "port", devName, fmt.Sprintf("trunks=%s", strings.Join(append([]string{"0"}, tags...), ",")), "vlan_mode=trunk")
+		}
+
+		return nil
+	}
+
+	// Nothing to do if VLAN filtering was never enabled on the bridge
+	filteringPath := fmt.Sprintf("/sys/class/net/%s/bridge/vlan_filtering", netName)
+	content, err := ioutil.ReadFile(filteringPath)
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(string(content)) != "1" {
+		if vlan == "" && len(tags) == 0 {
+			return nil
+		}
+
+		err = ioutil.WriteFile(filteringPath, []byte("1"), 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Untagged traffic uses the bridge default VLAN unless specified
+	if vlan == "" {
+		content, err := ioutil.ReadFile(fmt.Sprintf("/sys/class/net/%s/bridge/default_pvid", netName))
+		if err != nil {
+			return err
+		}
+
+		vlan = strings.TrimSpace(string(content))
+	}
+
+	// Reset the port
+	output, err := exec.Command("bridge", "vlan", "show", "dev", devName).Output()
+	if err != nil {
+		return fmt.Errorf("Failed to list the VLANs of %s: %s", devName, err)
+	}
+
+	for _, vid := range networkBridgeVLANs(string(output), devName) {
+		err = shared.RunCommand("bridge", "vlan", "del", "dev", devName, "vid", vid)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Apply the new VLANs
+	if vlan != "0" {
+		err = shared.RunCommand("bridge", "vlan", "add", "dev", devName, "vid", vlan, "pvid", "untagged")
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, tag := range tags {
+		if tag == vlan {
+			continue
+		}
+
+		err = shared.RunCommand("bridge", "vlan", "add", "dev", devName, "vid", tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func networkGetInterfaces(d *Daemon) ([]string, error) {
 	networks, err := dbNetworks(d.db)
 	if err != nil {
@@ -452,6 +598,39 @@ func networkValidPort(value string) error {
 	return nil
 }
 
+func networkValidVLAN(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	valueInt, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("Invalid value for an integer: %s", value)
+	}
+
+	if valueInt < 1 || valueInt > 4094 {
+		return fmt.Errorf("Invalid VLAN ID: %s", value)
+	}
+
+	return nil
+}
+
+func networkValidVLANList(value string) error {
+	for _, vlan := range strings.Split(value, ",") {
+		vlan = strings.TrimSpace(vlan)
+		if vlan == "" {
+			return fmt.Errorf("Invalid VLAN list: %s", value)
+		}
+
+		err := networkValidVLAN(vlan)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func networkValidAddressCIDRV6(value string) error {
 	if value == "" {
 		return nil
@@ -866,3 +1045,278 @@ func networkClearLease(d *Daemon, network string, hwaddr string) error {
 
 	return nil
 }
+
+// networkLeases returns the static address reservations of the containers
+// attached to a network followed by the dynamic leases handed out by its
+// dnsmasq.
+func networkLeases(d *Daemon, network string) ([]api.NetworkLease, error) {
+	leases, _, err := networkLeasesAndHosts(d, network)
+	return leases, err
+}
+
+// networkLeasesAndHosts returns the same leases as networkLeases along with
+// the names of the containers attached to the network by MAC address.
+func networkLeasesAndHosts(d *Daemon, network string) ([]api.NetworkLease, map[string]string, error) {
+	leases := []api.NetworkLease{}
+
+	containers, err := dbContainersList(d.db, cTypeRegular)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Container names by MAC address, to name the dynamic leases of
+	// clients which didn't send a hostname
+	hosts := map[string]string{}
+	for _, cName := range containers {
+		c, err := containerLoadByName(d, cName)
+		if err != nil {
+			continue
+		}
+
+		for k, dev := range c.ExpandedDevices() {
+			if dev["type"] != "nic" || dev["nictype"] != "bridged" || dev["parent"] != network {
+				continue
+			}
+
+			hwaddr := dev["hwaddr"]
+			if hwaddr == "" {
+				hwaddr = c.LocalConfig()[fmt.Sprintf("volatile.%s.hwaddr", k)]
+			}
+			hwaddr = strings.ToLower(hwaddr)
+
+			if hwaddr != "" {
+				hosts[hwaddr] = cName
+			}
+
+			for _, key := range []string{"ipv4.address", "ipv6.address"} {
+				if dev[key] == "" {
+					continue
+				}
+
+				leases = append(leases, api.NetworkLease{
+					Hostname: cName,
+					Hwaddr:   hwaddr,
+					Address:  dev[key],
+					Type:     "static",
+				})
+			}
+		}
+	}
+
+	content, err := ioutil.ReadFile(shared.VarPath("networks", network, "dnsmasq.leases"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return leases, hosts, nil
+		}
+
+		return nil, nil, err
+	}
+
+	for _, lease := range strings.Split(string(content), "\n") {
+		// <expiry> <MAC or IAID> <address> <hostname> <client id>
+		fields := strings.Fields(lease)
+		if len(fields) < 5 {
+			continue
+		}
+
+		// DHCPv6 leases are keyed by IAID, the MAC can only be found
+		// at the end of link-layer DUIDs.
+		hwaddr := strings.ToLower(fields[1])
+		_, err := net.ParseMAC(hwaddr)
+		if err != nil {
+			hwaddr = ""
+			if len(fields[4]) >= 17 {
+				_, err := net.ParseMAC(fields[4][len(fields[4])-17:])
+				if err == nil {
+					hwaddr = strings.ToLower(fields[4][len(fields[4])-17:])
+				}
+			}
+		}
+
+		hostname := fields[3]
+		if hostname == "*" {
+			hostname = hosts[hwaddr]
+		}
+
+		// Skip the leases matching a static reservation
+		reserved := false
+		for _, entry := range leases {
+			if entry.Type == "static" && entry.Hwaddr == hwaddr && entry.Address == fields[2] {
+				reserved = true
+				break
+			}
+		}
+
+		if reserved {
+			continue
+		}
+
+		leases = append(leases, api.NetworkLease{
+			Hostname: hostname,
+			Hwaddr:   hwaddr,
+			Address:  fields[2],
+			Type:     "dynamic",
+		})
+	}
+
+	return leases, hosts, nil
+}
+
+// networkDNSRecords returns the records served by the built-in DNS server,
+// built from the leases of the managed networks using the "managed" DNS mode.
+func networkDNSRecords(d *Daemon) (*dnsRecords, error) {
+	records := newDNSRecords()
+
+	networks, err := dbNetworks(d.db)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range networks {
+		_, network, err := dbNetworkGet(d.db, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if !shared.StringInSlice(network.Config["dns.mode"], []string{"", "managed"}) {
+			continue
+		}
+
+		domain := network.Config["dns.domain"]
+		if domain == "" {
+			domain = "lxd"
+		}
+
+		records.addZone(domain)
+		for _, key := range []string{"ipv4.address", "ipv6.address"} {
+			_, subnet, err := net.ParseCIDR(network.Config[key])
+			if err == nil {
+				records.addReverseZone(subnet)
+			}
+		}
+
+		leases, hosts, err := networkLeasesAndHosts(d, name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, lease := range leases {
+			ip := net.ParseIP(lease.Address)
+			if ip == nil {
+				continue
+			}
+
+			// The hostname of dynamic leases is chosen by the client,
+			// only trust the name of the container owning the MAC.
+			hostname := lease.Hostname
+			if lease.Type == "dynamic" {
+				hostname = hosts[lease.Hwaddr]
+			}
+
+			if hostname == "" {
+				continue
+			}
+
+			records.addHost(fmt.Sprintf("%s.%s", hostname, domain), ip)
+		}
+	}
+
+	return records, nil
+}
+
+// networkAddressScope returns the scope of an IPv4 or IPv6 address.
+func networkAddressScope(address string) string {
+	if strings.HasPrefix(address, "127") || address == "::1" {
+		return "local"
+	}
+
+	if strings.HasPrefix(address, "169.254") || strings.HasPrefix(address, "fe80:") {
+		return "link"
+	}
+
+	return "global"
+}
+
+// networkGetInterfaceState returns the state of a host network interface, as
+// found in /sys/class/net.
+func networkGetInterfaceState(name string) (*api.NetworkState, error) {
+	netIf, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, os.ErrNotExist
+	}
+
+	sysPath := fmt.Sprintf("/sys/class/net/%s", name)
+	readValue := func(key string) string {
+		content, err := ioutil.ReadFile(filepath.Join(sysPath, key))
+		if err != nil {
+			return ""
+		}
+
+		return strings.TrimSpace(string(content))
+	}
+
+	readCounter := func(key string) int64 {
+		value, err := strconv.ParseInt(readValue(filepath.Join("statistics", key)), 10, 64)
+		if err != nil {
+			return 0
+		}
+
+		return value
+	}
+
+	state := api.NetworkState{
+		Addresses: []api.NetworkStateAddress{},
+		Counters: api.NetworkStateCounters{
+			BytesReceived:   readCounter("rx_bytes"),
+			BytesSent:       readCounter("tx_bytes"),
+			PacketsReceived: readCounter("rx_packets"),
+			PacketsSent:     readCounter("tx_packets"),
+		},
+		Hwaddr: readValue("address"),
+		State:  readValue("operstate"),
+		Type:   "unknown",
+	}
+
+	state.Mtu, err = strconv.Atoi(readValue("mtu"))
+	if err != nil {
+		state.Mtu = netIf.MTU
+	}
+
+	if netIf.Flags&net.FlagBroadcast > 0 {
+		state.Type = "broadcast"
+	}
+
+	if netIf.Flags&net.FlagPointToPoint > 0 {
+		state.Type = "point-to-point"
+	}
+
+	if netIf.Flags&net.FlagLoopback > 0 {
+		state.Type = "loopback"
+	}
+
+	addrs, err := netIf.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, addr := range addrs {
+		fields := strings.SplitN(addr.String(), "/", 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		family := "inet"
+		if strings.Contains(fields[0], ":") {
+			family = "inet6"
+		}
+
+		state.Addresses = append(state.Addresses, api.NetworkStateAddress{
+			Family:  family,
+			Address: fields[0],
+			Netmask: fields[1],
+			Scope:   networkAddressScope(fields[0]),
+		})
+	}
+
+	return &state, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_network_bridge_vlans(t *testing.T) {
+	output := `port	vlan ids
+veth1234	 10 PVID Egress Untagged
+	 20
+	 30
+
+`
+
+	vlans := networkBridgeVLANs(output, "veth1234")
+	if !reflect.DeepEqual(vlans, []string{"10", "20", "30"}) {
+		t.Fatalf("Unexpected VLANs: %v", vlans)
+	}
+
+	// Newer iproute2 header
+	output = `port              vlan-id
+veth1234          1 PVID Egress Untagged
+`
+
+	vlans = networkBridgeVLANs(output, "veth1234")
+	if !reflect.DeepEqual(vlans, []string{"1"}) {
+		t.Fatalf("Unexpected VLANs: %v", vlans)
+	}
+}
+
+func Test_network_valid_vlan(t *testing.T) {
+	for _, value := range []string{"", "1", "4094"} {
+		err := networkValidVLAN(value)
+		if err != nil {
+			t.Fatalf("Valid VLAN %q rejected: %s", value, err)
+		}
+	}
+
+	for _, value := range []string{"0", "4095", "abc"} {
+		err := networkValidVLAN(value)
+		if err == nil {
+			t.Fatalf("Invalid VLAN %q accepted", value)
+		}
+	}
+
+	err := networkValidVLANList("10, 20,30")
+	if err != nil {
+		t.Fatalf("Valid VLAN list rejected: %s", err)
+	}
+
+	for _, value := range []string{"10,,20", "10,5000"} {
+		err = networkValidVLANList(value)
+		if err == nil {
+			t.Fatalf("Invalid VLAN list %q accepted", value)
+		}
+	}
+}
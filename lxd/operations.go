@@ -1,6 +1,7 @@
 package main
 
 import (
+	"database/sql"
 	"fmt"
 	"net/http"
 	"runtime"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/pborman/uuid"
+	log "gopkg.in/inconshreveable/log15.v2"
 
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
@@ -19,6 +21,12 @@ import (
 var operationsLock sync.Mutex
 var operations map[string]*operation = make(map[string]*operation)
 
+// Database used to keep track of operations across daemon restarts
+var operationsDB *sql.DB
+
+// Minimum delay between two database writes caused by metadata updates
+var operationPersistInterval = 5 * time.Second
+
 type operationClass int
 
 const (
@@ -47,6 +55,9 @@ type operation struct {
 	err       string
 	readonly  bool
 
+	// Last time the operation was written to the database
+	persistedAt time.Time
+
 	// Those functions are called at various points in the operation lifecycle
 	onRun     func(*operation) error
 	onCancel  func(*operation) error
@@ -59,6 +70,48 @@ type operation struct {
 	lock sync.Mutex
 }
 
+func operationsInit(db *sql.DB) error {
+	operationsDB = db
+
+	// Anything which was still going on when the daemon went away failed
+	return dbOperationsInterrupt(db, "Operation interrupted by a daemon restart")
+}
+
+func operationsExpire(expiry int64) error {
+	if operationsDB == nil {
+		return nil
+	}
+
+	return dbOperationsExpire(operationsDB, time.Now().Add(-time.Duration(expiry)*time.Hour))
+}
+
+func (op *operation) persist() {
+	if operationsDB == nil {
+		return
+	}
+
+	// Copy the fields to record under the lock, the database write
+	// itself happening without holding it
+	op.lock.Lock()
+	op.persistedAt = time.Now()
+	record := dbOperation{
+		uuid:      op.id,
+		class:     op.class,
+		createdAt: op.createdAt,
+		updatedAt: op.updatedAt,
+		status:    op.status,
+		resources: op.resources,
+		metadata:  op.metadata,
+		err:       op.err,
+	}
+	op.lock.Unlock()
+
+	err := dbOperationSave(operationsDB, record)
+	if err != nil {
+		shared.LogError("Failed to record operation", log.Ctx{"id": op.id, "err": err})
+	}
+}
+
 func (op *operation) done() {
 	if op.readonly {
 		return
@@ -66,6 +119,7 @@ func (op *operation) done() {
 
 	op.lock.Lock()
 	op.readonly = true
+	op.updatedAt = time.Now()
 	op.onRun = nil
 	op.onCancel = nil
 	op.onConnect = nil
@@ -120,6 +174,7 @@ func (op *operation) Run() (chan error, error) {
 
 				shared.LogDebugf("Failure for %s operation: %s: %s", op.class.String(), op.id, err)
 
+				op.persist()
 				_, md, _ := op.Render()
 				eventSend("operation", md)
 				return
@@ -131,8 +186,10 @@ func (op *operation) Run() (chan error, error) {
 			op.done()
 			chanRun <- nil
 
-			op.lock.Lock()
 			shared.LogDebugf("Success for %s operation: %s", op.class.String(), op.id)
+			op.persist()
+
+			op.lock.Lock()
 			_, md, _ := op.Render()
 			eventSend("operation", md)
 			op.lock.Unlock()
@@ -141,6 +198,7 @@ func (op *operation) Run() (chan error, error) {
 	op.lock.Unlock()
 
 	shared.LogDebugf("Started %s operation: %s", op.class.String(), op.id)
+	op.persist()
 	_, md, _ := op.Render()
 	eventSend("operation", md)
 
@@ -173,6 +231,7 @@ func (op *operation) Cancel() (chan error, error) {
 				chanCancel <- err
 
 				shared.LogDebugf("Failed to cancel %s operation: %s: %s", op.class.String(), op.id, err)
+				op.persist()
 				_, md, _ := op.Render()
 				eventSend("operation", md)
 				return
@@ -185,12 +244,14 @@ func (op *operation) Cancel() (chan error, error) {
 			chanCancel <- nil
 
 			shared.LogDebugf("Cancelled %s operation: %s", op.class.String(), op.id)
+			op.persist()
 			_, md, _ := op.Render()
 			eventSend("operation", md)
 		}(op, oldStatus, chanCancel)
 	}
 
 	shared.LogDebugf("Cancelling %s operation: %s", op.class.String(), op.id)
+	op.persist()
 	_, md, _ := op.Render()
 	eventSend("operation", md)
 
@@ -203,6 +264,7 @@ func (op *operation) Cancel() (chan error, error) {
 	}
 
 	shared.LogDebugf("Cancelled %s operation: %s", op.class.String(), op.id)
+	op.persist()
 	_, md, _ = op.Render()
 	eventSend("operation", md)
 
@@ -254,6 +316,12 @@ func (op *operation) Render() (string, *api.Operation, error) {
 		for key, value := range resources {
 			var values []string
 			for _, c := range value {
+				// Containers are tracked under their internal name
+				if key == "containers" {
+					values = append(values, projectContainerURL(c))
+					continue
+				}
+
 				values = append(values, fmt.Sprintf("/%s/%s/%s", version.APIVersion, key, c))
 			}
 			tmpResources[key] = values
@@ -321,6 +389,7 @@ func (op *operation) UpdateResources(opResources map[string][]string) error {
 	op.lock.Unlock()
 
 	shared.LogDebugf("Updated resources for %s operation: %s", op.class.String(), op.id)
+	op.persist()
 	_, md, _ := op.Render()
 	eventSend("operation", md)
 
@@ -341,12 +410,19 @@ func (op *operation) UpdateMetadata(opMetadata interface{}) error {
 		return err
 	}
 
+	// Metadata updates are frequent during transfers (progress), so only
+	// record them periodically, status changes always being recorded
 	op.lock.Lock()
 	op.updatedAt = time.Now()
 	op.metadata = newMetadata
+	persist := time.Since(op.persistedAt) >= operationPersistInterval
 	op.lock.Unlock()
 
 	shared.LogDebugf("Updated metadata for %s operation: %s", op.class.String(), op.id)
+
+	if persist {
+		op.persist()
+	}
 	_, md, _ := op.Render()
 	eventSend("operation", md)
 
@@ -402,6 +478,7 @@ func operationCreate(opClass operationClass, opResources map[string][]string, op
 	operationsLock.Unlock()
 
 	shared.LogDebugf("New %s operation: %s", op.class.String(), op.id)
+	op.persist()
 	_, md, _ := op.Render()
 	eventSend("operation", md)
 
@@ -413,11 +490,38 @@ func operationGet(id string) (*operation, error) {
 	op, ok := operations[id]
 	operationsLock.Unlock()
 
-	if !ok {
-		return nil, fmt.Errorf("Operation '%s' doesn't exist", id)
+	if ok {
+		return op, nil
 	}
 
-	return op, nil
+	// Look for a finished or interrupted operation in the database
+	if operationsDB != nil {
+		dbOp, err := dbOperationGet(operationsDB, id)
+		if err == nil {
+			return operationLoad(dbOp), nil
+		}
+	}
+
+	return nil, fmt.Errorf("Operation '%s' doesn't exist", id)
+}
+
+// operationLoad turns a database record into a read-only operation.
+func operationLoad(dbOp *dbOperation) *operation {
+	op := operation{}
+	op.id = dbOp.uuid
+	op.class = dbOp.class
+	op.createdAt = dbOp.createdAt
+	op.updatedAt = dbOp.updatedAt
+	op.status = dbOp.status
+	op.url = fmt.Sprintf("/%s/operations/%s", version.APIVersion, op.id)
+	op.resources = dbOp.resources
+	op.metadata = dbOp.metadata
+	op.err = dbOp.err
+	op.readonly = true
+	op.chanDone = make(chan error)
+	close(op.chanDone)
+
+	return &op
 }
 
 // API functions
@@ -463,9 +567,34 @@ func operationsAPIGet(d *Daemon, r *http.Request) Response {
 	md = shared.Jmap{}
 
 	operationsLock.Lock()
-	ops := operations
+	ops := map[string]*operation{}
+	for k, v := range operations {
+		ops[k] = v
+	}
 	operationsLock.Unlock()
 
+	// Add the operations which are only left in the database
+	if operationsDB != nil {
+		ids, err := dbOperations(operationsDB)
+		if err != nil {
+			return InternalError(err)
+		}
+
+		for _, id := range ids {
+			_, ok := ops[id]
+			if ok {
+				continue
+			}
+
+			dbOp, err := dbOperationGet(operationsDB, id)
+			if err != nil {
+				continue
+			}
+
+			ops[id] = operationLoad(dbOp)
+		}
+	}
+
 	for _, v := range ops {
 		status := strings.ToLower(v.status.String())
 		_, ok := md[status]
@@ -100,7 +100,7 @@ DELETE FROM profiles_devices_config WHERE profile_device_id NOT IN (SELECT id FR
 }
 
 func patchInvalidProfileNames(name string, d *Daemon) error {
-	profiles, err := dbProfiles(d.db)
+	profiles, err := dbProfiles(d.db, projectDefault)
 	if err != nil {
 		return err
 	}
@@ -108,7 +108,7 @@ func patchInvalidProfileNames(name string, d *Daemon) error {
 	for _, profile := range profiles {
 		if strings.Contains(profile, "/") || shared.StringInSlice(profile, []string{".", ".."}) {
 			shared.LogInfo("Removing unreachable profile (invalid name)", log.Ctx{"name": profile})
-			err := dbProfileDelete(d.db, profile)
+			err := dbProfileDelete(d.db, projectDefault, profile)
 			if err != nil {
 				return err
 			}
@@ -199,13 +199,13 @@ func patchStorageApi(name string, d *Daemon) error {
 	}
 
 	// Get list of existing public images.
-	imgPublic, err := dbImagesGet(d.db, true)
+	imgPublic, err := dbImagesGet(d.db, projectDefault, true)
 	if err != nil {
 		return err
 	}
 
 	// Get list of existing private images.
-	imgPrivate, err := dbImagesGet(d.db, false)
+	imgPrivate, err := dbImagesGet(d.db, projectDefault, false)
 	if err != nil {
 		return err
 	}
@@ -1467,10 +1467,10 @@ func updatePoolPropertyForAllObjects(d *Daemon, poolName string, allcontainers [
 	// appropriate device including a pool is added to the default profile
 	// or the user explicitly passes the pool the container's storage volume
 	// is supposed to be created on.
-	profiles, err := dbProfiles(d.db)
+	profiles, err := dbProfiles(d.db, projectDefault)
 	if err == nil {
 		for _, pName := range profiles {
-			pID, p, err := dbProfileGet(d.db, pName)
+			pID, p, err := dbProfileGet(d.db, projectDefault, pName)
 			if err != nil {
 				shared.LogErrorf("Could not query database: %s.", err)
 				return err
@@ -0,0 +1,468 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	log "gopkg.in/inconshreveable/log15.v2"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
+)
+
+const projectDefault = "default"
+
+var projectConfigKeys = map[string]func(value string) error{
+	"features.images":   shared.IsBool,
+	"features.profiles": shared.IsBool,
+}
+
+// projectParam returns the project a request applies to.
+func projectParam(r *http.Request) string {
+	project := r.URL.Query().Get("project")
+	if project == "" {
+		return projectDefault
+	}
+
+	return project
+}
+
+// projectPrefix returns the name under which a container or custom volume of
+// the given project is stored. Objects of the default project keep their
+// plain name so that existing setups are unaffected.
+func projectPrefix(project string, name string) string {
+	if project == projectDefault || project == "" {
+		return name
+	}
+
+	return fmt.Sprintf("%s_%s", project, name)
+}
+
+// projectSplitName is the reverse of projectPrefix, returning the project and
+// the name as seen by the user. Container names can't contain underscores, so
+// only the part before the snapshot separator needs to be looked at.
+func projectSplitName(name string) (string, string) {
+	base := strings.SplitN(name, shared.SnapshotDelimiter, 2)[0]
+	if !strings.Contains(base, "_") {
+		return projectDefault, name
+	}
+
+	fields := strings.SplitN(name, "_", 2)
+	return fields[0], fields[1]
+}
+
+// projectContainerURL returns the API URL of a container (or of one of its
+// sub-resources) given its internal name.
+func projectContainerURL(name string, elems ...string) string {
+	project, name := projectSplitName(name)
+
+	elems = append([]string{name}, elems...)
+	return fmt.Sprintf("/%s/containers/%s%s", version.APIVersion, strings.Join(elems, "/"), projectQuery(project))
+}
+
+// projectQuery returns the query string to append to API URLs of objects of
+// the given project.
+func projectQuery(project string) string {
+	if project == projectDefault || project == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("?project=%s", project)
+}
+
+// projectHasFeature returns whether the given project has its own set of the
+// given objects ("images" or "profiles") rather than using those of the
+// default project.
+func projectHasFeature(db *sql.DB, project string, feature string) (bool, error) {
+	if project == projectDefault {
+		return true, nil
+	}
+
+	id, err := dbProjectID(db, project)
+	if err != nil {
+		return false, err
+	}
+
+	config, err := dbProjectConfigGet(db, id)
+	if err != nil {
+		return false, err
+	}
+
+	return shared.IsTrue(config[fmt.Sprintf("features.%s", feature)]), nil
+}
+
+// profileProject returns the project holding the profiles used by the
+// given project.
+func profileProject(db *sql.DB, project string) (string, error) {
+	enabled, err := projectHasFeature(db, project, "profiles")
+	if err != nil {
+		return "", err
+	}
+
+	if !enabled {
+		return projectDefault, nil
+	}
+
+	return project, nil
+}
+
+// imageProject returns the project holding the images used by the given
+// project.
+func imageProject(db *sql.DB, project string) (string, error) {
+	enabled, err := projectHasFeature(db, project, "images")
+	if err != nil {
+		return "", err
+	}
+
+	if !enabled {
+		return projectDefault, nil
+	}
+
+	return project, nil
+}
+
+func projectValidName(name string) error {
+	if name == "" {
+		return fmt.Errorf("No name provided")
+	}
+
+	if strings.Contains(name, "/") {
+		return fmt.Errorf("Project names may not contain slashes")
+	}
+
+	if strings.Contains(name, "_") {
+		return fmt.Errorf("Project names may not contain underscores")
+	}
+
+	if strings.Contains(name, " ") || strings.Contains(name, "?") || strings.Contains(name, "&") {
+		return fmt.Errorf("Project names may not contain spaces or query characters")
+	}
+
+	if shared.StringInSlice(name, []string{".", ".."}) {
+		return fmt.Errorf("Invalid project name '%s'", name)
+	}
+
+	return nil
+}
+
+func projectValidateConfig(config map[string]string) error {
+	for k, v := range config {
+		validator, ok := projectConfigKeys[k]
+		if !ok {
+			return fmt.Errorf("Invalid project configuration key: %s", k)
+		}
+
+		err := validator(v)
+		if err != nil {
+			return fmt.Errorf("Invalid value for '%s': %s", k, err)
+		}
+	}
+
+	return nil
+}
+
+// projectIsEmpty returns whether the project holds anything besides the
+// default profile it was created with.
+func projectIsEmpty(project *api.Project) bool {
+	for _, entry := range project.UsedBy {
+		if strings.HasPrefix(entry, fmt.Sprintf("/%s/profiles/default", version.APIVersion)) {
+			continue
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// API endpoints
+func projectsGet(d *Daemon, r *http.Request) Response {
+	results, err := dbProjects(d.db)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	recursion := d.isRecursionRequest(r)
+
+	resultString := []string{}
+	resultMap := []*api.Project{}
+	for _, name := range results {
+		if !recursion {
+			resultString = append(resultString, fmt.Sprintf("/%s/projects/%s", version.APIVersion, name))
+		} else {
+			_, project, err := dbProjectGet(d.db, name)
+			if err != nil {
+				shared.LogError("Failed to get project", log.Ctx{"project": name})
+				continue
+			}
+			resultMap = append(resultMap, project)
+		}
+	}
+
+	if !recursion {
+		return SyncResponse(true, resultString)
+	}
+
+	return SyncResponse(true, resultMap)
+}
+
+func projectsPost(d *Daemon, r *http.Request) Response {
+	req := api.ProjectsPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	// Sanity checks
+	err = projectValidName(req.Name)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	_, err = dbProjectID(d.db, req.Name)
+	if err == nil {
+		return BadRequest(fmt.Errorf("The project already exists"))
+	}
+
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	// New projects get their own images and profiles unless told otherwise
+	for _, key := range []string{"features.images", "features.profiles"} {
+		_, ok := req.Config[key]
+		if !ok {
+			req.Config[key] = "true"
+		}
+	}
+
+	err = projectValidateConfig(req.Config)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	_, err = dbProjectCreate(d.db, req.Name, req.Description, req.Config)
+	if err != nil {
+		return InternalError(fmt.Errorf("Error inserting %s into database: %s", req.Name, err))
+	}
+
+	if shared.IsTrue(req.Config["features.profiles"]) {
+		err = dbProfileCreateDefault(d.db, req.Name)
+		if err != nil {
+			dbProjectDelete(d.db, req.Name)
+			return SmartError(err)
+		}
+	}
+
+	return SyncResponseLocation(true, nil, fmt.Sprintf("/%s/projects/%s", version.APIVersion, req.Name))
+}
+
+var projectsCmd = Command{name: "projects", get: projectsGet, post: projectsPost}
+
+func projectGet(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+
+	_, project, err := dbProjectGet(d.db, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	etag := []interface{}{project.Description, project.Config}
+	return SyncResponseETag(true, project, etag)
+}
+
+func projectPut(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+
+	id, project, err := dbProjectGet(d.db, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	// Validate the ETag
+	etag := []interface{}{project.Description, project.Config}
+	err = etagCheck(r, etag)
+	if err != nil {
+		return PreconditionFailed(err)
+	}
+
+	req := api.ProjectPut{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	return doProjectUpdate(d, id, project, req)
+}
+
+func projectPatch(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+
+	id, project, err := dbProjectGet(d.db, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	// Validate the ETag
+	etag := []interface{}{project.Description, project.Config}
+	err = etagCheck(r, etag)
+	if err != nil {
+		return PreconditionFailed(err)
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	rdr1 := ioutil.NopCloser(bytes.NewBuffer(body))
+	rdr2 := ioutil.NopCloser(bytes.NewBuffer(body))
+
+	reqRaw := shared.Jmap{}
+	if err := json.NewDecoder(rdr1).Decode(&reqRaw); err != nil {
+		return BadRequest(err)
+	}
+
+	req := api.ProjectPut{}
+	if err := json.NewDecoder(rdr2).Decode(&req); err != nil {
+		return BadRequest(err)
+	}
+
+	// Get Description
+	_, err = reqRaw.GetString("description")
+	if err != nil {
+		req.Description = project.Description
+	}
+
+	// Get Config
+	if req.Config == nil {
+		req.Config = project.Config
+	} else {
+		for k, v := range project.Config {
+			_, ok := req.Config[k]
+			if !ok {
+				req.Config[k] = v
+			}
+		}
+	}
+
+	return doProjectUpdate(d, id, project, req)
+}
+
+func doProjectUpdate(d *Daemon, id int64, project *api.Project, req api.ProjectPut) Response {
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	err := projectValidateConfig(req.Config)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	// The features decide where objects get looked up, so they can only be
+	// changed while nothing relies on them.
+	for _, key := range []string{"features.images", "features.profiles"} {
+		if shared.IsTrue(project.Config[key]) == shared.IsTrue(req.Config[key]) {
+			continue
+		}
+
+		if project.Name == projectDefault {
+			return BadRequest(fmt.Errorf("Features can't be changed on the default project"))
+		}
+
+		if !projectIsEmpty(project) {
+			return BadRequest(fmt.Errorf("Features can only be changed on empty projects"))
+		}
+	}
+
+	err = dbProjectUpdate(d.db, id, req.Description, req.Config)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	if shared.IsTrue(req.Config["features.profiles"]) {
+		err = dbProfileCreateDefault(d.db, project.Name)
+		if err != nil {
+			return SmartError(err)
+		}
+	}
+
+	return EmptySyncResponse
+}
+
+func projectPost(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+
+	req := api.ProjectPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	// Sanity checks
+	if name == projectDefault {
+		return Forbidden
+	}
+
+	err = projectValidName(req.Name)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	_, err = dbProjectID(d.db, req.Name)
+	if err == nil {
+		return Conflict
+	}
+
+	_, project, err := dbProjectGet(d.db, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	// Containers and volumes are stored under a name derived from the
+	// project, so only empty projects can be renamed.
+	if !projectIsEmpty(project) {
+		return BadRequest(fmt.Errorf("Only empty projects can be renamed"))
+	}
+
+	err = dbProjectRename(d.db, name, req.Name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	return SyncResponseLocation(true, nil, fmt.Sprintf("/%s/projects/%s", version.APIVersion, req.Name))
+}
+
+func projectDelete(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+
+	// Sanity checks
+	if name == projectDefault {
+		return Forbidden
+	}
+
+	_, project, err := dbProjectGet(d.db, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	if !projectIsEmpty(project) {
+		return BadRequest(fmt.Errorf("Only empty projects can be removed"))
+	}
+
+	err = dbProjectDelete(d.db, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	return EmptySyncResponse
+}
+
+var projectCmd = Command{name: "projects/{name}", get: projectGet, put: projectPut, patch: projectPatch, post: projectPost, delete: projectDelete}
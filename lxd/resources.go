@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// /1.0/resources
+// Get system resources
+func api10ResourcesGet(d *Daemon, r *http.Request) Response {
+	res := api.Resources{}
+
+	cpu, err := resourcesCPU()
+	if err != nil {
+		return SmartError(err)
+	}
+	res.CPU = *cpu
+
+	memory, err := resourcesMemory()
+	if err != nil {
+		return SmartError(err)
+	}
+	res.Memory = *memory
+
+	pools, err := dbStoragePools(d.db)
+	if err != nil && err != NoSuchObjectError {
+		return SmartError(err)
+	}
+
+	res.StoragePools = map[string]api.ResourcesStoragePool{}
+	for _, poolName := range pools {
+		pool, err := resourcesStoragePool(d, poolName)
+		if err != nil {
+			return SmartError(err)
+		}
+
+		res.StoragePools[poolName] = *pool
+	}
+
+	return SyncResponse(true, res)
+}
+
+// /1.0/storage-pools/{name}/resources
+// Get resources for a specific storage pool
+func storagePoolResourcesGet(d *Daemon, r *http.Request) Response {
+	poolName := mux.Vars(r)["name"]
+
+	_, _, err := dbStoragePoolGet(d.db, poolName)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	res, err := resourcesStoragePool(d, poolName)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	return SyncResponse(true, res)
+}
+
+func resourcesStoragePool(d *Daemon, poolName string) (*api.ResourcesStoragePool, error) {
+	s, err := storagePoolInit(d, poolName)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.StoragePoolInit()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.StoragePoolResources()
+}
+
+func resourcesCPU() (*api.ResourcesCPU, error) {
+	f, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Each thread is described by a block of "key: value" lines
+	threads := []map[string]string{}
+	thread := map[string]string{}
+
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		fields := strings.SplitN(scan.Text(), ":", 2)
+		if len(fields) != 2 {
+			if len(thread) > 0 {
+				threads = append(threads, thread)
+				thread = map[string]string{}
+			}
+
+			continue
+		}
+
+		thread[strings.TrimSpace(fields[0])] = strings.TrimSpace(fields[1])
+	}
+
+	if len(thread) > 0 {
+		threads = append(threads, thread)
+	}
+
+	err = scan.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	sockets := map[uint64]*api.ResourcesCPUSocket{}
+	cores := map[uint64]map[string]bool{}
+
+	for _, thread := range threads {
+		_, ok := thread["processor"]
+		if !ok {
+			continue
+		}
+
+		// Systems without topology information have a single socket
+		id := uint64(0)
+		if thread["physical id"] != "" {
+			id, err = strconv.ParseUint(thread["physical id"], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		socket, ok := sockets[id]
+		if !ok {
+			socket = &api.ResourcesCPUSocket{
+				Socket: id,
+				Vendor: thread["vendor_id"],
+				Name:   thread["model name"],
+			}
+
+			mhz, err := strconv.ParseFloat(thread["cpu MHz"], 64)
+			if err == nil {
+				socket.Frequency = uint64(mhz)
+			}
+
+			sockets[id] = socket
+			cores[id] = map[string]bool{}
+		}
+
+		socket.Threads++
+		if thread["core id"] != "" {
+			cores[id][thread["core id"]] = true
+		}
+
+		// The turbo frequency is the highest any thread can reach
+		path := fmt.Sprintf("/sys/devices/system/cpu/cpu%s/cpufreq/cpuinfo_max_freq", thread["processor"])
+		content, err := ioutil.ReadFile(path)
+		if err == nil {
+			khz, err := strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+			if err == nil && khz/1000 > socket.FrequencyTurbo {
+				socket.FrequencyTurbo = khz / 1000
+			}
+		}
+	}
+
+	cpu := api.ResourcesCPU{Sockets: []api.ResourcesCPUSocket{}}
+
+	for id := uint64(0); len(cpu.Sockets) < len(sockets); id++ {
+		socket, ok := sockets[id]
+		if !ok {
+			continue
+		}
+
+		socket.Cores = uint64(len(cores[id]))
+		if socket.Cores == 0 {
+			// No core information, consider each thread a core
+			socket.Cores = socket.Threads
+		}
+
+		cpu.Sockets = append(cpu.Sockets, *socket)
+		cpu.Total += socket.Threads
+	}
+
+	return &cpu, nil
+}
+
+func resourcesMemory() (*api.ResourcesMemory, error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := map[string]uint64{}
+
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		fields := strings.Fields(scan.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		key := strings.TrimSuffix(fields[0], ":")
+		value := fields[1]
+		if len(fields) == 3 {
+			value += fields[2]
+		}
+
+		valueBytes, err := shared.ParseByteSizeString(value)
+		if err != nil {
+			continue
+		}
+
+		values[key] = uint64(valueBytes)
+	}
+
+	err = scan.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	total, ok := values["MemTotal"]
+	if !ok {
+		return nil, fmt.Errorf("Couldn't find MemTotal")
+	}
+
+	mem := api.ResourcesMemory{Total: total}
+
+	free := values["MemFree"] + values["Buffers"] + values["Cached"]
+	if free < total {
+		mem.Used = total - free
+	}
+
+	return &mem, nil
+}
+
+var api10ResourcesCmd = Command{name: "resources", get: api10ResourcesGet}
+var storagePoolResourcesCmd = Command{name: "storage-pools/{name}/resources", get: storagePoolResourcesGet}
@@ -207,6 +207,28 @@ func FileResponse(r *http.Request, files []fileResponseEntry, headers map[string
 	return &fileResponse{r, files, headers, removeAfterServe}
 }
 
+// Plain text response
+type textResponse struct {
+	contentType string
+	body        string
+}
+
+func (r *textResponse) Render(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", r.contentType)
+	w.WriteHeader(http.StatusOK)
+
+	_, err := io.WriteString(w, r.body)
+	return err
+}
+
+func (r *textResponse) String() string {
+	return fmt.Sprintf("%d bytes", len(r.body))
+}
+
+func TextResponse(contentType string, body string) Response {
+	return &textResponse{contentType, body}
+}
+
 // Operation response
 type operationResponse struct {
 	op *operation
@@ -123,14 +123,31 @@ func RsyncSend(path string, conn *websocket.Conn, readWrapper func(io.ReadCloser
 // half set up by RsyncSend), putting the contents in the directory specified
 // by path.
 func RsyncRecv(path string, conn *websocket.Conn, writeWrapper func(io.WriteCloser) io.WriteCloser) error {
-	cmd := exec.Command("rsync",
+	return rsyncRecv(path, conn, writeWrapper, false)
+}
+
+// RsyncRecvDelete is like RsyncRecv but also removes anything from the
+// directory specified by path which isn't part of the transfer, as needed when
+// refreshing an existing copy.
+func RsyncRecvDelete(path string, conn *websocket.Conn, writeWrapper func(io.WriteCloser) io.WriteCloser) error {
+	return rsyncRecv(path, conn, writeWrapper, true)
+}
+
+func rsyncRecv(path string, conn *websocket.Conn, writeWrapper func(io.WriteCloser) io.WriteCloser, deleteExtra bool) error {
+	args := []string{
 		"--server",
 		"-vlogDtpre.iLsfx",
 		"--numeric-ids",
 		"--devices",
 		"--partial",
-		".",
-		path)
+	}
+
+	if deleteExtra {
+		args = append(args, "--delete")
+	}
+
+	args = append(args, ".", path)
+	cmd := exec.Command("rsync", args...)
 
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
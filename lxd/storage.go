@@ -148,9 +148,10 @@ const (
 	storageTypeLvm
 	storageTypeDir
 	storageTypeMock
+	storageTypeCeph
 )
 
-var supportedStorageTypes = []string{"btrfs", "zfs", "lvm", "dir"}
+var supportedStorageTypes = []string{"btrfs", "zfs", "lvm", "dir", "ceph"}
 
 func storageTypeToString(sType storageType) (string, error) {
 	switch sType {
@@ -164,6 +165,8 @@ func storageTypeToString(sType storageType) (string, error) {
 		return "mock", nil
 	case storageTypeDir:
 		return "dir", nil
+	case storageTypeCeph:
+		return "ceph", nil
 	}
 
 	return "", fmt.Errorf("Invalid storage type.")
@@ -181,6 +184,8 @@ func storageStringToType(sName string) (storageType, error) {
 		return storageTypeMock, nil
 	case "dir":
 		return storageTypeDir, nil
+	case "ceph":
+		return storageTypeCeph, nil
 	}
 
 	return -1, fmt.Errorf("Invalid storage type name.")
@@ -203,6 +208,10 @@ type storage interface {
 	StoragePoolMount() (bool, error)
 	StoragePoolUmount() (bool, error)
 	StoragePoolUpdate(changedConfig []string) error
+	StoragePoolResources() (*api.ResourcesStoragePool, error)
+	// StoragePoolVolumesCheck compares the volumes found on the storage
+	// pool with the database and fixes the safe cases when repair is set.
+	StoragePoolVolumesCheck(repair bool) ([]storageCheckIssue, error)
 	GetStoragePoolWritable() api.StoragePoolPut
 	SetStoragePoolWritable(writable *api.StoragePoolPut)
 
@@ -212,9 +221,20 @@ type storage interface {
 	StoragePoolVolumeMount() (bool, error)
 	StoragePoolVolumeUmount() (bool, error)
 	StoragePoolVolumeUpdate(changedConfig []string) error
+	StoragePoolVolumeGetUsage() (int64, error)
+	StoragePoolVolumeRename(newName string) error
+	// StoragePoolVolumeCopy fills the (already created in the database)
+	// storage volume with the contents of the given source volume.
+	StoragePoolVolumeCopy(source *api.StorageVolumeSource) error
 	GetStoragePoolVolumeWritable() api.StorageVolumePut
 	SetStoragePoolVolumeWritable(writable *api.StorageVolumePut)
 
+	// Functions dealing with custom storage volume snapshots.
+	StoragePoolVolumeSnapshotCreate(snapshotName string) error
+	StoragePoolVolumeSnapshotDelete(snapshotName string) error
+	StoragePoolVolumeSnapshotRename(snapshotName string, newName string) error
+	StoragePoolVolumeSnapshotRestore(snapshotName string) error
+
 	// Functions dealing with container storage volumes.
 	// ContainerCreate creates an empty container (no rootfs/metadata.yaml)
 	ContainerCreate(container container) error
@@ -242,11 +262,24 @@ type storage interface {
 	// For use in migrating snapshots.
 	ContainerSnapshotCreateEmpty(snapshotContainer container) error
 
+	// Functions dealing with optimized container backups.
+	// ContainerBackupDump writes the driver specific representation of
+	// the container and of the given snapshots to the target directory.
+	ContainerBackupDump(container container, snapshots []container, target string) error
+	// ContainerBackupLoad fills an empty container and its (already
+	// created in the database) snapshots from a directory produced by
+	// ContainerBackupDump.
+	ContainerBackupLoad(container container, snapshots []container, source string) error
+
 	// Functions dealing with image storage volumes.
 	ImageCreate(fingerprint string) error
 	ImageDelete(fingerprint string) error
 	ImageMount(fingerprint string) (bool, error)
 	ImageUmount(fingerprint string) (bool, error)
+	// ImageVolumesGC returns the image volumes of the storage pool which
+	// aren't referenced in the database and which no container was cloned
+	// from, deleting them unless dryRun is set.
+	ImageVolumesGC(dryRun bool) ([]string, error)
 
 	// Functions dealing with migration.
 	MigrationType() MigrationFSType
@@ -272,7 +305,7 @@ type storage interface {
 	// already present on the target instance as an exercise for the
 	// enterprising developer.
 	MigrationSource(container container) (MigrationStorageSourceDriver, error)
-	MigrationSink(live bool, container container, objects []*Snapshot, conn *websocket.Conn, srcIdmap *shared.IdmapSet, op *operation) error
+	MigrationSink(live bool, container container, objects []*Snapshot, conn *websocket.Conn, srcIdmap *shared.IdmapSet, op *operation, refresh bool) error
 }
 
 func storageCoreInit(driver string) (storage, error) {
@@ -289,6 +322,13 @@ func storageCoreInit(driver string) (storage, error) {
 			return nil, err
 		}
 		return &btrfs, nil
+	case storageTypeCeph:
+		ceph := storageCeph{}
+		err = ceph.StorageCoreInit()
+		if err != nil {
+			return nil, err
+		}
+		return &ceph, nil
 	case storageTypeDir:
 		dir := storageDir{}
 		err = dir.StorageCoreInit()
@@ -362,6 +402,17 @@ func storageInit(d *Daemon, poolName string, volumeName string, volumeType int)
 			return nil, err
 		}
 		return &btrfs, nil
+	case storageTypeCeph:
+		ceph := storageCeph{}
+		ceph.poolID = poolID
+		ceph.pool = pool
+		ceph.volume = volume
+		ceph.d = d
+		err = ceph.StoragePoolInit()
+		if err != nil {
+			return nil, err
+		}
+		return &ceph, nil
 	case storageTypeDir:
 		dir := storageDir{}
 		dir.poolID = poolID
@@ -478,6 +529,11 @@ func getStoragePoolVolumeMountPoint(poolName string, volumeName string) string {
 	return shared.VarPath("storage-pools", poolName, "custom", volumeName)
 }
 
+// ${LXD_DIR}/storage-pools/<pool>/custom-snapshots/<storage_volume>/<snapshot_name>
+func getStoragePoolVolumeSnapshotMountPoint(poolName string, volumeName string, snapshotName string) string {
+	return shared.VarPath("storage-pools", poolName, "custom-snapshots", volumeName, snapshotName)
+}
+
 func createContainerMountpoint(mountPoint string, mountPointSymlink string, privileged bool) error {
 	var mode os.FileMode
 	if privileged {
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -419,7 +420,78 @@ func (s *storageBtrfs) StoragePoolUmount() (bool, error) {
 }
 
 func (s *storageBtrfs) StoragePoolUpdate(changedConfig []string) error {
-	return fmt.Errorf("Btrfs storage properties cannot be changed.")
+	shared.LogInfof("Updating BTRFS storage pool \"%s\".", s.pool.Name)
+
+	for _, key := range changedConfig {
+		if key != "size" && !strings.HasPrefix(key, "user.") {
+			return fmt.Errorf("Btrfs storage properties cannot be changed.")
+		}
+	}
+
+	if shared.StringInSlice("size", changedConfig) {
+		err := s.btrfsPoolResize()
+		if err != nil {
+			return err
+		}
+	}
+
+	shared.LogInfof("Updated BTRFS storage pool \"%s\".", s.pool.Name)
+	return nil
+}
+
+// btrfsPoolResize resizes the loop file backing the pool and the filesystem
+// on it to the "size" property. When shrinking, the filesystem is shrunk
+// first so that btrfs can refuse if the data doesn't fit anymore.
+func (s *storageBtrfs) btrfsPoolResize() error {
+	source := s.pool.Config["source"]
+	if source != storageLoopFilePath(s.pool.Name) || s.d.BackingFs == "btrfs" {
+		return fmt.Errorf("The \"size\" property can only be changed on loop backed pools.")
+	}
+
+	size, err := shared.ParseByteSizeString(s.pool.Config["size"])
+	if err != nil {
+		return err
+	}
+
+	st, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+
+	if size == st.Size() {
+		return nil
+	}
+
+	ourMount, err := s.StoragePoolMount()
+	if err != nil {
+		return err
+	}
+	if ourMount {
+		defer s.StoragePoolUmount()
+	}
+
+	poolMntPoint := getStoragePoolMountPoint(s.pool.Name)
+
+	if size < st.Size() {
+		output, err := exec.Command("btrfs", "filesystem", "resize", fmt.Sprintf("%d", size), poolMntPoint).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("Failed to shrink the BTRFS filesystem: %s", output)
+		}
+
+		return storageLoopFileResize(source, size)
+	}
+
+	err = storageLoopFileResize(source, size)
+	if err != nil {
+		return err
+	}
+
+	output, err := exec.Command("btrfs", "filesystem", "resize", "max", poolMntPoint).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Failed to grow the BTRFS filesystem: %s", output)
+	}
+
+	return nil
 }
 
 func (s *storageBtrfs) GetStoragePoolWritable() api.StoragePoolPut {
@@ -430,6 +502,51 @@ func (s *storageBtrfs) SetStoragePoolWritable(writable *api.StoragePoolPut) {
 	s.pool.StoragePoolPut = *writable
 }
 
+func (s *storageBtrfs) StoragePoolResources() (*api.ResourcesStoragePool, error) {
+	ourMount, err := s.StoragePoolMount()
+	if err != nil {
+		return nil, err
+	}
+	if ourMount {
+		defer s.StoragePoolUmount()
+	}
+
+	return storageResource(getStoragePoolMountPoint(s.pool.Name))
+}
+
+func (s *storageBtrfs) StoragePoolVolumesCheck(repair bool) ([]storageCheckIssue, error) {
+	ourMount, err := s.StoragePoolMount()
+	if err != nil {
+		return nil, err
+	}
+	if ourMount {
+		defer s.StoragePoolUmount()
+	}
+
+	volumes, err := storageVolumesInDirs(s.pool.Name, isBtrfsSubVolume)
+	if err != nil {
+		return nil, err
+	}
+
+	// Leftover "<fingerprint>_tmp" subvolumes are reported as orphans.
+	volumes[storagePoolVolumeTypeImage] = []string{}
+	imageSubvolumePath := s.getImageSubvolumePath(s.pool.Name)
+	if shared.PathExists(imageSubvolumePath) {
+		dents, err := ioutil.ReadDir(imageSubvolumePath)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dent := range dents {
+			if isBtrfsSubVolume(filepath.Join(imageSubvolumePath, dent.Name())) {
+				volumes[storagePoolVolumeTypeImage] = append(volumes[storagePoolVolumeTypeImage], dent.Name())
+			}
+		}
+	}
+
+	return s.checkVolumes(volumes, repair)
+}
+
 func (s *storageBtrfs) GetContainerPoolInfo() (int64, string) {
 	return s.poolID, s.pool.Name
 }
@@ -459,6 +576,14 @@ func (s *storageBtrfs) StoragePoolVolumeCreate() error {
 		return err
 	}
 
+	if s.volume.Config["size"] != "" {
+		err = s.storagePoolVolumeSetQuota()
+		if err != nil {
+			btrfsSubVolumeDelete(customSubvolumeName)
+			return err
+		}
+	}
+
 	shared.LogInfof("Created BTRFS storage volume \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
 	return nil
 }
@@ -486,6 +611,27 @@ func (s *storageBtrfs) StoragePoolVolumeDelete() error {
 		}
 	}
 
+	// Delete all snapshots of the storage volume.
+	snapshotsPath := shared.VarPath("storage-pools", s.pool.Name, "custom-snapshots", s.volume.Name)
+	if shared.PathExists(snapshotsPath) {
+		snapshots, err := ioutil.ReadDir(snapshotsPath)
+		if err != nil {
+			return err
+		}
+
+		for _, snapshot := range snapshots {
+			err = btrfsSubVolumesDelete(filepath.Join(snapshotsPath, snapshot.Name()))
+			if err != nil {
+				return err
+			}
+		}
+
+		err = os.RemoveAll(snapshotsPath)
+		if err != nil {
+			return err
+		}
+	}
+
 	shared.LogInfof("Deleted BTRFS storage volume \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
 	return nil
 }
@@ -508,7 +654,230 @@ func (s *storageBtrfs) StoragePoolVolumeUmount() (bool, error) {
 }
 
 func (s *storageBtrfs) StoragePoolVolumeUpdate(changedConfig []string) error {
-	return fmt.Errorf("Btrfs storage properties cannot be changed.")
+	shared.LogInfof("Updating BTRFS storage volume \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
+	for _, key := range changedConfig {
+		if key != "size" && !strings.HasPrefix(key, "user.") {
+			return fmt.Errorf("The \"%s\" property cannot be changed.", key)
+		}
+	}
+
+	if shared.StringInSlice("size", changedConfig) {
+		err := s.storagePoolVolumeSetQuota()
+		if err != nil {
+			return err
+		}
+	}
+
+	shared.LogInfof("Updated BTRFS storage volume \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	return nil
+}
+
+// storagePoolVolumeSetQuota applies the "size" property of the custom storage
+// volume as a qgroup limit. An empty size removes the limit.
+func (s *storageBtrfs) storagePoolVolumeSetQuota() error {
+	subvol := getStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name)
+
+	_, err := btrfsSubVolumeQGroup(subvol)
+	if err != nil {
+		return err
+	}
+
+	limit := "none"
+	if s.volume.Config["size"] != "" {
+		size, err := shared.ParseByteSizeString(s.volume.Config["size"])
+		if err != nil {
+			return err
+		}
+
+		if size > 0 {
+			limit = fmt.Sprintf("%d", size)
+		}
+	}
+
+	output, err := exec.Command(
+		"btrfs",
+		"qgroup",
+		"limit",
+		"-e", limit,
+		subvol).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Failed to set btrfs quota: %s", output)
+	}
+
+	return nil
+}
+
+func (s *storageBtrfs) StoragePoolVolumeGetUsage() (int64, error) {
+	_, err := s.StoragePoolMount()
+	if err != nil {
+		return -1, err
+	}
+
+	return s.btrfsPoolVolumeQGroupUsage(getStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name))
+}
+
+func (s *storageBtrfs) StoragePoolVolumeRename(newName string) error {
+	shared.LogInfof("Renaming BTRFS storage volume on storage pool \"%s\" from \"%s\" to \"%s\".", s.pool.Name, s.volume.Name, newName)
+
+	_, err := s.StoragePoolMount()
+	if err != nil {
+		return err
+	}
+
+	oldPath := getStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name)
+	newPath := getStoragePoolVolumeMountPoint(s.pool.Name, newName)
+	err = os.Rename(oldPath, newPath)
+	if err != nil {
+		return err
+	}
+
+	oldSnapshotsPath := shared.VarPath("storage-pools", s.pool.Name, "custom-snapshots", s.volume.Name)
+	newSnapshotsPath := shared.VarPath("storage-pools", s.pool.Name, "custom-snapshots", newName)
+	if shared.PathExists(oldSnapshotsPath) {
+		err = os.Rename(oldSnapshotsPath, newSnapshotsPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	shared.LogInfof("Renamed BTRFS storage volume on storage pool \"%s\" from \"%s\" to \"%s\".", s.pool.Name, s.volume.Name, newName)
+	s.volume.Name = newName
+	return nil
+}
+
+func (s *storageBtrfs) StoragePoolVolumeCopy(source *api.StorageVolumeSource) error {
+	shared.LogInfof("Copying BTRFS storage volume \"%s\" on storage pool \"%s\" to \"%s\" on storage pool \"%s\".", source.Name, source.Pool, s.volume.Name, s.pool.Name)
+
+	// Volumes on different storage pools can't be snapshotted into each
+	// other so fall back to rsync.
+	if source.Pool != s.pool.Name {
+		err := storagePoolVolumeCopyRsync(s.d, s, source)
+		if err != nil {
+			return err
+		}
+
+		shared.LogInfof("Copied BTRFS storage volume \"%s\" on storage pool \"%s\" to \"%s\" on storage pool \"%s\".", source.Name, source.Pool, s.volume.Name, s.pool.Name)
+		return nil
+	}
+
+	_, err := s.StoragePoolMount()
+	if err != nil {
+		return err
+	}
+
+	customSubvolumePath := s.getCustomSubvolumePath(s.pool.Name)
+	if !shared.PathExists(customSubvolumePath) {
+		err := os.MkdirAll(customSubvolumePath, 0700)
+		if err != nil {
+			return err
+		}
+	}
+
+	sourcePath := getStoragePoolVolumeMountPoint(source.Pool, source.Name)
+	targetPath := getStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name)
+	err = s.btrfsPoolVolumesSnapshot(sourcePath, targetPath, false)
+	if err != nil {
+		return err
+	}
+
+	shared.LogInfof("Copied BTRFS storage volume \"%s\" on storage pool \"%s\" to \"%s\" on storage pool \"%s\".", source.Name, source.Pool, s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageBtrfs) StoragePoolVolumeSnapshotCreate(snapshotName string) error {
+	shared.LogInfof("Creating BTRFS storage volume snapshot \"%s\" of \"%s\" on storage pool \"%s\".", snapshotName, s.volume.Name, s.pool.Name)
+
+	_, err := s.StoragePoolMount()
+	if err != nil {
+		return err
+	}
+
+	sourcePath := getStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name)
+	targetPath := getStoragePoolVolumeSnapshotMountPoint(s.pool.Name, s.volume.Name, snapshotName)
+	err = os.MkdirAll(filepath.Dir(targetPath), 0700)
+	if err != nil {
+		return err
+	}
+
+	err = s.btrfsPoolVolumesSnapshot(sourcePath, targetPath, true)
+	if err != nil {
+		return err
+	}
+
+	shared.LogInfof("Created BTRFS storage volume snapshot \"%s\" of \"%s\" on storage pool \"%s\".", snapshotName, s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageBtrfs) StoragePoolVolumeSnapshotDelete(snapshotName string) error {
+	shared.LogInfof("Deleting BTRFS storage volume snapshot \"%s\" of \"%s\" on storage pool \"%s\".", snapshotName, s.volume.Name, s.pool.Name)
+
+	_, err := s.StoragePoolMount()
+	if err != nil {
+		return err
+	}
+
+	snapshotPath := getStoragePoolVolumeSnapshotMountPoint(s.pool.Name, s.volume.Name, snapshotName)
+	if shared.PathExists(snapshotPath) {
+		err = btrfsSubVolumesDelete(snapshotPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Remove the snapshots directory of the volume if it is now empty.
+	snapshotsPath := filepath.Dir(snapshotPath)
+	if ok, _ := shared.PathIsEmpty(snapshotsPath); ok {
+		os.Remove(snapshotsPath)
+	}
+
+	shared.LogInfof("Deleted BTRFS storage volume snapshot \"%s\" of \"%s\" on storage pool \"%s\".", snapshotName, s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageBtrfs) StoragePoolVolumeSnapshotRename(snapshotName string, newName string) error {
+	_, err := s.StoragePoolMount()
+	if err != nil {
+		return err
+	}
+
+	oldPath := getStoragePoolVolumeSnapshotMountPoint(s.pool.Name, s.volume.Name, snapshotName)
+	newPath := getStoragePoolVolumeSnapshotMountPoint(s.pool.Name, s.volume.Name, newName)
+	return os.Rename(oldPath, newPath)
+}
+
+func (s *storageBtrfs) StoragePoolVolumeSnapshotRestore(snapshotName string) error {
+	shared.LogInfof("Restoring BTRFS storage volume \"%s\" on storage pool \"%s\" from snapshot \"%s\".", s.volume.Name, s.pool.Name, snapshotName)
+
+	_, err := s.StoragePoolMount()
+	if err != nil {
+		return err
+	}
+
+	sourcePath := getStoragePoolVolumeSnapshotMountPoint(s.pool.Name, s.volume.Name, snapshotName)
+	targetPath := getStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name)
+
+	// Move the current subvolume out of the way so that we can put it back
+	// in case anything goes wrong.
+	backupPath := fmt.Sprintf("%s.back", targetPath)
+	err = os.Rename(targetPath, backupPath)
+	if err != nil {
+		return err
+	}
+
+	err = s.btrfsPoolVolumesSnapshot(sourcePath, targetPath, false)
+	if err != nil {
+		os.Rename(backupPath, targetPath)
+		return err
+	}
+
+	err = btrfsSubVolumesDelete(backupPath)
+	if err != nil {
+		return err
+	}
+
+	shared.LogInfof("Restored BTRFS storage volume \"%s\" on storage pool \"%s\" from snapshot \"%s\".", s.volume.Name, s.pool.Name, snapshotName)
+	return nil
 }
 
 func (s *storageBtrfs) GetStoragePoolVolumeWritable() api.StorageVolumePut {
@@ -1162,6 +1531,164 @@ func (s *storageBtrfs) ContainerSnapshotCreateEmpty(snapshotContainer container)
 	return nil
 }
 
+func (s *storageBtrfs) ContainerBackupDump(container container, snapshots []container, target string) error {
+	shared.LogDebugf("Dumping BTRFS storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
+	_, err := s.StoragePoolMount()
+	if err != nil {
+		return err
+	}
+
+	btrfsSend := func(subvol string, file string) error {
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		var stderr bytes.Buffer
+		cmd := exec.Command("btrfs", "send", subvol)
+		cmd.Stdout = f
+		cmd.Stderr = &stderr
+
+		err = cmd.Run()
+		if err != nil {
+			return fmt.Errorf("Failed to send \"%s\": %s", subvol, stderr.String())
+		}
+
+		return nil
+	}
+
+	if len(snapshots) > 0 {
+		err := os.MkdirAll(filepath.Join(target, "snapshots"), 0700)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Snapshots are read-only subvolumes so can be sent directly.
+	for _, snap := range snapshots {
+		fields := strings.SplitN(snap.Name(), shared.SnapshotDelimiter, 2)
+		snapshotSubvolumeName := getSnapshotMountPoint(s.pool.Name, snap.Name())
+		err := btrfsSend(snapshotSubvolumeName, filepath.Join(target, "snapshots", fields[1]+".bin"))
+		if err != nil {
+			return err
+		}
+	}
+
+	// The container itself needs a temporary read-only snapshot.
+	tmpPath, err := ioutil.TempDir(s.getContainerSubvolumePath(s.pool.Name), "backup_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpPath)
+
+	containerSubvolumeName := getContainerMountPoint(s.pool.Name, container.Name())
+	tmpSubvolumeName := filepath.Join(tmpPath, "container")
+	err = s.btrfsPoolVolumesSnapshot(containerSubvolumeName, tmpSubvolumeName, true)
+	if err != nil {
+		return err
+	}
+	defer btrfsSubVolumesDelete(tmpSubvolumeName)
+
+	err = btrfsSend(tmpSubvolumeName, filepath.Join(target, "container.bin"))
+	if err != nil {
+		return err
+	}
+
+	shared.LogDebugf("Dumped BTRFS storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageBtrfs) ContainerBackupLoad(container container, snapshots []container, source string) error {
+	shared.LogDebugf("Loading BTRFS storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
+	_, err := s.StoragePoolMount()
+	if err != nil {
+		return err
+	}
+
+	// btrfs receive names the new subvolume after the one which was sent,
+	// so receive into a temporary directory and snapshot from there.
+	btrfsRecv := func(file string, dest string, readonly bool) error {
+		tmpPath, err := ioutil.TempDir(s.getContainerSubvolumePath(s.pool.Name), "backup_")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmpPath)
+
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		cmd := exec.Command("btrfs", "receive", tmpPath)
+		cmd.Stdin = f
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("Failed to receive \"%s\": %s", file, string(output))
+		}
+
+		entries, err := ioutil.ReadDir(tmpPath)
+		if err != nil {
+			return err
+		}
+
+		if len(entries) != 1 {
+			return fmt.Errorf("Unexpected content in btrfs stream \"%s\".", file)
+		}
+
+		receivedSubvolumeName := filepath.Join(tmpPath, entries[0].Name())
+		defer btrfsSubVolumesDelete(receivedSubvolumeName)
+
+		return s.btrfsPoolVolumesSnapshot(receivedSubvolumeName, dest, readonly)
+	}
+
+	if len(snapshots) > 0 {
+		snapshotSubvolumePath := s.getSnapshotSubvolumePath(s.pool.Name, container.Name())
+		if !shared.PathExists(snapshotSubvolumePath) {
+			err := os.MkdirAll(snapshotSubvolumePath, 0711)
+			if err != nil {
+				return err
+			}
+		}
+
+		snapshotMntPointSymlinkTarget := shared.VarPath("storage-pools", s.pool.Name, "snapshots", container.Name())
+		snapshotMntPointSymlink := shared.VarPath("snapshots", container.Name())
+		if !shared.PathExists(snapshotMntPointSymlink) {
+			err := createContainerMountpoint(snapshotMntPointSymlinkTarget, snapshotMntPointSymlink, container.IsPrivileged())
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, snap := range snapshots {
+		fields := strings.SplitN(snap.Name(), shared.SnapshotDelimiter, 2)
+		snapshotSubvolumeName := getSnapshotMountPoint(s.pool.Name, snap.Name())
+		err := btrfsRecv(filepath.Join(source, "snapshots", fields[1]+".bin"), snapshotSubvolumeName, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Replace the empty subvolume of the container.
+	containerSubvolumeName := getContainerMountPoint(s.pool.Name, container.Name())
+	err = btrfsSubVolumesDelete(containerSubvolumeName)
+	if err != nil {
+		return err
+	}
+
+	err = btrfsRecv(filepath.Join(source, "container.bin"), containerSubvolumeName, false)
+	if err != nil {
+		return err
+	}
+
+	shared.LogDebugf("Loaded BTRFS storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	return nil
+}
+
 func (s *storageBtrfs) ImageCreate(fingerprint string) error {
 	shared.LogDebugf("Creating BTRFS storage volume for image \"%s\" on storage pool \"%s\".", fingerprint, s.pool.Name)
 
@@ -1295,6 +1822,61 @@ func (s *storageBtrfs) ImageUmount(fingerprint string) (bool, error) {
 	return true, nil
 }
 
+func (s *storageBtrfs) ImageVolumesGC(dryRun bool) ([]string, error) {
+	_, err := s.StoragePoolMount()
+	if err != nil {
+		return nil, err
+	}
+
+	referenced, err := s.imageVolumesReferenced()
+	if err != nil {
+		return nil, err
+	}
+
+	imageSubvolumePath := s.getImageSubvolumePath(s.pool.Name)
+	if !shared.PathExists(imageSubvolumePath) {
+		return []string{}, nil
+	}
+
+	dents, err := ioutil.ReadDir(imageSubvolumePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Containers are independent snapshots of the image subvolume, so
+	// nothing ever depends on it. Failed creates can also leave their
+	// temporary "<fingerprint>_tmp" subvolume behind.
+	orphans := []string{}
+	for _, dent := range dents {
+		fingerprint := strings.TrimSuffix(dent.Name(), "_tmp")
+		if shared.StringInSlice(fingerprint, referenced) {
+			continue
+		}
+
+		subvol := filepath.Join(imageSubvolumePath, dent.Name())
+		if !isBtrfsSubVolume(subvol) {
+			continue
+		}
+
+		orphans = append(orphans, fmt.Sprintf("images/%s", dent.Name()))
+		if dryRun {
+			continue
+		}
+
+		err := btrfsSubVolumesDelete(subvol)
+		if err != nil {
+			return nil, err
+		}
+
+		err = os.RemoveAll(subvol)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return orphans, nil
+}
+
 func btrfsSubVolumeCreate(subvol string) error {
 	parentDestPath := filepath.Dir(subvol)
 	if !shared.PathExists(parentDestPath) {
@@ -1572,6 +2154,7 @@ type btrfsMigrationSourceDriver struct {
 	btrfs              *storageBtrfs
 	runningSnapName    string
 	stoppedSnapName    string
+	refreshSnapName    string
 }
 
 func (s *btrfsMigrationSourceDriver) Snapshots() []container {
@@ -1656,12 +2239,15 @@ func (s *btrfsMigrationSourceDriver) SendWhileRunning(conn *websocket.Conn, op *
 		return s.send(conn, migrationSendSnapshot, "", wrapper)
 	}
 
-	for i, snap := range s.snapshots {
-		prev := ""
-		if i > 0 {
-			prev = getSnapshotMountPoint(containerPool, s.snapshots[i-1].Name())
+	lastSnap := s.refreshSnapName
+	for _, snap := range s.snapshots {
+		prev := lastSnap
+		if prev != "" {
+			prev = getSnapshotMountPoint(containerPool, prev)
 		}
 
+		lastSnap = snap.Name()
+
 		snapMntPoint := getSnapshotMountPoint(containerPool, snap.Name())
 		wrapper := StorageProgressReader(op, "fs_progress", snap.Name())
 		if err := s.send(conn, snapMntPoint, prev, wrapper); err != nil {
@@ -1690,8 +2276,16 @@ func (s *btrfsMigrationSourceDriver) SendWhileRunning(conn *websocket.Conn, op *
 	}
 	defer btrfsSubVolumesDelete(migrationSendSnapshot)
 
+	/* When refreshing, the target has all our snapshots by now, so we can
+	 * just send what changed since the newest one.
+	 */
+	parent := ""
+	if s.refreshSnapName != "" {
+		parent = getSnapshotMountPoint(containerPool, lastSnap)
+	}
+
 	wrapper := StorageProgressReader(op, "fs_progress", containerName)
-	return s.send(conn, migrationSendSnapshot, "", wrapper)
+	return s.send(conn, migrationSendSnapshot, parent, wrapper)
 }
 
 func (s *btrfsMigrationSourceDriver) SendAfterCheckpoint(conn *websocket.Conn) error {
@@ -1719,6 +2313,19 @@ func (s *btrfsMigrationSourceDriver) Cleanup() {
 	}
 }
 
+func (s *btrfsMigrationSourceDriver) Refresh(snapshotNames []string) {
+	parent, snapshots := migrationRefreshSnapshots(s.snapshots, snapshotNames)
+	if parent != nil {
+		s.refreshSnapName = parent.Name()
+	}
+
+	s.snapshots = snapshots
+	s.btrfsSnapshotNames = []string{}
+	for _, snap := range snapshots {
+		s.btrfsSnapshotNames = append(s.btrfsSnapshotNames, snap.Path())
+	}
+}
+
 func (s *storageBtrfs) MigrationType() MigrationFSType {
 	if runningInUserns {
 		return MigrationFSType_RSYNC
@@ -1764,9 +2371,9 @@ func (s *storageBtrfs) MigrationSource(c container) (MigrationStorageSourceDrive
 	return driver, nil
 }
 
-func (s *storageBtrfs) MigrationSink(live bool, container container, snapshots []*Snapshot, conn *websocket.Conn, srcIdmap *shared.IdmapSet, op *operation) error {
+func (s *storageBtrfs) MigrationSink(live bool, container container, snapshots []*Snapshot, conn *websocket.Conn, srcIdmap *shared.IdmapSet, op *operation, refresh bool) error {
 	if runningInUserns {
-		return rsyncMigrationSink(live, container, snapshots, conn, srcIdmap, op)
+		return rsyncMigrationSink(live, container, snapshots, conn, srcIdmap, op, refresh)
 	}
 
 	btrfsRecv := func(snapName string, btrfsPath string, targetPath string, isSnapshot bool, writeWrapper func(io.WriteCloser) io.WriteCloser) error {
@@ -1853,15 +2460,7 @@ func (s *storageBtrfs) MigrationSink(live bool, container container, snapshots [
 	}
 
 	for _, snap := range snapshots {
-		args := snapshotProtobufToContainerArgs(container.Name(), snap)
-		// Unset the pool of the orginal container and let
-		// containerLXCCreate figure out on which pool to  send it.
-		// Later we might make this more flexible.
-		for k, v := range args.Devices {
-			if v["type"] == "disk" && v["path"] == "/" {
-				args.Devices[k]["pool"] = ""
-			}
-		}
+		args := migrationSnapshotArgs(container, snap)
 		containerMntPoint := getSnapshotMountPoint(containerPool, args.Name)
 		_, err := containerCreateEmptySnapshot(container.Daemon(), args)
 		if err != nil {
@@ -0,0 +1,1809 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+type storageCeph struct {
+	clusterName string
+	osdPoolName string
+	userName    string
+	pgNum       string
+	storageShared
+}
+
+func (s *storageCeph) getRBDMountOptions() string {
+	if s.volume.Config["block.mount_options"] != "" {
+		return s.volume.Config["block.mount_options"]
+	}
+
+	if s.pool.Config["volume.block.mount_options"] != "" {
+		return s.pool.Config["volume.block.mount_options"]
+	}
+
+	return "discard"
+}
+
+func (s *storageCeph) getRBDFilesystem() string {
+	if s.volume.Config["block.filesystem"] != "" {
+		return s.volume.Config["block.filesystem"]
+	}
+
+	if s.pool.Config["volume.block.filesystem"] != "" {
+		return s.pool.Config["volume.block.filesystem"]
+	}
+
+	return "ext4"
+}
+
+func (s *storageCeph) getRBDSize() (string, error) {
+	sz, err := shared.ParseByteSizeString(s.volume.Config["size"])
+	if err != nil {
+		return "", err
+	}
+
+	// Safety net: Set to default value.
+	if sz == 0 {
+		sz, _ = shared.ParseByteSizeString("10GB")
+	}
+
+	return fmt.Sprintf("%d", sz), nil
+}
+
+// getRBDSnapshotMountOptions returns the options needed to mount a read-only
+// snapshot of a filesystem which was in use when the snapshot was taken.
+func (s *storageCeph) getRBDSnapshotMountOptions() string {
+	if s.getRBDFilesystem() == "xfs" {
+		return "ro,norecovery,nouuid"
+	}
+
+	return "ro,noload"
+}
+
+// rbdMap maps the RBD image backing a storage volume.
+func (s *storageCeph) rbdMap(volumeName string, volumeType string, snapshotName string) (string, error) {
+	return cephRBDVolumeMap(s.clusterName, s.osdPoolName, volumeName, volumeType, snapshotName, s.userName)
+}
+
+// rbdUnmap unmaps the RBD image backing a storage volume.
+func (s *storageCeph) rbdUnmap(volumeName string, volumeType string, snapshotName string) error {
+	return cephRBDVolumeUnmap(s.clusterName, s.osdPoolName, volumeName, volumeType, snapshotName, s.userName)
+}
+
+// rbdMount maps the RBD image backing a storage volume and mounts it.
+func (s *storageCeph) rbdMount(volumeName string, volumeType string, mntPoint string) error {
+	devPath, err := s.rbdMap(volumeName, volumeType, "")
+	if err != nil {
+		return err
+	}
+
+	err = tryMount(devPath, mntPoint, s.getRBDFilesystem(), 0, s.getRBDMountOptions())
+	if err != nil {
+		s.rbdUnmap(volumeName, volumeType, "")
+		return err
+	}
+
+	return nil
+}
+
+// rbdUmount unmounts a storage volume and unmaps the RBD image backing it.
+func (s *storageCeph) rbdUmount(volumeName string, volumeType string, mntPoint string) error {
+	if shared.IsMountPoint(mntPoint) {
+		err := tryUnmount(mntPoint, 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.rbdUnmap(volumeName, volumeType, "")
+}
+
+// rbdCreate creates an RBD image with an empty filesystem on it.
+func (s *storageCeph) rbdCreate(volumeName string, volumeType string, size string) error {
+	err := cephRBDVolumeCreate(s.clusterName, s.osdPoolName, volumeName, volumeType, size, s.userName)
+	if err != nil {
+		return err
+	}
+
+	devPath, err := s.rbdMap(volumeName, volumeType, "")
+	if err != nil {
+		cephRBDVolumeDelete(s.clusterName, s.osdPoolName, volumeName, volumeType, s.userName)
+		return err
+	}
+
+	err = cephMakeFilesystem(s.getRBDFilesystem(), devPath)
+	s.rbdUnmap(volumeName, volumeType, "")
+	if err != nil {
+		cephRBDVolumeDelete(s.clusterName, s.osdPoolName, volumeName, volumeType, s.userName)
+		return err
+	}
+
+	return nil
+}
+
+// rbdDelete deletes an RBD image together with all its snapshots.
+func (s *storageCeph) rbdDelete(volumeName string, volumeType string) error {
+	if !cephRBDVolumeExists(s.clusterName, s.osdPoolName, volumeName, volumeType, s.userName) {
+		return nil
+	}
+
+	err := s.rbdUnmap(volumeName, volumeType, "")
+	if err != nil {
+		return err
+	}
+
+	err = cephRBDSnapshotPurge(s.clusterName, s.osdPoolName, volumeName, volumeType, s.userName)
+	if err != nil {
+		return err
+	}
+
+	return cephRBDVolumeDelete(s.clusterName, s.osdPoolName, volumeName, volumeType, s.userName)
+}
+
+// rbdGenerateNewUUID gives a copied xfs filesystem a new UUID so that it can
+// be mounted next to its origin.
+func (s *storageCeph) rbdGenerateNewUUID(volumeName string, volumeType string) error {
+	if s.getRBDFilesystem() != "xfs" {
+		return nil
+	}
+
+	devPath, err := s.rbdMap(volumeName, volumeType, "")
+	if err != nil {
+		return err
+	}
+	defer s.rbdUnmap(volumeName, volumeType, "")
+
+	return xfsGenerateNewUUID(devPath)
+}
+
+// rbdGrow grows an RBD image and the filesystem on it. The volume needs to be
+// mounted at mntPoint.
+func (s *storageCeph) rbdGrow(volumeName string, volumeType string, mntPoint string, size int64) error {
+	info, err := cephRBDVolumeGetInfo(s.clusterName, s.osdPoolName, volumeName, volumeType, s.userName)
+	if err != nil {
+		return err
+	}
+
+	if uint64(size) == info.Size {
+		return nil
+	}
+
+	if uint64(size) < info.Size {
+		return fmt.Errorf("Shrinking RBD storage volumes is not supported.")
+	}
+
+	err = cephRBDVolumeResize(s.clusterName, s.osdPoolName, volumeName, volumeType, size, s.userName)
+	if err != nil {
+		return err
+	}
+
+	devPath, err := s.rbdMap(volumeName, volumeType, "")
+	if err != nil {
+		return err
+	}
+
+	return cephGrowFilesystem(s.getRBDFilesystem(), devPath, mntPoint)
+}
+
+// Only initialize the minimal information we need about a given storage type.
+func (s *storageCeph) StorageCoreInit() error {
+	s.sType = storageTypeCeph
+	typeName, err := storageTypeToString(s.sType)
+	if err != nil {
+		return err
+	}
+	s.sTypeName = typeName
+
+	output, err := cephRunCommand("rbd", "--version")
+	if err != nil {
+		return fmt.Errorf("Error getting CEPH version: %s", err)
+	}
+
+	// "ceph version 12.2.0 (<commit>) luminous (stable)"
+	fields := strings.Fields(output)
+	if len(fields) >= 3 {
+		s.sTypeVersion = fields[2]
+	} else {
+		s.sTypeVersion = strings.TrimSpace(output)
+	}
+
+	shared.LogDebugf("Initializing a CEPH driver.")
+	return nil
+}
+
+func (s *storageCeph) StoragePoolInit() error {
+	err := s.StorageCoreInit()
+	if err != nil {
+		return err
+	}
+
+	s.clusterName = "ceph"
+	if s.pool.Config["ceph.cluster_name"] != "" {
+		s.clusterName = s.pool.Config["ceph.cluster_name"]
+	}
+
+	s.userName = "admin"
+	if s.pool.Config["ceph.user.name"] != "" {
+		s.userName = s.pool.Config["ceph.user.name"]
+	}
+
+	s.osdPoolName = s.pool.Name
+	if s.pool.Config["ceph.osd.pool_name"] != "" {
+		s.osdPoolName = s.pool.Config["ceph.osd.pool_name"]
+	}
+
+	s.pgNum = "32"
+	if s.pool.Config["ceph.osd.pg_num"] != "" {
+		s.pgNum = s.pool.Config["ceph.osd.pg_num"]
+	}
+
+	return nil
+}
+
+func (s *storageCeph) StoragePoolCheck() error {
+	shared.LogDebugf("Checking CEPH storage pool \"%s\".", s.pool.Name)
+
+	if !cephOSDPoolExists(s.clusterName, s.osdPoolName, s.userName) {
+		return fmt.Errorf("CEPH OSD storage pool \"%s\" does not exist in cluster \"%s\".", s.osdPoolName, s.clusterName)
+	}
+
+	shared.LogDebugf("Checked CEPH storage pool \"%s\".", s.pool.Name)
+	return nil
+}
+
+func (s *storageCeph) StoragePoolCreate() error {
+	shared.LogInfof("Creating CEPH storage pool \"%s\".", s.pool.Name)
+	tryUndo := true
+
+	// Create the mountpoint for the storage pool.
+	poolMntPoint := getStoragePoolMountPoint(s.pool.Name)
+	err := os.MkdirAll(poolMntPoint, 0711)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tryUndo {
+			os.Remove(poolMntPoint)
+		}
+	}()
+
+	// Clear size as the space is managed by the CEPH cluster.
+	s.pool.Config["size"] = ""
+
+	// Set source to the OSD pool name.
+	s.pool.Config["source"] = s.osdPoolName
+
+	if !cephOSDPoolExists(s.clusterName, s.osdPoolName, s.userName) {
+		err := cephOSDPoolCreate(s.clusterName, s.osdPoolName, s.pgNum, s.userName)
+		if err != nil {
+			return fmt.Errorf("Failed to create the CEPH OSD storage pool \"%s\": %s", s.osdPoolName, err)
+		}
+		defer func() {
+			if tryUndo {
+				cephOSDPoolDestroy(s.clusterName, s.osdPoolName, s.userName)
+			}
+		}()
+	} else if !shared.IsTrue(s.pool.Config["ceph.osd.force_reuse"]) {
+		// Don't take over an OSD pool which is already in use unless
+		// explicitly asked to.
+		volumes, err := cephRBDVolumesList(s.clusterName, s.osdPoolName, s.userName)
+		if err != nil {
+			return err
+		}
+
+		if len(volumes) > 0 {
+			return fmt.Errorf("CEPH OSD storage pool \"%s\" in cluster \"%s\" is already in use. Set \"ceph.osd.force_reuse=true\" to use it anyway.", s.osdPoolName, s.clusterName)
+		}
+	}
+
+	// Mark the OSD pool as being in use by LXD.
+	if !cephRBDVolumeExists(s.clusterName, s.osdPoolName, s.osdPoolName, "lxd", s.userName) {
+		err := cephRBDVolumeCreate(s.clusterName, s.osdPoolName, s.osdPoolName, "lxd", "0", s.userName)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Deregister cleanup.
+	tryUndo = false
+
+	shared.LogInfof("Created CEPH storage pool \"%s\".", s.pool.Name)
+	return nil
+}
+
+func (s *storageCeph) StoragePoolDelete() error {
+	shared.LogInfof("Deleting CEPH storage pool \"%s\".", s.pool.Name)
+
+	if cephRBDVolumeExists(s.clusterName, s.osdPoolName, s.osdPoolName, "lxd", s.userName) {
+		err := cephRBDVolumeDelete(s.clusterName, s.osdPoolName, s.osdPoolName, "lxd", s.userName)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Only destroy the OSD pool if nothing else is using it.
+	volumes, err := cephRBDVolumesList(s.clusterName, s.osdPoolName, s.userName)
+	if err != nil {
+		return err
+	}
+
+	if len(volumes) == 0 {
+		err := cephOSDPoolDestroy(s.clusterName, s.osdPoolName, s.userName)
+		if err != nil {
+			return err
+		}
+	} else {
+		shared.LogWarnf("Not destroying CEPH OSD storage pool \"%s\" as it still contains %d RBD volumes.", s.osdPoolName, len(volumes))
+	}
+
+	// Delete the mountpoint for the storage pool.
+	poolMntPoint := getStoragePoolMountPoint(s.pool.Name)
+	err = os.RemoveAll(poolMntPoint)
+	if err != nil {
+		return err
+	}
+
+	shared.LogInfof("Deleted CEPH storage pool \"%s\".", s.pool.Name)
+	return nil
+}
+
+func (s *storageCeph) StoragePoolMount() (bool, error) {
+	return true, nil
+}
+
+func (s *storageCeph) StoragePoolUmount() (bool, error) {
+	return true, nil
+}
+
+func (s *storageCeph) StoragePoolVolumesCheck(repair bool) ([]storageCheckIssue, error) {
+	rbdVolumes, err := cephRBDVolumesList(s.clusterName, s.osdPoolName, s.userName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Zombies are kept around for their clones and aren't known to the
+	// database, they're left alone.
+	volumes := map[int][]string{}
+	for _, volumeType := range supportedVolumeTypes {
+		volumeTypeApiEndpoint, err := storagePoolVolumeTypeToApiEndpoint(volumeType)
+		if err != nil {
+			return nil, err
+		}
+
+		volumes[volumeType] = []string{}
+		prefix := cephRBDVolumeName(volumeTypeApiEndpoint, "")
+		for _, rbdVolume := range rbdVolumes {
+			if !strings.HasPrefix(rbdVolume, prefix) {
+				continue
+			}
+
+			name := strings.TrimPrefix(rbdVolume, prefix)
+			volumes[volumeType] = append(volumes[volumeType], name)
+			if volumeType == storagePoolVolumeTypeImage {
+				continue
+			}
+
+			snapshots, err := cephRBDSnapshotsList(s.clusterName, s.osdPoolName, name, volumeTypeApiEndpoint, s.userName)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, snapshot := range snapshots {
+				prefix := cephRBDSnapshotName("")
+				if !strings.HasPrefix(snapshot, prefix) {
+					continue
+				}
+
+				snapshotName := strings.TrimPrefix(snapshot, prefix)
+				volumes[volumeType] = append(volumes[volumeType], fmt.Sprintf("%s%s%s", name, shared.SnapshotDelimiter, snapshotName))
+			}
+		}
+	}
+
+	return s.checkVolumes(volumes, repair)
+}
+
+func (s *storageCeph) StoragePoolResources() (*api.ResourcesStoragePool, error) {
+	used, available, err := cephOSDPoolUsage(s.clusterName, s.osdPoolName, s.userName)
+	if err != nil {
+		return nil, err
+	}
+
+	res := api.ResourcesStoragePool{}
+	res.Space.Used = used
+	res.Space.Total = used + available
+
+	return &res, nil
+}
+
+func (s *storageCeph) GetStoragePoolWritable() api.StoragePoolPut {
+	return s.pool.Writable()
+}
+
+func (s *storageCeph) GetStoragePoolVolumeWritable() api.StorageVolumePut {
+	return s.volume.Writable()
+}
+
+func (s *storageCeph) SetStoragePoolWritable(writable *api.StoragePoolPut) {
+	s.pool.StoragePoolPut = *writable
+}
+
+func (s *storageCeph) SetStoragePoolVolumeWritable(writable *api.StorageVolumePut) {
+	s.volume.StorageVolumePut = *writable
+}
+
+func (s *storageCeph) GetContainerPoolInfo() (int64, string) {
+	return s.poolID, s.pool.Name
+}
+
+func (s *storageCeph) StoragePoolUpdate(changedConfig []string) error {
+	shared.LogInfof("Updating CEPH storage pool \"%s\".", s.pool.Name)
+
+	for _, key := range []string{"size", "source", "ceph.cluster_name", "ceph.user.name", "ceph.osd.pool_name", "ceph.osd.pg_num"} {
+		if shared.StringInSlice(key, changedConfig) {
+			return fmt.Errorf("The \"%s\" property cannot be changed.", key)
+		}
+	}
+
+	if shared.StringInSlice("ceph.osd.force_reuse", changedConfig) {
+		// noop
+	}
+
+	if shared.StringInSlice("volume.block.mount_options", changedConfig) {
+		// noop
+	}
+
+	if shared.StringInSlice("volume.block.filesystem", changedConfig) {
+		// noop
+	}
+
+	if shared.StringInSlice("volume.size", changedConfig) {
+		// noop
+	}
+
+	shared.LogInfof("Updated CEPH storage pool \"%s\".", s.pool.Name)
+	return nil
+}
+
+func (s *storageCeph) StoragePoolVolumeCreate() error {
+	shared.LogInfof("Creating CEPH storage volume \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
+	tryUndo := true
+
+	size, err := s.getRBDSize()
+	if err != nil {
+		return err
+	}
+
+	err = s.rbdCreate(s.volume.Name, storagePoolVolumeApiEndpointCustom, size)
+	if err != nil {
+		return fmt.Errorf("Failed to create RBD storage volume \"%s\": %s", s.volume.Name, err)
+	}
+	defer func() {
+		if tryUndo {
+			s.StoragePoolVolumeDelete()
+		}
+	}()
+
+	customPoolVolumeMntPoint := getStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name)
+	err = os.MkdirAll(customPoolVolumeMntPoint, 0711)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.StoragePoolVolumeMount()
+	if err != nil {
+		return err
+	}
+
+	tryUndo = false
+
+	shared.LogInfof("Created CEPH storage volume \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageCeph) StoragePoolVolumeDelete() error {
+	shared.LogInfof("Deleting CEPH storage volume \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
+	_, err := s.StoragePoolVolumeUmount()
+	if err != nil {
+		return err
+	}
+
+	// This also removes the snapshots of the storage volume.
+	err = s.rbdDelete(s.volume.Name, storagePoolVolumeApiEndpointCustom)
+	if err != nil {
+		return err
+	}
+
+	customPoolVolumeMntPoint := getStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name)
+	if shared.PathExists(customPoolVolumeMntPoint) {
+		err := os.Remove(customPoolVolumeMntPoint)
+		if err != nil {
+			return err
+		}
+	}
+
+	shared.LogInfof("Deleted CEPH storage volume \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageCeph) StoragePoolVolumeMount() (bool, error) {
+	shared.LogDebugf("Mounting CEPH storage volume \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
+	customPoolVolumeMntPoint := getStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name)
+
+	customMountLockID := getCustomMountLockID(s.pool.Name, s.volume.Name)
+	lxdStorageMapLock.Lock()
+	if waitChannel, ok := lxdStorageOngoingOperationMap[customMountLockID]; ok {
+		lxdStorageMapLock.Unlock()
+		if _, ok := <-waitChannel; ok {
+			shared.LogWarnf("Received value over semaphore. This should not have happened.")
+		}
+		// Give the benefit of the doubt and assume that the other
+		// thread actually succeeded in mounting the storage volume.
+		return false, nil
+	}
+
+	lxdStorageOngoingOperationMap[customMountLockID] = make(chan bool)
+	lxdStorageMapLock.Unlock()
+
+	var customerr error
+	ourMount := false
+	if !shared.IsMountPoint(customPoolVolumeMntPoint) {
+		customerr = s.rbdMount(s.volume.Name, storagePoolVolumeApiEndpointCustom, customPoolVolumeMntPoint)
+		ourMount = true
+	}
+
+	lxdStorageMapLock.Lock()
+	if waitChannel, ok := lxdStorageOngoingOperationMap[customMountLockID]; ok {
+		close(waitChannel)
+		delete(lxdStorageOngoingOperationMap, customMountLockID)
+	}
+	lxdStorageMapLock.Unlock()
+
+	if customerr != nil {
+		return false, customerr
+	}
+
+	shared.LogDebugf("Mounted CEPH storage volume \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	return ourMount, nil
+}
+
+func (s *storageCeph) StoragePoolVolumeUmount() (bool, error) {
+	shared.LogDebugf("Unmounting CEPH storage volume \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
+	customPoolVolumeMntPoint := getStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name)
+
+	customUmountLockID := getCustomUmountLockID(s.pool.Name, s.volume.Name)
+	lxdStorageMapLock.Lock()
+	if waitChannel, ok := lxdStorageOngoingOperationMap[customUmountLockID]; ok {
+		lxdStorageMapLock.Unlock()
+		if _, ok := <-waitChannel; ok {
+			shared.LogWarnf("Received value over semaphore. This should not have happened.")
+		}
+		// Give the benefit of the doubt and assume that the other
+		// thread actually succeeded in unmounting the storage volume.
+		return false, nil
+	}
+
+	lxdStorageOngoingOperationMap[customUmountLockID] = make(chan bool)
+	lxdStorageMapLock.Unlock()
+
+	var customerr error
+	ourUmount := false
+	if shared.IsMountPoint(customPoolVolumeMntPoint) {
+		customerr = s.rbdUmount(s.volume.Name, storagePoolVolumeApiEndpointCustom, customPoolVolumeMntPoint)
+		ourUmount = true
+	}
+
+	lxdStorageMapLock.Lock()
+	if waitChannel, ok := lxdStorageOngoingOperationMap[customUmountLockID]; ok {
+		close(waitChannel)
+		delete(lxdStorageOngoingOperationMap, customUmountLockID)
+	}
+	lxdStorageMapLock.Unlock()
+
+	if customerr != nil {
+		return false, customerr
+	}
+
+	shared.LogDebugf("Unmounted CEPH storage volume \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	return ourUmount, nil
+}
+
+func (s *storageCeph) StoragePoolVolumeUpdate(changedConfig []string) error {
+	shared.LogInfof("Updating CEPH storage volume \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
+	for _, key := range changedConfig {
+		if key != "block.mount_options" && key != "size" && !strings.HasPrefix(key, "user.") {
+			return fmt.Errorf("The properties \"%v\" cannot be changed.", changedConfig)
+		}
+	}
+
+	if shared.StringInSlice("size", changedConfig) {
+		size, err := shared.ParseByteSizeString(s.volume.Config["size"])
+		if err != nil {
+			return err
+		}
+
+		if size <= 0 {
+			return fmt.Errorf("CEPH storage volumes require a size.")
+		}
+
+		ourMount, err := s.StoragePoolVolumeMount()
+		if err != nil {
+			return err
+		}
+		if ourMount {
+			defer s.StoragePoolVolumeUmount()
+		}
+
+		customPoolVolumeMntPoint := getStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name)
+		err = s.rbdGrow(s.volume.Name, storagePoolVolumeApiEndpointCustom, customPoolVolumeMntPoint, size)
+		if err != nil {
+			return err
+		}
+	}
+
+	shared.LogInfof("Updated CEPH storage volume \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageCeph) StoragePoolVolumeGetUsage() (int64, error) {
+	ourMount, err := s.StoragePoolVolumeMount()
+	if err != nil {
+		return -1, err
+	}
+	if ourMount {
+		defer s.StoragePoolVolumeUmount()
+	}
+
+	res, err := storageResource(getStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name))
+	if err != nil {
+		return -1, err
+	}
+
+	return int64(res.Space.Used), nil
+}
+
+func (s *storageCeph) StoragePoolVolumeRename(newName string) error {
+	shared.LogInfof("Renaming CEPH storage volume on storage pool \"%s\" from \"%s\" to \"%s\".", s.pool.Name, s.volume.Name, newName)
+
+	_, err := s.StoragePoolVolumeUmount()
+	if err != nil {
+		return err
+	}
+
+	// The snapshots of the storage volume follow the RBD image.
+	err = cephRBDVolumeRename(s.clusterName, s.osdPoolName, storagePoolVolumeApiEndpointCustom, s.volume.Name, newName, s.userName)
+	if err != nil {
+		return err
+	}
+
+	oldMntPoint := getStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name)
+	newMntPoint := getStoragePoolVolumeMountPoint(s.pool.Name, newName)
+	err = os.Rename(oldMntPoint, newMntPoint)
+	if err != nil {
+		return err
+	}
+
+	s.volume.Name = newName
+	_, err = s.StoragePoolVolumeMount()
+	if err != nil {
+		return err
+	}
+
+	shared.LogInfof("Renamed CEPH storage volume on storage pool \"%s\" to \"%s\".", s.pool.Name, newName)
+	return nil
+}
+
+func (s *storageCeph) StoragePoolVolumeCopy(source *api.StorageVolumeSource) error {
+	shared.LogInfof("Copying CEPH storage volume \"%s\" on storage pool \"%s\" to \"%s\" on storage pool \"%s\".", source.Name, source.Pool, s.volume.Name, s.pool.Name)
+
+	// Only volumes within the same OSD pool can be copied by CEPH so fall
+	// back to rsync.
+	if source.Pool != s.pool.Name {
+		err := storagePoolVolumeCopyRsync(s.d, s, source)
+		if err != nil {
+			return err
+		}
+
+		shared.LogInfof("Copied CEPH storage volume \"%s\" on storage pool \"%s\" to \"%s\" on storage pool \"%s\".", source.Name, source.Pool, s.volume.Name, s.pool.Name)
+		return nil
+	}
+
+	sourceName, sourceSnapshot := cephContainerSnapshotSplit(source.Name)
+	err := cephRBDVolumeCopy(s.clusterName, s.osdPoolName, sourceName, storagePoolVolumeApiEndpointCustom, sourceSnapshot, s.volume.Name, storagePoolVolumeApiEndpointCustom, s.userName)
+	if err != nil {
+		return err
+	}
+
+	tryUndo := true
+	defer func() {
+		if tryUndo {
+			s.StoragePoolVolumeDelete()
+		}
+	}()
+
+	// The copy doesn't carry the snapshots of the source volume.
+	err = cephRBDSnapshotPurge(s.clusterName, s.osdPoolName, s.volume.Name, storagePoolVolumeApiEndpointCustom, s.userName)
+	if err != nil {
+		return err
+	}
+
+	err = s.rbdGenerateNewUUID(s.volume.Name, storagePoolVolumeApiEndpointCustom)
+	if err != nil {
+		return err
+	}
+
+	customPoolVolumeMntPoint := getStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name)
+	err = os.MkdirAll(customPoolVolumeMntPoint, 0711)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.StoragePoolVolumeMount()
+	if err != nil {
+		return err
+	}
+
+	tryUndo = false
+
+	shared.LogInfof("Copied CEPH storage volume \"%s\" on storage pool \"%s\" to \"%s\" on storage pool \"%s\".", source.Name, source.Pool, s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageCeph) StoragePoolVolumeSnapshotCreate(snapshotName string) error {
+	shared.LogInfof("Creating CEPH storage volume snapshot \"%s\" of \"%s\" on storage pool \"%s\".", snapshotName, s.volume.Name, s.pool.Name)
+
+	// Flush pending writes so that the snapshot is as consistent as
+	// possible.
+	syscall.Sync()
+
+	err := cephRBDSnapshotCreate(s.clusterName, s.osdPoolName, s.volume.Name, storagePoolVolumeApiEndpointCustom, cephRBDSnapshotName(snapshotName), s.userName)
+	if err != nil {
+		return err
+	}
+
+	shared.LogInfof("Created CEPH storage volume snapshot \"%s\" of \"%s\" on storage pool \"%s\".", snapshotName, s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageCeph) StoragePoolVolumeSnapshotDelete(snapshotName string) error {
+	shared.LogInfof("Deleting CEPH storage volume snapshot \"%s\" of \"%s\" on storage pool \"%s\".", snapshotName, s.volume.Name, s.pool.Name)
+
+	err := cephRBDSnapshotDelete(s.clusterName, s.osdPoolName, s.volume.Name, storagePoolVolumeApiEndpointCustom, cephRBDSnapshotName(snapshotName), s.userName)
+	if err != nil {
+		return err
+	}
+
+	shared.LogInfof("Deleted CEPH storage volume snapshot \"%s\" of \"%s\" on storage pool \"%s\".", snapshotName, s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageCeph) StoragePoolVolumeSnapshotRename(snapshotName string, newName string) error {
+	return cephRBDSnapshotRename(s.clusterName, s.osdPoolName, s.volume.Name, storagePoolVolumeApiEndpointCustom, cephRBDSnapshotName(snapshotName), cephRBDSnapshotName(newName), s.userName)
+}
+
+func (s *storageCeph) StoragePoolVolumeSnapshotRestore(snapshotName string) error {
+	shared.LogInfof("Restoring CEPH storage volume \"%s\" on storage pool \"%s\" from snapshot \"%s\".", s.volume.Name, s.pool.Name, snapshotName)
+
+	_, err := s.StoragePoolVolumeUmount()
+	if err != nil {
+		return err
+	}
+
+	err = s.rbdUnmap(s.volume.Name, storagePoolVolumeApiEndpointCustom, "")
+	if err != nil {
+		return err
+	}
+
+	err = cephRBDSnapshotRollback(s.clusterName, s.osdPoolName, s.volume.Name, storagePoolVolumeApiEndpointCustom, cephRBDSnapshotName(snapshotName), s.userName)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.StoragePoolVolumeMount()
+	if err != nil {
+		return err
+	}
+
+	shared.LogInfof("Restored CEPH storage volume \"%s\" on storage pool \"%s\" from snapshot \"%s\".", s.volume.Name, s.pool.Name, snapshotName)
+	return nil
+}
+
+func (s *storageCeph) ContainerStorageReady(name string) bool {
+	return cephRBDVolumeExists(s.clusterName, s.osdPoolName, name, storagePoolVolumeApiEndpointContainers, s.userName)
+}
+
+func (s *storageCeph) ContainerCreate(container container) error {
+	shared.LogDebugf("Creating empty CEPH storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
+	if container.IsSnapshot() {
+		return s.ContainerSnapshotCreateEmpty(container)
+	}
+
+	tryUndo := true
+
+	containerName := container.Name()
+	size, err := s.getRBDSize()
+	if err != nil {
+		return err
+	}
+
+	err = s.rbdCreate(containerName, storagePoolVolumeApiEndpointContainers, size)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tryUndo {
+			s.ContainerDelete(container)
+		}
+	}()
+
+	containerMntPoint := getContainerMountPoint(s.pool.Name, containerName)
+	err = createContainerMountpoint(containerMntPoint, container.Path(), container.IsPrivileged())
+	if err != nil {
+		return err
+	}
+
+	tryUndo = false
+
+	shared.LogDebugf("Created empty CEPH storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageCeph) ContainerCreateFromImage(container container, fingerprint string) error {
+	shared.LogDebugf("Creating CEPH storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
+	tryUndo := true
+
+	imageStoragePoolLockID := getImageCreateLockID(s.pool.Name, fingerprint)
+	lxdStorageMapLock.Lock()
+	if waitChannel, ok := lxdStorageOngoingOperationMap[imageStoragePoolLockID]; ok {
+		lxdStorageMapLock.Unlock()
+		if _, ok := <-waitChannel; ok {
+			shared.LogWarnf("Received value over semaphore. This should not have happened.")
+		}
+	} else {
+		lxdStorageOngoingOperationMap[imageStoragePoolLockID] = make(chan bool)
+		lxdStorageMapLock.Unlock()
+
+		var imgerr error
+		if !cephRBDVolumeExists(s.clusterName, s.osdPoolName, fingerprint, storagePoolVolumeApiEndpointImages, s.userName) {
+			imgerr = s.ImageCreate(fingerprint)
+		}
+
+		lxdStorageMapLock.Lock()
+		if waitChannel, ok := lxdStorageOngoingOperationMap[imageStoragePoolLockID]; ok {
+			close(waitChannel)
+			delete(lxdStorageOngoingOperationMap, imageStoragePoolLockID)
+		}
+		lxdStorageMapLock.Unlock()
+
+		if imgerr != nil {
+			return imgerr
+		}
+	}
+
+	containerName := container.Name()
+	err := cephRBDCloneCreate(s.clusterName, s.osdPoolName, fingerprint, storagePoolVolumeApiEndpointImages, "readonly", containerName, storagePoolVolumeApiEndpointContainers, s.userName)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tryUndo {
+			s.ContainerDelete(container)
+		}
+	}()
+
+	containerMntPoint := getContainerMountPoint(s.pool.Name, containerName)
+	containerPath := container.Path()
+	err = createContainerMountpoint(containerMntPoint, containerPath, container.IsPrivileged())
+	if err != nil {
+		return err
+	}
+
+	err = s.rbdGenerateNewUUID(containerName, storagePoolVolumeApiEndpointContainers)
+	if err != nil {
+		return err
+	}
+
+	ourMount, err := s.ContainerMount(containerName, containerPath)
+	if err != nil {
+		return err
+	}
+	if ourMount {
+		defer s.ContainerUmount(containerName, containerPath)
+	}
+
+	// The clone has the size of the image, grow it if the container
+	// volume is supposed to be bigger.
+	size, err := s.getRBDSize()
+	if err != nil {
+		return err
+	}
+
+	info, err := cephRBDVolumeGetInfo(s.clusterName, s.osdPoolName, containerName, storagePoolVolumeApiEndpointContainers, s.userName)
+	if err != nil {
+		return err
+	}
+
+	newSize, err := shared.ParseByteSizeString(size)
+	if err != nil {
+		return err
+	}
+
+	if uint64(newSize) > info.Size {
+		err = s.rbdGrow(containerName, storagePoolVolumeApiEndpointContainers, containerMntPoint, newSize)
+		if err != nil {
+			return err
+		}
+	}
+
+	if container.IsPrivileged() {
+		err = os.Chmod(containerMntPoint, 0700)
+	} else {
+		err = os.Chmod(containerMntPoint, 0755)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !container.IsPrivileged() {
+		err := s.shiftRootfs(container)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = container.TemplateApply("create")
+	if err != nil {
+		shared.LogErrorf("Error in create template during ContainerCreateFromImage, continuing to unmount: %s.", err)
+		return err
+	}
+
+	tryUndo = false
+
+	shared.LogDebugf("Created CEPH storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageCeph) ContainerCanRestore(container container, sourceContainer container) error {
+	return nil
+}
+
+func (s *storageCeph) ContainerDelete(container container) error {
+	shared.LogDebugf("Deleting CEPH storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
+	if container.IsSnapshot() {
+		return s.ContainerSnapshotDelete(container)
+	}
+
+	containerName := container.Name()
+	containerMntPoint := getContainerMountPoint(s.pool.Name, containerName)
+
+	// Make sure that the container is really unmounted at this point.
+	// Otherwise we will fail.
+	err := s.rbdUmount(containerName, storagePoolVolumeApiEndpointContainers, containerMntPoint)
+	if err != nil {
+		return fmt.Errorf("Failed to unmount container path '%s': %s", containerMntPoint, err)
+	}
+
+	// Remember the image the container was cloned from so that it can be
+	// cleaned up if it was deleted in the meantime.
+	parent := ""
+	info, err := cephRBDVolumeGetInfo(s.clusterName, s.osdPoolName, containerName, storagePoolVolumeApiEndpointContainers, s.userName)
+	if err == nil {
+		parent = info.Parent.Image
+	}
+
+	err = s.rbdDelete(containerName, storagePoolVolumeApiEndpointContainers)
+	if err != nil {
+		return err
+	}
+
+	zombiePrefix := cephRBDVolumeName(cephZombieType(storagePoolVolumeApiEndpointImages), "")
+	if strings.HasPrefix(parent, zombiePrefix) {
+		err := s.zombieImageDelete(strings.TrimPrefix(parent, zombiePrefix))
+		if err != nil {
+			shared.LogWarnf("Failed to remove deleted image \"%s\": %s.", parent, err)
+		}
+	}
+
+	err = deleteContainerMountpoint(containerMntPoint, container.Path(), s.GetStorageTypeName())
+	if err != nil {
+		return err
+	}
+
+	// Remove the directory holding the snapshot mountpoints.
+	snapshotMntPoint := getSnapshotMountPoint(s.pool.Name, containerName)
+	if shared.PathExists(snapshotMntPoint) {
+		err := os.RemoveAll(snapshotMntPoint)
+		if err != nil {
+			return err
+		}
+	}
+
+	snapshotMntPointSymlink := shared.VarPath("snapshots", containerName)
+	if shared.PathExists(snapshotMntPointSymlink) {
+		err := os.Remove(snapshotMntPointSymlink)
+		if err != nil {
+			return err
+		}
+	}
+
+	shared.LogDebugf("Deleted CEPH storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageCeph) ContainerCopy(container container, sourceContainer container) error {
+	shared.LogDebugf("Copying CEPH container storage %s -> %s.", sourceContainer.Name(), container.Name())
+
+	tryUndo := true
+
+	err := sourceContainer.StorageStart()
+	if err != nil {
+		return err
+	}
+	defer sourceContainer.StorageStop()
+
+	sourceContainerName := sourceContainer.Name()
+	targetContainerName := container.Name()
+	_, sourcePool := sourceContainer.Storage().GetContainerPoolInfo()
+
+	if sourceContainer.Storage().GetStorageType() == storageTypeCeph && sourcePool == s.pool.Name {
+		sourceName, sourceSnapshot := cephContainerSnapshotSplit(sourceContainerName)
+
+		// Copy from a temporary snapshot so that the copy is
+		// consistent even if the source container is running.
+		tmpSnapshot := ""
+		if sourceSnapshot == "" {
+			syscall.Sync()
+
+			tmpSnapshot = fmt.Sprintf("copy_%s", targetContainerName)
+			sourceSnapshot = tmpSnapshot
+			err := cephRBDSnapshotCreate(s.clusterName, s.osdPoolName, sourceName, storagePoolVolumeApiEndpointContainers, tmpSnapshot, s.userName)
+			if err != nil {
+				return err
+			}
+			defer cephRBDSnapshotDelete(s.clusterName, s.osdPoolName, sourceName, storagePoolVolumeApiEndpointContainers, tmpSnapshot, s.userName)
+		}
+
+		err := cephRBDVolumeCopy(s.clusterName, s.osdPoolName, sourceName, storagePoolVolumeApiEndpointContainers, sourceSnapshot, targetContainerName, storagePoolVolumeApiEndpointContainers, s.userName)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if tryUndo {
+				s.ContainerDelete(container)
+			}
+		}()
+
+		err = s.rbdGenerateNewUUID(targetContainerName, storagePoolVolumeApiEndpointContainers)
+		if err != nil {
+			return err
+		}
+
+		targetContainerMntPoint := getContainerMountPoint(s.pool.Name, targetContainerName)
+		err = createContainerMountpoint(targetContainerMntPoint, container.Path(), container.IsPrivileged())
+		if err != nil {
+			return err
+		}
+	} else {
+		shared.LogDebugf("Copy from Non-CEPH container: %s -> %s.", sourceContainerName, targetContainerName)
+		err := s.ContainerCreate(container)
+		if err != nil {
+			shared.LogErrorf("Error creating empty container: %s.", err)
+			return err
+		}
+		defer func() {
+			if tryUndo {
+				s.ContainerDelete(container)
+			}
+		}()
+
+		targetContainerPath := container.Path()
+		ourTargetMount, err := s.ContainerMount(targetContainerName, targetContainerPath)
+		if err != nil {
+			shared.LogErrorf("Error starting/mounting container \"%s\": %s.", targetContainerName, err)
+			return err
+		}
+		if ourTargetMount {
+			defer s.ContainerUmount(targetContainerName, targetContainerPath)
+		}
+
+		sourceContainerMntPoint := getContainerMountPoint(sourcePool, sourceContainerName)
+		if sourceContainer.IsSnapshot() {
+			sourceContainerMntPoint = getSnapshotMountPoint(sourcePool, sourceContainerName)
+		}
+
+		targetContainerMntPoint := getContainerMountPoint(s.pool.Name, targetContainerName)
+		output, err := storageRsyncCopy(sourceContainerMntPoint, targetContainerMntPoint)
+		if err != nil {
+			shared.LogErrorf("ContainerCopy: rsync failed: %s.", string(output))
+			return fmt.Errorf("rsync failed: %s", string(output))
+		}
+	}
+
+	err = container.TemplateApply("copy")
+	if err != nil {
+		return err
+	}
+
+	tryUndo = false
+
+	shared.LogDebugf("Copied CEPH container storage %s -> %s.", sourceContainer.Name(), container.Name())
+	return nil
+}
+
+func (s *storageCeph) ContainerMount(name string, path string) (bool, error) {
+	shared.LogDebugf("Mounting CEPH storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
+	containerMntPoint := getContainerMountPoint(s.pool.Name, name)
+
+	containerMountLockID := getContainerMountLockID(s.pool.Name, name)
+	lxdStorageMapLock.Lock()
+	if waitChannel, ok := lxdStorageOngoingOperationMap[containerMountLockID]; ok {
+		lxdStorageMapLock.Unlock()
+		if _, ok := <-waitChannel; ok {
+			shared.LogWarnf("Received value over semaphore. This should not have happened.")
+		}
+		// Give the benefit of the doubt and assume that the other
+		// thread actually succeeded in mounting the storage volume.
+		return false, nil
+	}
+
+	lxdStorageOngoingOperationMap[containerMountLockID] = make(chan bool)
+	lxdStorageMapLock.Unlock()
+
+	var mounterr error
+	ourMount := false
+	if !shared.IsMountPoint(containerMntPoint) {
+		mounterr = s.rbdMount(name, storagePoolVolumeApiEndpointContainers, containerMntPoint)
+		ourMount = true
+	}
+
+	lxdStorageMapLock.Lock()
+	if waitChannel, ok := lxdStorageOngoingOperationMap[containerMountLockID]; ok {
+		close(waitChannel)
+		delete(lxdStorageOngoingOperationMap, containerMountLockID)
+	}
+	lxdStorageMapLock.Unlock()
+
+	if mounterr != nil {
+		return false, mounterr
+	}
+
+	shared.LogDebugf("Mounted CEPH storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	return ourMount, nil
+}
+
+func (s *storageCeph) ContainerUmount(name string, path string) (bool, error) {
+	shared.LogDebugf("Unmounting CEPH storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
+	containerMntPoint := getContainerMountPoint(s.pool.Name, name)
+
+	containerUmountLockID := getContainerUmountLockID(s.pool.Name, name)
+	lxdStorageMapLock.Lock()
+	if waitChannel, ok := lxdStorageOngoingOperationMap[containerUmountLockID]; ok {
+		lxdStorageMapLock.Unlock()
+		if _, ok := <-waitChannel; ok {
+			shared.LogWarnf("Received value over semaphore. This should not have happened.")
+		}
+		// Give the benefit of the doubt and assume that the other
+		// thread actually succeeded in unmounting the storage volume.
+		return false, nil
+	}
+
+	lxdStorageOngoingOperationMap[containerUmountLockID] = make(chan bool)
+	lxdStorageMapLock.Unlock()
+
+	var imgerr error
+	ourUmount := false
+	if shared.IsMountPoint(containerMntPoint) {
+		imgerr = s.rbdUmount(name, storagePoolVolumeApiEndpointContainers, containerMntPoint)
+		ourUmount = true
+	}
+
+	lxdStorageMapLock.Lock()
+	if waitChannel, ok := lxdStorageOngoingOperationMap[containerUmountLockID]; ok {
+		close(waitChannel)
+		delete(lxdStorageOngoingOperationMap, containerUmountLockID)
+	}
+	lxdStorageMapLock.Unlock()
+
+	if imgerr != nil {
+		return false, imgerr
+	}
+
+	shared.LogDebugf("Unmounted CEPH storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	return ourUmount, nil
+}
+
+func (s *storageCeph) ContainerRename(container container, newContainerName string) error {
+	shared.LogDebugf("Renaming CEPH storage volume for container \"%s\" from %s -> %s.", s.volume.Name, s.volume.Name, newContainerName)
+
+	if container.IsSnapshot() {
+		return s.ContainerSnapshotRename(container, newContainerName)
+	}
+
+	tryUndo := true
+
+	oldName := container.Name()
+	oldContainerMntPoint := getContainerMountPoint(s.pool.Name, oldName)
+
+	// The kernel keeps track of mapped images by name.
+	err := s.rbdUmount(oldName, storagePoolVolumeApiEndpointContainers, oldContainerMntPoint)
+	if err != nil {
+		return err
+	}
+
+	// The snapshots of the container follow the RBD image.
+	err = cephRBDVolumeRename(s.clusterName, s.osdPoolName, storagePoolVolumeApiEndpointContainers, oldName, newContainerName, s.userName)
+	if err != nil {
+		shared.LogErrorf("Failed to rename a container RBD volume: %s -> %s: %s.", oldName, newContainerName, err)
+		return err
+	}
+	defer func() {
+		if tryUndo {
+			cephRBDVolumeRename(s.clusterName, s.osdPoolName, storagePoolVolumeApiEndpointContainers, newContainerName, oldName, s.userName)
+		}
+	}()
+
+	oldContainerMntPointSymlink := container.Path()
+	newContainerMntPoint := getContainerMountPoint(s.pool.Name, newContainerName)
+	newContainerMntPointSymlink := shared.VarPath("containers", newContainerName)
+	err = renameContainerMountpoint(oldContainerMntPoint, oldContainerMntPointSymlink, newContainerMntPoint, newContainerMntPointSymlink)
+	if err != nil {
+		return err
+	}
+
+	oldSnapshotPath := getSnapshotMountPoint(s.pool.Name, oldName)
+	newSnapshotPath := getSnapshotMountPoint(s.pool.Name, newContainerName)
+	if shared.PathExists(oldSnapshotPath) {
+		err = os.Rename(oldSnapshotPath, newSnapshotPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	oldSnapshotSymlink := shared.VarPath("snapshots", oldName)
+	newSnapshotSymlink := shared.VarPath("snapshots", newContainerName)
+	if shared.PathExists(oldSnapshotSymlink) {
+		err := os.Remove(oldSnapshotSymlink)
+		if err != nil {
+			return err
+		}
+
+		err = os.Symlink(newSnapshotPath, newSnapshotSymlink)
+		if err != nil {
+			return err
+		}
+	}
+
+	tryUndo = false
+
+	shared.LogDebugf("Renamed CEPH storage volume for container \"%s\" from %s -> %s.", s.volume.Name, s.volume.Name, newContainerName)
+	return nil
+}
+
+func (s *storageCeph) ContainerRestore(container container, sourceContainer container) error {
+	shared.LogDebugf("Restoring CEPH storage volume for container \"%s\" from %s -> %s.", s.volume.Name, sourceContainer.Name(), container.Name())
+
+	_, sourcePool := sourceContainer.Storage().GetContainerPoolInfo()
+	if s.pool.Name != sourcePool {
+		return fmt.Errorf("Containers must be on the same pool to be restored.")
+	}
+
+	sourceName, sourceSnapshot := cephContainerSnapshotSplit(sourceContainer.Name())
+	if sourceName != container.Name() || sourceSnapshot == "" {
+		return fmt.Errorf("Containers can only be restored from their own snapshots.")
+	}
+
+	containerName := container.Name()
+	containerMntPoint := getContainerMountPoint(s.pool.Name, containerName)
+	err := s.rbdUmount(containerName, storagePoolVolumeApiEndpointContainers, containerMntPoint)
+	if err != nil {
+		return err
+	}
+
+	err = cephRBDSnapshotRollback(s.clusterName, s.osdPoolName, containerName, storagePoolVolumeApiEndpointContainers, sourceSnapshot, s.userName)
+	if err != nil {
+		return err
+	}
+
+	shared.LogDebugf("Restored CEPH storage volume for container \"%s\" from %s -> %s.", s.volume.Name, sourceContainer.Name(), container.Name())
+	return nil
+}
+
+func (s *storageCeph) ContainerSetQuota(container container, size int64) error {
+	shared.LogDebugf("Setting CEPH quota for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
+	containerName := container.Name()
+	containerPath := container.Path()
+	ourMount, err := s.ContainerMount(containerName, containerPath)
+	if err != nil {
+		return err
+	}
+	if ourMount {
+		defer s.ContainerUmount(containerName, containerPath)
+	}
+
+	containerMntPoint := getContainerMountPoint(s.pool.Name, containerName)
+	err = s.rbdGrow(containerName, storagePoolVolumeApiEndpointContainers, containerMntPoint, size)
+	if err != nil {
+		return err
+	}
+
+	shared.LogDebugf("Set CEPH quota for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageCeph) ContainerGetUsage(container container) (int64, error) {
+	containerName := container.Name()
+	containerPath := container.Path()
+	ourMount, err := s.ContainerMount(containerName, containerPath)
+	if err != nil {
+		return -1, err
+	}
+	if ourMount {
+		defer s.ContainerUmount(containerName, containerPath)
+	}
+
+	res, err := storageResource(getContainerMountPoint(s.pool.Name, containerName))
+	if err != nil {
+		return -1, err
+	}
+
+	return int64(res.Space.Used), nil
+}
+
+func (s *storageCeph) ContainerSnapshotCreate(snapshotContainer container, sourceContainer container) error {
+	shared.LogDebugf("Creating CEPH storage volume for snapshot \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
+	sourceName := sourceContainer.Name()
+	targetName := snapshotContainer.Name()
+	_, snapshotName := cephContainerSnapshotSplit(targetName)
+
+	// Flush pending writes so that the snapshot is as consistent as
+	// possible.
+	syscall.Sync()
+
+	err := cephRBDSnapshotCreate(s.clusterName, s.osdPoolName, sourceName, storagePoolVolumeApiEndpointContainers, snapshotName, s.userName)
+	if err != nil {
+		return err
+	}
+
+	targetMntPoint := getSnapshotMountPoint(s.pool.Name, targetName)
+	snapshotMntPointSymlinkTarget := shared.VarPath("storage-pools", s.pool.Name, "snapshots", sourceName)
+	snapshotMntPointSymlink := shared.VarPath("snapshots", sourceName)
+	err = createSnapshotMountpoint(targetMntPoint, snapshotMntPointSymlinkTarget, snapshotMntPointSymlink)
+	if err != nil {
+		cephRBDSnapshotDelete(s.clusterName, s.osdPoolName, sourceName, storagePoolVolumeApiEndpointContainers, snapshotName, s.userName)
+		return err
+	}
+
+	shared.LogDebugf("Created CEPH storage volume for snapshot \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageCeph) ContainerSnapshotDelete(snapshotContainer container) error {
+	shared.LogDebugf("Deleting CEPH storage volume for snapshot \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
+	snapshotContainerName := snapshotContainer.Name()
+	sourceName, snapshotName := cephContainerSnapshotSplit(snapshotContainerName)
+
+	err := s.ContainerSnapshotStop(snapshotContainer)
+	if err != nil {
+		return err
+	}
+
+	err = cephRBDSnapshotDelete(s.clusterName, s.osdPoolName, sourceName, storagePoolVolumeApiEndpointContainers, snapshotName, s.userName)
+	if err != nil {
+		return fmt.Errorf("Error deleting snapshot %s: %s", snapshotContainerName, err)
+	}
+
+	snapshotMntPoint := getSnapshotMountPoint(s.pool.Name, snapshotContainerName)
+	snapshotMntPointSymlinkTarget := shared.VarPath("storage-pools", s.pool.Name, "snapshots", sourceName)
+	snapshotMntPointSymlink := shared.VarPath("snapshots", sourceName)
+	err = deleteSnapshotMountpoint(snapshotMntPoint, snapshotMntPointSymlinkTarget, snapshotMntPointSymlink)
+	if err != nil {
+		return err
+	}
+
+	shared.LogDebugf("Deleted CEPH storage volume for snapshot \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageCeph) ContainerSnapshotRename(snapshotContainer container, newContainerName string) error {
+	shared.LogDebugf("Renaming CEPH storage volume for snapshot \"%s\" from %s -> %s.", s.volume.Name, s.volume.Name, newContainerName)
+
+	oldName := snapshotContainer.Name()
+	sourceName, oldSnapshotName := cephContainerSnapshotSplit(oldName)
+	_, newSnapshotName := cephContainerSnapshotSplit(newContainerName)
+
+	err := cephRBDSnapshotRename(s.clusterName, s.osdPoolName, sourceName, storagePoolVolumeApiEndpointContainers, oldSnapshotName, newSnapshotName, s.userName)
+	if err != nil {
+		return err
+	}
+
+	oldSnapshotMntPoint := getSnapshotMountPoint(s.pool.Name, oldName)
+	newSnapshotMntPoint := getSnapshotMountPoint(s.pool.Name, newContainerName)
+	err = os.Rename(oldSnapshotMntPoint, newSnapshotMntPoint)
+	if err != nil {
+		cephRBDSnapshotRename(s.clusterName, s.osdPoolName, sourceName, storagePoolVolumeApiEndpointContainers, newSnapshotName, oldSnapshotName, s.userName)
+		return err
+	}
+
+	shared.LogDebugf("Renamed CEPH storage volume for snapshot \"%s\" from %s -> %s.", s.volume.Name, s.volume.Name, newContainerName)
+	return nil
+}
+
+func (s *storageCeph) ContainerSnapshotStart(container container) error {
+	shared.LogDebugf("Initializing CEPH storage volume for snapshot \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
+	containerName := container.Name()
+	sourceName, snapshotName := cephContainerSnapshotSplit(containerName)
+	containerMntPoint := getSnapshotMountPoint(s.pool.Name, containerName)
+	if shared.IsMountPoint(containerMntPoint) {
+		return nil
+	}
+
+	// RBD snapshots are always mapped read-only.
+	devPath, err := s.rbdMap(sourceName, storagePoolVolumeApiEndpointContainers, snapshotName)
+	if err != nil {
+		return err
+	}
+
+	err = tryMount(devPath, containerMntPoint, s.getRBDFilesystem(), 0, s.getRBDSnapshotMountOptions())
+	if err != nil {
+		s.rbdUnmap(sourceName, storagePoolVolumeApiEndpointContainers, snapshotName)
+		return fmt.Errorf("Error mounting snapshot RBD path='%s': %s", containerMntPoint, err)
+	}
+
+	shared.LogDebugf("Initialized CEPH storage volume for snapshot \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageCeph) ContainerSnapshotStop(container container) error {
+	shared.LogDebugf("Stopping CEPH storage volume for snapshot \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
+	containerName := container.Name()
+	sourceName, snapshotName := cephContainerSnapshotSplit(containerName)
+	snapshotMntPoint := getSnapshotMountPoint(s.pool.Name, containerName)
+	if shared.IsMountPoint(snapshotMntPoint) {
+		err := tryUnmount(snapshotMntPoint, 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := s.rbdUnmap(sourceName, storagePoolVolumeApiEndpointContainers, snapshotName)
+	if err != nil {
+		return err
+	}
+
+	shared.LogDebugf("Stopped CEPH storage volume for snapshot \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageCeph) ContainerSnapshotCreateEmpty(snapshotContainer container) error {
+	shared.LogDebugf("Creating empty CEPH storage volume for snapshot \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
+	// Snapshots are RBD snapshots of the container's volume so the best we
+	// can do is to snapshot its current state.
+	sourceName, snapshotName := cephContainerSnapshotSplit(snapshotContainer.Name())
+	err := cephRBDSnapshotCreate(s.clusterName, s.osdPoolName, sourceName, storagePoolVolumeApiEndpointContainers, snapshotName, s.userName)
+	if err != nil {
+		return err
+	}
+
+	targetMntPoint := getSnapshotMountPoint(s.pool.Name, snapshotContainer.Name())
+	snapshotMntPointSymlinkTarget := shared.VarPath("storage-pools", s.pool.Name, "snapshots", sourceName)
+	snapshotMntPointSymlink := shared.VarPath("snapshots", sourceName)
+	err = createSnapshotMountpoint(targetMntPoint, snapshotMntPointSymlinkTarget, snapshotMntPointSymlink)
+	if err != nil {
+		return err
+	}
+
+	shared.LogDebugf("Created empty CEPH storage volume for snapshot \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageCeph) ContainerBackupDump(container container, snapshots []container, target string) error {
+	return fmt.Errorf("Optimized backups are not supported by the CEPH storage driver.")
+}
+
+func (s *storageCeph) ContainerBackupLoad(container container, snapshots []container, source string) error {
+	return fmt.Errorf("Optimized backups are not supported by the CEPH storage driver.")
+}
+
+func (s *storageCeph) ImageCreate(fingerprint string) error {
+	shared.LogDebugf("Creating CEPH storage volume for image \"%s\" on storage pool \"%s\".", fingerprint, s.pool.Name)
+
+	tryUndo := true
+
+	err := s.createImageDbPoolVolume(fingerprint)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tryUndo {
+			s.deleteImageDbPoolVolume(fingerprint)
+		}
+	}()
+
+	// The image might have been deleted while containers were still
+	// using it, in which case its RBD volume is still around.
+	if cephRBDVolumeExists(s.clusterName, s.osdPoolName, fingerprint, cephZombieType(storagePoolVolumeApiEndpointImages), s.userName) {
+		err := cephRBDVolumeUnmarkDeleted(s.clusterName, s.osdPoolName, fingerprint, storagePoolVolumeApiEndpointImages, s.userName)
+		if err != nil {
+			return err
+		}
+
+		tryUndo = false
+
+		shared.LogDebugf("Created CEPH storage volume for image \"%s\" on storage pool \"%s\".", fingerprint, s.pool.Name)
+		return nil
+	}
+
+	size, err := s.getRBDSize()
+	if err != nil {
+		return err
+	}
+
+	err = s.rbdCreate(fingerprint, storagePoolVolumeApiEndpointImages, size)
+	if err != nil {
+		return fmt.Errorf("Error creating RBD storage volume for new image: %s", err)
+	}
+	defer func() {
+		if tryUndo {
+			s.rbdDelete(fingerprint, storagePoolVolumeApiEndpointImages)
+		}
+	}()
+
+	// Create image mountpoint.
+	imageMntPoint := getImageMountPoint(s.pool.Name, fingerprint)
+	if !shared.PathExists(imageMntPoint) {
+		err := os.MkdirAll(imageMntPoint, 0700)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = s.ImageMount(fingerprint)
+	if err != nil {
+		return err
+	}
+
+	imagePath := shared.VarPath("images", fingerprint)
+	err = unpackImage(s.d, imagePath, imageMntPoint, storageTypeCeph)
+	if err != nil {
+		s.ImageUmount(fingerprint)
+		return err
+	}
+
+	_, err = s.ImageUmount(fingerprint)
+	if err != nil {
+		return err
+	}
+
+	// Containers are cloned from a protected snapshot of the image.
+	err = cephRBDSnapshotCreate(s.clusterName, s.osdPoolName, fingerprint, storagePoolVolumeApiEndpointImages, "readonly", s.userName)
+	if err != nil {
+		return err
+	}
+
+	err = cephRBDSnapshotProtect(s.clusterName, s.osdPoolName, fingerprint, storagePoolVolumeApiEndpointImages, "readonly", s.userName)
+	if err != nil {
+		return err
+	}
+
+	tryUndo = false
+
+	shared.LogDebugf("Created CEPH storage volume for image \"%s\" on storage pool \"%s\".", fingerprint, s.pool.Name)
+	return nil
+}
+
+func (s *storageCeph) ImageDelete(fingerprint string) error {
+	shared.LogDebugf("Deleting CEPH storage volume for image \"%s\" on storage pool \"%s\".", fingerprint, s.pool.Name)
+
+	_, err := s.ImageUmount(fingerprint)
+	if err != nil {
+		return err
+	}
+
+	if cephRBDVolumeExists(s.clusterName, s.osdPoolName, fingerprint, storagePoolVolumeApiEndpointImages, s.userName) {
+		clones, err := cephRBDSnapshotListClones(s.clusterName, s.osdPoolName, fingerprint, storagePoolVolumeApiEndpointImages, "readonly", s.userName)
+		if err != nil {
+			return err
+		}
+
+		if len(clones) > 0 {
+			// Containers still depend on the image, keep it
+			// around until the last of them is gone.
+			err = cephRBDVolumeMarkDeleted(s.clusterName, s.osdPoolName, fingerprint, storagePoolVolumeApiEndpointImages, s.userName)
+			if err != nil {
+				return err
+			}
+		} else {
+			err = s.rbdImageDelete(fingerprint, storagePoolVolumeApiEndpointImages)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = s.deleteImageDbPoolVolume(fingerprint)
+	if err != nil {
+		return err
+	}
+
+	imageMntPoint := getImageMountPoint(s.pool.Name, fingerprint)
+	if shared.PathExists(imageMntPoint) {
+		err := os.Remove(imageMntPoint)
+		if err != nil {
+			return err
+		}
+	}
+
+	shared.LogDebugf("Deleted CEPH storage volume for image \"%s\" on storage pool \"%s\".", fingerprint, s.pool.Name)
+	return nil
+}
+
+// zombieImageDelete removes the RBD volume of a deleted image once no
+// container is using it anymore.
+func (s *storageCeph) zombieImageDelete(fingerprint string) error {
+	zombieType := cephZombieType(storagePoolVolumeApiEndpointImages)
+	clones, err := cephRBDSnapshotListClones(s.clusterName, s.osdPoolName, fingerprint, zombieType, "readonly", s.userName)
+	if err != nil {
+		return err
+	}
+
+	if len(clones) > 0 {
+		return nil
+	}
+
+	return s.rbdImageDelete(fingerprint, zombieType)
+}
+
+// rbdImageDelete removes the RBD volume of an image together with the
+// protected snapshot containers are cloned from.
+func (s *storageCeph) rbdImageDelete(fingerprint string, volumeType string) error {
+	err := cephRBDSnapshotUnprotect(s.clusterName, s.osdPoolName, fingerprint, volumeType, "readonly", s.userName)
+	if err != nil {
+		return err
+	}
+
+	return s.rbdDelete(fingerprint, volumeType)
+}
+
+func (s *storageCeph) ImageMount(fingerprint string) (bool, error) {
+	shared.LogDebugf("Mounting CEPH storage volume for image \"%s\" on storage pool \"%s\".", fingerprint, s.pool.Name)
+
+	imageMntPoint := getImageMountPoint(s.pool.Name, fingerprint)
+	if shared.IsMountPoint(imageMntPoint) {
+		return false, nil
+	}
+
+	err := s.rbdMount(fingerprint, storagePoolVolumeApiEndpointImages, imageMntPoint)
+	if err != nil {
+		shared.LogErrorf(fmt.Sprintf("Error mounting image RBD volume for unpacking: %s", err))
+		return false, fmt.Errorf("Error mounting image RBD volume: %v", err)
+	}
+
+	shared.LogDebugf("Mounted CEPH storage volume for image \"%s\" on storage pool \"%s\".", fingerprint, s.pool.Name)
+	return true, nil
+}
+
+func (s *storageCeph) ImageUmount(fingerprint string) (bool, error) {
+	shared.LogDebugf("Unmounting CEPH storage volume for image \"%s\" on storage pool \"%s\".", fingerprint, s.pool.Name)
+
+	imageMntPoint := getImageMountPoint(s.pool.Name, fingerprint)
+	if !shared.IsMountPoint(imageMntPoint) {
+		return false, nil
+	}
+
+	err := s.rbdUmount(fingerprint, storagePoolVolumeApiEndpointImages, imageMntPoint)
+	if err != nil {
+		return false, err
+	}
+
+	shared.LogDebugf("Unmounted CEPH storage volume for image \"%s\" on storage pool \"%s\".", fingerprint, s.pool.Name)
+	return true, nil
+}
+
+func (s *storageCeph) ImageVolumesGC(dryRun bool) ([]string, error) {
+	referenced, err := s.imageVolumesReferenced()
+	if err != nil {
+		return nil, err
+	}
+
+	volumes, err := cephRBDVolumesList(s.clusterName, s.osdPoolName, s.userName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Images deleted while containers were still cloned from them are
+	// kept as zombies and can go once the last of those is gone.
+	orphans := []string{}
+	for _, volumeType := range []string{storagePoolVolumeApiEndpointImages, cephZombieType(storagePoolVolumeApiEndpointImages)} {
+		prefix := cephRBDVolumeName(volumeType, "")
+		for _, volume := range volumes {
+			if !strings.HasPrefix(volume, prefix) {
+				continue
+			}
+
+			fingerprint := strings.TrimPrefix(volume, prefix)
+			if volumeType == storagePoolVolumeApiEndpointImages && shared.StringInSlice(fingerprint, referenced) {
+				continue
+			}
+
+			// Failed creates may not have gotten to the snapshot.
+			clones, err := cephRBDSnapshotListClones(s.clusterName, s.osdPoolName, fingerprint, volumeType, "readonly", s.userName)
+			hasSnapshot := err == nil
+			if hasSnapshot && len(clones) > 0 {
+				continue
+			}
+
+			orphans = append(orphans, volume)
+			if dryRun {
+				continue
+			}
+
+			if volumeType == storagePoolVolumeApiEndpointImages {
+				_, err := s.ImageUmount(fingerprint)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			if hasSnapshot {
+				cephRBDSnapshotUnprotect(s.clusterName, s.osdPoolName, fingerprint, volumeType, "readonly", s.userName)
+			}
+
+			err = s.rbdDelete(fingerprint, volumeType)
+			if err != nil {
+				return nil, err
+			}
+
+			if volumeType == storagePoolVolumeApiEndpointImages {
+				imageMntPoint := getImageMountPoint(s.pool.Name, fingerprint)
+				if shared.PathExists(imageMntPoint) {
+					err := os.Remove(imageMntPoint)
+					if err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+
+	return orphans, nil
+}
+
+func (s *storageCeph) MigrationType() MigrationFSType {
+	return MigrationFSType_RSYNC
+}
+
+func (s *storageCeph) PreservesInodes() bool {
+	return false
+}
+
+func (s *storageCeph) MigrationSource(container container) (MigrationStorageSourceDriver, error) {
+	return rsyncMigrationSource(container)
+}
+
+func (s *storageCeph) MigrationSink(live bool, container container, snapshots []*Snapshot, conn *websocket.Conn, srcIdmap *shared.IdmapSet, op *operation, refresh bool) error {
+	return rsyncMigrationSink(live, container, snapshots, conn, srcIdmap, op, refresh)
+}
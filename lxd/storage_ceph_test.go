@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// cephMockCommands replaces the ceph command layer, recording the commands
+// run and answering them through the given function.
+func cephMockCommands(answer func(cmd string) (string, error)) (*[]string, func()) {
+	orig := cephRunCommand
+	cmds := []string{}
+
+	cephRunCommand = func(name string, arg ...string) (string, error) {
+		cmd := strings.Join(append([]string{name}, arg...), " ")
+		cmds = append(cmds, cmd)
+		return answer(cmd)
+	}
+
+	return &cmds, func() { cephRunCommand = orig }
+}
+
+func Test_ceph_clone_create(t *testing.T) {
+	cmds, restore := cephMockCommands(func(cmd string) (string, error) {
+		return "", nil
+	})
+	defer restore()
+
+	err := cephRBDCloneCreate("ceph", "lxd", "abcd", "images", "readonly", "c1", "containers", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "rbd --id admin --cluster ceph --pool lxd --image-feature layering clone lxd/images_abcd@readonly lxd/containers_c1"
+	if len(*cmds) != 1 || (*cmds)[0] != expected {
+		t.Fatalf("Unexpected commands: %v", *cmds)
+	}
+}
+
+func Test_ceph_map_reuses_existing_mapping(t *testing.T) {
+	cmds, restore := cephMockCommands(func(cmd string) (string, error) {
+		if strings.HasSuffix(cmd, "showmapped") {
+			return `id pool namespace image         snap device
+0  lxd            containers_c1 -    /dev/rbd0
+1  lxd            containers_c2 -    /dev/rbd1
+`, nil
+		}
+
+		return "", fmt.Errorf("unexpected command")
+	})
+	defer restore()
+
+	devPath, err := cephRBDVolumeMap("ceph", "lxd", "c2", "containers", "", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if devPath != "/dev/rbd1" {
+		t.Fatalf("Unexpected device: %s", devPath)
+	}
+
+	if len(*cmds) != 1 {
+		t.Fatalf("Unexpected commands: %v", *cmds)
+	}
+}
+
+func Test_ceph_map_new_device(t *testing.T) {
+	cmds, restore := cephMockCommands(func(cmd string) (string, error) {
+		if strings.HasSuffix(cmd, "showmapped") {
+			return "", nil
+		}
+
+		return "/dev/rbd3\n", nil
+	})
+	defer restore()
+
+	devPath, err := cephRBDVolumeMap("ceph", "lxd", "c1", "containers", "snapshot_snap0", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if devPath != "/dev/rbd3" {
+		t.Fatalf("Unexpected device: %s", devPath)
+	}
+
+	expected := "rbd --id admin --cluster ceph --pool lxd map containers_c1@snapshot_snap0"
+	if len(*cmds) != 2 || (*cmds)[1] != expected {
+		t.Fatalf("Unexpected commands: %v", *cmds)
+	}
+}
+
+func Test_ceph_volume_info(t *testing.T) {
+	_, restore := cephMockCommands(func(cmd string) (string, error) {
+		return `{"name":"containers_c1","size":26214400,"parent":{"pool":"lxd","image":"zombie_images_abcd","snapshot":"readonly"}}`, nil
+	})
+	defer restore()
+
+	info, err := cephRBDVolumeGetInfo("ceph", "lxd", "c1", "containers", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Size != 26214400 {
+		t.Fatalf("Unexpected size: %d", info.Size)
+	}
+
+	if info.Parent.Image != "zombie_images_abcd" || info.Parent.Snapshot != "readonly" {
+		t.Fatalf("Unexpected parent: %v", info.Parent)
+	}
+}
+
+func Test_ceph_snapshot_split(t *testing.T) {
+	name, snapshot := cephContainerSnapshotSplit("c1/snap0")
+	if name != "c1" || snapshot != "snapshot_snap0" {
+		t.Fatalf("Unexpected split: %s %s", name, snapshot)
+	}
+
+	name, snapshot = cephContainerSnapshotSplit("c1")
+	if name != "c1" || snapshot != "" {
+		t.Fatalf("Unexpected split: %s %s", name, snapshot)
+	}
+}
+
+func Test_ceph_snapshots_list(t *testing.T) {
+	cmds, restore := cephMockCommands(func(cmd string) (string, error) {
+		return `[{"id":4,"name":"snapshot_snap0","size":10737418240},{"id":7,"name":"readonly","size":10737418240}]`, nil
+	})
+	defer restore()
+
+	snapshots, err := cephRBDSnapshotsList("ceph", "lxd", "c1", "containers", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "rbd --id admin --cluster ceph --pool lxd snap ls --format json containers_c1"
+	if len(*cmds) != 1 || (*cmds)[0] != expected {
+		t.Fatalf("Unexpected commands: %v", *cmds)
+	}
+
+	if len(snapshots) != 2 || snapshots[0] != "snapshot_snap0" || snapshots[1] != "readonly" {
+		t.Fatalf("Unexpected snapshots: %v", snapshots)
+	}
+}
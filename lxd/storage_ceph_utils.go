@@ -0,0 +1,544 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/lxc/lxd/shared"
+)
+
+// cephRunCommand runs one of the ceph tools (ceph or rbd) and returns its
+// output. It is a variable so that the tests can replace the command layer
+// with a mocked one.
+var cephRunCommand = func(name string, arg ...string) (string, error) {
+	output, err := exec.Command(name, arg...).CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("Failed to run: %s %s: %s", name, strings.Join(arg, " "), strings.TrimSpace(string(output)))
+	}
+
+	return string(output), nil
+}
+
+// cephRBDVolumeName returns the name of the RBD image backing a storage
+// volume of the given type. Snapshots of containers and custom volumes are
+// RBD snapshots of their parent and don't have an image of their own.
+func cephRBDVolumeName(volumeType string, volumeName string) string {
+	return fmt.Sprintf("%s_%s", volumeType, volumeName)
+}
+
+// cephRBDSnapshotName returns the name of the RBD snapshot backing a
+// snapshot of a container or custom volume.
+func cephRBDSnapshotName(snapshotName string) string {
+	return fmt.Sprintf("snapshot_%s", snapshotName)
+}
+
+func cephRBDArgs(clusterName string, userName string, poolName string, arg ...string) []string {
+	return append([]string{"--id", userName, "--cluster", clusterName, "--pool", poolName}, arg...)
+}
+
+// cephOSDPoolExists checks whether a given OSD pool exists.
+func cephOSDPoolExists(clusterName string, poolName string, userName string) bool {
+	_, err := cephRunCommand(
+		"ceph",
+		"--name", fmt.Sprintf("client.%s", userName),
+		"--cluster", clusterName,
+		"osd",
+		"pool",
+		"get",
+		poolName,
+		"size")
+	if err != nil {
+		return false
+	}
+
+	return true
+}
+
+// cephOSDPoolCreate creates an OSD pool and enables the rbd application on it.
+func cephOSDPoolCreate(clusterName string, poolName string, pgNum string, userName string) error {
+	_, err := cephRunCommand(
+		"ceph",
+		"--name", fmt.Sprintf("client.%s", userName),
+		"--cluster", clusterName,
+		"osd",
+		"pool",
+		"create",
+		poolName,
+		pgNum)
+	if err != nil {
+		return err
+	}
+
+	// Pools need to be tagged with the application using them starting
+	// with luminous, older versions don't know about this.
+	_, err = cephRunCommand(
+		"ceph",
+		"--name", fmt.Sprintf("client.%s", userName),
+		"--cluster", clusterName,
+		"osd",
+		"pool",
+		"application",
+		"enable",
+		poolName,
+		"rbd")
+	if err != nil {
+		shared.LogDebugf("Failed to enable the rbd application on OSD pool \"%s\": %s.", poolName, err)
+	}
+
+	return nil
+}
+
+// cephOSDPoolDestroy destroys an OSD pool.
+func cephOSDPoolDestroy(clusterName string, poolName string, userName string) error {
+	_, err := cephRunCommand(
+		"ceph",
+		"--name", fmt.Sprintf("client.%s", userName),
+		"--cluster", clusterName,
+		"osd",
+		"pool",
+		"delete",
+		poolName,
+		poolName,
+		"--yes-i-really-really-mean-it")
+	return err
+}
+
+// cephOSDPoolUsage returns the number of bytes used in an OSD pool and the
+// number of bytes still available to it.
+func cephOSDPoolUsage(clusterName string, poolName string, userName string) (uint64, uint64, error) {
+	output, err := cephRunCommand(
+		"ceph",
+		"--name", fmt.Sprintf("client.%s", userName),
+		"--cluster", clusterName,
+		"df",
+		"--format", "json")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	df := struct {
+		Pools []struct {
+			Name  string `json:"name"`
+			Stats struct {
+				BytesUsed uint64 `json:"bytes_used"`
+				MaxAvail  uint64 `json:"max_avail"`
+			} `json:"stats"`
+		} `json:"pools"`
+	}{}
+
+	err = json.Unmarshal([]byte(output), &df)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, pool := range df.Pools {
+		if pool.Name == poolName {
+			return pool.Stats.BytesUsed, pool.Stats.MaxAvail, nil
+		}
+	}
+
+	return 0, 0, fmt.Errorf("OSD pool \"%s\" not found", poolName)
+}
+
+// cephRBDVolumeCreate creates an RBD image of the given size.
+func cephRBDVolumeCreate(clusterName string, poolName string, volumeName string, volumeType string, size string, userName string) error {
+	_, err := cephRunCommand("rbd", cephRBDArgs(clusterName, userName, poolName,
+		"--image-feature", "layering",
+		"--size", fmt.Sprintf("%sB", size),
+		"create",
+		cephRBDVolumeName(volumeType, volumeName))...)
+	return err
+}
+
+// cephRBDVolumeExists checks whether an RBD image exists.
+func cephRBDVolumeExists(clusterName string, poolName string, volumeName string, volumeType string, userName string) bool {
+	_, err := cephRunCommand("rbd", cephRBDArgs(clusterName, userName, poolName,
+		"info",
+		cephRBDVolumeName(volumeType, volumeName))...)
+	if err != nil {
+		return false
+	}
+
+	return true
+}
+
+// cephRBDVolumeDelete deletes an RBD image.
+func cephRBDVolumeDelete(clusterName string, poolName string, volumeName string, volumeType string, userName string) error {
+	_, err := cephRunCommand("rbd", cephRBDArgs(clusterName, userName, poolName,
+		"rm",
+		cephRBDVolumeName(volumeType, volumeName))...)
+	return err
+}
+
+// cephRBDVolumesList returns the names of all RBD images in an OSD pool.
+func cephRBDVolumesList(clusterName string, poolName string, userName string) ([]string, error) {
+	output, err := cephRunCommand("rbd", cephRBDArgs(clusterName, userName, poolName,
+		"ls")...)
+	if err != nil {
+		return nil, err
+	}
+
+	volumes := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		volumes = append(volumes, line)
+	}
+
+	return volumes, nil
+}
+
+// cephRBDVolumeRename renames an RBD image. The snapshots of the image follow
+// it.
+func cephRBDVolumeRename(clusterName string, poolName string, volumeType string, oldName string, newName string, userName string) error {
+	_, err := cephRunCommand("rbd", cephRBDArgs(clusterName, userName, poolName,
+		"mv",
+		cephRBDVolumeName(volumeType, oldName),
+		cephRBDVolumeName(volumeType, newName))...)
+	return err
+}
+
+// cephZombieType returns the volume type used for the RBD images of deleted
+// volumes which are kept around because other volumes were cloned from them.
+func cephZombieType(volumeType string) string {
+	return fmt.Sprintf("zombie_%s", volumeType)
+}
+
+// cephRBDVolumeMarkDeleted marks an RBD image as deleted while keeping it
+// around for its clones.
+func cephRBDVolumeMarkDeleted(clusterName string, poolName string, volumeName string, volumeType string, userName string) error {
+	_, err := cephRunCommand("rbd", cephRBDArgs(clusterName, userName, poolName,
+		"mv",
+		cephRBDVolumeName(volumeType, volumeName),
+		cephRBDVolumeName(cephZombieType(volumeType), volumeName))...)
+	return err
+}
+
+// cephRBDVolumeUnmarkDeleted makes an RBD image marked as deleted usable
+// again.
+func cephRBDVolumeUnmarkDeleted(clusterName string, poolName string, volumeName string, volumeType string, userName string) error {
+	_, err := cephRunCommand("rbd", cephRBDArgs(clusterName, userName, poolName,
+		"mv",
+		cephRBDVolumeName(cephZombieType(volumeType), volumeName),
+		cephRBDVolumeName(volumeType, volumeName))...)
+	return err
+}
+
+// cephRBDVolumeMappedDevice returns the block device an RBD image or snapshot
+// is currently mapped to or an empty string if it isn't mapped.
+func cephRBDVolumeMappedDevice(clusterName string, poolName string, volumeName string, volumeType string, snapshotName string, userName string) (string, error) {
+	output, err := cephRunCommand(
+		"rbd",
+		"--id", userName,
+		"--cluster", clusterName,
+		"showmapped")
+	if err != nil {
+		return "", err
+	}
+
+	if snapshotName == "" {
+		snapshotName = "-"
+	}
+
+	// Newer versions of rbd add a (usually empty) namespace column after
+	// the pool so only rely on the position of the last fields.
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || fields[0] == "id" {
+			continue
+		}
+
+		n := len(fields)
+		if fields[1] != poolName || fields[n-3] != cephRBDVolumeName(volumeType, volumeName) || fields[n-2] != snapshotName {
+			continue
+		}
+
+		return fields[n-1], nil
+	}
+
+	return "", nil
+}
+
+// cephRBDVolumeMap maps an RBD image or snapshot and returns the path of the
+// block device it was mapped to. Snapshots are always mapped read-only.
+func cephRBDVolumeMap(clusterName string, poolName string, volumeName string, volumeType string, snapshotName string, userName string) (string, error) {
+	devPath, err := cephRBDVolumeMappedDevice(clusterName, poolName, volumeName, volumeType, snapshotName, userName)
+	if err != nil {
+		return "", err
+	}
+
+	if devPath != "" {
+		return devPath, nil
+	}
+
+	spec := cephRBDVolumeName(volumeType, volumeName)
+	if snapshotName != "" {
+		spec = fmt.Sprintf("%s@%s", spec, snapshotName)
+	}
+
+	output, err := cephRunCommand("rbd", cephRBDArgs(clusterName, userName, poolName,
+		"map",
+		spec)...)
+	if err != nil {
+		return "", err
+	}
+
+	devPath = strings.TrimSpace(output)
+	idx := strings.LastIndex(devPath, "/dev/rbd")
+	if idx < 0 {
+		return "", fmt.Errorf("Failed to detect the mapped device of \"%s\": %s", spec, output)
+	}
+
+	return devPath[idx:], nil
+}
+
+// cephRBDVolumeUnmap unmaps an RBD image or snapshot. Trying to unmap an image
+// which isn't mapped isn't considered an error.
+func cephRBDVolumeUnmap(clusterName string, poolName string, volumeName string, volumeType string, snapshotName string, userName string) error {
+	spec := cephRBDVolumeName(volumeType, volumeName)
+	if snapshotName != "" {
+		spec = fmt.Sprintf("%s@%s", spec, snapshotName)
+	}
+
+	output, err := cephRunCommand("rbd", cephRBDArgs(clusterName, userName, poolName,
+		"unmap",
+		spec)...)
+	if err != nil && !strings.Contains(output, "not mapped") {
+		return err
+	}
+
+	return nil
+}
+
+// cephRBDVolumeInfo describes an RBD image.
+type cephRBDVolumeInfo struct {
+	Size   uint64 `json:"size"`
+	Parent struct {
+		Pool     string `json:"pool"`
+		Image    string `json:"image"`
+		Snapshot string `json:"snapshot"`
+	} `json:"parent"`
+}
+
+// cephRBDVolumeGetInfo returns the size and the parent of an RBD image.
+func cephRBDVolumeGetInfo(clusterName string, poolName string, volumeName string, volumeType string, userName string) (*cephRBDVolumeInfo, error) {
+	output, err := cephRunCommand("rbd", cephRBDArgs(clusterName, userName, poolName,
+		"info",
+		"--format", "json",
+		cephRBDVolumeName(volumeType, volumeName))...)
+	if err != nil {
+		return nil, err
+	}
+
+	info := cephRBDVolumeInfo{}
+	err = json.Unmarshal([]byte(output), &info)
+	if err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+// cephRBDVolumeResize changes the size of an RBD image.
+func cephRBDVolumeResize(clusterName string, poolName string, volumeName string, volumeType string, size int64, userName string) error {
+	_, err := cephRunCommand("rbd", cephRBDArgs(clusterName, userName, poolName,
+		"resize",
+		"--allow-shrink",
+		"--size", fmt.Sprintf("%dB", size),
+		cephRBDVolumeName(volumeType, volumeName))...)
+	return err
+}
+
+// cephRBDVolumeCopy makes a full, independent copy of an RBD image or
+// snapshot.
+func cephRBDVolumeCopy(clusterName string, poolName string, sourceName string, sourceType string, sourceSnapshot string, targetName string, targetType string, userName string) error {
+	source := cephRBDVolumeName(sourceType, sourceName)
+	if sourceSnapshot != "" {
+		source = fmt.Sprintf("%s@%s", source, sourceSnapshot)
+	}
+
+	_, err := cephRunCommand("rbd", cephRBDArgs(clusterName, userName, poolName,
+		"cp",
+		source,
+		cephRBDVolumeName(targetType, targetName))...)
+	return err
+}
+
+// cephRBDCloneCreate creates a copy-on-write clone of a protected RBD
+// snapshot.
+func cephRBDCloneCreate(clusterName string, poolName string, sourceName string, sourceType string, sourceSnapshot string, targetName string, targetType string, userName string) error {
+	_, err := cephRunCommand("rbd", cephRBDArgs(clusterName, userName, poolName,
+		"--image-feature", "layering",
+		"clone",
+		fmt.Sprintf("%s/%s@%s", poolName, cephRBDVolumeName(sourceType, sourceName), sourceSnapshot),
+		fmt.Sprintf("%s/%s", poolName, cephRBDVolumeName(targetType, targetName)))...)
+	return err
+}
+
+// cephRBDSnapshotCreate creates a snapshot of an RBD image.
+func cephRBDSnapshotCreate(clusterName string, poolName string, volumeName string, volumeType string, snapshotName string, userName string) error {
+	_, err := cephRunCommand("rbd", cephRBDArgs(clusterName, userName, poolName,
+		"snap",
+		"create",
+		fmt.Sprintf("%s@%s", cephRBDVolumeName(volumeType, volumeName), snapshotName))...)
+	return err
+}
+
+// cephRBDSnapshotDelete deletes a snapshot of an RBD image.
+func cephRBDSnapshotDelete(clusterName string, poolName string, volumeName string, volumeType string, snapshotName string, userName string) error {
+	_, err := cephRunCommand("rbd", cephRBDArgs(clusterName, userName, poolName,
+		"snap",
+		"rm",
+		fmt.Sprintf("%s@%s", cephRBDVolumeName(volumeType, volumeName), snapshotName))...)
+	return err
+}
+
+// cephRBDSnapshotPurge deletes all unprotected snapshots of an RBD image.
+func cephRBDSnapshotPurge(clusterName string, poolName string, volumeName string, volumeType string, userName string) error {
+	_, err := cephRunCommand("rbd", cephRBDArgs(clusterName, userName, poolName,
+		"snap",
+		"purge",
+		cephRBDVolumeName(volumeType, volumeName))...)
+	return err
+}
+
+// cephRBDSnapshotRename renames a snapshot of an RBD image.
+func cephRBDSnapshotRename(clusterName string, poolName string, volumeName string, volumeType string, oldSnapshotName string, newSnapshotName string, userName string) error {
+	_, err := cephRunCommand("rbd", cephRBDArgs(clusterName, userName, poolName,
+		"snap",
+		"rename",
+		fmt.Sprintf("%s@%s", cephRBDVolumeName(volumeType, volumeName), oldSnapshotName),
+		fmt.Sprintf("%s@%s", cephRBDVolumeName(volumeType, volumeName), newSnapshotName))...)
+	return err
+}
+
+// cephRBDSnapshotRollback restores an RBD image to the state of one of its
+// snapshots.
+func cephRBDSnapshotRollback(clusterName string, poolName string, volumeName string, volumeType string, snapshotName string, userName string) error {
+	_, err := cephRunCommand("rbd", cephRBDArgs(clusterName, userName, poolName,
+		"snap",
+		"rollback",
+		fmt.Sprintf("%s@%s", cephRBDVolumeName(volumeType, volumeName), snapshotName))...)
+	return err
+}
+
+// cephRBDSnapshotProtect protects a snapshot of an RBD image so that it can be
+// cloned.
+func cephRBDSnapshotProtect(clusterName string, poolName string, volumeName string, volumeType string, snapshotName string, userName string) error {
+	_, err := cephRunCommand("rbd", cephRBDArgs(clusterName, userName, poolName,
+		"snap",
+		"protect",
+		fmt.Sprintf("%s@%s", cephRBDVolumeName(volumeType, volumeName), snapshotName))...)
+	return err
+}
+
+// cephRBDSnapshotUnprotect allows a snapshot of an RBD image to be deleted
+// again.
+func cephRBDSnapshotUnprotect(clusterName string, poolName string, volumeName string, volumeType string, snapshotName string, userName string) error {
+	_, err := cephRunCommand("rbd", cephRBDArgs(clusterName, userName, poolName,
+		"snap",
+		"unprotect",
+		fmt.Sprintf("%s@%s", cephRBDVolumeName(volumeType, volumeName), snapshotName))...)
+	return err
+}
+
+// cephRBDSnapshotListClones returns the RBD images cloned from a snapshot.
+func cephRBDSnapshotListClones(clusterName string, poolName string, volumeName string, volumeType string, snapshotName string, userName string) ([]string, error) {
+	output, err := cephRunCommand("rbd", cephRBDArgs(clusterName, userName, poolName,
+		"children",
+		"--image", cephRBDVolumeName(volumeType, volumeName),
+		"--snap", snapshotName)...)
+	if err != nil {
+		return nil, err
+	}
+
+	clones := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		clones = append(clones, line)
+	}
+
+	return clones, nil
+}
+
+// cephRBDSnapshotsList returns the names of the snapshots of an RBD image.
+func cephRBDSnapshotsList(clusterName string, poolName string, volumeName string, volumeType string, userName string) ([]string, error) {
+	output, err := cephRunCommand("rbd", cephRBDArgs(clusterName, userName, poolName,
+		"snap",
+		"ls",
+		"--format", "json",
+		cephRBDVolumeName(volumeType, volumeName))...)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []struct {
+		Name string `json:"name"`
+	}{}
+	err = json.Unmarshal([]byte(output), &entries)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := []string{}
+	for _, entry := range entries {
+		snapshots = append(snapshots, entry.Name)
+	}
+
+	return snapshots, nil
+}
+
+// cephContainerSnapshotSplit returns the name of the RBD image and of the RBD
+// snapshot holding the given container or custom volume snapshot.
+func cephContainerSnapshotSplit(name string) (string, string) {
+	fields := strings.SplitN(name, shared.SnapshotDelimiter, 2)
+	if len(fields) != 2 {
+		return name, ""
+	}
+
+	return fields[0], cephRBDSnapshotName(fields[1])
+}
+
+// cephGrowFilesystem grows the filesystem on a block device to the size of the
+// device. The filesystem needs to be mounted for xfs.
+func cephGrowFilesystem(fsType string, devPath string, mntPoint string) error {
+	var err error
+	switch fsType {
+	case "xfs":
+		_, err = cephRunCommand("xfs_growfs", mntPoint)
+	default:
+		_, err = cephRunCommand("resize2fs", devPath)
+	}
+
+	return err
+}
+
+// cephMakeFilesystem creates a filesystem on a mapped RBD image.
+func cephMakeFilesystem(fsType string, devPath string) error {
+	var output []byte
+	var err error
+	switch fsType {
+	case "xfs":
+		output, err = tryExec("mkfs.xfs", devPath)
+	default:
+		output, err = tryExec(
+			"mkfs.ext4",
+			"-E", "nodiscard,lazy_itable_init=0,lazy_journal_init=0",
+			devPath)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Failed to create the filesystem on \"%s\": %s", devPath, output)
+	}
+
+	return nil
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// Kinds of inconsistencies reported by the storage check.
+const (
+	storageCheckMissingVolume   string = "missing volume"
+	storageCheckOrphanedVolume  string = "orphaned volume"
+	storageCheckWrongMountPoint string = "wrong mountpoint"
+	storageCheckStaleSymlink    string = "stale symlink"
+)
+
+// storageCheckIssue is a single inconsistency found between the database and
+// what exists on disk.
+type storageCheckIssue api.StorageCheckIssue
+
+func (i storageCheckIssue) String() string {
+	msg := fmt.Sprintf("%s: %s", i.Kind, i.Volume)
+	if i.Pool != "" {
+		msg = fmt.Sprintf("%s: %s", i.Pool, msg)
+	}
+
+	if i.Details != "" {
+		msg = fmt.Sprintf("%s (%s)", msg, i.Details)
+	}
+
+	if i.Repaired {
+		msg = fmt.Sprintf("%s [repaired]", msg)
+	}
+
+	return msg
+}
+
+// /1.0/storage-check
+// Compare the database with what exists on the storage pools and optionally
+// repair what can be fixed safely.
+func storageCheckPost(d *Daemon, r *http.Request) Response {
+	req := api.StorageCheckPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	run := func(op *operation) error {
+		issues, err := storageCheck(d, req.Repair)
+		if err != nil {
+			return err
+		}
+
+		// Same layout as api.StorageCheck.
+		metadata := map[string]interface{}{
+			"issues": issues,
+		}
+
+		return op.UpdateMetadata(metadata)
+	}
+
+	op, err := operationCreate(operationClassTask, map[string][]string{}, nil, run, nil, nil)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return OperationResponse(op)
+}
+
+var storageCheckCmd = Command{name: "storage-check", post: storageCheckPost}
+
+// storageCheck walks all storage pools and the container symlinks and
+// reports where they disagree with the database. With repair set, the cases
+// which can be fixed without risking any data are fixed.
+func storageCheck(d *Daemon, repair bool) ([]storageCheckIssue, error) {
+	issues := []storageCheckIssue{}
+
+	pools, err := dbStoragePools(d.db)
+	if err != nil && err != NoSuchObjectError {
+		return nil, err
+	}
+
+	for _, poolName := range pools {
+		s, err := storagePoolInit(d, poolName)
+		if err != nil {
+			return nil, err
+		}
+
+		poolIssues, err := s.StoragePoolVolumesCheck(repair)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to check storage pool \"%s\": %s", poolName, err)
+		}
+
+		issues = append(issues, poolIssues...)
+	}
+
+	symlinkIssues, err := storageCheckSymlinks(d, repair)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(issues, symlinkIssues...), nil
+}
+
+// storageCheckSymlinks verifies that the ${LXD_DIR}/containers and
+// ${LXD_DIR}/snapshots symlinks point to the mountpoints of the containers on
+// their storage pool and that no symlink is left for containers which don't
+// exist anymore.
+func storageCheckSymlinks(d *Daemon, repair bool) ([]storageCheckIssue, error) {
+	names, err := dbContainersList(d.db, cTypeRegular)
+	if err != nil {
+		return nil, err
+	}
+
+	pools := map[string]string{}
+	for _, name := range names {
+		poolName, err := dbContainerPool(d.db, name)
+		if err != nil {
+			return nil, err
+		}
+
+		pools[name] = poolName
+	}
+
+	issues := []storageCheckIssue{}
+	for _, name := range names {
+		poolName := pools[name]
+
+		symlink := containerPath(name, false)
+		issue, err := storageCheckSymlink(symlink, getContainerMountPoint(poolName, name), false, repair)
+		if err != nil {
+			return nil, err
+		}
+
+		if issue != nil {
+			issue.Pool = poolName
+			issue.Volume = fmt.Sprintf("%s/%s", storagePoolVolumeApiEndpointContainers, name)
+			issues = append(issues, *issue)
+		}
+
+		// The snapshots symlink only exists once the container got
+		// snapshotted.
+		symlink = containerPath(name, true)
+		issue, err = storageCheckSymlink(symlink, getSnapshotMountPoint(poolName, name), true, repair)
+		if err != nil {
+			return nil, err
+		}
+
+		if issue != nil {
+			issue.Pool = poolName
+			issue.Volume = fmt.Sprintf("%s/%s", storagePoolVolumeApiEndpointContainers, name)
+			issues = append(issues, *issue)
+		}
+	}
+
+	for _, dir := range []string{shared.VarPath("containers"), shared.VarPath("snapshots")} {
+		dents, err := ioutil.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		for _, dent := range dents {
+			_, ok := pools[dent.Name()]
+			if ok || dent.Mode()&os.ModeSymlink == 0 {
+				continue
+			}
+
+			symlink := filepath.Join(dir, dent.Name())
+			target, err := os.Readlink(symlink)
+			if err != nil {
+				return nil, err
+			}
+
+			issue := storageCheckIssue{
+				Kind:    storageCheckStaleSymlink,
+				Volume:  symlink,
+				Details: fmt.Sprintf("no container \"%s\" points to %s", dent.Name(), target),
+			}
+
+			// Removing the symlink leaves whatever it points to
+			// untouched.
+			if repair {
+				err := os.Remove(symlink)
+				if err != nil {
+					return nil, err
+				}
+
+				issue.Repaired = true
+			}
+
+			issues = append(issues, issue)
+		}
+	}
+
+	return issues, nil
+}
+
+// storageCheckSymlink checks that symlink points to mountPoint. Only the
+// symlink itself is ever changed when repairing it.
+func storageCheckSymlink(symlink string, mountPoint string, optional bool, repair bool) (*storageCheckIssue, error) {
+	issue := storageCheckIssue{Kind: storageCheckWrongMountPoint}
+
+	target, err := os.Readlink(symlink)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			issue.Details = fmt.Sprintf("%s is not a symlink", symlink)
+			return &issue, nil
+		}
+
+		if optional {
+			return nil, nil
+		}
+
+		issue.Details = fmt.Sprintf("%s is missing", symlink)
+	} else if target != mountPoint {
+		issue.Details = fmt.Sprintf("%s points to %s instead of %s", symlink, target, mountPoint)
+	} else {
+		return nil, nil
+	}
+
+	if !repair || !shared.PathExists(mountPoint) {
+		return &issue, nil
+	}
+
+	if target != "" {
+		err := os.Remove(symlink)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = os.Symlink(mountPoint, symlink)
+	if err != nil {
+		return nil, err
+	}
+
+	issue.Repaired = true
+	return &issue, nil
+}
+
+// checkVolumes compares the volumes found on the storage pool with the ones
+// recorded in the database. onDisk holds the names of the volumes found for
+// each volume type, with snapshots named "<volume>/<snapshot>" as in the
+// database. Volume types missing from onDisk aren't kept on the storage pool
+// by the driver and are skipped.
+func (s *storageShared) checkVolumes(onDisk map[int][]string, repair bool) ([]storageCheckIssue, error) {
+	issues := []storageCheckIssue{}
+
+	for _, volumeType := range supportedVolumeTypes {
+		found, ok := onDisk[volumeType]
+		if !ok {
+			continue
+		}
+
+		volumeTypeApiEndpoint, err := storagePoolVolumeTypeToApiEndpoint(volumeType)
+		if err != nil {
+			return nil, err
+		}
+
+		known, err := dbStoragePoolVolumesGetType(s.d.db, volumeType, s.poolID)
+		if err != nil {
+			return nil, err
+		}
+
+		sort.Strings(known)
+		for _, name := range known {
+			if shared.StringInSlice(name, found) {
+				continue
+			}
+
+			issue := storageCheckIssue{
+				Pool:    s.pool.Name,
+				Kind:    storageCheckMissingVolume,
+				Volume:  fmt.Sprintf("%s/%s", volumeTypeApiEndpoint, name),
+				Details: "recorded in the database but not found on the storage pool",
+			}
+
+			// Image volumes get unpacked again from the image
+			// store on next use, so forgetting about them is safe.
+			if repair && volumeType == storagePoolVolumeTypeImage {
+				err := dbStoragePoolVolumeDelete(s.d.db, name, volumeType, s.poolID)
+				if err != nil {
+					return nil, err
+				}
+
+				issue.Repaired = true
+			}
+
+			issues = append(issues, issue)
+		}
+
+		sort.Strings(found)
+		for _, name := range found {
+			if shared.StringInSlice(name, known) {
+				continue
+			}
+
+			issue := storageCheckIssue{
+				Pool:    s.pool.Name,
+				Kind:    storageCheckOrphanedVolume,
+				Volume:  fmt.Sprintf("%s/%s", volumeTypeApiEndpoint, name),
+				Details: "found on the storage pool but not recorded in the database",
+			}
+
+			if volumeType == storagePoolVolumeTypeImage {
+				issue.Details = fmt.Sprintf("%s, use \"lxc storage gc\" to remove it", issue.Details)
+			} else if volumeType == storagePoolVolumeTypeContainer && !strings.Contains(name, shared.SnapshotDelimiter) {
+				issue.Details = fmt.Sprintf("%s, use \"lxd import\" to recover it", issue.Details)
+			}
+
+			issues = append(issues, issue)
+		}
+	}
+
+	return issues, nil
+}
+
+// storageVolumesInDirs lists the volumes of drivers keeping each volume in
+// its own directory (or subvolume) below the pool's mountpoint. isVolume
+// tells the volumes apart from anything else found there.
+func storageVolumesInDirs(poolName string, isVolume func(path string) bool) (map[int][]string, error) {
+	poolMntPoint := getStoragePoolMountPoint(poolName)
+
+	list := func(dir string, nested bool) ([]string, error) {
+		names := []string{}
+
+		dents, err := ioutil.ReadDir(filepath.Join(poolMntPoint, dir))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return names, nil
+			}
+
+			return nil, err
+		}
+
+		for _, dent := range dents {
+			path := filepath.Join(poolMntPoint, dir, dent.Name())
+			if !nested {
+				if isVolume(path) {
+					names = append(names, dent.Name())
+				}
+
+				continue
+			}
+
+			if !dent.IsDir() {
+				continue
+			}
+
+			snapshots, err := ioutil.ReadDir(path)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, snapshot := range snapshots {
+				if isVolume(filepath.Join(path, snapshot.Name())) {
+					names = append(names, fmt.Sprintf("%s%s%s", dent.Name(), shared.SnapshotDelimiter, snapshot.Name()))
+				}
+			}
+		}
+
+		return names, nil
+	}
+
+	volumes := map[int][]string{}
+	for volumeType, dirs := range map[int][]string{
+		storagePoolVolumeTypeContainer: {"containers", "snapshots"},
+		storagePoolVolumeTypeCustom:    {"custom", "custom-snapshots"},
+	} {
+		names, err := list(dirs[0], false)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshots, err := list(dirs[1], true)
+		if err != nil {
+			return nil, err
+		}
+
+		volumes[volumeType] = append(names, snapshots...)
+	}
+
+	return volumes, nil
+}
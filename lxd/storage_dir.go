@@ -140,6 +140,19 @@ func (s *storageDir) SetStoragePoolVolumeWritable(writable *api.StorageVolumePut
 	s.volume.StorageVolumePut = *writable
 }
 
+func (s *storageDir) StoragePoolResources() (*api.ResourcesStoragePool, error) {
+	return storageResource(getStoragePoolMountPoint(s.pool.Name))
+}
+
+func (s *storageDir) StoragePoolVolumesCheck(repair bool) ([]storageCheckIssue, error) {
+	volumes, err := storageVolumesInDirs(s.pool.Name, shared.IsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.checkVolumes(volumes, repair)
+}
+
 func (s *storageDir) GetContainerPoolInfo() (int64, string) {
 	return s.poolID, s.pool.Name
 }
@@ -185,6 +198,12 @@ func (s *storageDir) StoragePoolVolumeDelete() error {
 		return err
 	}
 
+	snapshotsPath := shared.VarPath("storage-pools", s.pool.Name, "custom-snapshots", s.volume.Name)
+	err = os.RemoveAll(snapshotsPath)
+	if err != nil {
+		return err
+	}
+
 	shared.LogInfof("Deleted DIR storage volume \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
 	return nil
 }
@@ -201,6 +220,105 @@ func (s *storageDir) StoragePoolVolumeUpdate(changedConfig []string) error {
 	return fmt.Errorf("Dir storage properties cannot be changed.")
 }
 
+func (s *storageDir) StoragePoolVolumeGetUsage() (int64, error) {
+	return -1, fmt.Errorf("The directory storage backend doesn't support quotas.")
+}
+
+func (s *storageDir) StoragePoolVolumeRename(newName string) error {
+	shared.LogInfof("Renaming DIR storage volume on storage pool \"%s\" from \"%s\" to \"%s\".", s.pool.Name, s.volume.Name, newName)
+
+	oldPath := getStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name)
+	newPath := getStoragePoolVolumeMountPoint(s.pool.Name, newName)
+	err := os.Rename(oldPath, newPath)
+	if err != nil {
+		return err
+	}
+
+	oldSnapshotsPath := shared.VarPath("storage-pools", s.pool.Name, "custom-snapshots", s.volume.Name)
+	newSnapshotsPath := shared.VarPath("storage-pools", s.pool.Name, "custom-snapshots", newName)
+	if shared.PathExists(oldSnapshotsPath) {
+		err = os.Rename(oldSnapshotsPath, newSnapshotsPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	shared.LogInfof("Renamed DIR storage volume on storage pool \"%s\" from \"%s\" to \"%s\".", s.pool.Name, s.volume.Name, newName)
+	s.volume.Name = newName
+	return nil
+}
+
+func (s *storageDir) StoragePoolVolumeCopy(source *api.StorageVolumeSource) error {
+	shared.LogInfof("Copying DIR storage volume \"%s\" on storage pool \"%s\" to \"%s\" on storage pool \"%s\".", source.Name, source.Pool, s.volume.Name, s.pool.Name)
+
+	err := storagePoolVolumeCopyRsync(s.d, s, source)
+	if err != nil {
+		return err
+	}
+
+	shared.LogInfof("Copied DIR storage volume \"%s\" on storage pool \"%s\" to \"%s\" on storage pool \"%s\".", source.Name, source.Pool, s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageDir) StoragePoolVolumeSnapshotCreate(snapshotName string) error {
+	shared.LogInfof("Creating DIR storage volume snapshot \"%s\" of \"%s\" on storage pool \"%s\".", snapshotName, s.volume.Name, s.pool.Name)
+
+	sourcePath := getStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name)
+	targetPath := getStoragePoolVolumeSnapshotMountPoint(s.pool.Name, s.volume.Name, snapshotName)
+	err := os.MkdirAll(filepath.Dir(targetPath), 0700)
+	if err != nil {
+		return err
+	}
+
+	output, err := storageRsyncCopy(sourcePath, targetPath)
+	if err != nil {
+		os.RemoveAll(targetPath)
+		return fmt.Errorf("Failed to rsync storage volume \"%s\": %s: %s", s.volume.Name, string(output), err)
+	}
+
+	shared.LogInfof("Created DIR storage volume snapshot \"%s\" of \"%s\" on storage pool \"%s\".", snapshotName, s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageDir) StoragePoolVolumeSnapshotDelete(snapshotName string) error {
+	shared.LogInfof("Deleting DIR storage volume snapshot \"%s\" of \"%s\" on storage pool \"%s\".", snapshotName, s.volume.Name, s.pool.Name)
+
+	snapshotPath := getStoragePoolVolumeSnapshotMountPoint(s.pool.Name, s.volume.Name, snapshotName)
+	err := os.RemoveAll(snapshotPath)
+	if err != nil {
+		return err
+	}
+
+	// Remove the snapshots directory of the volume if it is now empty.
+	snapshotsPath := filepath.Dir(snapshotPath)
+	if ok, _ := shared.PathIsEmpty(snapshotsPath); ok {
+		os.Remove(snapshotsPath)
+	}
+
+	shared.LogInfof("Deleted DIR storage volume snapshot \"%s\" of \"%s\" on storage pool \"%s\".", snapshotName, s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageDir) StoragePoolVolumeSnapshotRename(snapshotName string, newName string) error {
+	oldPath := getStoragePoolVolumeSnapshotMountPoint(s.pool.Name, s.volume.Name, snapshotName)
+	newPath := getStoragePoolVolumeSnapshotMountPoint(s.pool.Name, s.volume.Name, newName)
+	return os.Rename(oldPath, newPath)
+}
+
+func (s *storageDir) StoragePoolVolumeSnapshotRestore(snapshotName string) error {
+	shared.LogInfof("Restoring DIR storage volume \"%s\" on storage pool \"%s\" from snapshot \"%s\".", s.volume.Name, s.pool.Name, snapshotName)
+
+	sourcePath := getStoragePoolVolumeSnapshotMountPoint(s.pool.Name, s.volume.Name, snapshotName)
+	targetPath := getStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name)
+	output, err := storageRsyncCopy(sourcePath, targetPath)
+	if err != nil {
+		return fmt.Errorf("Failed to rsync storage volume snapshot \"%s\": %s: %s", snapshotName, string(output), err)
+	}
+
+	shared.LogInfof("Restored DIR storage volume \"%s\" on storage pool \"%s\" from snapshot \"%s\".", s.volume.Name, s.pool.Name, snapshotName)
+	return nil
+}
+
 func (s *storageDir) ContainerStorageReady(name string) bool {
 	containerMntPoint := getContainerMountPoint(s.pool.Name, name)
 	ok, _ := shared.PathIsEmpty(containerMntPoint)
@@ -599,6 +717,14 @@ func (s *storageDir) ContainerSnapshotCreateEmpty(snapshotContainer container) e
 	return nil
 }
 
+func (s *storageDir) ContainerBackupDump(container container, snapshots []container, target string) error {
+	return fmt.Errorf("Optimized backups are not supported by the DIR storage driver.")
+}
+
+func (s *storageDir) ContainerBackupLoad(container container, snapshots []container, source string) error {
+	return fmt.Errorf("Optimized backups are not supported by the DIR storage driver.")
+}
+
 func (s *storageDir) ContainerSnapshotDelete(snapshotContainer container) error {
 	shared.LogDebugf("Deleting DIR storage volume for snapshot \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
 
@@ -691,6 +817,11 @@ func (s *storageDir) ImageUmount(fingerprint string) (bool, error) {
 	return true, nil
 }
 
+func (s *storageDir) ImageVolumesGC(dryRun bool) ([]string, error) {
+	// Images aren't unpacked into dir storage pools.
+	return []string{}, nil
+}
+
 func (s *storageDir) MigrationType() MigrationFSType {
 	return MigrationFSType_RSYNC
 }
@@ -703,6 +834,6 @@ func (s *storageDir) MigrationSource(container container) (MigrationStorageSourc
 	return rsyncMigrationSource(container)
 }
 
-func (s *storageDir) MigrationSink(live bool, container container, snapshots []*Snapshot, conn *websocket.Conn, srcIdmap *shared.IdmapSet, op *operation) error {
-	return rsyncMigrationSink(live, container, snapshots, conn, srcIdmap, op)
+func (s *storageDir) MigrationSink(live bool, container container, snapshots []*Snapshot, conn *websocket.Conn, srcIdmap *shared.IdmapSet, op *operation, refresh bool) error {
+	return rsyncMigrationSink(live, container, snapshots, conn, srcIdmap, op, refresh)
 }
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -10,6 +13,7 @@ import (
 	"syscall"
 
 	"github.com/gorilla/websocket"
+	"github.com/pborman/uuid"
 
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
@@ -130,7 +134,7 @@ func storageLVMGetThinPoolUsers(d *Daemon) ([]string, error) {
 		}
 	}
 
-	imageNames, err := dbImagesGet(d.db, false)
+	imageNames, err := dbImagesGet(d.db, projectDefault, false)
 	if err != nil {
 		return results, err
 	}
@@ -190,6 +194,16 @@ func containerNameToLVName(containerName string) string {
 	return strings.Replace(lvName, shared.SnapshotDelimiter, "-", -1)
 }
 
+// lvNameToContainerName is the reverse of containerNameToLVName.
+func lvNameToContainerName(lvName string) string {
+	fields := strings.Split(lvName, "--")
+	for i := range fields {
+		fields[i] = strings.Replace(fields[i], "-", shared.SnapshotDelimiter, -1)
+	}
+
+	return strings.Join(fields, "-")
+}
+
 type storageLvm struct {
 	vgName       string
 	thinPoolName string
@@ -393,7 +407,8 @@ func (s *storageLvm) StoragePoolCreate() error {
 		}
 	}()
 
-	// Clear size as we're currently not using it for LVM.
+	// Clear size as it only limits the thin pool and can be set once the
+	// pool exists.
 	s.pool.Config["size"] = ""
 	poolName := s.getOnDiskPoolName()
 	source := s.pool.Config["source"]
@@ -572,6 +587,19 @@ func (s *storageLvm) StoragePoolVolumeDelete() error {
 		return err
 	}
 
+	// Remove the snapshots of the storage volume.
+	snapshots, err := dbStoragePoolVolumeSnapshotsGetType(s.d.db, s.volume.Name, storagePoolVolumeTypeCustom, s.poolID)
+	if err != nil {
+		return err
+	}
+
+	for _, snapshot := range snapshots {
+		err = s.removeLV(poolName, volumeType, containerNameToLVName(snapshot))
+		if err != nil {
+			return err
+		}
+	}
+
 	if shared.PathExists(customPoolVolumeMntPoint) {
 		err := os.Remove(customPoolVolumeMntPoint)
 		if err != nil {
@@ -678,281 +706,753 @@ func (s *storageLvm) StoragePoolVolumeUmount() (bool, error) {
 	return ourUmount, nil
 }
 
-func (s *storageLvm) GetStoragePoolWritable() api.StoragePoolPut {
-	return s.pool.Writable()
-}
-
-func (s *storageLvm) GetStoragePoolVolumeWritable() api.StorageVolumePut {
-	return s.volume.Writable()
-}
-
-func (s *storageLvm) SetStoragePoolWritable(writable *api.StoragePoolPut) {
-	s.pool.StoragePoolPut = *writable
-}
-
-func (s *storageLvm) SetStoragePoolVolumeWritable(writable *api.StorageVolumePut) {
-	s.volume.StorageVolumePut = *writable
-}
-
-func (s *storageLvm) GetContainerPoolInfo() (int64, string) {
-	return s.poolID, s.pool.Name
-}
-
-func (s *storageLvm) StoragePoolUpdate(changedConfig []string) error {
-	shared.LogInfof("Updating LVM storage pool \"%s\".", s.pool.Name)
-
-	if shared.StringInSlice("size", changedConfig) {
-		return fmt.Errorf("The \"size\" property cannot be changed.")
-	}
-
-	if shared.StringInSlice("source", changedConfig) {
-		return fmt.Errorf("The \"source\" property cannot be changed.")
-	}
-
-	if shared.StringInSlice("volume.zfs.use_refquota", changedConfig) {
-		return fmt.Errorf("The \"volume.zfs.use_refquota\" property cannot be changed.")
-	}
+func (s *storageLvm) StoragePoolVolumeRename(newName string) error {
+	shared.LogInfof("Renaming LVM storage volume on storage pool \"%s\" from \"%s\" to \"%s\".", s.pool.Name, s.volume.Name, newName)
 
-	if shared.StringInSlice("volume.zfs.remove_snapshots", changedConfig) {
-		return fmt.Errorf("The \"volume.zfs.remove_snapshots\" property cannot be changed.")
+	_, err := s.StoragePoolVolumeUmount()
+	if err != nil {
+		return err
 	}
 
-	if shared.StringInSlice("zfs.pool_name", changedConfig) {
-		return fmt.Errorf("The \"zfs.pool_name\" property cannot be changed.")
+	volumeType, err := storagePoolVolumeTypeNameToApiEndpoint(s.volume.Type)
+	if err != nil {
+		return err
 	}
 
-	if shared.StringInSlice("volume.block.mount_options", changedConfig) {
-		// noop
+	output, err := s.renameLV(s.volume.Name, newName, volumeType)
+	if err != nil {
+		shared.LogErrorf("Failed to rename LV \"%s\" to \"%s\": %s.", s.volume.Name, newName, output)
+		return fmt.Errorf("Failed to rename LV \"%s\" to \"%s\": %s", s.volume.Name, newName, output)
 	}
 
-	if shared.StringInSlice("volume.block.filesystem", changedConfig) {
-		// noop
+	snapshots, err := dbStoragePoolVolumeSnapshotsGetType(s.d.db, s.volume.Name, storagePoolVolumeTypeCustom, s.poolID)
+	if err != nil {
+		return err
 	}
 
-	if shared.StringInSlice("volume.size", changedConfig) {
-		// noop
+	for _, snapshot := range snapshots {
+		snapshotName := shared.ExtractSnapshotName(snapshot)
+		oldLvName := containerNameToLVName(snapshot)
+		newLvName := containerNameToLVName(fmt.Sprintf("%s%s%s", newName, shared.SnapshotDelimiter, snapshotName))
+		output, err := s.renameLV(oldLvName, newLvName, volumeType)
+		if err != nil {
+			shared.LogErrorf("Failed to rename LV \"%s\" to \"%s\": %s.", oldLvName, newLvName, output)
+			return fmt.Errorf("Failed to rename LV \"%s\" to \"%s\": %s", oldLvName, newLvName, output)
+		}
 	}
 
-	if shared.StringInSlice("lvm.thinpool_name", changedConfig) {
-		return fmt.Errorf("The \"lvm.thinpool_name\" property cannot be changed.")
+	oldMntPoint := getStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name)
+	newMntPoint := getStoragePoolVolumeMountPoint(s.pool.Name, newName)
+	err = os.Rename(oldMntPoint, newMntPoint)
+	if err != nil {
+		return err
 	}
 
-	if shared.StringInSlice("lvm.vg_name", changedConfig) {
-		return fmt.Errorf("The \"lvm.vg_name\" property cannot be changed.")
+	s.volume.Name = newName
+	_, err = s.StoragePoolVolumeMount()
+	if err != nil {
+		return err
 	}
 
-	shared.LogInfof("Updated LVM storage pool \"%s\".", s.pool.Name)
+	shared.LogInfof("Renamed LVM storage volume on storage pool \"%s\" to \"%s\".", s.pool.Name, newName)
 	return nil
 }
 
-func (s *storageLvm) StoragePoolVolumeUpdate(changedConfig []string) error {
-	shared.LogInfof("Updating LVM storage volume \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
-
-	if shared.StringInSlice("block.mount_options", changedConfig) && len(changedConfig) == 1 {
-		// noop
-	} else {
-		return fmt.Errorf("The properties \"%v\" cannot be changed.", changedConfig)
-	}
+func (s *storageLvm) StoragePoolVolumeCopy(source *api.StorageVolumeSource) error {
+	shared.LogInfof("Copying LVM storage volume \"%s\" on storage pool \"%s\" to \"%s\" on storage pool \"%s\".", source.Name, source.Pool, s.volume.Name, s.pool.Name)
 
-	shared.LogInfof("Updated LVM storage volume \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
-	return nil
-}
+	// Only volumes within the same volume group can be snapshotted into
+	// each other so fall back to rsync.
+	if source.Pool != s.pool.Name {
+		err := storagePoolVolumeCopyRsync(s.d, s, source)
+		if err != nil {
+			return err
+		}
 
-func (s *storageLvm) ContainerStorageReady(name string) bool {
-	err := s.StoragePoolCheck()
-	if err != nil {
-		return false
+		shared.LogInfof("Copied LVM storage volume \"%s\" on storage pool \"%s\" to \"%s\" on storage pool \"%s\".", source.Name, source.Pool, s.volume.Name, s.pool.Name)
+		return nil
 	}
 
-	containerLvmName := containerNameToLVName(name)
-	poolName := s.getOnDiskPoolName()
-	containerLvmPath := getLvmDevPath(poolName, storagePoolVolumeApiEndpointContainers, containerLvmName)
-	ok, _ := storageLVExists(containerLvmPath)
-	return ok
-}
-
-func (s *storageLvm) ContainerCreate(container container) error {
-	shared.LogDebugf("Creating empty LVM storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
-
 	err := s.StoragePoolCheck()
 	if err != nil {
 		return err
 	}
 
-	tryUndo := true
-
-	containerName := container.Name()
-	containerLvmName := containerNameToLVName(containerName)
-	thinPoolName := s.getLvmThinpoolName()
-	lvFsType := s.getLvmFilesystem()
-	lvSize, err := s.getLvmVolumeSize()
-	if lvSize == "" {
-		return err
-	}
-
 	poolName := s.getOnDiskPoolName()
-	err = s.createThinLV(poolName, thinPoolName, containerLvmName, lvFsType, lvSize, storagePoolVolumeApiEndpointContainers)
+	lvmVolumePath, err := s.createSnapshotLV(poolName, source.Name, storagePoolVolumeApiEndpointCustom, s.volume.Name, storagePoolVolumeApiEndpointCustom, false)
 	if err != nil {
-		return err
+		return fmt.Errorf("Error creating snapshot LV: %s", err)
 	}
+
+	tryUndo := true
 	defer func() {
 		if tryUndo {
-			s.ContainerDelete(container)
+			s.StoragePoolVolumeDelete()
 		}
 	}()
 
-	if container.IsSnapshot() {
-		containerMntPoint := getSnapshotMountPoint(s.pool.Name, containerName)
-		fields := strings.SplitN(containerName, shared.SnapshotDelimiter, 2)
-		sourceName := fields[0]
-		snapshotMntPointSymlinkTarget := shared.VarPath("storage-pools", s.pool.Name, "snapshots", sourceName)
-		snapshotMntPointSymlink := shared.VarPath("snapshots", sourceName)
-		err := os.MkdirAll(containerMntPoint, 0755)
-		if err != nil {
-			return err
-		}
-		err = createSnapshotMountpoint(containerMntPoint, snapshotMntPointSymlinkTarget, snapshotMntPointSymlink)
-		if err != nil {
-			return err
-		}
-	} else {
-		containerMntPoint := getContainerMountPoint(s.pool.Name, containerName)
-		containerPath := container.Path()
-		err := os.MkdirAll(containerMntPoint, 0755)
-		if err != nil {
-			return err
-		}
-		err = createContainerMountpoint(containerMntPoint, containerPath, container.IsPrivileged())
+	// Generate a new xfs's UUID
+	if s.getLvmFilesystem() == "xfs" {
+		err := xfsGenerateNewUUID(lvmVolumePath)
 		if err != nil {
 			return err
 		}
 	}
 
+	customPoolVolumeMntPoint := getStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name)
+	err = os.MkdirAll(customPoolVolumeMntPoint, 0711)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.StoragePoolVolumeMount()
+	if err != nil {
+		return err
+	}
+
 	tryUndo = false
 
-	shared.LogDebugf("Created empty LVM storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	shared.LogInfof("Copied LVM storage volume \"%s\" on storage pool \"%s\" to \"%s\" on storage pool \"%s\".", source.Name, source.Pool, s.volume.Name, s.pool.Name)
 	return nil
 }
 
-func (s *storageLvm) ContainerCreateFromImage(container container, fingerprint string) error {
-	shared.LogDebugf("Creating LVM storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+func (s *storageLvm) StoragePoolVolumeSnapshotCreate(snapshotName string) error {
+	shared.LogInfof("Creating LVM storage volume snapshot \"%s\" of \"%s\" on storage pool \"%s\".", snapshotName, s.volume.Name, s.pool.Name)
 
 	err := s.StoragePoolCheck()
 	if err != nil {
 		return err
 	}
 
-	tryUndo := true
-
 	poolName := s.getOnDiskPoolName()
+	lvName := containerNameToLVName(fmt.Sprintf("%s%s%s", s.volume.Name, shared.SnapshotDelimiter, snapshotName))
+	_, err = s.createSnapshotLV(poolName, s.volume.Name, storagePoolVolumeApiEndpointCustom, lvName, storagePoolVolumeApiEndpointCustom, true)
+	if err != nil {
+		return fmt.Errorf("Error creating snapshot LV: %s", err)
+	}
 
-	// Check if the image already exists.
-	imageLvmDevPath := getLvmDevPath(poolName, storagePoolVolumeApiEndpointImages, fingerprint)
+	shared.LogInfof("Created LVM storage volume snapshot \"%s\" of \"%s\" on storage pool \"%s\".", snapshotName, s.volume.Name, s.pool.Name)
+	return nil
+}
 
-	imageStoragePoolLockID := getImageCreateLockID(poolName, fingerprint)
-	lxdStorageMapLock.Lock()
-	if waitChannel, ok := lxdStorageOngoingOperationMap[imageStoragePoolLockID]; ok {
-		lxdStorageMapLock.Unlock()
-		if _, ok := <-waitChannel; ok {
-			shared.LogWarnf("Received value over semaphore. This should not have happened.")
-		}
-	} else {
-		lxdStorageOngoingOperationMap[imageStoragePoolLockID] = make(chan bool)
-		lxdStorageMapLock.Unlock()
+func (s *storageLvm) StoragePoolVolumeSnapshotDelete(snapshotName string) error {
+	shared.LogInfof("Deleting LVM storage volume snapshot \"%s\" of \"%s\" on storage pool \"%s\".", snapshotName, s.volume.Name, s.pool.Name)
 
-		var imgerr error
-		ok, _ := storageLVExists(imageLvmDevPath)
-		if !ok {
-			imgerr = s.ImageCreate(fingerprint)
-		}
+	poolName := s.getOnDiskPoolName()
+	lvName := containerNameToLVName(fmt.Sprintf("%s%s%s", s.volume.Name, shared.SnapshotDelimiter, snapshotName))
+	err := s.removeLV(poolName, storagePoolVolumeApiEndpointCustom, lvName)
+	if err != nil {
+		return err
+	}
 
-		lxdStorageMapLock.Lock()
-		if waitChannel, ok := lxdStorageOngoingOperationMap[imageStoragePoolLockID]; ok {
-			close(waitChannel)
-			delete(lxdStorageOngoingOperationMap, imageStoragePoolLockID)
-		}
-		lxdStorageMapLock.Unlock()
+	shared.LogInfof("Deleted LVM storage volume snapshot \"%s\" of \"%s\" on storage pool \"%s\".", snapshotName, s.volume.Name, s.pool.Name)
+	return nil
+}
 
-		if imgerr != nil {
-			return imgerr
-		}
+func (s *storageLvm) StoragePoolVolumeSnapshotRename(snapshotName string, newName string) error {
+	oldLvName := containerNameToLVName(fmt.Sprintf("%s%s%s", s.volume.Name, shared.SnapshotDelimiter, snapshotName))
+	newLvName := containerNameToLVName(fmt.Sprintf("%s%s%s", s.volume.Name, shared.SnapshotDelimiter, newName))
+	output, err := s.renameLV(oldLvName, newLvName, storagePoolVolumeApiEndpointCustom)
+	if err != nil {
+		shared.LogErrorf("Failed to rename LV \"%s\" to \"%s\": %s.", oldLvName, newLvName, output)
+		return fmt.Errorf("Failed to rename LV \"%s\" to \"%s\": %s", oldLvName, newLvName, output)
 	}
 
-	containerName := container.Name()
-	containerLvmName := containerNameToLVName(containerName)
-	containerLvSnapshotPath, err := s.createSnapshotLV(poolName, fingerprint, storagePoolVolumeApiEndpointImages, containerLvmName, storagePoolVolumeApiEndpointContainers, false)
+	return nil
+}
+
+func (s *storageLvm) StoragePoolVolumeSnapshotRestore(snapshotName string) error {
+	shared.LogInfof("Restoring LVM storage volume \"%s\" on storage pool \"%s\" from snapshot \"%s\".", s.volume.Name, s.pool.Name, snapshotName)
+
+	_, err := s.StoragePoolVolumeUmount()
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if tryUndo {
-			s.ContainerDelete(container)
-		}
-	}()
 
-	containerMntPoint := getContainerMountPoint(s.pool.Name, containerName)
-	containerPath := container.Path()
-	err = os.MkdirAll(containerMntPoint, 0755)
+	// Move the current LV out of the way so that we can put it back in
+	// case anything goes wrong.
+	poolName := s.getOnDiskPoolName()
+	tmpLvName := getTmpSnapshotName(s.volume.Name)
+	output, err := s.renameLV(s.volume.Name, tmpLvName, storagePoolVolumeApiEndpointCustom)
 	if err != nil {
-		return err
+		return fmt.Errorf("Failed to rename LV \"%s\" to \"%s\": %s", s.volume.Name, tmpLvName, output)
 	}
-	err = createContainerMountpoint(containerMntPoint, containerPath, container.IsPrivileged())
+
+	snapshotLvName := containerNameToLVName(fmt.Sprintf("%s%s%s", s.volume.Name, shared.SnapshotDelimiter, snapshotName))
+	lvmVolumePath, err := s.createSnapshotLV(poolName, snapshotLvName, storagePoolVolumeApiEndpointCustom, s.volume.Name, storagePoolVolumeApiEndpointCustom, false)
 	if err != nil {
-		return err
+		s.renameLV(tmpLvName, s.volume.Name, storagePoolVolumeApiEndpointCustom)
+		return fmt.Errorf("Error creating snapshot LV: %s", err)
 	}
 
 	// Generate a new xfs's UUID
-	lvFsType := s.getLvmFilesystem()
-	if lvFsType == "xfs" {
-		err := xfsGenerateNewUUID(containerLvSnapshotPath)
+	if s.getLvmFilesystem() == "xfs" {
+		err := xfsGenerateNewUUID(lvmVolumePath)
 		if err != nil {
 			return err
 		}
 	}
 
-	ourMount, err := s.ContainerMount(containerName, containerPath)
-	if err != nil {
-		return err
-	}
-	if ourMount {
-		defer s.ContainerUmount(containerName, containerPath)
-	}
-
-	if container.IsPrivileged() {
-		err = os.Chmod(containerMntPoint, 0700)
-	} else {
-		err = os.Chmod(containerMntPoint, 0755)
-	}
+	err = s.removeLV(poolName, storagePoolVolumeApiEndpointCustom, tmpLvName)
 	if err != nil {
 		return err
 	}
 
-	if !container.IsPrivileged() {
-		err := s.shiftRootfs(container)
-		if err != nil {
-			return err
-		}
-	}
-
-	err = container.TemplateApply("create")
+	_, err = s.StoragePoolVolumeMount()
 	if err != nil {
-		shared.LogErrorf("Error in create template during ContainerCreateFromImage, continuing to unmount: %s.", err)
 		return err
 	}
 
-	tryUndo = false
-
-	shared.LogDebugf("Created LVM storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	shared.LogInfof("Restored LVM storage volume \"%s\" on storage pool \"%s\" from snapshot \"%s\".", s.volume.Name, s.pool.Name, snapshotName)
 	return nil
 }
 
-func (s *storageLvm) ContainerCanRestore(container container, sourceContainer container) error {
-	return nil
+func (s *storageLvm) GetStoragePoolWritable() api.StoragePoolPut {
+	return s.pool.Writable()
 }
 
-func (s *storageLvm) ContainerDelete(container container) error {
-	shared.LogDebugf("Deleting LVM storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
-
+func (s *storageLvm) GetStoragePoolVolumeWritable() api.StorageVolumePut {
+	return s.volume.Writable()
+}
+
+func (s *storageLvm) SetStoragePoolWritable(writable *api.StoragePoolPut) {
+	s.pool.StoragePoolPut = *writable
+}
+
+func (s *storageLvm) SetStoragePoolVolumeWritable(writable *api.StorageVolumePut) {
+	s.volume.StorageVolumePut = *writable
+}
+
+func (s *storageLvm) StoragePoolVolumesCheck(repair bool) ([]storageCheckIssue, error) {
+	err := s.StoragePoolCheck()
+	if err != nil {
+		return nil, err
+	}
+
+	poolName := s.getOnDiskPoolName()
+	output, err := exec.Command("lvs", "--noheadings", "-o", "lv_name", poolName).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list the logical volumes of \"%s\": %s", poolName, strings.TrimSpace(string(output)))
+	}
+
+	volumes := map[int][]string{}
+	for _, volumeType := range supportedVolumeTypes {
+		volumeTypeApiEndpoint, err := storagePoolVolumeTypeToApiEndpoint(volumeType)
+		if err != nil {
+			return nil, err
+		}
+
+		// The escaping of LV names can't always be reversed, so map
+		// them back through the names known to the database first.
+		known, err := dbStoragePoolVolumesGetType(s.d.db, volumeType, s.poolID)
+		if err != nil {
+			return nil, err
+		}
+
+		names := map[string]string{}
+		for _, name := range known {
+			lvName := name
+			if volumeType == storagePoolVolumeTypeContainer || strings.Contains(name, shared.SnapshotDelimiter) {
+				lvName = containerNameToLVName(name)
+			}
+
+			names[lvName] = name
+		}
+
+		volumes[volumeType] = []string{}
+		prefix := getPrefixedLvName(volumeTypeApiEndpoint, "")
+		for _, lvName := range strings.Fields(string(output)) {
+			if !strings.HasPrefix(lvName, prefix) {
+				continue
+			}
+
+			lvName = strings.TrimPrefix(lvName, prefix)
+			name, ok := names[lvName]
+			if !ok {
+				name = lvNameToContainerName(lvName)
+			}
+
+			volumes[volumeType] = append(volumes[volumeType], name)
+		}
+	}
+
+	return s.checkVolumes(volumes, repair)
+}
+
+func (s *storageLvm) StoragePoolResources() (*api.ResourcesStoragePool, error) {
+	vgName := s.getOnDiskPoolName()
+	poolName := s.getLvmThinpoolName()
+
+	res := api.ResourcesStoragePool{}
+
+	// Volumes are allocated from the thin pool once it exists, otherwise
+	// report the space of the volume group.
+	exists, err := storageLVMThinpoolExists(vgName, poolName)
+	if err != nil {
+		return nil, err
+	}
+
+	if exists {
+		output, err := exec.Command("lvs", "--noheadings", "--units", "b", "--nosuffix", "--separator", ",", "-o", "lv_size,data_percent", fmt.Sprintf("%s/%s", vgName, poolName)).Output()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get the size of the LVM thin pool \"%s\": %v", poolName, err)
+		}
+
+		fields := strings.Split(strings.TrimSpace(string(output)), ",")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Unexpected output from lvs: %s", output)
+		}
+
+		total, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		percent, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, err
+		}
+
+		res.Space.Total = total
+		res.Space.Used = uint64(float64(total) * percent / 100)
+
+		return &res, nil
+	}
+
+	output, err := exec.Command("vgs", "--noheadings", "--units", "b", "--nosuffix", "--separator", ",", "-o", "vg_size,vg_free", vgName).Output()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get the size of the LVM volume group \"%s\": %v", vgName, err)
+	}
+
+	fields := strings.Split(strings.TrimSpace(string(output)), ",")
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("Unexpected output from vgs: %s", output)
+	}
+
+	total, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	free, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Space.Total = total
+	res.Space.Used = total - free
+
+	return &res, nil
+}
+
+func (s *storageLvm) GetContainerPoolInfo() (int64, string) {
+	return s.poolID, s.pool.Name
+}
+
+func (s *storageLvm) StoragePoolUpdate(changedConfig []string) error {
+	shared.LogInfof("Updating LVM storage pool \"%s\".", s.pool.Name)
+
+	if shared.StringInSlice("size", changedConfig) {
+		err := s.lvmPoolResize()
+		if err != nil {
+			return err
+		}
+	}
+
+	if shared.StringInSlice("source", changedConfig) {
+		return fmt.Errorf("The \"source\" property cannot be changed.")
+	}
+
+	if shared.StringInSlice("volume.zfs.use_refquota", changedConfig) {
+		return fmt.Errorf("The \"volume.zfs.use_refquota\" property cannot be changed.")
+	}
+
+	if shared.StringInSlice("volume.zfs.remove_snapshots", changedConfig) {
+		return fmt.Errorf("The \"volume.zfs.remove_snapshots\" property cannot be changed.")
+	}
+
+	if shared.StringInSlice("zfs.pool_name", changedConfig) {
+		return fmt.Errorf("The \"zfs.pool_name\" property cannot be changed.")
+	}
+
+	if shared.StringInSlice("volume.block.mount_options", changedConfig) {
+		// noop
+	}
+
+	if shared.StringInSlice("volume.block.filesystem", changedConfig) {
+		// noop
+	}
+
+	if shared.StringInSlice("volume.size", changedConfig) {
+		// noop
+	}
+
+	if shared.StringInSlice("lvm.thinpool_name", changedConfig) {
+		return fmt.Errorf("The \"lvm.thinpool_name\" property cannot be changed.")
+	}
+
+	if shared.StringInSlice("lvm.vg_name", changedConfig) {
+		return fmt.Errorf("The \"lvm.vg_name\" property cannot be changed.")
+	}
+
+	shared.LogInfof("Updated LVM storage pool \"%s\".", s.pool.Name)
+	return nil
+}
+
+// lvmPoolResize makes the volume group pick up space added to its physical
+// volumes and grows the thin pool to the "size" property. LVM can't shrink
+// thin pools so only growing is supported.
+func (s *storageLvm) lvmPoolResize() error {
+	vgName := s.getOnDiskPoolName()
+	thinPoolName := s.getLvmThinpoolName()
+
+	output, err := exec.Command("pvs", "--noheadings", "-o", "pv_name", "-S", fmt.Sprintf("vg_name=%s", vgName)).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Failed to list the physical volumes of \"%s\": %s", vgName, output)
+	}
+
+	for _, pv := range strings.Fields(string(output)) {
+		output, err := tryExec("pvresize", pv)
+		if err != nil {
+			return fmt.Errorf("Failed to resize the physical volume \"%s\": %s", pv, output)
+		}
+	}
+
+	if s.pool.Config["size"] == "" {
+		return nil
+	}
+
+	size, err := shared.ParseByteSizeString(s.pool.Config["size"])
+	if err != nil {
+		return err
+	}
+
+	// The thin pool gets created with the first volume and will then use
+	// the new size.
+	exists, err := storageLVMThinpoolExists(vgName, thinPoolName)
+	if err != nil || !exists {
+		return err
+	}
+
+	lvmThinPool := fmt.Sprintf("%s/%s", vgName, thinPoolName)
+	output, err = exec.Command("lvs", "--noheadings", "--units", "b", "--nosuffix", "-o", "lv_size", lvmThinPool).Output()
+	if err != nil {
+		return fmt.Errorf("Failed to get the size of the LVM thin pool \"%s\": %v", thinPoolName, err)
+	}
+
+	currentSize, err := strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64)
+	if err != nil {
+		return err
+	}
+
+	if size == currentSize {
+		return nil
+	}
+
+	if size < currentSize {
+		return fmt.Errorf("Shrinking LVM thin pools is not supported.")
+	}
+
+	output, err = tryExec("lvextend", "-L", fmt.Sprintf("%dB", size), lvmThinPool)
+	if err != nil {
+		return fmt.Errorf("Could not grow LVM thin pool \"%s\": %s", thinPoolName, output)
+	}
+
+	return nil
+}
+
+func (s *storageLvm) StoragePoolVolumeUpdate(changedConfig []string) error {
+	shared.LogInfof("Updating LVM storage volume \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
+	for _, key := range changedConfig {
+		if key != "block.mount_options" && key != "size" && !strings.HasPrefix(key, "user.") {
+			return fmt.Errorf("The properties \"%v\" cannot be changed.", changedConfig)
+		}
+	}
+
+	if shared.StringInSlice("size", changedConfig) {
+		size, err := shared.ParseByteSizeString(s.volume.Config["size"])
+		if err != nil {
+			return err
+		}
+
+		if size <= 0 {
+			return fmt.Errorf("LVM storage volumes require a size.")
+		}
+
+		ourMount, err := s.StoragePoolVolumeMount()
+		if err != nil {
+			return err
+		}
+		if ourMount {
+			defer s.StoragePoolVolumeUmount()
+		}
+
+		poolName := s.getOnDiskPoolName()
+		customPoolVolumeMntPoint := getStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name)
+		lvmVolumePath := getLvmDevPath(poolName, storagePoolVolumeApiEndpointCustom, s.volume.Name)
+		err = s.lvmGrow(lvmVolumePath, customPoolVolumeMntPoint, size)
+		if err != nil {
+			return err
+		}
+	}
+
+	shared.LogInfof("Updated LVM storage volume \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageLvm) StoragePoolVolumeGetUsage() (int64, error) {
+	ourMount, err := s.StoragePoolVolumeMount()
+	if err != nil {
+		return -1, err
+	}
+	if ourMount {
+		defer s.StoragePoolVolumeUmount()
+	}
+
+	res, err := storageResource(getStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name))
+	if err != nil {
+		return -1, err
+	}
+
+	return int64(res.Space.Used), nil
+}
+
+// lvmGrow extends the LV to the given size and grows the filesystem on it.
+// The filesystem must be mounted on mntPoint.
+func (s *storageLvm) lvmGrow(lvmVolumePath string, mntPoint string, size int64) error {
+	dev, err := os.Open(lvmVolumePath)
+	if err != nil {
+		return err
+	}
+
+	currentSize, err := dev.Seek(0, 2)
+	dev.Close()
+	if err != nil {
+		return err
+	}
+
+	if size == currentSize {
+		return nil
+	}
+
+	if size < currentSize {
+		return fmt.Errorf("Shrinking LVM storage volumes is not supported.")
+	}
+
+	output, err := tryExec("lvextend", "-L", fmt.Sprintf("%dB", size), lvmVolumePath)
+	if err != nil {
+		return fmt.Errorf("Could not extend LV \"%s\": %s", lvmVolumePath, string(output))
+	}
+
+	switch s.getLvmFilesystem() {
+	case "xfs":
+		output, err = tryExec("xfs_growfs", mntPoint)
+	default:
+		output, err = tryExec("resize2fs", lvmVolumePath)
+	}
+	if err != nil {
+		return fmt.Errorf("Could not grow the filesystem on \"%s\": %s", lvmVolumePath, string(output))
+	}
+
+	return nil
+}
+
+func (s *storageLvm) ContainerStorageReady(name string) bool {
+	err := s.StoragePoolCheck()
+	if err != nil {
+		return false
+	}
+
+	containerLvmName := containerNameToLVName(name)
+	poolName := s.getOnDiskPoolName()
+	containerLvmPath := getLvmDevPath(poolName, storagePoolVolumeApiEndpointContainers, containerLvmName)
+	ok, _ := storageLVExists(containerLvmPath)
+	return ok
+}
+
+func (s *storageLvm) ContainerCreate(container container) error {
+	shared.LogDebugf("Creating empty LVM storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
+	err := s.StoragePoolCheck()
+	if err != nil {
+		return err
+	}
+
+	tryUndo := true
+
+	containerName := container.Name()
+	containerLvmName := containerNameToLVName(containerName)
+	thinPoolName := s.getLvmThinpoolName()
+	lvFsType := s.getLvmFilesystem()
+	lvSize, err := s.getLvmVolumeSize()
+	if lvSize == "" {
+		return err
+	}
+
+	poolName := s.getOnDiskPoolName()
+	err = s.createThinLV(poolName, thinPoolName, containerLvmName, lvFsType, lvSize, storagePoolVolumeApiEndpointContainers)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tryUndo {
+			s.ContainerDelete(container)
+		}
+	}()
+
+	if container.IsSnapshot() {
+		containerMntPoint := getSnapshotMountPoint(s.pool.Name, containerName)
+		fields := strings.SplitN(containerName, shared.SnapshotDelimiter, 2)
+		sourceName := fields[0]
+		snapshotMntPointSymlinkTarget := shared.VarPath("storage-pools", s.pool.Name, "snapshots", sourceName)
+		snapshotMntPointSymlink := shared.VarPath("snapshots", sourceName)
+		err := os.MkdirAll(containerMntPoint, 0755)
+		if err != nil {
+			return err
+		}
+		err = createSnapshotMountpoint(containerMntPoint, snapshotMntPointSymlinkTarget, snapshotMntPointSymlink)
+		if err != nil {
+			return err
+		}
+	} else {
+		containerMntPoint := getContainerMountPoint(s.pool.Name, containerName)
+		containerPath := container.Path()
+		err := os.MkdirAll(containerMntPoint, 0755)
+		if err != nil {
+			return err
+		}
+		err = createContainerMountpoint(containerMntPoint, containerPath, container.IsPrivileged())
+		if err != nil {
+			return err
+		}
+	}
+
+	tryUndo = false
+
+	shared.LogDebugf("Created empty LVM storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageLvm) ContainerCreateFromImage(container container, fingerprint string) error {
+	shared.LogDebugf("Creating LVM storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
+	err := s.StoragePoolCheck()
+	if err != nil {
+		return err
+	}
+
+	tryUndo := true
+
+	poolName := s.getOnDiskPoolName()
+
+	// Check if the image already exists.
+	imageLvmDevPath := getLvmDevPath(poolName, storagePoolVolumeApiEndpointImages, fingerprint)
+
+	imageStoragePoolLockID := getImageCreateLockID(poolName, fingerprint)
+	lxdStorageMapLock.Lock()
+	if waitChannel, ok := lxdStorageOngoingOperationMap[imageStoragePoolLockID]; ok {
+		lxdStorageMapLock.Unlock()
+		if _, ok := <-waitChannel; ok {
+			shared.LogWarnf("Received value over semaphore. This should not have happened.")
+		}
+	} else {
+		lxdStorageOngoingOperationMap[imageStoragePoolLockID] = make(chan bool)
+		lxdStorageMapLock.Unlock()
+
+		var imgerr error
+		ok, _ := storageLVExists(imageLvmDevPath)
+		if !ok {
+			imgerr = s.ImageCreate(fingerprint)
+		}
+
+		lxdStorageMapLock.Lock()
+		if waitChannel, ok := lxdStorageOngoingOperationMap[imageStoragePoolLockID]; ok {
+			close(waitChannel)
+			delete(lxdStorageOngoingOperationMap, imageStoragePoolLockID)
+		}
+		lxdStorageMapLock.Unlock()
+
+		if imgerr != nil {
+			return imgerr
+		}
+	}
+
+	containerName := container.Name()
+	containerLvmName := containerNameToLVName(containerName)
+	containerLvSnapshotPath, err := s.createSnapshotLV(poolName, fingerprint, storagePoolVolumeApiEndpointImages, containerLvmName, storagePoolVolumeApiEndpointContainers, false)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tryUndo {
+			s.ContainerDelete(container)
+		}
+	}()
+
+	containerMntPoint := getContainerMountPoint(s.pool.Name, containerName)
+	containerPath := container.Path()
+	err = os.MkdirAll(containerMntPoint, 0755)
+	if err != nil {
+		return err
+	}
+	err = createContainerMountpoint(containerMntPoint, containerPath, container.IsPrivileged())
+	if err != nil {
+		return err
+	}
+
+	// Generate a new xfs's UUID
+	lvFsType := s.getLvmFilesystem()
+	if lvFsType == "xfs" {
+		err := xfsGenerateNewUUID(containerLvSnapshotPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	ourMount, err := s.ContainerMount(containerName, containerPath)
+	if err != nil {
+		return err
+	}
+	if ourMount {
+		defer s.ContainerUmount(containerName, containerPath)
+	}
+
+	if container.IsPrivileged() {
+		err = os.Chmod(containerMntPoint, 0700)
+	} else {
+		err = os.Chmod(containerMntPoint, 0755)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !container.IsPrivileged() {
+		err := s.shiftRootfs(container)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = container.TemplateApply("create")
+	if err != nil {
+		shared.LogErrorf("Error in create template during ContainerCreateFromImage, continuing to unmount: %s.", err)
+		return err
+	}
+
+	tryUndo = false
+
+	shared.LogDebugf("Created LVM storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageLvm) ContainerCanRestore(container container, sourceContainer container) error {
+	return nil
+}
+
+func (s *storageLvm) ContainerDelete(container container) error {
+	shared.LogDebugf("Deleting LVM storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
 	containerName := container.Name()
 	containerLvmName := containerNameToLVName(containerName)
 	containerMntPoint := ""
@@ -1518,6 +2018,14 @@ func (s *storageLvm) ContainerSnapshotCreateEmpty(snapshotContainer container) e
 	return nil
 }
 
+func (s *storageLvm) ContainerBackupDump(container container, snapshots []container, target string) error {
+	return fmt.Errorf("Optimized backups are not supported by the LVM storage driver.")
+}
+
+func (s *storageLvm) ContainerBackupLoad(container container, snapshots []container, source string) error {
+	return fmt.Errorf("Optimized backups are not supported by the LVM storage driver.")
+}
+
 func (s *storageLvm) ImageCreate(fingerprint string) error {
 	shared.LogDebugf("Creating LVM storage volume for image \"%s\" on storage pool \"%s\".", fingerprint, s.pool.Name)
 
@@ -1660,6 +2168,64 @@ func (s *storageLvm) ImageUmount(fingerprint string) (bool, error) {
 	return true, nil
 }
 
+func (s *storageLvm) ImageVolumesGC(dryRun bool) ([]string, error) {
+	err := s.StoragePoolCheck()
+	if err != nil {
+		return nil, err
+	}
+
+	referenced, err := s.imageVolumesReferenced()
+	if err != nil {
+		return nil, err
+	}
+
+	poolName := s.getOnDiskPoolName()
+	output, err := exec.Command("lvs", "--noheadings", "-o", "lv_name", poolName).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list the logical volumes of \"%s\": %s", poolName, strings.TrimSpace(string(output)))
+	}
+
+	// Containers are thin snapshots of the image LV which don't prevent
+	// removing it.
+	orphans := []string{}
+	prefix := getPrefixedLvName(storagePoolVolumeApiEndpointImages, "")
+	for _, lvName := range strings.Fields(string(output)) {
+		if !strings.HasPrefix(lvName, prefix) {
+			continue
+		}
+
+		fingerprint := strings.TrimPrefix(lvName, prefix)
+		if shared.StringInSlice(fingerprint, referenced) {
+			continue
+		}
+
+		orphans = append(orphans, fmt.Sprintf("images/%s", fingerprint))
+		if dryRun {
+			continue
+		}
+
+		_, err := s.ImageUmount(fingerprint)
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.removeLV(poolName, storagePoolVolumeApiEndpointImages, fingerprint)
+		if err != nil {
+			return nil, err
+		}
+
+		imageMntPoint := getImageMountPoint(s.pool.Name, fingerprint)
+		if shared.PathExists(imageMntPoint) {
+			err := os.Remove(imageMntPoint)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return orphans, nil
+}
+
 func (s *storageLvm) createThinLV(vgName string, thinPoolName string, lvName string, lvFsType string, lvSize string, volumeType string) error {
 	exists, err := storageLVMThinpoolExists(vgName, thinPoolName)
 	if err != nil {
@@ -1679,18 +2245,12 @@ func (s *storageLvm) createThinLV(vgName string, thinPoolName string, lvName str
 		}
 	}
 
-	lvmThinPoolPath := fmt.Sprintf("%s/%s", vgName, thinPoolName)
-	lvmPoolVolumeName := getPrefixedLvName(volumeType, lvName)
-	output, err := tryExec(
-		"lvcreate",
-		"--thin",
-		"-n", lvmPoolVolumeName,
-		"--virtualsize", lvSize+"B", lvmThinPoolPath)
+	err = s.createRawThinLV(vgName, thinPoolName, lvName, lvSize, volumeType)
 	if err != nil {
-		shared.LogErrorf("Could not create LV \"%s\": %s.", lvmPoolVolumeName, string(output))
-		return fmt.Errorf("Could not create thin LV named %s", lvmPoolVolumeName)
+		return err
 	}
 
+	var output []byte
 	fsPath := getLvmDevPath(vgName, volumeType, lvName)
 	switch lvFsType {
 	case "xfs":
@@ -1704,8 +2264,26 @@ func (s *storageLvm) createThinLV(vgName string, thinPoolName string, lvName str
 	}
 
 	if err != nil {
-		shared.LogErrorf("Filesystem creation failed: %s.", string(output))
-		return fmt.Errorf("Error making filesystem on image LV: %v", err)
+		shared.LogErrorf("Filesystem creation failed: %s.", string(output))
+		return fmt.Errorf("Error making filesystem on image LV: %v", err)
+	}
+
+	return nil
+}
+
+// createRawThinLV creates a thin LV in an existing thin pool without putting a
+// filesystem on it.
+func (s *storageLvm) createRawThinLV(vgName string, thinPoolName string, lvName string, lvSize string, volumeType string) error {
+	lvmThinPoolPath := fmt.Sprintf("%s/%s", vgName, thinPoolName)
+	lvmPoolVolumeName := getPrefixedLvName(volumeType, lvName)
+	output, err := tryExec(
+		"lvcreate",
+		"--thin",
+		"-n", lvmPoolVolumeName,
+		"--virtualsize", lvSize+"B", lvmThinPoolPath)
+	if err != nil {
+		shared.LogErrorf("Could not create LV \"%s\": %s.", lvmPoolVolumeName, string(output))
+		return fmt.Errorf("Could not create thin LV named %s", lvmPoolVolumeName)
 	}
 
 	return nil
@@ -1720,7 +2298,18 @@ func (s *storageLvm) createDefaultThinPool(vgName string, thinPoolName string, l
 	// Create the thin pool
 	lvmThinPool := fmt.Sprintf("%s/%s", vgName, thinPoolName)
 	var output []byte
-	if isRecent {
+	if s.pool.Config["size"] != "" {
+		size, parseErr := shared.ParseByteSizeString(s.pool.Config["size"])
+		if parseErr != nil {
+			return parseErr
+		}
+
+		output, err = tryExec(
+			"lvcreate",
+			"--poolmetadatasize", "1G",
+			"-L", fmt.Sprintf("%dB", size),
+			"--thinpool", lvmThinPool)
+	} else if isRecent {
 		output, err = tryExec(
 			"lvcreate",
 			"--poolmetadatasize", "1G",
@@ -1739,7 +2328,7 @@ func (s *storageLvm) createDefaultThinPool(vgName string, thinPoolName string, l
 		return fmt.Errorf("Could not create LVM thin pool named %s", thinPoolName)
 	}
 
-	if !isRecent {
+	if !isRecent && s.pool.Config["size"] == "" {
 		// Grow it to the maximum VG size (two step process required by old LVM)
 		output, err = tryExec("lvextend", "--alloc", "anywhere", "-l", "100%FREE", lvmThinPool)
 
@@ -1808,18 +2397,463 @@ func (s *storageLvm) renameLV(oldName string, newName string, volumeType string)
 	return string(output), err
 }
 
+// lvmMigrationChunkSize is the granularity at which logical volumes are
+// compared and transferred during migration.
+const lvmMigrationChunkSize = 1024 * 1024
+
+// lvmMigrationWriteDelta writes the block-level difference between the device
+// at devPath and the device at basePath to w. When basePath is empty the
+// device is compared against zeroes, which is what a fresh thin LV reads as.
+//
+// The stream starts with the size of the device as a big endian uint64,
+// followed by one record per differing chunk made of the offset (uint64),
+// the length (uint32) and the data of the chunk.
+func lvmMigrationWriteDelta(w io.Writer, devPath string, basePath string) error {
+	dev, err := os.Open(devPath)
+	if err != nil {
+		return err
+	}
+	defer dev.Close()
+
+	size, err := dev.Seek(0, 2)
+	if err != nil {
+		return err
+	}
+
+	_, err = dev.Seek(0, 0)
+	if err != nil {
+		return err
+	}
+
+	var base *os.File
+	if basePath != "" {
+		base, err = os.Open(basePath)
+		if err != nil {
+			return err
+		}
+		defer base.Close()
+	}
+
+	err = binary.Write(w, binary.BigEndian, uint64(size))
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, lvmMigrationChunkSize)
+	baseBuf := make([]byte, lvmMigrationChunkSize)
+	zeroBuf := make([]byte, lvmMigrationChunkSize)
+	for offset := int64(0); offset < size; offset += lvmMigrationChunkSize {
+		n, err := io.ReadFull(dev, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+
+		if n == 0 {
+			break
+		}
+
+		ref := zeroBuf[:n]
+		if base != nil {
+			m, err := base.ReadAt(baseBuf[:n], offset)
+			if err != nil && err != io.EOF {
+				return err
+			}
+
+			// Anything past the end of the base reads as zeroes.
+			copy(baseBuf[m:n], zeroBuf)
+			ref = baseBuf[:n]
+		}
+
+		if bytes.Equal(buf[:n], ref) {
+			continue
+		}
+
+		err = binary.Write(w, binary.BigEndian, uint64(offset))
+		if err != nil {
+			return err
+		}
+
+		err = binary.Write(w, binary.BigEndian, uint32(n))
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(buf[:n])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lvmMigrationReadSize reads the size header of a stream generated by
+// lvmMigrationWriteDelta.
+func lvmMigrationReadSize(r io.Reader) (int64, error) {
+	var size uint64
+	err := binary.Read(r, binary.BigEndian, &size)
+	if err != nil {
+		return -1, err
+	}
+
+	return int64(size), nil
+}
+
+// lvmMigrationApplyDelta applies the records of a stream generated by
+// lvmMigrationWriteDelta (past its size header) to w.
+func lvmMigrationApplyDelta(r io.Reader, w io.WriterAt) error {
+	buf := make([]byte, lvmMigrationChunkSize)
+	for {
+		var offset uint64
+		err := binary.Read(r, binary.BigEndian, &offset)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var length uint32
+		err = binary.Read(r, binary.BigEndian, &length)
+		if err != nil {
+			return err
+		}
+
+		if length > lvmMigrationChunkSize {
+			return fmt.Errorf("Invalid chunk length %d at offset %d", length, offset)
+		}
+
+		_, err = io.ReadFull(r, buf[:length])
+		if err != nil {
+			return err
+		}
+
+		_, err = w.WriteAt(buf[:length], int64(offset))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+type lvmMigrationSourceDriver struct {
+	container       container
+	snapshots       []container
+	lvm             *storageLvm
+	runningSnapName string
+	stoppedSnapName string
+	refreshSnapName string
+}
+
+func (s *lvmMigrationSourceDriver) Snapshots() []container {
+	return s.snapshots
+}
+
+func (s *lvmMigrationSourceDriver) send(conn *websocket.Conn, lvName string, parentLvName string, readWrapper func(io.ReadCloser) io.ReadCloser) error {
+	poolName := s.lvm.getOnDiskPoolName()
+	devPath := getLvmDevPath(poolName, storagePoolVolumeApiEndpointContainers, lvName)
+	err := storageLVActivate(devPath, true)
+	if err != nil {
+		return err
+	}
+
+	parentPath := ""
+	if parentLvName != "" {
+		parentPath = getLvmDevPath(poolName, storagePoolVolumeApiEndpointContainers, parentLvName)
+		err := storageLVActivate(parentPath, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	reader, writer := io.Pipe()
+	result := make(chan error, 1)
+	go func() {
+		err := lvmMigrationWriteDelta(writer, devPath, parentPath)
+		writer.CloseWithError(err)
+		result <- err
+	}()
+
+	readPipe := io.ReadCloser(reader)
+	if readWrapper != nil {
+		readPipe = readWrapper(reader)
+	}
+
+	<-shared.WebsocketSendStream(conn, readPipe, 4*1024*1024)
+
+	// Unblock the generator in case the websocket went away early.
+	reader.Close()
+
+	err = <-result
+	if err != nil {
+		shared.LogErrorf("Problem sending LV \"%s\": %s.", lvName, err)
+	}
+
+	return err
+}
+
+func (s *lvmMigrationSourceDriver) SendWhileRunning(conn *websocket.Conn, op *operation) error {
+	if s.container.IsSnapshot() {
+		lvName := containerNameToLVName(s.container.Name())
+		wrapper := StorageProgressReader(op, "fs_progress", s.container.Name())
+		return s.send(conn, lvName, "", wrapper)
+	}
+
+	lastSnap := s.refreshSnapName
+
+	for _, snap := range s.snapshots {
+		lvName := containerNameToLVName(snap.Name())
+
+		wrapper := StorageProgressReader(op, "fs_progress", snap.Name())
+		if err := s.send(conn, lvName, lastSnap, wrapper); err != nil {
+			return err
+		}
+
+		lastSnap = lvName
+	}
+
+	poolName := s.lvm.getOnDiskPoolName()
+	containerLvmName := containerNameToLVName(s.container.Name())
+	s.runningSnapName = fmt.Sprintf("%s-migration-send-%s", containerLvmName, uuid.NewRandom().String())
+	_, err := s.lvm.createSnapshotLV(poolName, containerLvmName, storagePoolVolumeApiEndpointContainers, s.runningSnapName, storagePoolVolumeApiEndpointContainers, true)
+	if err != nil {
+		s.runningSnapName = ""
+		return err
+	}
+
+	wrapper := StorageProgressReader(op, "fs_progress", s.container.Name())
+	if err := s.send(conn, s.runningSnapName, lastSnap, wrapper); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *lvmMigrationSourceDriver) SendAfterCheckpoint(conn *websocket.Conn) error {
+	poolName := s.lvm.getOnDiskPoolName()
+	containerLvmName := containerNameToLVName(s.container.Name())
+	s.stoppedSnapName = fmt.Sprintf("%s-migration-send-%s", containerLvmName, uuid.NewRandom().String())
+	_, err := s.lvm.createSnapshotLV(poolName, containerLvmName, storagePoolVolumeApiEndpointContainers, s.stoppedSnapName, storagePoolVolumeApiEndpointContainers, true)
+	if err != nil {
+		s.stoppedSnapName = ""
+		return err
+	}
+
+	if err := s.send(conn, s.stoppedSnapName, s.runningSnapName, nil); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *lvmMigrationSourceDriver) Cleanup() {
+	poolName := s.lvm.getOnDiskPoolName()
+
+	if s.stoppedSnapName != "" {
+		s.lvm.removeLV(poolName, storagePoolVolumeApiEndpointContainers, s.stoppedSnapName)
+	}
+
+	if s.runningSnapName != "" {
+		s.lvm.removeLV(poolName, storagePoolVolumeApiEndpointContainers, s.runningSnapName)
+	}
+}
+
+func (s *lvmMigrationSourceDriver) Refresh(snapshotNames []string) {
+	parent, snapshots := migrationRefreshSnapshots(s.snapshots, snapshotNames)
+	if parent != nil {
+		s.refreshSnapName = containerNameToLVName(parent.Name())
+	}
+
+	s.snapshots = snapshots
+}
+
 func (s *storageLvm) MigrationType() MigrationFSType {
-	return MigrationFSType_RSYNC
+	return MigrationFSType_LVM
 }
 
 func (s *storageLvm) PreservesInodes() bool {
-	return false
+	return true
 }
 
 func (s *storageLvm) MigrationSource(container container) (MigrationStorageSourceDriver, error) {
-	return rsyncMigrationSource(container)
+	/* If the container is a snapshot, let's just send that; we don't need
+	 * to send anything else, because that's all the user asked for.
+	 */
+	if container.IsSnapshot() {
+		return &lvmMigrationSourceDriver{container: container, lvm: s}, nil
+	}
+
+	snapshots, err := container.Snapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	/* Snapshots are sent from oldest to newest, each one as a delta
+	 * against the previous one, followed by the container itself.
+	 */
+	driver := lvmMigrationSourceDriver{
+		container: container,
+		snapshots: snapshots,
+		lvm:       s,
+	}
+
+	return &driver, nil
 }
 
-func (s *storageLvm) MigrationSink(live bool, container container, snapshots []*Snapshot, conn *websocket.Conn, srcIdmap *shared.IdmapSet, op *operation) error {
-	return rsyncMigrationSink(live, container, snapshots, conn, srcIdmap, op)
+func (s *storageLvm) MigrationSink(live bool, container container, snapshots []*Snapshot, conn *websocket.Conn, srcIdmap *shared.IdmapSet, op *operation, refresh bool) error {
+	poolName := s.getOnDiskPoolName()
+	thinPoolName := s.getLvmThinpoolName()
+	containerName := container.Name()
+	containerLvmName := containerNameToLVName(containerName)
+	containerLvmPath := getLvmDevPath(poolName, storagePoolVolumeApiEndpointContainers, containerLvmName)
+
+	/* The first volume we receive replaces the freshly formatted LV the
+	 * container was created with, every following one is applied on top
+	 * of what we already have.
+	 */
+	created := false
+	lvmRecvDelta := func(r io.Reader) error {
+		size, err := lvmMigrationReadSize(r)
+		if err != nil {
+			return err
+		}
+
+		if !created {
+			err := s.removeLV(poolName, storagePoolVolumeApiEndpointContainers, containerLvmName)
+			if err != nil {
+				return err
+			}
+
+			err = s.createRawThinLV(poolName, thinPoolName, containerLvmName, fmt.Sprintf("%d", size), storagePoolVolumeApiEndpointContainers)
+			if err != nil {
+				return err
+			}
+
+			created = true
+		}
+
+		dev, err := os.OpenFile(containerLvmPath, os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		defer dev.Close()
+
+		currentSize, err := dev.Seek(0, 2)
+		if err != nil {
+			return err
+		}
+
+		// The source volume got bigger since the last snapshot.
+		if size > currentSize {
+			dev.Close()
+
+			output, err := tryExec("lvextend", "-L", fmt.Sprintf("%dB", size), containerLvmPath)
+			if err != nil {
+				return fmt.Errorf("Could not extend LV \"%s\": %s", containerLvmName, string(output))
+			}
+
+			dev, err = os.OpenFile(containerLvmPath, os.O_WRONLY, 0)
+			if err != nil {
+				return err
+			}
+			defer dev.Close()
+		}
+
+		err = lvmMigrationApplyDelta(r, dev)
+		if err != nil {
+			return err
+		}
+
+		return dev.Sync()
+	}
+
+	lvmRecv := func(writeWrapper func(io.WriteCloser) io.WriteCloser) error {
+		reader, writer := io.Pipe()
+		result := make(chan error, 1)
+		go func() {
+			err := lvmRecvDelta(reader)
+			// Make sure the websocket reader doesn't block on a
+			// pipe nobody reads from anymore.
+			reader.CloseWithError(err)
+			result <- err
+		}()
+
+		writePipe := io.WriteCloser(writer)
+		if writeWrapper != nil {
+			writePipe = writeWrapper(writer)
+		}
+
+		<-shared.WebsocketRecvStream(writePipe, conn)
+		writer.Close()
+
+		err := <-result
+		if err != nil {
+			shared.LogErrorf("Problem receiving LV \"%s\": %s.", containerLvmName, err)
+		}
+
+		return err
+	}
+
+	/* We write to the block device directly, so make sure nothing has it
+	 * mounted while we do.
+	 */
+	_, err := s.ContainerUmount(containerName, container.Path())
+	if err != nil {
+		return err
+	}
+
+	/* When refreshing, the source sends deltas against the newest snapshot
+	 * we already have, so start over from that one.
+	 */
+	if refresh {
+		existingSnapshots, err := container.Snapshots()
+		if err != nil {
+			return err
+		}
+
+		if len(existingSnapshots) > 0 {
+			parent := existingSnapshots[len(existingSnapshots)-1]
+			parentLvmName := containerNameToLVName(parent.Name())
+
+			err := s.removeLV(poolName, storagePoolVolumeApiEndpointContainers, containerLvmName)
+			if err != nil {
+				return err
+			}
+
+			_, err = s.createSnapshotLV(poolName, parentLvmName, storagePoolVolumeApiEndpointContainers, containerLvmName, storagePoolVolumeApiEndpointContainers, false)
+			if err != nil {
+				return err
+			}
+
+			created = true
+		}
+	}
+
+	for _, snap := range snapshots {
+		args := migrationSnapshotArgs(container, snap)
+
+		wrapper := StorageProgressWriter(op, "fs_progress", snap.GetName())
+		if err := lvmRecv(wrapper); err != nil {
+			return err
+		}
+
+		_, err := containerCreateAsSnapshot(container.Daemon(), args, container)
+		if err != nil {
+			return err
+		}
+	}
+
+	/* finally, do the real container */
+	wrapper := StorageProgressWriter(op, "fs_progress", containerName)
+	if err := lvmRecv(wrapper); err != nil {
+		return err
+	}
+
+	if live {
+		/* and again for the post-running snapshot if this was a live migration */
+		wrapper := StorageProgressWriter(op, "fs_progress", containerName)
+		if err := lvmRecv(wrapper); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
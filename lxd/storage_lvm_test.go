@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// lvmTestVolume writes data to a temporary file standing in for a LV.
+func lvmTestVolume(t *testing.T, data []byte) string {
+	f, err := ioutil.TempFile("", "lxd_test_lvm_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return f.Name()
+}
+
+func Test_lvm_migration_delta_roundtrip(t *testing.T) {
+	size := 3*lvmMigrationChunkSize + 512
+
+	base := make([]byte, size)
+	for i := range base {
+		base[i] = byte(i % 251)
+	}
+
+	target := make([]byte, size)
+	copy(target, base)
+	target[lvmMigrationChunkSize+10] ^= 0xff
+	target[size-1] ^= 0xff
+
+	basePath := lvmTestVolume(t, base)
+	defer os.Remove(basePath)
+
+	targetPath := lvmTestVolume(t, target)
+	defer os.Remove(targetPath)
+
+	buf := bytes.Buffer{}
+	err := lvmMigrationWriteDelta(&buf, targetPath, basePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the two modified chunks should have been sent.
+	expected := 8 + 2*12 + lvmMigrationChunkSize + 512
+	if buf.Len() != expected {
+		t.Fatalf("Unexpected delta size %d, expected %d", buf.Len(), expected)
+	}
+
+	readSize, err := lvmMigrationReadSize(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if readSize != int64(size) {
+		t.Fatalf("Unexpected volume size %d", readSize)
+	}
+
+	resultPath := lvmTestVolume(t, base)
+	defer os.Remove(resultPath)
+
+	result, err := os.OpenFile(resultPath, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = lvmMigrationApplyDelta(&buf, result)
+	result.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(resultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data, target) {
+		t.Fatal("Applied delta doesn't match the source volume")
+	}
+}
+
+func Test_lvm_migration_delta_skips_zeroes(t *testing.T) {
+	data := make([]byte, 2*lvmMigrationChunkSize)
+	data[lvmMigrationChunkSize] = 1
+
+	path := lvmTestVolume(t, data)
+	defer os.Remove(path)
+
+	buf := bytes.Buffer{}
+	err := lvmMigrationWriteDelta(&buf, path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := 8 + 12 + lvmMigrationChunkSize
+	if buf.Len() != expected {
+		t.Fatalf("Unexpected delta size %d, expected %d", buf.Len(), expected)
+	}
+}
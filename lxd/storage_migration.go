@@ -27,6 +27,35 @@ type MigrationStorageSourceDriver interface {
 	 * to clean up any temporary snapshots, etc.
 	 */
 	Cleanup()
+
+	/* Called when refreshing an existing container on the target. Only
+	 * the named snapshots (the newest ones) are to be sent, everything
+	 * older is already present on the target and can be used as the base
+	 * of incremental transfers.
+	 */
+	Refresh(snapshotNames []string)
+}
+
+/* migrationRefreshSnapshots splits the snapshots of a container being
+ * refreshed into the newest one the target already has (nil if there is none)
+ * and the ones which still need to be sent.
+ */
+func migrationRefreshSnapshots(snapshots []container, snapshotNames []string) (container, []container) {
+	var parent container
+	send := []container{}
+
+	for _, snap := range snapshots {
+		if !shared.StringInSlice(shared.ExtractSnapshotName(snap.Name()), snapshotNames) {
+			if len(send) == 0 {
+				parent = snap
+			}
+			continue
+		}
+
+		send = append(send, snap)
+	}
+
+	return parent, send
 }
 
 type rsyncStorageSourceDriver struct {
@@ -34,11 +63,11 @@ type rsyncStorageSourceDriver struct {
 	snapshots []container
 }
 
-func (s rsyncStorageSourceDriver) Snapshots() []container {
+func (s *rsyncStorageSourceDriver) Snapshots() []container {
 	return s.snapshots
 }
 
-func (s rsyncStorageSourceDriver) SendWhileRunning(conn *websocket.Conn, op *operation) error {
+func (s *rsyncStorageSourceDriver) SendWhileRunning(conn *websocket.Conn, op *operation) error {
 	for _, send := range s.snapshots {
 		if err := send.StorageStart(); err != nil {
 			return err
@@ -56,22 +85,29 @@ func (s rsyncStorageSourceDriver) SendWhileRunning(conn *websocket.Conn, op *ope
 	return RsyncSend(shared.AddSlash(s.container.Path()), conn, wrapper)
 }
 
-func (s rsyncStorageSourceDriver) SendAfterCheckpoint(conn *websocket.Conn) error {
+func (s *rsyncStorageSourceDriver) SendAfterCheckpoint(conn *websocket.Conn) error {
 	/* resync anything that changed between our first send and the checkpoint */
 	return RsyncSend(shared.AddSlash(s.container.Path()), conn, nil)
 }
 
-func (s rsyncStorageSourceDriver) Cleanup() {
+func (s *rsyncStorageSourceDriver) Cleanup() {
 	/* no-op */
 }
 
+func (s *rsyncStorageSourceDriver) Refresh(snapshotNames []string) {
+	/* rsync only transfers what changed anyway, so all we need to do is
+	 * skip the snapshots the target already has.
+	 */
+	_, s.snapshots = migrationRefreshSnapshots(s.snapshots, snapshotNames)
+}
+
 func rsyncMigrationSource(container container) (MigrationStorageSourceDriver, error) {
 	snapshots, err := container.Snapshots()
 	if err != nil {
 		return nil, err
 	}
 
-	return rsyncStorageSourceDriver{container, snapshots}, nil
+	return &rsyncStorageSourceDriver{container, snapshots}, nil
 }
 
 func snapshotProtobufToContainerArgs(containerName string, snap *Snapshot) containerArgs {
@@ -104,7 +140,31 @@ func snapshotProtobufToContainerArgs(containerName string, snap *Snapshot) conta
 	}
 }
 
-func rsyncMigrationSink(live bool, container container, snapshots []*Snapshot, conn *websocket.Conn, srcIdmap *shared.IdmapSet, op *operation) error {
+// migrationSnapshotArgs returns the arguments to create a received snapshot of
+// the given container with. Snapshots always live on the storage pool of their
+// container so a local root disk device is pointed at that pool.
+func migrationSnapshotArgs(container container, snap *Snapshot) containerArgs {
+	args := snapshotProtobufToContainerArgs(container.Name(), snap)
+
+	_, poolName := container.Storage().GetContainerPoolInfo()
+	for k, v := range args.Devices {
+		if v["type"] == "disk" && v["path"] == "/" {
+			args.Devices[k]["pool"] = poolName
+		}
+	}
+
+	return args
+}
+
+func rsyncMigrationSink(live bool, container container, snapshots []*Snapshot, conn *websocket.Conn, srcIdmap *shared.IdmapSet, op *operation, refresh bool) error {
+	/* When refreshing an existing container, anything which got removed on
+	 * the source since the last copy has to go away on the target too.
+	 */
+	recv := RsyncRecv
+	if refresh {
+		recv = RsyncRecvDelete
+	}
+
 	if err := container.StorageStart(); err != nil {
 		return err
 	}
@@ -113,15 +173,7 @@ func rsyncMigrationSink(live bool, container container, snapshots []*Snapshot, c
 	isDirBackend := container.Storage().GetStorageType() == storageTypeDir
 	if isDirBackend {
 		for _, snap := range snapshots {
-			args := snapshotProtobufToContainerArgs(container.Name(), snap)
-			// Unset the pool of the orginal container and let
-			// containerLXCCreate figure out on which pool to  send
-			// it. Later we might make this more flexible.
-			for k, v := range args.Devices {
-				if v["type"] == "disk" && v["path"] == "/" {
-					args.Devices[k]["pool"] = ""
-				}
-			}
+			args := migrationSnapshotArgs(container, snap)
 			s, err := containerCreateEmptySnapshot(container.Daemon(), args)
 			if err != nil {
 				return err
@@ -138,22 +190,14 @@ func rsyncMigrationSink(live bool, container container, snapshots []*Snapshot, c
 		}
 
 		wrapper := StorageProgressWriter(op, "fs_progress", container.Name())
-		if err := RsyncRecv(shared.AddSlash(container.Path()), conn, wrapper); err != nil {
+		if err := recv(shared.AddSlash(container.Path()), conn, wrapper); err != nil {
 			return err
 		}
 	} else {
 		for _, snap := range snapshots {
-			args := snapshotProtobufToContainerArgs(container.Name(), snap)
-			// Unset the pool of the orginal container and let
-			// containerLXCCreate figure out on which pool to  send
-			// it. Later we might make this more flexible.
-			for k, v := range args.Devices {
-				if v["type"] == "disk" && v["path"] == "/" {
-					args.Devices[k]["pool"] = ""
-				}
-			}
+			args := migrationSnapshotArgs(container, snap)
 			wrapper := StorageProgressWriter(op, "fs_progress", snap.GetName())
-			if err := RsyncRecv(shared.AddSlash(container.Path()), conn, wrapper); err != nil {
+			if err := recv(shared.AddSlash(container.Path()), conn, wrapper); err != nil {
 				return err
 			}
 
@@ -168,7 +212,7 @@ func rsyncMigrationSink(live bool, container container, snapshots []*Snapshot, c
 		}
 
 		wrapper := StorageProgressWriter(op, "fs_progress", container.Name())
-		if err := RsyncRecv(shared.AddSlash(container.Path()), conn, wrapper); err != nil {
+		if err := recv(shared.AddSlash(container.Path()), conn, wrapper); err != nil {
 			return err
 		}
 	}
@@ -176,7 +220,7 @@ func rsyncMigrationSink(live bool, container container, snapshots []*Snapshot, c
 	if live {
 		/* now receive the final sync */
 		wrapper := StorageProgressWriter(op, "fs_progress", container.Name())
-		if err := RsyncRecv(shared.AddSlash(container.Path()), conn, wrapper); err != nil {
+		if err := recv(shared.AddSlash(container.Path()), conn, wrapper); err != nil {
 			return err
 		}
 	}
@@ -75,6 +75,14 @@ func (s *storageMock) SetStoragePoolVolumeWritable(writable *api.StorageVolumePu
 	s.volume.StorageVolumePut = *writable
 }
 
+func (s *storageMock) StoragePoolResources() (*api.ResourcesStoragePool, error) {
+	return &api.ResourcesStoragePool{}, nil
+}
+
+func (s *storageMock) StoragePoolVolumesCheck(repair bool) ([]storageCheckIssue, error) {
+	return []storageCheckIssue{}, nil
+}
+
 func (s *storageMock) GetContainerPoolInfo() (int64, string) {
 	return s.poolID, s.pool.Name
 }
@@ -99,6 +107,34 @@ func (s *storageMock) StoragePoolVolumeUpdate(changedConfig []string) error {
 	return nil
 }
 
+func (s *storageMock) StoragePoolVolumeGetUsage() (int64, error) {
+	return 0, nil
+}
+
+func (s *storageMock) StoragePoolVolumeRename(newName string) error {
+	return nil
+}
+
+func (s *storageMock) StoragePoolVolumeCopy(source *api.StorageVolumeSource) error {
+	return nil
+}
+
+func (s *storageMock) StoragePoolVolumeSnapshotCreate(snapshotName string) error {
+	return nil
+}
+
+func (s *storageMock) StoragePoolVolumeSnapshotDelete(snapshotName string) error {
+	return nil
+}
+
+func (s *storageMock) StoragePoolVolumeSnapshotRename(snapshotName string, newName string) error {
+	return nil
+}
+
+func (s *storageMock) StoragePoolVolumeSnapshotRestore(snapshotName string) error {
+	return nil
+}
+
 func (s *storageMock) StoragePoolUpdate(changedConfig []string) error {
 	return nil
 }
@@ -189,6 +225,14 @@ func (s *storageMock) ContainerSnapshotCreateEmpty(snapshotContainer container)
 	return nil
 }
 
+func (s *storageMock) ContainerBackupDump(container container, snapshots []container, target string) error {
+	return nil
+}
+
+func (s *storageMock) ContainerBackupLoad(container container, snapshots []container, source string) error {
+	return nil
+}
+
 func (s *storageMock) ImageCreate(fingerprint string) error {
 	return nil
 }
@@ -205,6 +249,10 @@ func (s *storageMock) ImageUmount(fingerprint string) (bool, error) {
 	return true, nil
 }
 
+func (s *storageMock) ImageVolumesGC(dryRun bool) ([]string, error) {
+	return []string{}, nil
+}
+
 func (s *storageMock) MigrationType() MigrationFSType {
 	return MigrationFSType_RSYNC
 }
@@ -216,6 +264,6 @@ func (s *storageMock) PreservesInodes() bool {
 func (s *storageMock) MigrationSource(container container) (MigrationStorageSourceDriver, error) {
 	return nil, fmt.Errorf("not implemented")
 }
-func (s *storageMock) MigrationSink(live bool, container container, snapshots []*Snapshot, conn *websocket.Conn, srcIdmap *shared.IdmapSet, op *operation) error {
+func (s *storageMock) MigrationSink(live bool, container container, snapshots []*Snapshot, conn *websocket.Conn, srcIdmap *shared.IdmapSet, op *operation, refresh bool) error {
 	return nil
 }
@@ -304,7 +304,7 @@ func storagePoolDelete(d *Daemon, r *http.Request) Response {
 
 	// In case we deleted the default storage pool, try to update the
 	// default profile.
-	defaultID, defaultProfile, err := dbProfileGet(d.db, "default")
+	defaultID, defaultProfile, err := dbProfileGet(d.db, projectDefault, "default")
 	if err != nil {
 		return EmptySyncResponse
 	}
@@ -348,3 +348,48 @@ func storagePoolDelete(d *Daemon, r *http.Request) Response {
 }
 
 var storagePoolCmd = Command{name: "storage-pools/{name}", get: storagePoolGet, put: storagePoolPut, patch: storagePoolPatch, delete: storagePoolDelete}
+
+// /1.0/storage-pools/{name}/gc
+// Find the image volumes of a storage pool which are neither referenced in
+// the database nor used by any container and reclaim them.
+func storagePoolGCPost(d *Daemon, r *http.Request) Response {
+	poolName := mux.Vars(r)["name"]
+
+	req := api.StoragePoolGCPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	s, err := storagePoolInit(d, poolName)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	run := func(op *operation) error {
+		volumes, err := s.ImageVolumesGC(req.DryRun)
+		if err != nil {
+			return err
+		}
+
+		// Mirrors api.StoragePoolGC, operation metadata must be a map.
+		metadata := map[string]interface{}{
+			"volumes":   volumes,
+			"reclaimed": !req.DryRun,
+		}
+
+		return op.UpdateMetadata(metadata)
+	}
+
+	resources := map[string][]string{}
+	resources["storage-pools"] = []string{poolName}
+
+	op, err := operationCreate(operationClassTask, resources, nil, run, nil, nil)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return OperationResponse(op)
+}
+
+var storagePoolGCCmd = Command{name: "storage-pools/{name}/gc", post: storagePoolGCPost}
@@ -36,6 +36,18 @@ var storagePoolConfigKeys = map[string]func(value string) error{
 	"lvm.thinpool_name":           shared.IsAny,
 	"lvm.vg_name":                 shared.IsAny,
 	"zfs.pool_name":               shared.IsAny,
+	"ceph.cluster_name":           shared.IsAny,
+	"ceph.user.name":              shared.IsAny,
+	"ceph.osd.pool_name":          shared.IsAny,
+	"ceph.osd.pg_num": func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		_, err := strconv.ParseUint(value, 10, 64)
+		return err
+	},
+	"ceph.osd.force_reuse": shared.IsBool,
 }
 
 func storagePoolValidateConfig(name string, driver string, config map[string]string) error {
@@ -77,6 +89,14 @@ func storagePoolValidateConfig(name string, driver string, config map[string]str
 			}
 		}
 
+		if driver != "ceph" {
+			for _, key := range []string{"ceph.cluster_name", "ceph.user.name", "ceph.osd.pool_name", "ceph.osd.pg_num", "ceph.osd.force_reuse"} {
+				if config[key] != "" {
+					return fmt.Errorf("The key %s cannot be used with non ceph storage pools.", key)
+				}
+			}
+		}
+
 		if driver == "dir" {
 			if config["size"] != "" {
 				return fmt.Errorf("The key size cannot be used with dir storage pools.")
@@ -101,7 +121,7 @@ func storagePoolValidateConfig(name string, driver string, config map[string]str
 
 func storagePoolFillDefault(name string, driver string, config map[string]string) error {
 	if driver != "dir" {
-		if driver != "lvm" && config["size"] == "" {
+		if driver != "lvm" && driver != "ceph" && config["size"] == "" {
 			st := syscall.Statfs_t{}
 			err := syscall.Statfs(shared.VarPath(), &st)
 			if err != nil {
@@ -139,5 +159,34 @@ func storagePoolFillDefault(name string, driver string, config map[string]string
 		}
 	}
 
+	if driver == "ceph" {
+		if config["ceph.cluster_name"] == "" {
+			config["ceph.cluster_name"] = "ceph"
+		}
+
+		if config["ceph.user.name"] == "" {
+			config["ceph.user.name"] = "admin"
+		}
+
+		if config["ceph.osd.pool_name"] == "" {
+			config["ceph.osd.pool_name"] = config["source"]
+		}
+
+		if config["ceph.osd.pool_name"] == "" {
+			config["ceph.osd.pool_name"] = name
+		}
+
+		if config["ceph.osd.pg_num"] == "" {
+			config["ceph.osd.pg_num"] = "32"
+		}
+
+		if config["volume.size"] != "" {
+			_, err := shared.ParseByteSizeString(config["volume.size"])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
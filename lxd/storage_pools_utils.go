@@ -42,6 +42,12 @@ func storagePoolUpdate(d *Daemon, name string, newConfig map[string]string) erro
 		return nil
 	}
 
+	newWritable.Config = newConfig
+
+	// Apply the new configuration so that the storage driver sees the
+	// new values (e.g. the new size) when updating the pool.
+	s.SetStoragePoolWritable(&newWritable)
+
 	// Update the storage pool
 	if !userOnly {
 		if shared.StringInSlice("driver", changedConfig) {
@@ -54,11 +60,6 @@ func storagePoolUpdate(d *Daemon, name string, newConfig map[string]string) erro
 		}
 	}
 
-	newWritable.Config = newConfig
-
-	// Apply the new configuration
-	s.SetStoragePoolWritable(&newWritable)
-
 	// Update the database
 	err = dbStoragePoolUpdate(d.db, name, newConfig)
 	if err != nil {
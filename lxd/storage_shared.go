@@ -116,6 +116,12 @@ func (s *storageShared) createImageDbPoolVolume(fingerprint string) error {
 	return nil
 }
 
+// imageVolumesReferenced returns the fingerprints of the images the database
+// knows to have a storage volume on the storage pool.
+func (s *storageShared) imageVolumesReferenced() ([]string, error) {
+	return dbStoragePoolVolumesGetType(s.d.db, storagePoolVolumeTypeImage, s.poolID)
+}
+
 func (s *storageShared) deleteImageDbPoolVolume(fingerprint string) error {
 	err := dbStoragePoolVolumeDelete(s.d.db, fingerprint, storagePoolVolumeTypeImage, s.poolID)
 	if err != nil {
@@ -1,9 +1,15 @@
 package main
 
 import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
+	"syscall"
 
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 )
 
 func storageValidName(value string) error {
@@ -44,3 +50,56 @@ func storageConfigDiff(oldConfig map[string]string, newConfig map[string]string)
 
 	return changedConfig, userOnly
 }
+
+// storageResource returns the space and inodes used on the filesystem backing
+// the given path.
+func storageResource(path string) (*api.ResourcesStoragePool, error) {
+	st := syscall.Statfs_t{}
+	err := syscall.Statfs(path, &st)
+	if err != nil {
+		return nil, err
+	}
+
+	res := api.ResourcesStoragePool{}
+	res.Space.Total = st.Blocks * uint64(st.Bsize)
+	res.Space.Used = (st.Blocks - st.Bfree) * uint64(st.Bsize)
+	res.Inodes.Total = st.Files
+	res.Inodes.Used = st.Files - st.Ffree
+
+	return &res, nil
+}
+
+// storageLoopFilePath returns the path of the loop file LXD creates for a
+// storage pool which was created without a source.
+func storageLoopFilePath(poolName string) string {
+	return filepath.Join(shared.VarPath("disks"), fmt.Sprintf("%s.img", poolName))
+}
+
+// storageLoopFileResize resizes the loop file at path and makes the kernel
+// pick up the new size on every loop device it's attached to.
+func storageLoopFileResize(path string, size int64) error {
+	err := os.Truncate(path, size)
+	if err != nil {
+		return fmt.Errorf("Failed to resize %s: %s", path, err)
+	}
+
+	output, err := exec.Command("losetup", "-j", path).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Failed to find the loop devices for %s: %s", path, output)
+	}
+
+	// "/dev/loop0: []: (/var/lib/lxd/disks/default.img)"
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.SplitN(line, ":", 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		output, err := exec.Command("losetup", "-c", fields[0]).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("Failed to refresh the size of %s: %s", fields[0], output)
+		}
+	}
+
+	return nil
+}
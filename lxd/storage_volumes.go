@@ -1,12 +1,18 @@
 package main
 
 import (
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	log "gopkg.in/inconshreveable/log15.v2"
+
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/version"
@@ -37,21 +43,63 @@ func storagePoolVolumesGet(d *Daemon, r *http.Request) Response {
 		return SmartError(err)
 	}
 
+	// Custom storage volumes are only listed for the project they
+	// belong to.
+	project := projectParam(r)
+	customVolumes, err := dbStoragePoolVolumesGetTypeProject(d.db, project, storagePoolVolumeTypeCustom, poolID)
+	if err != nil {
+		return SmartError(err)
+	}
+
 	resultString := []string{}
+	resultMap := []*api.StorageVolume{}
 	for _, volume := range volumes {
+		// Snapshots of custom storage volumes are listed separately.
+		if volume.Type == storagePoolVolumeTypeNameCustom && shared.IsSnapshot(volume.Name) {
+			continue
+		}
+
+		volumeName := volume.Name
+		if volume.Type == storagePoolVolumeTypeNameCustom {
+			if !shared.StringInSlice(volume.Name, customVolumes) {
+				continue
+			}
+
+			volume.Name = strings.TrimPrefix(volume.Name, projectPrefix(project, ""))
+		}
+
 		apiEndpoint, err := storagePoolVolumeTypeNameToApiEndpoint(volume.Type)
 		if err != nil {
 			return InternalError(err)
 		}
 
 		if recursion == 0 {
-			resultString = append(resultString, fmt.Sprintf("/%s/storage-pools/%s/volumes/%s/%s", version.APIVersion, poolName, apiEndpoint, volume.Name))
+			url := fmt.Sprintf("/%s/storage-pools/%s/volumes/%s/%s", version.APIVersion, poolName, apiEndpoint, volume.Name)
+			if volume.Type == storagePoolVolumeTypeNameCustom {
+				url += projectQuery(project)
+			}
+
+			resultString = append(resultString, url)
 		} else {
-			volumeUsedBy, err := storagePoolVolumeUsedByGet(d, volume.Name, volume.Type)
+			volumeUsedBy, err := storagePoolVolumeUsedByGet(d, volumeName, volume.Type)
 			if err != nil {
 				return InternalError(err)
 			}
 			volume.UsedBy = volumeUsedBy
+
+			if volume.Type == storagePoolVolumeTypeNameCustom {
+				volumeID, err := dbStoragePoolVolumeGetTypeID(d.db, volumeName, storagePoolVolumeTypeCustom, poolID)
+				if err != nil {
+					return SmartError(err)
+				}
+
+				volume.Attachments, err = storagePoolVolumeAttachmentsGet(d, volumeID)
+				if err != nil {
+					return InternalError(err)
+				}
+			}
+
+			resultMap = append(resultMap, volume)
 		}
 	}
 
@@ -59,7 +107,7 @@ func storagePoolVolumesGet(d *Daemon, r *http.Request) Response {
 		return SyncResponse(true, resultString)
 	}
 
-	return SyncResponse(true, volumes)
+	return SyncResponse(true, resultMap)
 }
 
 var storagePoolVolumesCmd = Command{name: "storage-pools/{name}/volumes", get: storagePoolVolumesGet}
@@ -98,8 +146,15 @@ func storagePoolVolumesTypeGet(d *Daemon, r *http.Request) Response {
 	}
 
 	// Get the names of all storage volumes of a given volume type currently
-	// attached to the storage pool.
-	volumes, err := dbStoragePoolVolumesGetType(d.db, volumeType, poolID)
+	// attached to the storage pool. Custom storage volumes are restricted
+	// to those of the requested project.
+	project := projectParam(r)
+	var volumes []string
+	if volumeType == storagePoolVolumeTypeCustom {
+		volumes, err = dbStoragePoolVolumesGetTypeProject(d.db, project, volumeType, poolID)
+	} else {
+		volumes, err = dbStoragePoolVolumesGetType(d.db, volumeType, poolID)
+	}
 	if err != nil {
 		return InternalError(err)
 	}
@@ -107,14 +162,24 @@ func storagePoolVolumesTypeGet(d *Daemon, r *http.Request) Response {
 	resultString := []string{}
 	resultMap := []*api.StorageVolume{}
 	for _, volume := range volumes {
+		// Snapshots of custom storage volumes are listed separately.
+		if volumeType == storagePoolVolumeTypeCustom && shared.IsSnapshot(volume) {
+			continue
+		}
+
 		if recursion == 0 {
 			apiEndpoint, err := storagePoolVolumeTypeToApiEndpoint(volumeType)
 			if err != nil {
 				return InternalError(err)
 			}
-			resultString = append(resultString, fmt.Sprintf("/%s/storage-pools/%s/volumes/%s/%s", version.APIVersion, poolName, apiEndpoint, volume))
+			query := ""
+			if volumeType == storagePoolVolumeTypeCustom {
+				volume = strings.TrimPrefix(volume, projectPrefix(project, ""))
+				query = projectQuery(project)
+			}
+			resultString = append(resultString, fmt.Sprintf("/%s/storage-pools/%s/volumes/%s/%s%s", version.APIVersion, poolName, apiEndpoint, volume, query))
 		} else {
-			_, vol, err := dbStoragePoolVolumeGetType(d.db, volume, volumeType, poolID)
+			volumeID, vol, err := dbStoragePoolVolumeGetType(d.db, volume, volumeType, poolID)
 			if err != nil {
 				continue
 			}
@@ -125,6 +190,15 @@ func storagePoolVolumesTypeGet(d *Daemon, r *http.Request) Response {
 			}
 			vol.UsedBy = volumeUsedBy
 
+			vol.Attachments, err = storagePoolVolumeAttachmentsGet(d, volumeID)
+			if err != nil {
+				return InternalError(err)
+			}
+
+			if volumeType == storagePoolVolumeTypeCustom {
+				vol.Name = strings.TrimPrefix(vol.Name, projectPrefix(project, ""))
+			}
+
 			resultMap = append(resultMap, vol)
 		}
 	}
@@ -139,7 +213,7 @@ func storagePoolVolumesTypeGet(d *Daemon, r *http.Request) Response {
 // /1.0/storage-pools/{name}/volumes/{type}
 // Create a storage volume of a given volume type in a given storage pool.
 func storagePoolVolumesTypePost(d *Daemon, r *http.Request) Response {
-	req := api.StorageVolume{}
+	req := api.StorageVolumesPost{}
 
 	// Parse the request.
 	err := json.NewDecoder(r.Body).Decode(&req)
@@ -159,6 +233,19 @@ func storagePoolVolumesTypePost(d *Daemon, r *http.Request) Response {
 		return BadRequest(err)
 	}
 
+	// The snapshot delimiter is reserved for storage volume snapshots.
+	if shared.IsSnapshot(req.Name) {
+		return BadRequest(fmt.Errorf("Storage volume names may not contain slashes."))
+	}
+
+	// Custom storage volumes of a project other than the default one are
+	// stored under a name prefixed with the project.
+	project := projectParam(r)
+	req.Name = projectPrefix(project, req.Name)
+	if req.Source.Type == "copy" && req.Source.Name != "" {
+		req.Source.Name = projectPrefix(project, req.Source.Name)
+	}
+
 	// Check that the user gave use a storage volume type for the storage
 	// volume we are about to create.
 	if req.Type == "" {
@@ -200,8 +287,21 @@ func storagePoolVolumesTypePost(d *Daemon, r *http.Request) Response {
 		req.Config = map[string]string{}
 	}
 
+	switch req.Source.Type {
+	case "":
+		return doVolumeCreate(d, project, poolName, poolID, poolStruct, volumeType, &req)
+	case "copy":
+		return doVolumeCopy(d, project, poolName, poolID, poolStruct, volumeType, &req)
+	case "migration":
+		return doVolumeMigration(d, project, poolName, poolID, poolStruct, volumeType, &req)
+	default:
+		return BadRequest(fmt.Errorf("Unknown source type %s", req.Source.Type))
+	}
+}
+
+func doVolumeCreate(d *Daemon, project string, poolName string, poolID int64, poolStruct *api.StoragePool, volumeType int, req *api.StorageVolumesPost) Response {
 	// Validate the requested storage volume configuration.
-	err = storageVolumeValidateConfig(poolName, req.Config, poolStruct)
+	err := storageVolumeValidateConfig(poolName, req.Config, poolStruct)
 	if err != nil {
 		return BadRequest(err)
 	}
@@ -212,11 +312,17 @@ func storagePoolVolumesTypePost(d *Daemon, r *http.Request) Response {
 	}
 
 	// Create the database entry for the storage volume.
-	_, err = dbStoragePoolVolumeCreate(d.db, req.Name, volumeType, poolID, req.Config)
+	volumeID, err := dbStoragePoolVolumeCreate(d.db, req.Name, volumeType, poolID, req.Config)
 	if err != nil {
 		return InternalError(fmt.Errorf("Error inserting %s of type %s into database: %s", poolName, req.Type, err))
 	}
 
+	err = dbStoragePoolVolumeProjectSet(d.db, volumeID, project)
+	if err != nil {
+		dbStoragePoolVolumeDelete(d.db, req.Name, volumeType, poolID)
+		return InternalError(err)
+	}
+
 	s, err := storagePoolVolumeInit(d, poolName, req.Name, volumeType)
 	if err != nil {
 		return InternalError(err)
@@ -236,6 +342,180 @@ func storagePoolVolumesTypePost(d *Daemon, r *http.Request) Response {
 	return SyncResponseLocation(true, nil, fmt.Sprintf("/%s/storage-pools/%s/volumes/%s", version.APIVersion, poolName, apiEndpoint))
 }
 
+func doVolumeCopy(d *Daemon, project string, poolName string, poolID int64, poolStruct *api.StoragePool, volumeType int, req *api.StorageVolumesPost) Response {
+	if req.Source.Name == "" {
+		return BadRequest(fmt.Errorf("must specify a source storage volume"))
+	}
+
+	if req.Source.Pool == "" {
+		req.Source.Pool = poolName
+	}
+
+	// Check that the source storage volume exists.
+	srcPoolID, err := dbStoragePoolGetID(d.db, req.Source.Pool)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	_, srcVolume, err := dbStoragePoolVolumeGetType(d.db, req.Source.Name, volumeType, srcPoolID)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	// Copies within the same storage pool inherit the configuration of
+	// the source volume unless told otherwise.
+	if len(req.Config) == 0 && req.Source.Pool == poolName {
+		req.Config = srcVolume.Config
+	}
+
+	err = storageVolumeValidateConfig(poolName, req.Config, poolStruct)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	err = storageVolumeFillDefault(poolName, req.Config, poolStruct)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	volumeID, err := dbStoragePoolVolumeCreate(d.db, req.Name, volumeType, poolID, req.Config)
+	if err != nil {
+		return InternalError(fmt.Errorf("Error inserting %s of type %s into database: %s", poolName, req.Type, err))
+	}
+
+	err = dbStoragePoolVolumeProjectSet(d.db, volumeID, project)
+	if err != nil {
+		dbStoragePoolVolumeDelete(d.db, req.Name, volumeType, poolID)
+		return InternalError(err)
+	}
+
+	run := func(op *operation) error {
+		s, err := storagePoolVolumeInit(d, poolName, req.Name, volumeType)
+		if err != nil {
+			dbStoragePoolVolumeDelete(d.db, req.Name, volumeType, poolID)
+			return err
+		}
+
+		err = s.StoragePoolVolumeCopy(&req.Source)
+		if err != nil {
+			dbStoragePoolVolumeDelete(d.db, req.Name, volumeType, poolID)
+			return err
+		}
+
+		return nil
+	}
+
+	resources := map[string][]string{}
+	resources["storage_volumes"] = []string{req.Name}
+
+	op, err := operationCreate(operationClassTask, resources, nil, run, nil, nil)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return OperationResponse(op)
+}
+
+func doVolumeMigration(d *Daemon, project string, poolName string, poolID int64, poolStruct *api.StoragePool, volumeType int, req *api.StorageVolumesPost) Response {
+	// Validate migration mode
+	if req.Source.Mode != "pull" {
+		return NotImplemented
+	}
+
+	err := storageVolumeValidateConfig(poolName, req.Config, poolStruct)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	err = storageVolumeFillDefault(poolName, req.Config, poolStruct)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	var cert *x509.Certificate
+	if req.Source.Certificate != "" {
+		certBlock, _ := pem.Decode([]byte(req.Source.Certificate))
+		if certBlock == nil {
+			return InternalError(fmt.Errorf("Invalid certificate"))
+		}
+
+		cert, err = x509.ParseCertificate(certBlock.Bytes)
+		if err != nil {
+			return InternalError(err)
+		}
+	}
+
+	config, err := shared.GetTLSConfig("", "", "", cert)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	// Create the database entry and the empty storage volume the data will
+	// be received into.
+	volumeID, err := dbStoragePoolVolumeCreate(d.db, req.Name, volumeType, poolID, req.Config)
+	if err != nil {
+		return InternalError(fmt.Errorf("Error inserting %s of type %s into database: %s", poolName, req.Type, err))
+	}
+
+	err = dbStoragePoolVolumeProjectSet(d.db, volumeID, project)
+	if err != nil {
+		dbStoragePoolVolumeDelete(d.db, req.Name, volumeType, poolID)
+		return InternalError(err)
+	}
+
+	s, err := storagePoolVolumeInit(d, poolName, req.Name, volumeType)
+	if err != nil {
+		dbStoragePoolVolumeDelete(d.db, req.Name, volumeType, poolID)
+		return InternalError(err)
+	}
+
+	err = s.StoragePoolVolumeCreate()
+	if err != nil {
+		dbStoragePoolVolumeDelete(d.db, req.Name, volumeType, poolID)
+		return InternalError(err)
+	}
+
+	migrationArgs := MigrationSinkArgs{
+		Url: req.Source.Operation,
+		Dialer: websocket.Dialer{
+			TLSClientConfig: config,
+			NetDial:         shared.RFC3493Dialer},
+		Secrets: req.Source.Websockets,
+		Push:    false,
+		Storage: s,
+	}
+
+	sink, err := NewStorageMigrationSink(&migrationArgs)
+	if err != nil {
+		s.StoragePoolVolumeDelete()
+		dbStoragePoolVolumeDelete(d.db, req.Name, volumeType, poolID)
+		return InternalError(err)
+	}
+
+	run := func(op *operation) error {
+		// And finally run the migration.
+		err = sink.DoStorage(op)
+		if err != nil {
+			shared.LogError("Error during migration sink", log.Ctx{"err": err})
+			s.StoragePoolVolumeDelete()
+			dbStoragePoolVolumeDelete(d.db, req.Name, volumeType, poolID)
+			return fmt.Errorf("Error transferring storage volume: %s", err)
+		}
+
+		return nil
+	}
+
+	resources := map[string][]string{}
+	resources["storage_volumes"] = []string{req.Name}
+
+	op, err := operationCreate(operationClassTask, resources, nil, run, nil, nil)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return OperationResponse(op)
+}
+
 var storagePoolVolumesTypeCmd = Command{name: "storage-pools/{name}/volumes/{type}", get: storagePoolVolumesTypeGet, post: storagePoolVolumesTypePost}
 
 // /1.0/storage-pools/{pool}/volumes/{type}/{name}
@@ -256,6 +536,11 @@ func storagePoolVolumeTypeGet(d *Daemon, r *http.Request) Response {
 	if err != nil {
 		return BadRequest(err)
 	}
+
+	// Custom storage volumes are namespaced by project.
+	if volumeType == storagePoolVolumeTypeCustom {
+		volumeName = projectPrefix(projectParam(r), volumeName)
+	}
 	// Check that the storage volume type is valid.
 	if !shared.IntInSlice(volumeType, supportedVolumeTypes) {
 		return BadRequest(fmt.Errorf("Invalid storage volume type %s.", volumeTypeName))
@@ -269,7 +554,7 @@ func storagePoolVolumeTypeGet(d *Daemon, r *http.Request) Response {
 	}
 
 	// Get the storage volume.
-	_, volume, err := dbStoragePoolVolumeGetType(d.db, volumeName, volumeType, poolID)
+	volumeID, volume, err := dbStoragePoolVolumeGetType(d.db, volumeName, volumeType, poolID)
 	if err != nil {
 		return SmartError(err)
 	}
@@ -280,11 +565,210 @@ func storagePoolVolumeTypeGet(d *Daemon, r *http.Request) Response {
 	}
 	volume.UsedBy = volumeUsedBy
 
+	volume.Attachments, err = storagePoolVolumeAttachmentsGet(d, volumeID)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	// Report the name as seen from within the project.
+	volume.Name = mux.Vars(r)["name"]
+
 	etag := []interface{}{volume.Name, volume.Type, volume.UsedBy, volume.Config}
 
 	return SyncResponseETag(true, volume, etag)
 }
 
+// /1.0/storage-pools/{pool}/volumes/{type}/{name}
+// Rename a storage volume, move it to another storage pool or start a
+// migration of it to another LXD host.
+func storagePoolVolumeTypePost(d *Daemon, r *http.Request) Response {
+	// Get the name of the storage volume.
+	volumeName := mux.Vars(r)["name"]
+
+	// Get the name of the storage pool the volume is supposed to be
+	// attached to.
+	poolName := mux.Vars(r)["pool"]
+
+	// Get the name of the volume type.
+	volumeTypeName := mux.Vars(r)["type"]
+
+	// Convert the volume type name to our internal integer representation.
+	volumeType, err := storagePoolVolumeTypeNameToType(volumeTypeName)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	// Custom storage volumes are namespaced by project.
+	if volumeType == storagePoolVolumeTypeCustom {
+		volumeName = projectPrefix(projectParam(r), volumeName)
+	}
+
+	// We currently only allow to rename, move or migrate storage volumes
+	// of type storagePoolVolumeTypeCustom.
+	if volumeType != storagePoolVolumeTypeCustom {
+		return BadRequest(fmt.Errorf("Renaming storage volumes of type %s is not allowed.", volumeTypeName))
+	}
+
+	if shared.IsSnapshot(volumeName) {
+		return BadRequest(fmt.Errorf("Storage volume snapshots must be renamed through the snapshots endpoint."))
+	}
+
+	req := api.StorageVolumePost{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	poolID, err := dbStoragePoolGetID(d.db, poolName)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	// Check that the storage volume exists.
+	_, volume, err := dbStoragePoolVolumeGetType(d.db, volumeName, volumeType, poolID)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	if req.Migration {
+		s, err := storagePoolVolumeInit(d, poolName, volumeName, volumeType)
+		if err != nil {
+			return InternalError(err)
+		}
+
+		ws, err := NewStorageMigrationSource(s)
+		if err != nil {
+			return InternalError(err)
+		}
+
+		resources := map[string][]string{}
+		resources["storage_volumes"] = []string{volumeName}
+
+		op, err := operationCreate(operationClassWebsocket, resources, ws.Metadata(), ws.DoStorage, nil, ws.Connect)
+		if err != nil {
+			return InternalError(err)
+		}
+
+		return OperationResponse(op)
+	}
+
+	// Sanity checks.
+	if req.Name == "" {
+		return BadRequest(fmt.Errorf("No name provided"))
+	}
+
+	if shared.IsSnapshot(req.Name) {
+		return BadRequest(fmt.Errorf("Storage volume names may not contain slashes."))
+	}
+
+	req.Name = projectPrefix(projectParam(r), req.Name)
+
+	if req.Pool == "" {
+		req.Pool = poolName
+	}
+
+	// Check that the target storage pool exists.
+	targetPoolID, err := dbStoragePoolGetID(d.db, req.Pool)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	// Check that the name isn't already in use.
+	id, _ := dbStoragePoolVolumeGetTypeID(d.db, req.Name, volumeType, targetPoolID)
+	if id > 0 {
+		return Conflict
+	}
+
+	// Storage volumes that are in use can't be renamed or moved.
+	volumeUsedBy, err := storagePoolVolumeUsedByGet(d, volumeName, volumeTypeName)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	if len(volumeUsedBy) > 0 {
+		return BadRequest(fmt.Errorf("The storage volume is still in use by containers."))
+	}
+
+	resources := map[string][]string{}
+	resources["storage_volumes"] = []string{volumeName}
+
+	// Rename the storage volume within the same storage pool.
+	if req.Pool == poolName {
+		rename := func(op *operation) error {
+			return storagePoolVolumeRename(d, poolName, poolID, volumeName, req.Name, volumeType)
+		}
+
+		op, err := operationCreate(operationClassTask, resources, nil, rename, nil, nil)
+		if err != nil {
+			return InternalError(err)
+		}
+
+		return OperationResponse(op)
+	}
+
+	// Move the storage volume to another storage pool by copying it over
+	// and deleting the source volume afterwards. Snapshots are not moved
+	// along with the volume so refuse to do this if there are any.
+	snapshots, err := dbStoragePoolVolumeSnapshotsGetType(d.db, volumeName, volumeType, poolID)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	if len(snapshots) > 0 {
+		return BadRequest(fmt.Errorf("Storage volumes with snapshots can't be moved to another storage pool."))
+	}
+
+	volumeID, err := dbStoragePoolVolumeCreate(d.db, req.Name, volumeType, targetPoolID, volume.Config)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = dbStoragePoolVolumeProjectSet(d.db, volumeID, projectParam(r))
+	if err != nil {
+		dbStoragePoolVolumeDelete(d.db, req.Name, volumeType, targetPoolID)
+		return InternalError(err)
+	}
+
+	move := func(op *operation) error {
+		s, err := storagePoolVolumeInit(d, req.Pool, req.Name, volumeType)
+		if err != nil {
+			dbStoragePoolVolumeDelete(d.db, req.Name, volumeType, targetPoolID)
+			return err
+		}
+
+		source := api.StorageVolumeSource{
+			Name: volumeName,
+			Type: "copy",
+			Pool: poolName,
+		}
+
+		err = s.StoragePoolVolumeCopy(&source)
+		if err != nil {
+			dbStoragePoolVolumeDelete(d.db, req.Name, volumeType, targetPoolID)
+			return err
+		}
+
+		src, err := storagePoolVolumeInit(d, poolName, volumeName, volumeType)
+		if err != nil {
+			return err
+		}
+
+		err = src.StoragePoolVolumeDelete()
+		if err != nil {
+			return err
+		}
+
+		return dbStoragePoolVolumeDelete(d.db, volumeName, volumeType, poolID)
+	}
+
+	op, err := operationCreate(operationClassTask, resources, nil, move, nil, nil)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return OperationResponse(op)
+}
+
 // /1.0/storage-pools/{pool}/volumes/{type}/{name}
 func storagePoolVolumeTypePut(d *Daemon, r *http.Request) Response {
 	// Get the name of the storage volume.
@@ -302,6 +786,11 @@ func storagePoolVolumeTypePut(d *Daemon, r *http.Request) Response {
 	if err != nil {
 		return BadRequest(err)
 	}
+
+	// Custom storage volumes are namespaced by project.
+	if volumeType == storagePoolVolumeTypeCustom {
+		volumeName = projectPrefix(projectParam(r), volumeName)
+	}
 	// Check that the storage volume type is valid.
 	if !shared.IntInSlice(volumeType, supportedVolumeTypes) {
 		return BadRequest(fmt.Errorf("Invalid storage volume type %s.", volumeTypeName))
@@ -317,6 +806,7 @@ func storagePoolVolumeTypePut(d *Daemon, r *http.Request) Response {
 	if err != nil {
 		return SmartError(err)
 	}
+	volume.Name = mux.Vars(r)["name"]
 
 	// Validate the ETag
 	etag := []interface{}{volume.Name, volume.Type, volume.UsedBy, volume.Config}
@@ -331,13 +821,37 @@ func storagePoolVolumeTypePut(d *Daemon, r *http.Request) Response {
 		return BadRequest(err)
 	}
 
+	if req.Restore != "" {
+		if volumeType != storagePoolVolumeTypeCustom {
+			return BadRequest(fmt.Errorf("Restoring storage volumes of type %s is not allowed.", volumeTypeName))
+		}
+
+		// Check that the snapshot exists.
+		_, _, err = dbStoragePoolVolumeGetType(d.db, volumeName+shared.SnapshotDelimiter+req.Restore, volumeType, poolID)
+		if err != nil {
+			return SmartError(err)
+		}
+
+		s, err := storagePoolVolumeInit(d, poolName, volumeName, volumeType)
+		if err != nil {
+			return InternalError(err)
+		}
+
+		err = s.StoragePoolVolumeSnapshotRestore(req.Restore)
+		if err != nil {
+			return InternalError(err)
+		}
+
+		return EmptySyncResponse
+	}
+
 	// Validate the configuration
-	err = storageVolumeValidateConfig(req.Name, req.Config, pool)
+	err = storageVolumeValidateConfig(volumeName, req.Config, pool)
 	if err != nil {
 		return BadRequest(err)
 	}
 
-	err = storagePoolVolumeUpdate(d, poolName, req.Name, volumeType, req.Config)
+	err = storagePoolVolumeUpdate(d, poolName, volumeName, volumeType, req.Config)
 	if err != nil {
 		return InternalError(err)
 	}
@@ -362,6 +876,11 @@ func storagePoolVolumeTypePatch(d *Daemon, r *http.Request) Response {
 	if err != nil {
 		return BadRequest(err)
 	}
+
+	// Custom storage volumes are namespaced by project.
+	if volumeType == storagePoolVolumeTypeCustom {
+		volumeName = projectPrefix(projectParam(r), volumeName)
+	}
 	// Check that the storage volume type is valid.
 	if !shared.IntInSlice(volumeType, supportedVolumeTypes) {
 		return BadRequest(fmt.Errorf("Invalid storage volume type %s.", volumeTypeName))
@@ -379,6 +898,7 @@ func storagePoolVolumeTypePatch(d *Daemon, r *http.Request) Response {
 	if err != nil {
 		return SmartError(err)
 	}
+	volume.Name = mux.Vars(r)["name"]
 
 	// Validate the ETag
 	etag := []interface{}{volume.Name, volume.Type, volume.UsedBy, volume.Config}
@@ -410,7 +930,7 @@ func storagePoolVolumeTypePatch(d *Daemon, r *http.Request) Response {
 		return BadRequest(err)
 	}
 
-	err = storagePoolVolumeUpdate(d, poolName, req.Name, volumeType, req.Config)
+	err = storagePoolVolumeUpdate(d, poolName, volumeName, volumeType, req.Config)
 	if err != nil {
 		return InternalError(err)
 	}
@@ -435,6 +955,11 @@ func storagePoolVolumeTypeDelete(d *Daemon, r *http.Request) Response {
 	if err != nil {
 		return BadRequest(err)
 	}
+
+	// Custom storage volumes are namespaced by project.
+	if volumeType == storagePoolVolumeTypeCustom {
+		volumeName = projectPrefix(projectParam(r), volumeName)
+	}
 	// Check that the storage volume type is valid.
 	if !shared.IntInSlice(volumeType, supportedVolumeTypes) {
 		return BadRequest(fmt.Errorf("Invalid storage volume type %s.", volumeTypeName))
@@ -464,6 +989,20 @@ func storagePoolVolumeTypeDelete(d *Daemon, r *http.Request) Response {
 		return InternalError(err)
 	}
 
+	// The storage driver removed the snapshots together with the volume
+	// so drop their database entries too.
+	snapshots, err := dbStoragePoolVolumeSnapshotsGetType(d.db, volumeName, volumeType, poolID)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	for _, snapshot := range snapshots {
+		err = dbStoragePoolVolumeDelete(d.db, snapshot, volumeType, poolID)
+		if err != nil {
+			return InternalError(err)
+		}
+	}
+
 	err = dbStoragePoolVolumeDelete(d.db, volumeName, volumeType, poolID)
 	if err != nil {
 		return InternalError(err)
@@ -472,4 +1011,92 @@ func storagePoolVolumeTypeDelete(d *Daemon, r *http.Request) Response {
 	return EmptySyncResponse
 }
 
-var storagePoolVolumeTypeCmd = Command{name: "storage-pools/{pool}/volumes/{type}/{name:.*}", get: storagePoolVolumeTypeGet, put: storagePoolVolumeTypePut, patch: storagePoolVolumeTypePatch, delete: storagePoolVolumeTypeDelete}
+var storagePoolVolumeTypeCmd = Command{name: "storage-pools/{pool}/volumes/{type}/{name:.*}", get: storagePoolVolumeTypeGet, post: storagePoolVolumeTypePost, put: storagePoolVolumeTypePut, patch: storagePoolVolumeTypePatch, delete: storagePoolVolumeTypeDelete}
+
+// /1.0/storage-pools/{pool}/volumes/{type}/{name}/state
+// Get the disk usage of a custom storage volume.
+func storagePoolVolumeTypeStateGet(d *Daemon, r *http.Request) Response {
+	// Get the name of the storage volume.
+	volumeName := mux.Vars(r)["name"]
+
+	// Get the name of the storage pool the volume is supposed to be
+	// attached to.
+	poolName := mux.Vars(r)["pool"]
+
+	// Get the name of the volume type.
+	volumeTypeName := mux.Vars(r)["type"]
+
+	// Convert the volume type name to our internal integer representation.
+	volumeType, err := storagePoolVolumeTypeNameToType(volumeTypeName)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	// Only custom storage volumes have a state.
+	if volumeType != storagePoolVolumeTypeCustom {
+		return BadRequest(fmt.Errorf("Invalid storage volume type %s.", volumeTypeName))
+	}
+
+	// Custom storage volumes are namespaced by project.
+	volumeName = projectPrefix(projectParam(r), volumeName)
+
+	// Get the ID of the storage pool the storage volume is supposed to be
+	// attached to.
+	poolID, err := dbStoragePoolGetID(d.db, poolName)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	// Check that the storage volume exists.
+	_, volume, err := dbStoragePoolVolumeGetType(d.db, volumeName, volumeType, poolID)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	s, err := storagePoolVolumeInit(d, poolName, volumeName, volumeType)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	ourMount, err := s.StoragePoolVolumeMount()
+	if err != nil {
+		return SmartError(err)
+	}
+	if ourMount {
+		defer s.StoragePoolVolumeUmount()
+	}
+
+	used, err := s.StoragePoolVolumeGetUsage()
+	if err != nil {
+		return SmartError(err)
+	}
+
+	// Without an explicit size the volume can grow up to the space
+	// available to its filesystem.
+	var total uint64
+	if volume.Config["size"] != "" {
+		size, err := shared.ParseByteSizeString(volume.Config["size"])
+		if err != nil {
+			return InternalError(err)
+		}
+
+		total = uint64(size)
+	}
+
+	if total == 0 {
+		res, err := storageResource(getStoragePoolVolumeMountPoint(poolName, volumeName))
+		if err != nil {
+			return InternalError(err)
+		}
+
+		total = res.Space.Total
+	}
+
+	state := api.StorageVolumeState{}
+	state.Usage.Used = uint64(used)
+	state.Usage.Total = total
+
+	return SyncResponse(true, state)
+}
+
+var storagePoolVolumeTypeStateCmd = Command{name: "storage-pools/{pool}/volumes/{type}/{name}/state", get: storagePoolVolumeTypeStateGet}
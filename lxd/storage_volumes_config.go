@@ -21,6 +21,7 @@ var storageVolumeConfigKeys = map[string]func(value string) error{
 		_, err := shared.ParseByteSizeString(value)
 		return err
 	},
+	"security.shared":      shared.IsBool,
 	"zfs.use_refquota":     shared.IsBool,
 	"zfs.remove_snapshots": shared.IsBool,
 }
@@ -74,7 +75,7 @@ func storageVolumeValidateConfig(name string, config map[string]string, parentPo
 func storageVolumeFillDefault(name string, config map[string]string, parentPool *api.StoragePool) error {
 	if parentPool.Driver == "dir" {
 		config["size"] = ""
-	} else if parentPool.Driver == "lvm" {
+	} else if parentPool.Driver == "lvm" || parentPool.Driver == "ceph" {
 		if config["block.filesystem"] == "" {
 			config["block.filesystem"] = parentPool.Config["volume.block.filesystem"]
 		}
@@ -96,14 +97,10 @@ func storageVolumeFillDefault(name string, config map[string]string, parentPool
 			config["size"] = "10GB"
 		}
 	} else {
-		if config["size"] != "" {
-			_, err := shared.ParseByteSizeString("10GB")
-			if err != nil {
-				return err
-			}
-			config["size"] = "10GB"
+		// An empty size means the volume isn't limited.
+		if config["size"] == "0" {
+			config["size"] = ""
 		}
-
 	}
 
 	return nil
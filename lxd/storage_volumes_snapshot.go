@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
+)
+
+// /1.0/storage-pools/{pool}/volumes/{type}/{name}/snapshots
+// List all snapshots of a given storage volume.
+func storagePoolVolumeSnapshotsTypeGet(d *Daemon, r *http.Request) Response {
+	poolName := mux.Vars(r)["pool"]
+	volumeTypeName := mux.Vars(r)["type"]
+	volumeName := projectPrefix(projectParam(r), mux.Vars(r)["name"])
+
+	recursionStr := r.FormValue("recursion")
+	recursion, err := strconv.Atoi(recursionStr)
+	if err != nil {
+		recursion = 0
+	}
+
+	// Convert the volume type name to our internal integer representation.
+	volumeType, err := storagePoolVolumeTypeNameToType(volumeTypeName)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	// Snapshots are only supported for custom storage volumes.
+	if volumeType != storagePoolVolumeTypeCustom {
+		return BadRequest(fmt.Errorf("Invalid storage volume type %s.", volumeTypeName))
+	}
+
+	poolID, err := dbStoragePoolGetID(d.db, poolName)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	// Check that the storage volume exists.
+	_, _, err = dbStoragePoolVolumeGetType(d.db, volumeName, volumeType, poolID)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	snapshots, err := dbStoragePoolVolumeSnapshotsGetType(d.db, volumeName, volumeType, poolID)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	resultString := []string{}
+	resultMap := []*api.StorageVolumeSnapshot{}
+	for _, snapshot := range snapshots {
+		snapshotName := shared.ExtractSnapshotName(snapshot)
+		if recursion == 0 {
+			resultString = append(resultString, fmt.Sprintf("/%s/storage-pools/%s/volumes/%s/%s/snapshots/%s", version.APIVersion, poolName, volumeTypeName, mux.Vars(r)["name"], snapshotName)+projectQuery(projectParam(r)))
+		} else {
+			_, vol, err := dbStoragePoolVolumeGetType(d.db, snapshot, volumeType, poolID)
+			if err != nil {
+				continue
+			}
+
+			resultMap = append(resultMap, &api.StorageVolumeSnapshot{Name: snapshotName, Config: vol.Config})
+		}
+	}
+
+	if recursion == 0 {
+		return SyncResponse(true, resultString)
+	}
+
+	return SyncResponse(true, resultMap)
+}
+
+/*
+ * Note, the code below doesn't deal with snapshots of snapshots.
+ * To do that, we'll need to weed out based on # slashes in names
+ */
+func nextStoragePoolVolumeSnapshot(d *Daemon, volumeName string, volumeType int, poolID int64) int {
+	base := volumeName + shared.SnapshotDelimiter + "snap"
+	length := len(base)
+	q := fmt.Sprintf("SELECT name FROM storage_volumes WHERE storage_pool_id=? AND type=? AND SUBSTR(name,1,?)=?")
+	var numstr string
+	inargs := []interface{}{poolID, volumeType, length, base}
+	outfmt := []interface{}{numstr}
+	results, err := dbQueryScan(d.db, q, inargs, outfmt)
+	if err != nil {
+		return 0
+	}
+	max := 0
+
+	for _, r := range results {
+		numstr = r[0].(string)
+		if len(numstr) <= length {
+			continue
+		}
+		substr := numstr[length:]
+		var num int
+		count, err := fmt.Sscanf(substr, "%d", &num)
+		if err != nil || count != 1 {
+			continue
+		}
+		if num >= max {
+			max = num + 1
+		}
+	}
+
+	return max
+}
+
+// /1.0/storage-pools/{pool}/volumes/{type}/{name}/snapshots
+// Create a new snapshot of a given storage volume.
+func storagePoolVolumeSnapshotsTypePost(d *Daemon, r *http.Request) Response {
+	poolName := mux.Vars(r)["pool"]
+	volumeTypeName := mux.Vars(r)["type"]
+	volumeName := projectPrefix(projectParam(r), mux.Vars(r)["name"])
+
+	// Convert the volume type name to our internal integer representation.
+	volumeType, err := storagePoolVolumeTypeNameToType(volumeTypeName)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	// Snapshots are only supported for custom storage volumes.
+	if volumeType != storagePoolVolumeTypeCustom {
+		return BadRequest(fmt.Errorf("Invalid storage volume type %s.", volumeTypeName))
+	}
+
+	req := api.StorageVolumeSnapshotsPost{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	poolID, err := dbStoragePoolGetID(d.db, poolName)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	// Get the storage volume we are going to snapshot.
+	_, volume, err := dbStoragePoolVolumeGetType(d.db, volumeName, volumeType, poolID)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	if req.Name == "" {
+		// come up with a name
+		i := nextStoragePoolVolumeSnapshot(d, volumeName, volumeType, poolID)
+		req.Name = fmt.Sprintf("snap%d", i)
+	}
+
+	if shared.IsSnapshot(req.Name) {
+		return BadRequest(fmt.Errorf("Invalid snapshot name"))
+	}
+
+	fullName := volumeName + shared.SnapshotDelimiter + req.Name
+
+	// Check that the name isn't already in use
+	id, _ := dbStoragePoolVolumeGetTypeID(d.db, fullName, volumeType, poolID)
+	if id > 0 {
+		return Conflict
+	}
+
+	snapshot := func(op *operation) error {
+		s, err := storagePoolVolumeInit(d, poolName, volumeName, volumeType)
+		if err != nil {
+			return err
+		}
+
+		volumeID, err := dbStoragePoolVolumeCreate(d.db, fullName, volumeType, poolID, volume.Config)
+		if err != nil {
+			return err
+		}
+
+		err = dbStoragePoolVolumeProjectSet(d.db, volumeID, projectParam(r))
+		if err != nil {
+			dbStoragePoolVolumeDelete(d.db, fullName, volumeType, poolID)
+			return err
+		}
+
+		err = s.StoragePoolVolumeSnapshotCreate(req.Name)
+		if err != nil {
+			dbStoragePoolVolumeDelete(d.db, fullName, volumeType, poolID)
+			return err
+		}
+
+		return nil
+	}
+
+	resources := map[string][]string{}
+	resources["storage_volumes"] = []string{volumeName}
+
+	op, err := operationCreate(operationClassTask, resources, nil, snapshot, nil, nil)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return OperationResponse(op)
+}
+
+var storagePoolVolumeSnapshotsTypeCmd = Command{name: "storage-pools/{pool}/volumes/{type}/{name}/snapshots", get: storagePoolVolumeSnapshotsTypeGet, post: storagePoolVolumeSnapshotsTypePost}
+
+// /1.0/storage-pools/{pool}/volumes/{type}/{name}/snapshots/{snapshotName}
+func storagePoolVolumeSnapshotTypeHandler(d *Daemon, r *http.Request) Response {
+	poolName := mux.Vars(r)["pool"]
+	volumeTypeName := mux.Vars(r)["type"]
+	volumeName := projectPrefix(projectParam(r), mux.Vars(r)["name"])
+	snapshotName := mux.Vars(r)["snapshotName"]
+
+	// Convert the volume type name to our internal integer representation.
+	volumeType, err := storagePoolVolumeTypeNameToType(volumeTypeName)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	// Snapshots are only supported for custom storage volumes.
+	if volumeType != storagePoolVolumeTypeCustom {
+		return BadRequest(fmt.Errorf("Invalid storage volume type %s.", volumeTypeName))
+	}
+
+	poolID, err := dbStoragePoolGetID(d.db, poolName)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	fullName := volumeName + shared.SnapshotDelimiter + snapshotName
+	_, snapshot, err := dbStoragePoolVolumeGetType(d.db, fullName, volumeType, poolID)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	switch r.Method {
+	case "GET":
+		return SyncResponse(true, &api.StorageVolumeSnapshot{Name: snapshotName, Config: snapshot.Config})
+	case "POST":
+		return storagePoolVolumeSnapshotTypePost(d, r, poolName, volumeName, snapshotName, volumeType, poolID)
+	case "DELETE":
+		return storagePoolVolumeSnapshotTypeDelete(d, poolName, volumeName, snapshotName, volumeType, poolID)
+	default:
+		return NotFound
+	}
+}
+
+func storagePoolVolumeSnapshotTypePost(d *Daemon, r *http.Request, poolName string, volumeName string, snapshotName string, volumeType int, poolID int64) Response {
+	req := api.StorageVolumeSnapshotPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	if req.Name == "" || shared.IsSnapshot(req.Name) {
+		return BadRequest(fmt.Errorf("Invalid snapshot name"))
+	}
+
+	oldFullName := volumeName + shared.SnapshotDelimiter + snapshotName
+	newFullName := volumeName + shared.SnapshotDelimiter + req.Name
+
+	// Check that the name isn't already in use
+	id, _ := dbStoragePoolVolumeGetTypeID(d.db, newFullName, volumeType, poolID)
+	if id > 0 {
+		return Conflict
+	}
+
+	rename := func(op *operation) error {
+		s, err := storagePoolVolumeInit(d, poolName, volumeName, volumeType)
+		if err != nil {
+			return err
+		}
+
+		err = s.StoragePoolVolumeSnapshotRename(snapshotName, req.Name)
+		if err != nil {
+			return err
+		}
+
+		return dbStoragePoolVolumeRename(d.db, oldFullName, newFullName, volumeType, poolID)
+	}
+
+	resources := map[string][]string{}
+	resources["storage_volumes"] = []string{volumeName}
+
+	op, err := operationCreate(operationClassTask, resources, nil, rename, nil, nil)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return OperationResponse(op)
+}
+
+func storagePoolVolumeSnapshotTypeDelete(d *Daemon, poolName string, volumeName string, snapshotName string, volumeType int, poolID int64) Response {
+	remove := func(op *operation) error {
+		s, err := storagePoolVolumeInit(d, poolName, volumeName, volumeType)
+		if err != nil {
+			return err
+		}
+
+		err = s.StoragePoolVolumeSnapshotDelete(snapshotName)
+		if err != nil {
+			return err
+		}
+
+		return dbStoragePoolVolumeDelete(d.db, volumeName+shared.SnapshotDelimiter+snapshotName, volumeType, poolID)
+	}
+
+	resources := map[string][]string{}
+	resources["storage_volumes"] = []string{volumeName}
+
+	op, err := operationCreate(operationClassTask, resources, nil, remove, nil, nil)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return OperationResponse(op)
+}
+
+var storagePoolVolumeSnapshotTypeCmd = Command{name: "storage-pools/{pool}/volumes/{type}/{name}/snapshots/{snapshotName}", get: storagePoolVolumeSnapshotTypeHandler, post: storagePoolVolumeSnapshotTypeHandler, delete: storagePoolVolumeSnapshotTypeHandler}
@@ -3,9 +3,10 @@ package main
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/lxc/lxd/shared"
-	"github.com/lxc/lxd/shared/version"
+	"github.com/lxc/lxd/shared/api"
 )
 
 const (
@@ -113,12 +114,13 @@ func storagePoolVolumeUpdate(d *Daemon, poolName string, volumeName string, volu
 		}
 	}()
 
-	// Diff the configurations
+	// Diff the configurations. The storage drivers don't deal with user
+	// keys nor with "security.shared".
 	changedConfig := []string{}
 	userOnly := true
 	for key := range oldConfig {
 		if oldConfig[key] != newConfig[key] {
-			if !strings.HasPrefix(key, "user.") {
+			if !strings.HasPrefix(key, "user.") && key != "security.shared" {
 				userOnly = false
 			}
 
@@ -130,7 +132,7 @@ func storagePoolVolumeUpdate(d *Daemon, poolName string, volumeName string, volu
 
 	for key := range newConfig {
 		if oldConfig[key] != newConfig[key] {
-			if !strings.HasPrefix(key, "user.") {
+			if !strings.HasPrefix(key, "user.") && key != "security.shared" {
 				userOnly = false
 			}
 
@@ -145,36 +147,204 @@ func storagePoolVolumeUpdate(d *Daemon, poolName string, volumeName string, volu
 		return nil
 	}
 
+	newWritable.Config = newConfig
+
+	// Apply the new configuration so that the storage driver sees the
+	// new values (e.g. the new size) when updating the volume.
+	s.SetStoragePoolVolumeWritable(&newWritable)
+
+	poolID, pool, err := dbStoragePoolGet(d.db, poolName)
+	if err != nil {
+		return err
+	}
+
+	// Refuse to stop sharing a volume which is in use by more than one
+	// running container.
+	if shared.StringInSlice("security.shared", changedConfig) && !shared.IsTrue(newConfig["security.shared"]) && storagePoolVolumeIsBlockBacked(pool.Driver) {
+		volumeID, err := dbStoragePoolVolumeGetTypeID(d.db, volumeName, volumeType, poolID)
+		if err != nil {
+			return err
+		}
+
+		attachments, err := dbStorageVolumeAttachmentsGet(d.db, volumeID)
+		if err != nil {
+			return err
+		}
+
+		containers := []string{}
+		for _, attachment := range attachments {
+			if !shared.StringInSlice(attachment.Container, containers) {
+				containers = append(containers, attachment.Container)
+			}
+		}
+
+		if len(containers) > 1 {
+			return fmt.Errorf("The storage volume is attached to more than one running container")
+		}
+	}
+
 	// Update the storage pool
 	if !userOnly {
-		err = s.StoragePoolVolumeUpdate(changedConfig)
+		driverConfig := []string{}
+		for _, key := range changedConfig {
+			if key != "security.shared" {
+				driverConfig = append(driverConfig, key)
+			}
+		}
+
+		err = s.StoragePoolVolumeUpdate(driverConfig)
 		if err != nil {
 			return err
 		}
 	}
 
-	newWritable.Config = newConfig
+	// Update the database
+	err = dbStoragePoolVolumeUpdate(d.db, volumeName, volumeType, poolID, newConfig)
+	if err != nil {
+		return err
+	}
 
-	// Apply the new configuration
-	s.SetStoragePoolVolumeWritable(&newWritable)
+	// Success, update the closure to mark that the changes should be kept.
+	undoChanges = false
 
-	poolID, err := dbStoragePoolGetID(d.db, poolName)
+	return nil
+}
+
+func storagePoolVolumeRename(d *Daemon, poolName string, poolID int64, oldName string, newName string, volumeType int) error {
+	s, err := storagePoolVolumeInit(d, poolName, oldName, volumeType)
 	if err != nil {
 		return err
 	}
 
-	// Update the database
-	err = dbStoragePoolVolumeUpdate(d.db, volumeName, volumeType, poolID, newConfig)
+	// Retrieve the snapshots before the storage driver renames them.
+	snapshots, err := dbStoragePoolVolumeSnapshotsGetType(d.db, oldName, volumeType, poolID)
 	if err != nil {
 		return err
 	}
 
-	// Success, update the closure to mark that the changes should be kept.
-	undoChanges = false
+	err = s.StoragePoolVolumeRename(newName)
+	if err != nil {
+		return err
+	}
+
+	err = dbStoragePoolVolumeRename(d.db, oldName, newName, volumeType, poolID)
+	if err != nil {
+		return err
+	}
+
+	for _, snapshot := range snapshots {
+		newSnapshotName := newName + shared.SnapshotDelimiter + shared.ExtractSnapshotName(snapshot)
+		err = dbStoragePoolVolumeRename(d.db, snapshot, newSnapshotName, volumeType, poolID)
+		if err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
+// storageVolumeAttachmentsPrune drops the storage volume attachments of
+// containers which aren't running anymore, e.g. after a host reboot.
+func storageVolumeAttachmentsPrune(d *Daemon) error {
+	names, err := dbStorageVolumeAttachmentContainers(d.db)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		c, err := containerLoadByName(d, name)
+		if err != nil {
+			continue
+		}
+
+		if c.IsRunning() {
+			continue
+		}
+
+		err = dbStorageVolumeAttachmentRemove(d.db, c.Id(), "")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// storagePoolVolumeAttachmentsGet returns the running containers a storage
+// volume is attached to, named as seen from within their project.
+func storagePoolVolumeAttachmentsGet(d *Daemon, volumeID int64) ([]api.StorageVolumeAttachment, error) {
+	attachments, err := dbStorageVolumeAttachmentsGet(d.db, volumeID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range attachments {
+		_, attachments[i].Container = projectSplitName(attachments[i].Container)
+	}
+
+	return attachments, nil
+}
+
+// storagePoolVolumeIsBlockBacked returns whether the storage volumes of the
+// given driver hold a regular filesystem on a block device, which can't be
+// safely mounted by more than one container at a time.
+func storagePoolVolumeIsBlockBacked(driver string) bool {
+	return shared.StringInSlice(driver, []string{"lvm", "ceph"})
+}
+
+// Locks serializing the attachment of each storage volume, so that checking
+// for existing attachments and recording a new one happen atomically.
+var storagePoolVolumeAttachLocksLock sync.Mutex
+var storagePoolVolumeAttachLocks map[int64]*sync.Mutex = make(map[int64]*sync.Mutex)
+
+func storagePoolVolumeAttachLock(volumeID int64) *sync.Mutex {
+	storagePoolVolumeAttachLocksLock.Lock()
+	defer storagePoolVolumeAttachLocksLock.Unlock()
+
+	lock, ok := storagePoolVolumeAttachLocks[volumeID]
+	if !ok {
+		lock = &sync.Mutex{}
+		storagePoolVolumeAttachLocks[volumeID] = lock
+	}
+
+	return lock
+}
+
+// storagePoolVolumeAttach records the attachment of a custom storage volume to
+// a container through the given disk device. Block backed volumes can only be
+// attached to a single running container unless "security.shared" is set on
+// them.
+func storagePoolVolumeAttach(d *Daemon, c container, poolName string, volumeName string, volumeType int, device string) error {
+	poolID, pool, err := dbStoragePoolGet(d.db, poolName)
+	if err != nil {
+		return err
+	}
+
+	volumeID, volume, err := dbStoragePoolVolumeGetType(d.db, volumeName, volumeType, poolID)
+	if err != nil {
+		return err
+	}
+
+	lock := storagePoolVolumeAttachLock(volumeID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if storagePoolVolumeIsBlockBacked(pool.Driver) && !shared.IsTrue(volume.Config["security.shared"]) {
+		attachments, err := dbStorageVolumeAttachmentsGet(d.db, volumeID)
+		if err != nil {
+			return err
+		}
+
+		for _, attachment := range attachments {
+			if attachment.Container != c.Name() {
+				return fmt.Errorf("The storage volume \"%s\" is already attached to running container \"%s\", set \"security.shared\" on it to allow sharing it", volumeName, attachment.Container)
+			}
+		}
+	}
+
+	return dbStorageVolumeAttachmentAdd(d.db, volumeID, c.Id(), device)
+}
+
 func storagePoolVolumeUsedByGet(d *Daemon, volumeName string, volumeTypeName string) ([]string, error) {
 	// Look for containers using the interface
 	cts, err := dbContainersList(d.db, cTypeRegular)
@@ -199,6 +369,7 @@ func storagePoolVolumeUsedByGet(d *Daemon, volumeName string, volumeTypeName str
 				return []string{}, err
 			}
 
+			source := d["source"]
 			mustBeEqualTo := ""
 			switch apiEndpoint {
 			case storagePoolVolumeApiEndpointImages:
@@ -206,13 +377,62 @@ func storagePoolVolumeUsedByGet(d *Daemon, volumeName string, volumeTypeName str
 			case storagePoolVolumeApiEndpointContainers:
 				mustBeEqualTo = fmt.Sprintf("%s/%s", apiEndpoint, volumeName)
 			default:
+				// Custom volumes are looked up within the
+				// project of the container.
+				source = projectPrefix(c.Project(), source)
 				mustBeEqualTo = volumeName
 			}
-			if d["source"] == mustBeEqualTo {
-				volumeUsedBy = append(volumeUsedBy, fmt.Sprintf("/%s/containers/%s", version.APIVersion, ct))
+			if source == mustBeEqualTo {
+				volumeUsedBy = append(volumeUsedBy, projectContainerURL(ct))
 			}
 		}
 	}
 
 	return volumeUsedBy, nil
 }
+
+// storagePoolVolumeCopyRsync creates the storage volume described by target and
+// fills it with the contents of the source volume using rsync. This is the
+// fallback used whenever the storage driver cannot perform an optimized copy
+// (e.g. because source and target live on different storage pools).
+func storagePoolVolumeCopyRsync(d *Daemon, target storage, source *api.StorageVolumeSource) error {
+	_, targetPoolName := target.GetContainerPoolInfo()
+	targetVolumeName := target.GetStoragePoolVolumeWritable().Name
+
+	srcStorage, err := storagePoolVolumeInit(d, source.Pool, source.Name, storagePoolVolumeTypeCustom)
+	if err != nil {
+		return err
+	}
+
+	ourMount, err := srcStorage.StoragePoolVolumeMount()
+	if err != nil {
+		return err
+	}
+	if ourMount {
+		defer srcStorage.StoragePoolVolumeUmount()
+	}
+
+	err = target.StoragePoolVolumeCreate()
+	if err != nil {
+		return err
+	}
+
+	ourMount, err = target.StoragePoolVolumeMount()
+	if err != nil {
+		target.StoragePoolVolumeDelete()
+		return err
+	}
+	if ourMount {
+		defer target.StoragePoolVolumeUmount()
+	}
+
+	srcMntPoint := getStoragePoolVolumeMountPoint(source.Pool, source.Name)
+	dstMntPoint := getStoragePoolVolumeMountPoint(targetPoolName, targetVolumeName)
+	output, err := storageRsyncCopy(srcMntPoint, dstMntPoint)
+	if err != nil {
+		target.StoragePoolVolumeDelete()
+		return fmt.Errorf("Failed to rsync storage volume \"%s\": %s: %s", source.Name, string(output), err)
+	}
+
+	return nil
+}
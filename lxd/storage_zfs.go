@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -181,6 +182,11 @@ func (s *storageZfs) StoragePoolVolumeCreate() error {
 		s.zfsPoolVolumeMount(fs)
 	}
 
+	err = s.storagePoolVolumeSetQuota()
+	if err != nil {
+		return err
+	}
+
 	revert = false
 
 	shared.LogInfof("Created ZFS storage volume \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
@@ -311,6 +317,130 @@ func (s *storageZfs) SetStoragePoolVolumeWritable(writable *api.StorageVolumePut
 	s.volume.StorageVolumePut = *writable
 }
 
+func (s *storageZfs) StoragePoolVolumesCheck(repair bool) ([]storageCheckIssue, error) {
+	poolName := s.getOnDiskPoolName()
+	volumes := map[int][]string{}
+	issues := []storageCheckIssue{}
+
+	for _, volumeType := range supportedVolumeTypes {
+		parent, err := storagePoolVolumeTypeToApiEndpoint(volumeType)
+		if err != nil {
+			return nil, err
+		}
+
+		volumes[volumeType] = []string{}
+		if !s.zfsFilesystemEntityExists(parent, true) {
+			continue
+		}
+
+		subvols, err := s.zfsPoolListSubvolumes(fmt.Sprintf("%s/%s", poolName, parent))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, fs := range subvols {
+			name := strings.TrimPrefix(fs, fmt.Sprintf("%s/", parent))
+			if strings.Contains(name, "/") {
+				continue
+			}
+
+			volumes[volumeType] = append(volumes[volumeType], name)
+			if volumeType == storagePoolVolumeTypeImage {
+				continue
+			}
+
+			snapshots, err := s.zfsPoolListSnapshots(fs)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, snapshot := range snapshots {
+				// Skip the temporary snapshots used by copies
+				// and migrations.
+				if !strings.HasPrefix(snapshot, "snapshot-") {
+					continue
+				}
+
+				snapshotName := strings.TrimPrefix(snapshot, "snapshot-")
+				volumes[volumeType] = append(volumes[volumeType], fmt.Sprintf("%s%s%s", name, shared.SnapshotDelimiter, snapshotName))
+			}
+
+			// Images aren't mounted through their mountpoint
+			// property, everything else is.
+			mountPoint := getContainerMountPoint(s.pool.Name, name)
+			if volumeType == storagePoolVolumeTypeCustom {
+				mountPoint = getStoragePoolVolumeMountPoint(s.pool.Name, name)
+			}
+
+			current, err := s.zfsFilesystemEntityPropertyGet(fs, "mountpoint", true)
+			if err != nil {
+				return nil, err
+			}
+
+			if current == mountPoint {
+				continue
+			}
+
+			issue := storageCheckIssue{
+				Pool:    s.pool.Name,
+				Kind:    storageCheckWrongMountPoint,
+				Volume:  fs,
+				Details: fmt.Sprintf("mountpoint is %s instead of %s", current, mountPoint),
+			}
+
+			if repair {
+				err := s.zfsPoolVolumeSet(fs, "mountpoint", mountPoint)
+				if err != nil {
+					return nil, err
+				}
+
+				issue.Repaired = true
+			}
+
+			issues = append(issues, issue)
+		}
+	}
+
+	volumeIssues, err := s.checkVolumes(volumes, repair)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(volumeIssues, issues...), nil
+}
+
+func (s *storageZfs) StoragePoolResources() (*api.ResourcesStoragePool, error) {
+	poolName := s.getOnDiskPoolName()
+
+	totalBuf, err := s.zfsFilesystemEntityPropertyGet(poolName, "available", false)
+	if err != nil {
+		return nil, err
+	}
+
+	usedBuf, err := s.zfsFilesystemEntityPropertyGet(poolName, "used", false)
+	if err != nil {
+		return nil, err
+	}
+
+	available, err := strconv.ParseUint(strings.TrimSpace(totalBuf), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	used, err := strconv.ParseUint(strings.TrimSpace(usedBuf), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	res := api.ResourcesStoragePool{}
+	res.Space.Total = used + available
+	res.Space.Used = used
+
+	// Inode allocation is dynamic so no use in reporting them.
+
+	return &res, nil
+}
+
 func (s *storageZfs) GetContainerPoolInfo() (int64, string) {
 	return s.poolID, s.pool.Name
 }
@@ -319,7 +449,10 @@ func (s *storageZfs) StoragePoolUpdate(changedConfig []string) error {
 	shared.LogInfof("Updating ZFS storage pool \"%s\".", s.pool.Name)
 
 	if shared.StringInSlice("size", changedConfig) {
-		return fmt.Errorf("The \"size\" property cannot be changed.")
+		err := s.zfsPoolResize()
+		if err != nil {
+			return err
+		}
 	}
 
 	if shared.StringInSlice("source", changedConfig) {
@@ -354,6 +487,46 @@ func (s *storageZfs) StoragePoolUpdate(changedConfig []string) error {
 	return nil
 }
 
+// zfsPoolResize grows the loop file backing the pool to the "size" property
+// and lets the zpool expand onto the new space. ZFS can't remove space from a
+// vdev so shrinking isn't supported.
+func (s *storageZfs) zfsPoolResize() error {
+	vdev := s.pool.Config["source"]
+	if vdev != storageLoopFilePath(s.pool.Name) {
+		return fmt.Errorf("The \"size\" property can only be changed on loop backed pools.")
+	}
+
+	size, err := shared.ParseByteSizeString(s.pool.Config["size"])
+	if err != nil {
+		return err
+	}
+
+	st, err := os.Stat(vdev)
+	if err != nil {
+		return err
+	}
+
+	if size == st.Size() {
+		return nil
+	}
+
+	if size < st.Size() {
+		return fmt.Errorf("Shrinking ZFS storage pools is not supported.")
+	}
+
+	err = storageLoopFileResize(vdev, size)
+	if err != nil {
+		return err
+	}
+
+	output, err := exec.Command("zpool", "online", "-e", s.getOnDiskPoolName(), vdev).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Failed to expand the ZFS pool: %s", output)
+	}
+
+	return nil
+}
+
 func (s *storageZfs) StoragePoolVolumeUpdate(changedConfig []string) error {
 	shared.LogInfof("Updating ZFS storage volume \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
 
@@ -365,14 +538,257 @@ func (s *storageZfs) StoragePoolVolumeUpdate(changedConfig []string) error {
 		return fmt.Errorf("The \"block.filesystem\" property cannot be changed.")
 	}
 
-	if shared.StringInSlice("size", changedConfig) {
-		return fmt.Errorf("The \"size\" property cannot be changed.")
+	if shared.StringInSlice("size", changedConfig) || shared.StringInSlice("zfs.use_refquota", changedConfig) {
+		err := s.storagePoolVolumeSetQuota()
+		if err != nil {
+			return err
+		}
 	}
 
 	shared.LogInfof("Updated ZFS storage volume \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
 	return nil
 }
 
+// storagePoolVolumeQuotaProperties returns the ZFS properties used to limit
+// and to account for the space used by the custom storage volume.
+func (s *storageZfs) storagePoolVolumeQuotaProperties() (string, string) {
+	useRefquota := s.pool.Config["volume.zfs.use_refquota"]
+	if s.volume.Config["zfs.use_refquota"] != "" {
+		useRefquota = s.volume.Config["zfs.use_refquota"]
+	}
+
+	if shared.IsTrue(useRefquota) {
+		return "refquota", "usedbydataset"
+	}
+
+	return "quota", "used"
+}
+
+// storagePoolVolumeSetQuota applies the "size" property of the custom storage
+// volume as a ZFS quota. An empty size removes the quota.
+func (s *storageZfs) storagePoolVolumeSetQuota() error {
+	fs := fmt.Sprintf("custom/%s", s.volume.Name)
+
+	quota, _ := s.storagePoolVolumeQuotaProperties()
+
+	// Make sure a previously used quota property doesn't linger around.
+	for _, property := range []string{"quota", "refquota"} {
+		if property == quota {
+			continue
+		}
+
+		err := s.zfsPoolVolumeSet(fs, property, "none")
+		if err != nil {
+			return err
+		}
+	}
+
+	value := "none"
+	if s.volume.Config["size"] != "" {
+		size, err := shared.ParseByteSizeString(s.volume.Config["size"])
+		if err != nil {
+			return err
+		}
+
+		if size > 0 {
+			value = fmt.Sprintf("%d", size)
+		}
+	}
+
+	return s.zfsPoolVolumeSet(fs, quota, value)
+}
+
+func (s *storageZfs) StoragePoolVolumeGetUsage() (int64, error) {
+	fs := fmt.Sprintf("custom/%s", s.volume.Name)
+
+	_, property := s.storagePoolVolumeQuotaProperties()
+
+	value, err := s.zfsFilesystemEntityPropertyGet(fs, property, true)
+	if err != nil {
+		return -1, err
+	}
+
+	valueInt, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return -1, err
+	}
+
+	return valueInt, nil
+}
+
+func (s *storageZfs) StoragePoolVolumeRename(newName string) error {
+	shared.LogInfof("Renaming ZFS storage volume on storage pool \"%s\" from \"%s\" to \"%s\".", s.pool.Name, s.volume.Name, newName)
+
+	oldFs := fmt.Sprintf("custom/%s", s.volume.Name)
+	newFs := fmt.Sprintf("custom/%s", newName)
+	oldMntPoint := getStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name)
+	newMntPoint := getStoragePoolVolumeMountPoint(s.pool.Name, newName)
+
+	err := s.zfsPoolVolumeRename(oldFs, newFs)
+	if err != nil {
+		return err
+	}
+
+	err = s.zfsPoolVolumeSet(newFs, "mountpoint", newMntPoint)
+	if err != nil {
+		return err
+	}
+
+	if shared.PathExists(oldMntPoint) {
+		os.Remove(oldMntPoint)
+	}
+
+	shared.LogInfof("Renamed ZFS storage volume on storage pool \"%s\" from \"%s\" to \"%s\".", s.pool.Name, s.volume.Name, newName)
+	s.volume.Name = newName
+	return nil
+}
+
+func (s *storageZfs) StoragePoolVolumeCopy(source *api.StorageVolumeSource) error {
+	shared.LogInfof("Copying ZFS storage volume \"%s\" on storage pool \"%s\" to \"%s\" on storage pool \"%s\".", source.Name, source.Pool, s.volume.Name, s.pool.Name)
+
+	// Datasets on different storage pools can't be sent into each other
+	// here so fall back to rsync.
+	if source.Pool != s.pool.Name {
+		err := storagePoolVolumeCopyRsync(s.d, s, source)
+		if err != nil {
+			return err
+		}
+
+		shared.LogInfof("Copied ZFS storage volume \"%s\" on storage pool \"%s\" to \"%s\" on storage pool \"%s\".", source.Name, source.Pool, s.volume.Name, s.pool.Name)
+		return nil
+	}
+
+	sourceFs := fmt.Sprintf("custom/%s", source.Name)
+	targetFs := fmt.Sprintf("custom/%s", s.volume.Name)
+	targetMntPoint := getStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name)
+
+	// Use a temporary snapshot and a full send/receive instead of a clone
+	// so that the new volume doesn't depend on the source volume.
+	snapshotName := fmt.Sprintf("copy-%s", uuid.NewRandom().String())
+	err := s.zfsPoolVolumeSnapshotCreate(sourceFs, snapshotName)
+	if err != nil {
+		return err
+	}
+	defer s.zfsPoolVolumeSnapshotDestroy(sourceFs, snapshotName)
+
+	poolName := s.getOnDiskPoolName()
+	zfsSendCmd := exec.Command("zfs", "send", fmt.Sprintf("%s/%s@%s", poolName, sourceFs, snapshotName))
+	zfsRecvCmd := exec.Command("zfs", "receive", "-F", "-u", fmt.Sprintf("%s/%s", poolName, targetFs))
+
+	zfsRecvCmd.Stdin, err = zfsSendCmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	err = zfsRecvCmd.Start()
+	if err != nil {
+		return err
+	}
+
+	output := bytes.Buffer{}
+	zfsSendCmd.Stderr = &output
+	err = zfsSendCmd.Run()
+	if err != nil {
+		zfsRecvCmd.Wait()
+		return fmt.Errorf("Failed to send ZFS storage volume \"%s\": %s: %s", source.Name, output.String(), err)
+	}
+
+	err = zfsRecvCmd.Wait()
+	if err != nil {
+		return fmt.Errorf("Failed to receive ZFS storage volume \"%s\": %s", s.volume.Name, err)
+	}
+
+	revert := true
+	defer func() {
+		if !revert {
+			return
+		}
+		s.StoragePoolVolumeDelete()
+	}()
+
+	// The received dataset carries the temporary snapshot with it.
+	err = s.zfsPoolVolumeSnapshotDestroy(targetFs, snapshotName)
+	if err != nil {
+		return err
+	}
+
+	err = s.zfsPoolVolumeSet(targetFs, "mountpoint", targetMntPoint)
+	if err != nil {
+		return err
+	}
+
+	if !shared.IsMountPoint(targetMntPoint) {
+		err = s.zfsPoolVolumeMount(targetFs)
+		if err != nil {
+			return err
+		}
+	}
+
+	revert = false
+
+	shared.LogInfof("Copied ZFS storage volume \"%s\" on storage pool \"%s\" to \"%s\" on storage pool \"%s\".", source.Name, source.Pool, s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageZfs) StoragePoolVolumeSnapshotCreate(snapshotName string) error {
+	shared.LogInfof("Creating ZFS storage volume snapshot \"%s\" of \"%s\" on storage pool \"%s\".", snapshotName, s.volume.Name, s.pool.Name)
+
+	fs := fmt.Sprintf("custom/%s", s.volume.Name)
+	err := s.zfsPoolVolumeSnapshotCreate(fs, fmt.Sprintf("snapshot-%s", snapshotName))
+	if err != nil {
+		return err
+	}
+
+	shared.LogInfof("Created ZFS storage volume snapshot \"%s\" of \"%s\" on storage pool \"%s\".", snapshotName, s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageZfs) StoragePoolVolumeSnapshotDelete(snapshotName string) error {
+	shared.LogInfof("Deleting ZFS storage volume snapshot \"%s\" of \"%s\" on storage pool \"%s\".", snapshotName, s.volume.Name, s.pool.Name)
+
+	fs := fmt.Sprintf("custom/%s", s.volume.Name)
+	snapName := fmt.Sprintf("snapshot-%s", snapshotName)
+	if s.zfsFilesystemEntityExists(fmt.Sprintf("%s@%s", fs, snapName), true) {
+		err := s.zfsPoolVolumeSnapshotDestroy(fs, snapName)
+		if err != nil {
+			return err
+		}
+	}
+
+	shared.LogInfof("Deleted ZFS storage volume snapshot \"%s\" of \"%s\" on storage pool \"%s\".", snapshotName, s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageZfs) StoragePoolVolumeSnapshotRename(snapshotName string, newName string) error {
+	fs := fmt.Sprintf("custom/%s", s.volume.Name)
+	return s.zfsPoolVolumeSnapshotRename(fs, fmt.Sprintf("snapshot-%s", snapshotName), fmt.Sprintf("snapshot-%s", newName))
+}
+
+func (s *storageZfs) StoragePoolVolumeSnapshotRestore(snapshotName string) error {
+	shared.LogInfof("Restoring ZFS storage volume \"%s\" on storage pool \"%s\" from snapshot \"%s\".", s.volume.Name, s.pool.Name, snapshotName)
+
+	fs := fmt.Sprintf("custom/%s", s.volume.Name)
+	snapName := fmt.Sprintf("snapshot-%s", snapshotName)
+
+	// zfs rollback only works for the most recent snapshot.
+	snaps, err := s.zfsPoolListSnapshots(fs)
+	if err != nil {
+		return err
+	}
+
+	if len(snaps) == 0 || snaps[len(snaps)-1] != snapName {
+		return fmt.Errorf("ZFS can only restore from the latest snapshot. Delete newer snapshots or copy the snapshot into a new volume instead.")
+	}
+
+	err = s.zfsPoolVolumeSnapshotRestore(fs, snapName)
+	if err != nil {
+		return err
+	}
+
+	shared.LogInfof("Restored ZFS storage volume \"%s\" on storage pool \"%s\" from snapshot \"%s\".", s.volume.Name, s.pool.Name, snapshotName)
+	return nil
+}
+
 // Things we don't need to care about
 func (s *storageZfs) ContainerMount(name string, path string) (bool, error) {
 	shared.LogDebugf("Mounting ZFS storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
@@ -866,7 +1282,7 @@ func (s *storageZfs) ContainerRename(container container, newName string) error
 
 	// Create new symlink.
 	newSnapshotPath := shared.VarPath("snapshots", newName)
-	if shared.PathExists(newSnapshotPath) {
+	if shared.PathExists(newSnapshotMntPoint) {
 		err := os.Symlink(newSnapshotMntPoint, newSnapshotPath)
 		if err != nil {
 			return err
@@ -1222,6 +1638,155 @@ func (s *storageZfs) ContainerSnapshotCreateEmpty(snapshotContainer container) e
 	return nil
 }
 
+func (s *storageZfs) ContainerBackupDump(container container, snapshots []container, target string) error {
+	shared.LogDebugf("Dumping ZFS storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
+	poolName := s.getOnDiskPoolName()
+	zfsName := fmt.Sprintf("containers/%s", container.Name())
+
+	zfsSend := func(name string, parent string, file string) error {
+		args := []string{"send", fmt.Sprintf("%s/%s@%s", poolName, zfsName, name)}
+		if parent != "" {
+			args = append(args, "-i", fmt.Sprintf("%s/%s@%s", poolName, zfsName, parent))
+		}
+
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		var stderr bytes.Buffer
+		cmd := exec.Command("zfs", args...)
+		cmd.Stdout = f
+		cmd.Stderr = &stderr
+
+		err = cmd.Run()
+		if err != nil {
+			return fmt.Errorf("Failed to send \"%s\": %s", name, stderr.String())
+		}
+
+		return nil
+	}
+
+	if len(snapshots) > 0 {
+		err := os.MkdirAll(filepath.Join(target, "snapshots"), 0700)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Send the snapshots incrementally, each one based on the previous.
+	prev := ""
+	for _, snap := range snapshots {
+		fields := strings.SplitN(snap.Name(), shared.SnapshotDelimiter, 2)
+		snapshotName := fmt.Sprintf("snapshot-%s", fields[1])
+		err := zfsSend(snapshotName, prev, filepath.Join(target, "snapshots", fields[1]+".bin"))
+		if err != nil {
+			return err
+		}
+
+		prev = snapshotName
+	}
+
+	// And finally the container, through a temporary snapshot.
+	tmpSnapshotName := fmt.Sprintf("backup-%s", uuid.NewRandom().String())
+	err := s.zfsPoolVolumeSnapshotCreate(zfsName, tmpSnapshotName)
+	if err != nil {
+		return err
+	}
+	defer s.zfsPoolVolumeSnapshotDestroy(zfsName, tmpSnapshotName)
+
+	err = zfsSend(tmpSnapshotName, prev, filepath.Join(target, "container.bin"))
+	if err != nil {
+		return err
+	}
+
+	shared.LogDebugf("Dumped ZFS storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	return nil
+}
+
+func (s *storageZfs) ContainerBackupLoad(container container, snapshots []container, source string) error {
+	shared.LogDebugf("Loading ZFS storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+
+	poolName := s.getOnDiskPoolName()
+	zfsName := fmt.Sprintf("containers/%s", container.Name())
+
+	zfsRecv := func(file string) error {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		cmd := exec.Command("zfs", "receive", "-F", "-u", fmt.Sprintf("%s/%s", poolName, zfsName))
+		cmd.Stdin = f
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("Failed to receive \"%s\": %s", file, string(output))
+		}
+
+		return nil
+	}
+
+	// zfs receive needs the (empty) target filesystem to be unmounted.
+	err := s.zfsPoolVolumeUmount(zfsName)
+	if err != nil {
+		return err
+	}
+
+	if len(snapshots) > 0 {
+		snapshotMntPointSymlinkTarget := shared.VarPath("storage-pools", s.pool.Name, "snapshots", container.Name())
+		snapshotMntPointSymlink := shared.VarPath("snapshots", container.Name())
+		if !shared.PathExists(snapshotMntPointSymlink) {
+			err := os.Symlink(snapshotMntPointSymlinkTarget, snapshotMntPointSymlink)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, snap := range snapshots {
+		fields := strings.SplitN(snap.Name(), shared.SnapshotDelimiter, 2)
+		err := zfsRecv(filepath.Join(source, "snapshots", fields[1]+".bin"))
+		if err != nil {
+			return err
+		}
+
+		snapshotMntPoint := getSnapshotMountPoint(s.pool.Name, snap.Name())
+		if !shared.PathExists(snapshotMntPoint) {
+			err := os.MkdirAll(snapshotMntPoint, 0700)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = zfsRecv(filepath.Join(source, "container.bin"))
+	if err != nil {
+		return err
+	}
+
+	// Remove the temporary snapshot used to send the container.
+	zfsSnapshots, err := s.zfsPoolListSnapshots(zfsName)
+	if err != nil {
+		return err
+	}
+
+	for _, snap := range zfsSnapshots {
+		if strings.HasPrefix(snap, "backup-") {
+			s.zfsPoolVolumeSnapshotDestroy(zfsName, snap)
+		}
+	}
+
+	// zfs receive may or may not have mounted the filesystem already
+	// despite -u, so don't complain if mounting fails.
+	s.zfsPoolVolumeMount(zfsName)
+
+	shared.LogDebugf("Loaded ZFS storage volume for container \"%s\" on storage pool \"%s\".", s.volume.Name, s.pool.Name)
+	return nil
+}
+
 // - create temporary directory ${LXD_DIR}/images/lxd_images_
 // - create new zfs volume images/<fingerprint>
 // - mount the zfs volume on ${LXD_DIR}/images/lxd_images_
@@ -1415,6 +1980,70 @@ func (s *storageZfs) ImageUmount(fingerprint string) (bool, error) {
 	return true, nil
 }
 
+func (s *storageZfs) ImageVolumesGC(dryRun bool) ([]string, error) {
+	referenced, err := s.imageVolumesReferenced()
+	if err != nil {
+		return nil, err
+	}
+
+	// Images deleted while containers still depended on them are kept
+	// under "deleted/" and can go once the last of those is gone.
+	poolName := s.getOnDiskPoolName()
+	orphans := []string{}
+	for _, parent := range []string{"images", "deleted/images"} {
+		if !s.zfsFilesystemEntityExists(parent, true) {
+			continue
+		}
+
+		subvols, err := s.zfsPoolListSubvolumes(fmt.Sprintf("%s/%s", poolName, parent))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, fs := range subvols {
+			fingerprint := strings.TrimPrefix(fs, fmt.Sprintf("%s/", parent))
+			if strings.Contains(fingerprint, "/") {
+				continue
+			}
+
+			if parent == "images" && shared.StringInSlice(fingerprint, referenced) {
+				continue
+			}
+
+			// Failed creates may not have gotten to the snapshot.
+			if s.zfsFilesystemEntityExists(fmt.Sprintf("%s@readonly", fs), true) {
+				removable, err := s.zfsPoolVolumeSnapshotRemovable(fs, "readonly")
+				if err != nil {
+					return nil, err
+				}
+
+				if !removable {
+					continue
+				}
+			}
+
+			orphans = append(orphans, fs)
+			if dryRun {
+				continue
+			}
+
+			err := s.zfsPoolVolumeDestroy(fs)
+			if err != nil {
+				return nil, err
+			}
+
+			if parent == "images" {
+				err := os.RemoveAll(getImageMountPoint(s.pool.Name, fingerprint))
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return orphans, nil
+}
+
 // Helper functions
 func (s *storageZfs) zfsPoolCheck(pool string) error {
 	output, err := exec.Command(
@@ -2097,6 +2726,7 @@ type zfsMigrationSourceDriver struct {
 	zfs              *storageZfs
 	runningSnapName  string
 	stoppedSnapName  string
+	refreshSnapName  string
 }
 
 func (s *zfsMigrationSourceDriver) Snapshots() []container {
@@ -2155,14 +2785,10 @@ func (s *zfsMigrationSourceDriver) SendWhileRunning(conn *websocket.Conn, op *op
 		return s.send(conn, snapshotName, "", wrapper)
 	}
 
-	lastSnap := ""
-
-	for i, snap := range s.zfsSnapshotNames {
-		prev := ""
-		if i > 0 {
-			prev = s.zfsSnapshotNames[i-1]
-		}
+	lastSnap := s.refreshSnapName
 
+	for _, snap := range s.zfsSnapshotNames {
+		prev := lastSnap
 		lastSnap = snap
 
 		wrapper := StorageProgressReader(op, "fs_progress", snap)
@@ -2207,6 +2833,19 @@ func (s *zfsMigrationSourceDriver) Cleanup() {
 	}
 }
 
+func (s *zfsMigrationSourceDriver) Refresh(snapshotNames []string) {
+	parent, snapshots := migrationRefreshSnapshots(s.snapshots, snapshotNames)
+	if parent != nil {
+		s.refreshSnapName = fmt.Sprintf("snapshot-%s", shared.ExtractSnapshotName(parent.Name()))
+	}
+
+	s.snapshots = snapshots
+	s.zfsSnapshotNames = []string{}
+	for _, snap := range snapshots {
+		s.zfsSnapshotNames = append(s.zfsSnapshotNames, fmt.Sprintf("snapshot-%s", shared.ExtractSnapshotName(snap.Name())))
+	}
+}
+
 func (s *storageZfs) MigrationType() MigrationFSType {
 	return MigrationFSType_ZFS
 }
@@ -2262,7 +2901,7 @@ func (s *storageZfs) MigrationSource(ct container) (MigrationStorageSourceDriver
 	return &driver, nil
 }
 
-func (s *storageZfs) MigrationSink(live bool, container container, snapshots []*Snapshot, conn *websocket.Conn, srcIdmap *shared.IdmapSet, op *operation) error {
+func (s *storageZfs) MigrationSink(live bool, container container, snapshots []*Snapshot, conn *websocket.Conn, srcIdmap *shared.IdmapSet, op *operation, refresh bool) error {
 	poolName := s.getOnDiskPoolName()
 	zfsRecv := func(zfsName string, writeWrapper func(io.WriteCloser) io.WriteCloser) error {
 		zfsFsName := fmt.Sprintf("%s/%s", poolName, zfsName)
@@ -2326,15 +2965,7 @@ func (s *storageZfs) MigrationSink(live bool, container container, snapshots []*
 	}
 
 	for _, snap := range snapshots {
-		args := snapshotProtobufToContainerArgs(container.Name(), snap)
-		// Unset the pool of the orginal container and let
-		// containerLXCCreate figure out on which pool to  send it.
-		// Later we might make this more flexible.
-		for k, v := range args.Devices {
-			if v["type"] == "disk" && v["path"] == "/" {
-				args.Devices[k]["pool"] = ""
-			}
-		}
+		args := migrationSnapshotArgs(container, snap)
 		_, err := containerCreateEmptySnapshot(container.Daemon(), args)
 		if err != nil {
 			return err
@@ -2364,8 +2995,10 @@ func (s *storageZfs) MigrationSink(live bool, container container, snapshots []*
 		}
 
 		for _, snap := range zfsSnapshots {
-			// If we received a bunch of snapshots, remove the migration-send-* ones, if not, wipe any snapshot we got
-			if snapshots != nil && len(snapshots) > 0 && !strings.HasPrefix(snap, "migration-send") {
+			// If we received a bunch of snapshots or refreshed an
+			// existing container, remove the migration-send-* ones,
+			// if not, wipe any snapshot we got
+			if (refresh || len(snapshots) > 0) && !strings.HasPrefix(snap, "migration-send") {
 				continue
 			}
 
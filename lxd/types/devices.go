@@ -80,11 +80,19 @@ func (old Devices) Update(newlist Devices) (map[string]Device, map[string]Device
 			continue
 		}
 
-		for _, k := range []string{"limits.max", "limits.read", "limits.write", "limits.egress", "limits.ingress", "ipv4.address", "ipv6.address"} {
+		for _, k := range []string{"limits.max", "limits.read", "limits.write", "limits.egress", "limits.ingress", "ipv4.address", "ipv6.address", "security.acls"} {
 			delete(oldDevice, k)
 			delete(newDevice, k)
 		}
 
+		// VLANs can only be changed live on bridged nics
+		if oldDevice["nictype"] == "bridged" && newDevice["nictype"] == "bridged" {
+			for _, k := range []string{"vlan", "vlan.tagged"} {
+				delete(oldDevice, k)
+				delete(newDevice, k)
+			}
+		}
+
 		if deviceEquals(oldDevice, newDevice) {
 			delete(rmlist, key)
 			delete(addlist, key)
@@ -14,6 +14,10 @@ type CertificatesPost struct {
 type CertificatePut struct {
 	Name string `json:"name" yaml:"name"`
 	Type string `json:"type" yaml:"type"`
+
+	// API extension: certificate_restrictions
+	Restricted bool     `json:"restricted" yaml:"restricted"`
+	Containers []string `json:"containers" yaml:"containers"`
 }
 
 // Certificate represents a LXD certificate
@@ -10,12 +10,18 @@ type ContainersPost struct {
 
 	Name   string          `json:"name" yaml:"name"`
 	Source ContainerSource `json:"source" yaml:"source"`
+
+	// API extension: container_instance_type
+	InstanceType string `json:"instance_type" yaml:"instance_type"`
 }
 
 // ContainerPost represents the fields required to rename/move a LXD container
 type ContainerPost struct {
 	Migration bool   `json:"migration" yaml:"migration"`
 	Name      string `json:"name" yaml:"name"`
+
+	// API extension: container_storage_move
+	Pool string `json:"pool,omitempty" yaml:"pool,omitempty"`
 }
 
 // ContainerPut represents the modifiable fields of a LXD container
@@ -85,6 +91,9 @@ type ContainerSource struct {
 	// API extension: container_push
 	Live bool `json:"live,omitempty" yaml:"live,omitempty"`
 
+	// API extension: container_incremental_copy
+	Refresh bool `json:"refresh,omitempty" yaml:"refresh,omitempty"`
+
 	// For "copy" type
 	Source string `json:"source,omitempty" yaml:"source,omitempty"`
 }
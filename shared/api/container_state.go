@@ -25,6 +25,21 @@ type ContainerState struct {
 // ContainerStateDisk represents the disk information section of a LXD container's state
 type ContainerStateDisk struct {
 	Usage int64 `json:"usage" yaml:"usage"`
+
+	// API extension: container_disk_io
+	Counters ContainerStateDiskCounters `json:"counters" yaml:"counters"`
+	Rates    ContainerStateDiskCounters `json:"rates" yaml:"rates"`
+}
+
+// ContainerStateDiskCounters represents the block I/O counters of a disk device
+// as part of a LXD container's state, either as totals or as rates per second
+//
+// API extension: container_disk_io
+type ContainerStateDiskCounters struct {
+	BytesRead    int64 `json:"bytes_read" yaml:"bytes_read"`
+	BytesWritten int64 `json:"bytes_written" yaml:"bytes_written"`
+	OpsRead      int64 `json:"ops_read" yaml:"ops_read"`
+	OpsWritten   int64 `json:"ops_written" yaml:"ops_written"`
 }
 
 // ContainerStateCPU represents the cpu information section of a LXD container's state
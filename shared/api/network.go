@@ -37,6 +37,54 @@ type Network struct {
 	Managed bool `json:"managed" yaml:"managed"`
 }
 
+// NetworkLease represents a DHCP lease or a static address reservation on a
+// LXD managed network
+//
+// API extension: network_leases
+type NetworkLease struct {
+	Hostname string `json:"hostname" yaml:"hostname"`
+	Hwaddr   string `json:"hwaddr" yaml:"hwaddr"`
+	Address  string `json:"address" yaml:"address"`
+	Type     string `json:"type" yaml:"type"`
+}
+
+// NetworkState represents the state of a network interface and of the
+// tunnels of a LXD managed network
+//
+// API extension: network_state
+type NetworkState struct {
+	Addresses []NetworkStateAddress `json:"addresses" yaml:"addresses"`
+	Counters  NetworkStateCounters  `json:"counters" yaml:"counters"`
+	Hwaddr    string                `json:"hwaddr" yaml:"hwaddr"`
+	Mtu       int                   `json:"mtu" yaml:"mtu"`
+	State     string                `json:"state" yaml:"state"`
+	Type      string                `json:"type" yaml:"type"`
+
+	Tunnels map[string]NetworkState `json:"tunnels,omitempty" yaml:"tunnels,omitempty"`
+}
+
+// NetworkStateAddress represents a network address as part of the network
+// state
+//
+// API extension: network_state
+type NetworkStateAddress struct {
+	Family  string `json:"family" yaml:"family"`
+	Address string `json:"address" yaml:"address"`
+	Netmask string `json:"netmask" yaml:"netmask"`
+	Scope   string `json:"scope" yaml:"scope"`
+}
+
+// NetworkStateCounters represents the traffic counters as part of the
+// network state
+//
+// API extension: network_state
+type NetworkStateCounters struct {
+	BytesReceived   int64 `json:"bytes_received" yaml:"bytes_received"`
+	BytesSent       int64 `json:"bytes_sent" yaml:"bytes_sent"`
+	PacketsReceived int64 `json:"packets_received" yaml:"packets_received"`
+	PacketsSent     int64 `json:"packets_sent" yaml:"packets_sent"`
+}
+
 // Writable converts a full Network struct into a NetworkPut struct (filters read-only fields)
 func (network *Network) Writable() NetworkPut {
 	return network.NetworkPut
@@ -0,0 +1,49 @@
+package api
+
+// NetworkACLsPost represents the fields of a new LXD network ACL
+//
+// API extension: network_acl
+type NetworkACLsPost struct {
+	NetworkACLPut `yaml:",inline"`
+
+	Name string `json:"name" yaml:"name"`
+}
+
+// NetworkACLPost represents the fields required to rename a LXD network ACL
+//
+// API extension: network_acl
+type NetworkACLPost struct {
+	Name string `json:"name" yaml:"name"`
+}
+
+// NetworkACLPut represents the modifiable fields of a LXD network ACL
+//
+// API extension: network_acl
+type NetworkACLPut struct {
+	Description string           `json:"description" yaml:"description"`
+	Egress      []NetworkACLRule `json:"egress" yaml:"egress"`
+	Ingress     []NetworkACLRule `json:"ingress" yaml:"ingress"`
+}
+
+// NetworkACL represents a LXD network ACL
+//
+// API extension: network_acl
+type NetworkACL struct {
+	NetworkACLPut `yaml:",inline"`
+
+	Name   string   `json:"name" yaml:"name"`
+	UsedBy []string `json:"used_by" yaml:"used_by"`
+}
+
+// NetworkACLRule represents a single rule of a LXD network ACL
+//
+// API extension: network_acl
+type NetworkACLRule struct {
+	Action          string `json:"action" yaml:"action"`
+	Source          string `json:"source" yaml:"source"`
+	Destination     string `json:"destination" yaml:"destination"`
+	Protocol        string `json:"protocol" yaml:"protocol"`
+	SourcePort      string `json:"source_port" yaml:"source_port"`
+	DestinationPort string `json:"destination_port" yaml:"destination_port"`
+	Description     string `json:"description" yaml:"description"`
+}
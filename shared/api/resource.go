@@ -0,0 +1,63 @@
+package api
+
+// Resources represents the system resources available for LXD
+//
+// API extension: resources
+type Resources struct {
+	CPU          ResourcesCPU                    `json:"cpu" yaml:"cpu"`
+	Memory       ResourcesMemory                 `json:"memory" yaml:"memory"`
+	StoragePools map[string]ResourcesStoragePool `json:"storage_pools" yaml:"storage_pools"`
+}
+
+// ResourcesCPUSocket represents a CPU socket on the system
+//
+// API extension: resources
+type ResourcesCPUSocket struct {
+	Socket         uint64 `json:"socket" yaml:"socket"`
+	Vendor         string `json:"vendor,omitempty" yaml:"vendor,omitempty"`
+	Name           string `json:"name,omitempty" yaml:"name,omitempty"`
+	Cores          uint64 `json:"cores" yaml:"cores"`
+	Threads        uint64 `json:"threads" yaml:"threads"`
+	Frequency      uint64 `json:"frequency,omitempty" yaml:"frequency,omitempty"`
+	FrequencyTurbo uint64 `json:"frequency_turbo,omitempty" yaml:"frequency_turbo,omitempty"`
+}
+
+// ResourcesCPU represents the cpu resources available on the system
+//
+// API extension: resources
+type ResourcesCPU struct {
+	Sockets []ResourcesCPUSocket `json:"sockets" yaml:"sockets"`
+	Total   uint64               `json:"total" yaml:"total"`
+}
+
+// ResourcesMemory represents the memory resources available on the system
+//
+// API extension: resources
+type ResourcesMemory struct {
+	Used  uint64 `json:"used" yaml:"used"`
+	Total uint64 `json:"total" yaml:"total"`
+}
+
+// ResourcesStoragePool represents the resources available to a given storage pool
+//
+// API extension: resources
+type ResourcesStoragePool struct {
+	Space  ResourcesStoragePoolSpace  `json:"space,omitempty" yaml:"space,omitempty"`
+	Inodes ResourcesStoragePoolInodes `json:"inodes,omitempty" yaml:"inodes,omitempty"`
+}
+
+// ResourcesStoragePoolSpace represents the space available to a given storage pool
+//
+// API extension: resources
+type ResourcesStoragePoolSpace struct {
+	Used  uint64 `json:"used,omitempty" yaml:"used,omitempty"`
+	Total uint64 `json:"total" yaml:"total"`
+}
+
+// ResourcesStoragePoolInodes represents the inodes available to a given storage pool
+//
+// API extension: resources
+type ResourcesStoragePoolInodes struct {
+	Used  uint64 `json:"used" yaml:"used"`
+	Total uint64 `json:"total" yaml:"total"`
+}
@@ -26,6 +26,18 @@ type StorageVolume struct {
 
 	Type   string   `json:"type" yaml:"type"`
 	UsedBy []string `json:"used_by" yaml:"used_by"`
+
+	// API extension: storage_volume_attachments
+	Attachments []StorageVolumeAttachment `json:"attachments,omitempty" yaml:"attachments,omitempty"`
+}
+
+// StorageVolumeAttachment represents a running container a storage volume is
+// currently attached to.
+//
+// API extension: storage_volume_attachments
+type StorageVolumeAttachment struct {
+	Container string `json:"container" yaml:"container"`
+	Device    string `json:"device" yaml:"device"`
 }
 
 // StorageVolumePut represents the modifiable fields of a LXD storage volume.
@@ -34,6 +46,84 @@ type StorageVolume struct {
 type StorageVolumePut struct {
 	Name   string            `json:"name" yaml:"name"`
 	Config map[string]string `json:"config" yaml:"config"`
+
+	// API extension: storage_api_volume_snapshots
+	Restore string `json:"restore,omitempty" yaml:"restore,omitempty"`
+}
+
+// StorageVolumesPost represents the fields of a new LXD storage volume.
+//
+// API extension: storage_api_volume_copy
+type StorageVolumesPost struct {
+	StorageVolumePut `yaml:",inline"`
+
+	Type   string              `json:"type" yaml:"type"`
+	Source StorageVolumeSource `json:"source" yaml:"source"`
+}
+
+// StorageVolumePost represents the fields required to rename or move a LXD
+// storage volume.
+//
+// API extension: storage_api_volume_copy
+type StorageVolumePost struct {
+	Name      string `json:"name" yaml:"name"`
+	Pool      string `json:"pool,omitempty" yaml:"pool,omitempty"`
+	Migration bool   `json:"migration" yaml:"migration"`
+}
+
+// StorageVolumeSource represents the creation source for a new storage
+// volume.
+//
+// API extension: storage_api_volume_copy
+type StorageVolumeSource struct {
+	Name string `json:"name" yaml:"name"`
+	Type string `json:"type" yaml:"type"`
+	Pool string `json:"pool" yaml:"pool"`
+
+	// For "migration" type
+	Certificate string            `json:"certificate" yaml:"certificate"`
+	Mode        string            `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Operation   string            `json:"operation,omitempty" yaml:"operation,omitempty"`
+	Websockets  map[string]string `json:"secrets,omitempty" yaml:"secrets,omitempty"`
+}
+
+// StorageVolumeSnapshotsPost represents the fields available for a new LXD
+// storage volume snapshot.
+//
+// API extension: storage_api_volume_snapshots
+type StorageVolumeSnapshotsPost struct {
+	Name string `json:"name" yaml:"name"`
+}
+
+// StorageVolumeSnapshotPost represents the fields required to rename a LXD
+// storage volume snapshot.
+//
+// API extension: storage_api_volume_snapshots
+type StorageVolumeSnapshotPost struct {
+	Name string `json:"name" yaml:"name"`
+}
+
+// StorageVolumeSnapshot represents a LXD storage volume snapshot.
+//
+// API extension: storage_api_volume_snapshots
+type StorageVolumeSnapshot struct {
+	Name   string            `json:"name" yaml:"name"`
+	Config map[string]string `json:"config" yaml:"config"`
+}
+
+// StorageVolumeState represents the live state of a LXD storage volume.
+//
+// API extension: storage_volume_state
+type StorageVolumeState struct {
+	Usage StorageVolumeStateUsage `json:"usage" yaml:"usage"`
+}
+
+// StorageVolumeStateUsage represents the disk usage of a LXD storage volume.
+//
+// API extension: storage_volume_state
+type StorageVolumeStateUsage struct {
+	Used  uint64 `json:"used" yaml:"used"`
+	Total uint64 `json:"total" yaml:"total"`
 }
 
 // Writable converts a full StoragePool struct into a StoragePoolPut struct
@@ -47,3 +137,47 @@ func (storagePool *StoragePool) Writable() StoragePoolPut {
 func (storageVolume *StorageVolume) Writable() StorageVolumePut {
 	return storageVolume.StorageVolumePut
 }
+
+// StoragePoolGCPost represents the fields required to garbage collect the
+// image volumes of a LXD storage pool.
+//
+// API extension: storage_pool_gc
+type StoragePoolGCPost struct {
+	DryRun bool `json:"dry_run" yaml:"dry_run"`
+}
+
+// StoragePoolGC represents the image volumes found by garbage collecting a
+// LXD storage pool.
+//
+// API extension: storage_pool_gc
+type StoragePoolGC struct {
+	Volumes   []string `json:"volumes" yaml:"volumes"`
+	Reclaimed bool     `json:"reclaimed" yaml:"reclaimed"`
+}
+
+// StorageCheckPost represents the fields required to check the consistency of
+// the storage pools of a LXD host.
+//
+// API extension: storage_check
+type StorageCheckPost struct {
+	Repair bool `json:"repair" yaml:"repair"`
+}
+
+// StorageCheckIssue represents an inconsistency found between the database
+// and the storage pools of a LXD host.
+//
+// API extension: storage_check
+type StorageCheckIssue struct {
+	Pool     string `json:"pool" yaml:"pool"`
+	Kind     string `json:"kind" yaml:"kind"`
+	Volume   string `json:"volume" yaml:"volume"`
+	Details  string `json:"details" yaml:"details"`
+	Repaired bool   `json:"repaired" yaml:"repaired"`
+}
+
+// StorageCheck represents the result of a storage consistency check.
+//
+// API extension: storage_check
+type StorageCheck struct {
+	Issues []StorageCheckIssue `json:"issues" yaml:"issues"`
+}
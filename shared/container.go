@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type ContainerAction string
@@ -87,6 +88,46 @@ func IsAny(value string) error {
 	return nil
 }
 
+// GetSnapshotExpiry computes the expiry date of a snapshot created at refDate
+// given an expiry such as "2w" or "1d 12H". Supported units are M (minutes),
+// H (hours), d (days), w (weeks), m (months) and y (years).
+func GetSnapshotExpiry(refDate time.Time, s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	expiry := refDate
+	for _, entry := range strings.Fields(s) {
+		if len(entry) < 2 {
+			return time.Time{}, fmt.Errorf("Invalid snapshot expiry: %s", s)
+		}
+
+		value, err := strconv.Atoi(entry[:len(entry)-1])
+		if err != nil || value < 0 {
+			return time.Time{}, fmt.Errorf("Invalid snapshot expiry: %s", s)
+		}
+
+		switch entry[len(entry)-1] {
+		case 'M':
+			expiry = expiry.Add(time.Duration(value) * time.Minute)
+		case 'H':
+			expiry = expiry.Add(time.Duration(value) * time.Hour)
+		case 'd':
+			expiry = expiry.AddDate(0, 0, value)
+		case 'w':
+			expiry = expiry.AddDate(0, 0, value*7)
+		case 'm':
+			expiry = expiry.AddDate(0, value, 0)
+		case 'y':
+			expiry = expiry.AddDate(value, 0, 0)
+		default:
+			return time.Time{}, fmt.Errorf("Invalid unit in snapshot expiry: %s", s)
+		}
+	}
+
+	return expiry, nil
+}
+
 // KnownContainerConfigKeys maps all fully defined, well-known config keys
 // to an appropriate checker function, which validates whether or not a
 // given value is syntactically legal.
@@ -167,6 +208,55 @@ var KnownContainerConfigKeys = map[string]func(value string) error{
 
 	"linux.kernel_modules": IsAny,
 
+	"snapshots.schedule": func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		_, err := ParseCronSchedule(value)
+		return err
+	},
+	"snapshots.pattern": func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		if strings.Contains(value, "/") {
+			return fmt.Errorf("Invalid snapshot pattern \"%s\": it can't contain \"/\"", value)
+		}
+
+		// Only allow a single "%d", "%%" being a literal percent sign
+		count := 0
+		for i := 0; i < len(value); i++ {
+			if value[i] != '%' {
+				continue
+			}
+
+			if i+1 < len(value) && value[i+1] == '%' {
+				i++
+				continue
+			}
+
+			if i+1 < len(value) && value[i+1] == 'd' {
+				count++
+				i++
+				continue
+			}
+
+			return fmt.Errorf("Invalid snapshot pattern \"%s\": only \"%%d\" and \"%%%%\" are allowed", value)
+		}
+
+		if count != 1 {
+			return fmt.Errorf("Invalid snapshot pattern \"%s\": it must contain \"%%d\" exactly once", value)
+		}
+
+		return nil
+	},
+	"snapshots.expiry": func(value string) error {
+		_, err := GetSnapshotExpiry(time.Now(), value)
+		return err
+	},
+
 	"security.nesting":    IsBool,
 	"security.privileged": IsBool,
 
@@ -0,0 +1,155 @@
+package shared
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed cron expression (minute, hour, day of month,
+// month and day of week).
+type CronSchedule struct {
+	minutes  map[int]bool
+	hours    map[int]bool
+	days     map[int]bool
+	months   map[int]bool
+	weekdays map[int]bool
+
+	// Whether the day of month and day of week fields are unrestricted
+	// ("*"). Following cron semantics, if both are restricted, a day
+	// matches if either does.
+	anyDay     bool
+	anyWeekday bool
+}
+
+var cronAliases = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCronSchedule parses a standard five fields cron expression, such as
+// "0 */6 * * *", or one of the @yearly, @monthly, @weekly, @daily and @hourly
+// aliases.
+func ParseCronSchedule(spec string) (*CronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	alias, ok := cronAliases[spec]
+	if ok {
+		spec = alias
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("Invalid cron expression \"%s\": expected 5 fields, got %d", spec, len(fields))
+	}
+
+	var err error
+	schedule := CronSchedule{}
+
+	schedule.minutes, err = parseCronField(fields[0], 0, 59)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule.hours, err = parseCronField(fields[1], 0, 23)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule.days, err = parseCronField(fields[2], 1, 31)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule.months, err = parseCronField(fields[3], 1, 12)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule.weekdays, err = parseCronField(fields[4], 0, 7)
+	if err != nil {
+		return nil, err
+	}
+
+	// Both 0 and 7 are Sunday
+	if schedule.weekdays[7] {
+		schedule.weekdays[0] = true
+	}
+
+	schedule.anyDay = fields[2] == "*"
+	schedule.anyWeekday = fields[4] == "*"
+
+	return &schedule, nil
+}
+
+func parseCronField(field string, min int, max int) (map[int]bool, error) {
+	values := map[int]bool{}
+
+	for _, entry := range strings.Split(field, ",") {
+		step := 1
+		fields := strings.SplitN(entry, "/", 2)
+		if len(fields) == 2 {
+			var err error
+			step, err = strconv.Atoi(fields[1])
+			if err != nil || step < 1 {
+				return nil, fmt.Errorf("Invalid step in cron field \"%s\"", field)
+			}
+		}
+
+		start := min
+		end := max
+		if fields[0] != "*" {
+			bounds := strings.SplitN(fields[0], "-", 2)
+
+			var err error
+			start, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return nil, fmt.Errorf("Invalid value in cron field \"%s\"", field)
+			}
+
+			end = start
+			if len(bounds) == 2 {
+				end, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return nil, fmt.Errorf("Invalid value in cron field \"%s\"", field)
+				}
+			} else if len(fields) == 2 {
+				// "N/step" means starting at N
+				end = max
+			}
+		}
+
+		if start < min || end > max || start > end {
+			return nil, fmt.Errorf("Out of range value in cron field \"%s\" (allowed: %d-%d)", field, min, max)
+		}
+
+		for i := start; i <= end; i += step {
+			values[i] = true
+		}
+	}
+
+	return values, nil
+}
+
+// Matches returns whether the schedule triggers during the minute of the
+// given time.
+func (s *CronSchedule) Matches(t time.Time) bool {
+	if !s.minutes[t.Minute()] || !s.hours[t.Hour()] || !s.months[int(t.Month())] {
+		return false
+	}
+
+	dayMatch := s.days[t.Day()]
+	weekdayMatch := s.weekdays[int(t.Weekday())]
+
+	if s.anyDay || s.anyWeekday {
+		return dayMatch && weekdayMatch
+	}
+
+	return dayMatch || weekdayMatch
+}
@@ -0,0 +1,55 @@
+package shared
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronSchedule(t *testing.T) {
+	valid := []string{"* * * * *", "0 */6 * * *", "30 2 1-15 * 1,3,5", "5/10 * * 1-12/2 *", "@daily"}
+	for _, spec := range valid {
+		_, err := ParseCronSchedule(spec)
+		if err != nil {
+			t.Errorf("Failed to parse \"%s\": %s", spec, err)
+		}
+	}
+
+	invalid := []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "5-1 * * * *"}
+	for _, spec := range invalid {
+		_, err := ParseCronSchedule(spec)
+		if err == nil {
+			t.Errorf("Parsed invalid cron expression \"%s\"", spec)
+		}
+	}
+}
+
+func TestCronScheduleMatches(t *testing.T) {
+	// Wednesday
+	date := time.Date(2017, time.March, 15, 6, 30, 0, 0, time.UTC)
+
+	matches := map[string]bool{
+		"* * * * *":       true,
+		"30 6 * * *":      true,
+		"0 */6 * * *":     false,
+		"30 */6 * * *":    true,
+		"30 6 * * 3":      true,
+		"30 6 * * 1":      false,
+		"30 6 1 * 3":      true,
+		"30 6 1 * 1":      false,
+		"30 6 15 3 *":     true,
+		"30 6 15 4 *":     false,
+		"@hourly":         false,
+		"0-40/10 6 * * *": true,
+	}
+
+	for spec, expected := range matches {
+		schedule, err := ParseCronSchedule(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if schedule.Matches(date) != expected {
+			t.Errorf("Wrong match result for \"%s\": expected %v", spec, expected)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+#!/bin/sh
+
+ceph_setup() {
+  # shellcheck disable=2039
+  local LXD_DIR
+
+  LXD_DIR=$1
+
+  echo "==> Setting up CEPH backend in ${LXD_DIR}"
+
+  if ! which rbd >/dev/null 2>&1; then
+    echo "Couldn't find the rbd binary"; false
+  fi
+
+  # The tests expect a running single-node cluster (e.g. microceph or
+  # vstart.sh) reachable through the default configuration.
+  if ! ceph --cluster "${LXD_CEPH_CLUSTER:-ceph}" status >/dev/null 2>&1; then
+    echo "Couldn't reach the CEPH cluster"; false
+  fi
+}
+
+ceph_configure() {
+  # shellcheck disable=2039
+  local LXD_DIR
+
+  LXD_DIR=$1
+
+  echo "==> Configuring CEPH backend in ${LXD_DIR}"
+
+  lxc storage create "lxdtest-$(basename "${LXD_DIR}")" ceph ceph.cluster_name="${LXD_CEPH_CLUSTER:-ceph}" volume.size=25MB ceph.osd.pg_num=1
+  lxc profile device add default root disk path="/" pool="lxdtest-$(basename "${LXD_DIR}")"
+}
+
+ceph_teardown() {
+  # shellcheck disable=2039
+  local LXD_DIR
+
+  LXD_DIR=$1
+
+  echo "==> Tearing down CEPH backend in ${LXD_DIR}"
+
+  # The OSD pool is removed along with the storage pool, make sure nothing
+  # is left behind if that didn't happen.
+  ceph --cluster "${LXD_CEPH_CLUSTER:-ceph}" osd pool delete "lxdtest-$(basename "${LXD_DIR}")" "lxdtest-$(basename "${LXD_DIR}")" --yes-i-really-really-mean-it >/dev/null 2>&1 || true
+}
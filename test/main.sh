@@ -302,6 +302,7 @@ kill_lxd() {
     check_empty_table "${daemon_dir}/lxd.db" "containers_profiles"
     check_empty_table "${daemon_dir}/lxd.db" "networks"
     check_empty_table "${daemon_dir}/lxd.db" "networks_config"
+    check_empty_table "${daemon_dir}/lxd.db" "networks_acls"
     check_empty_table "${daemon_dir}/lxd.db" "images"
     check_empty_table "${daemon_dir}/lxd.db" "images_aliases"
     check_empty_table "${daemon_dir}/lxd.db" "images_properties"
@@ -577,10 +578,18 @@ run_test test_concurrent_exec "concurrent exec"
 run_test test_concurrent "concurrent startup"
 run_test test_snapshots "container snapshots"
 run_test test_snap_restore "snapshot restores"
+run_test test_container_import_export "container import and export"
 run_test test_config_profiles "profiles and configuration"
 run_test test_server_config "server configuration"
 run_test test_filemanip "file manipulations"
 run_test test_network "network management"
+run_test test_network_acl "network ACLs"
+run_test test_proxy_device "proxy device"
+run_test test_projects "projects"
+run_test test_certificate_restrictions "restricted certificates"
+run_test test_metrics "metrics"
+run_test test_instance_types "instance types"
+run_test test_resources "resources"
 run_test test_idmap "id mapping"
 run_test test_template "file templating"
 run_test test_pki "PKI mode"
@@ -593,5 +602,11 @@ run_test test_mem_profiling "memory profiling"
 run_test test_storage "storage"
 run_test test_lxd_autoinit "lxd init auto"
 run_test test_storage_profiles "storage profiles"
+run_test test_storage_volume_state "storage volume state"
+run_test test_storage_pool_resize "storage pool resize"
+run_test test_container_move_pool "container move between storage pools"
+run_test test_storage_volume_attach "storage volume attachments"
+run_test test_storage_pool_gc "storage pool image volume garbage collection"
+run_test test_storage_check "storage consistency check"
 
 TEST_RESULT=success
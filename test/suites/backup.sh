@@ -0,0 +1,62 @@
+#!/bin/sh
+
+test_container_import_export() {
+  ensure_import_testimage
+  ensure_has_localhost_remote "${LXD_ADDR}"
+
+  lxc init testimage foo
+  lxc snapshot foo
+
+  # create and list a backup through the API
+  wait_for "${LXD_ADDR}" my_curl -X POST "https://${LXD_ADDR}/1.0/containers/foo/backups" -d "{\"name\":\"backup0\"}"
+  my_curl "https://${LXD_ADDR}/1.0/containers/foo/backups" | grep -q "/1.0/containers/foo/backups/backup0"
+  [ -f "${LXD_DIR}/backups/foo/backup0" ]
+
+  # rename it
+  wait_for "${LXD_ADDR}" my_curl -X POST "https://${LXD_ADDR}/1.0/containers/foo/backups/backup0" -d "{\"name\":\"backup1\"}"
+  [ ! -f "${LXD_DIR}/backups/foo/backup0" ]
+  [ -f "${LXD_DIR}/backups/foo/backup1" ]
+
+  # and remove it
+  wait_for "${LXD_ADDR}" my_curl -X DELETE "https://${LXD_ADDR}/1.0/containers/foo/backups/backup1"
+  [ ! -f "${LXD_DIR}/backups/foo/backup1" ]
+
+  # export with snapshots and import back
+  lxc export foo "${LXD_DIR}/foo.backup"
+  tar -tf "${LXD_DIR}/foo.backup" | grep -q "backup/snapshots/snap0"
+  lxc delete --force foo
+  ! lxc list | grep -q foo || false
+
+  lxc import "${LXD_DIR}/foo.backup"
+  lxc info foo | grep -q snap0
+  lxc start foo
+  lxc stop --force foo
+
+  # importing an existing container must fail
+  ! lxc import "${LXD_DIR}/foo.backup" || false
+
+  # export without snapshots
+  lxc export foo "${LXD_DIR}/foo-container-only.backup" --container-only
+  ! tar -tf "${LXD_DIR}/foo-container-only.backup" | grep -q "backup/snapshots/" || false
+  lxc delete --force foo
+
+  lxc import "${LXD_DIR}/foo-container-only.backup"
+  ! lxc info foo | grep -q snap0 || false
+  lxc delete --force foo
+
+  # optimized backups are only supported by btrfs and zfs
+  if [ "${LXD_BACKEND}" = "btrfs" ] || [ "${LXD_BACKEND}" = "zfs" ]; then
+    lxc init testimage foo
+    lxc snapshot foo
+    lxc export foo "${LXD_DIR}/foo-optimized.backup" --optimized-storage
+    tar -tf "${LXD_DIR}/foo-optimized.backup" | grep -q "backup/container.bin"
+    lxc delete --force foo
+
+    lxc import "${LXD_DIR}/foo-optimized.backup"
+    lxc info foo | grep -q snap0
+    lxc start foo
+    lxc delete --force foo
+  fi
+
+  rm -f "${LXD_DIR}"/foo*.backup
+}
@@ -0,0 +1,51 @@
+#!/bin/sh
+
+test_certificate_restrictions() {
+  ensure_import_testimage
+  ensure_has_localhost_remote "${LXD_ADDR}"
+
+  gen_cert restricted
+  lxc config trust add "${LXD_CONF}/restricted.crt" --restricted --containers=ci1
+  lxc config trust list | grep -q "$(openssl x509 -in "${LXD_CONF}/restricted.crt" -noout -fingerprint -sha256 | cut -d= -f2 | tr -d : | tr '[:upper:]' '[:lower:]' | cut -c1-12)"
+
+  lxc init testimage ci1
+  lxc init testimage ci2
+
+  restricted_curl() {
+    curl -k -s --cert "${LXD_CONF}/restricted.crt" --key "${LXD_CONF}/restricted.key" "$@"
+  }
+
+  # Only the allowed container is visible
+  restricted_curl "https://${LXD_ADDR}/1.0" | jq -r .metadata.auth | grep -q trusted
+  restricted_curl "https://${LXD_ADDR}/1.0/containers" | jq -r ".metadata[]" | grep -q ci1
+  ! restricted_curl "https://${LXD_ADDR}/1.0/containers" | jq -r ".metadata[]" | grep -q ci2 || false
+  [ "$(restricted_curl "https://${LXD_ADDR}/1.0/containers/ci1" | jq -r .status_code)" = "200" ]
+  [ "$(restricted_curl "https://${LXD_ADDR}/1.0/containers/ci2" | jq -r .error_code)" = "403" ]
+
+  # Nothing outside of the containers can be touched
+  [ "$(restricted_curl "https://${LXD_ADDR}/1.0/certificates" | jq -r .error_code)" = "403" ]
+  [ "$(restricted_curl "https://${LXD_ADDR}/1.0/profiles" | jq -r .error_code)" = "403" ]
+  [ "$(restricted_curl -X POST -d '{"name": "ci3", "source": {"type": "image", "alias": "testimage"}}' "https://${LXD_ADDR}/1.0/containers" | jq -r .error_code)" = "403" ]
+  [ "$(restricted_curl -X POST -d '{"name": "ci3"}' "https://${LXD_ADDR}/1.0/containers/ci1" | jq -r .error_code)" = "403" ]
+  lxc info ci1
+
+  # Nor can the containers be used to escape
+  [ "$(restricted_curl -X PATCH -d '{"config": {"security.privileged": "true"}}' "https://${LXD_ADDR}/1.0/containers/ci1" | jq -r .error_code)" = "400" ]
+  [ "$(restricted_curl -X PATCH -d '{"devices": {"root": {"type": "disk", "path": "/mnt", "source": "/"}}}' "https://${LXD_ADDR}/1.0/containers/ci1" | jq -r .error_code)" = "400" ]
+  [ "$(restricted_curl -X PATCH -d '{"devices": {"p": {"type": "proxy", "listen": "tcp:0.0.0.0:1234", "connect": "tcp:127.0.0.1:22"}}}' "https://${LXD_ADDR}/1.0/containers/ci1" | jq -r .error_code)" = "400" ]
+  [ "$(restricted_curl -X PATCH -d '{"devices": {"n": {"type": "nic", "nictype": "macvlan", "parent": "lo"}}}' "https://${LXD_ADDR}/1.0/containers/ci1" | jq -r .error_code)" = "400" ]
+  [ "$(restricted_curl -X PATCH -d '{"config": {"linux.kernel_modules": "dummy"}}' "https://${LXD_ADDR}/1.0/containers/ci1" | jq -r .error_code)" = "400" ]
+
+  # Profiles are checked once applied
+  lxc profile create restricted
+  lxc profile device add restricted kvm unix-char path=/dev/kvm
+  [ "$(restricted_curl -X PATCH -d '{"profiles": ["default", "restricted"]}' "https://${LXD_ADDR}/1.0/containers/ci1" | jq -r .error_code)" = "400" ]
+  lxc profile delete restricted
+
+  # Harmless changes are allowed
+  [ "$(restricted_curl -X PATCH -d '{"config": {"limits.cpu": "1"}}' "https://${LXD_ADDR}/1.0/containers/ci1" | jq -r .status_code)" = "200" ]
+
+  lxc delete ci1
+  lxc delete ci2
+  lxc config trust remove "$(openssl x509 -in "${LXD_CONF}/restricted.crt" -noout -fingerprint -sha256 | cut -d= -f2 | tr -d : | tr '[:upper:]' '[:lower:]')"
+}
@@ -0,0 +1,67 @@
+#!/bin/sh
+
+test_container_move_pool() {
+  # shellcheck disable=2039
+
+  LXD_STORAGE_DIR=$(mktemp -d -p "${TEST_DIR}" XXXXXXXXX)
+  chmod +x "${LXD_STORAGE_DIR}"
+  spawn_lxd "${LXD_STORAGE_DIR}" false
+  (
+    set -e
+    # shellcheck disable=2030
+    LXD_DIR="${LXD_STORAGE_DIR}"
+
+    ensure_import_testimage
+
+    lxc storage create "lxdtest-$(basename "${LXD_DIR}")-dir1" dir
+    lxc storage create "lxdtest-$(basename "${LXD_DIR}")-dir2" dir
+
+    lxc init testimage c1 -s "lxdtest-$(basename "${LXD_DIR}")-dir1"
+    lxc snapshot c1 snap0
+    echo "moved" | lxc file push - c1/root/moved
+
+    # Moving to the pool the container already is on fails.
+    ! lxc move c1 c1 --storage "lxdtest-$(basename "${LXD_DIR}")-dir1"
+
+    # Containers can't be renamed while being moved.
+    ! lxc move c1 c2 --storage "lxdtest-$(basename "${LXD_DIR}")-dir2"
+
+    lxc move c1 c1 --storage "lxdtest-$(basename "${LXD_DIR}")-dir2"
+    [ "$(lxc config device get c1 root pool)" = "lxdtest-$(basename "${LXD_DIR}")-dir2" ]
+    [ -d "${LXD_DIR}/storage-pools/lxdtest-$(basename "${LXD_DIR}")-dir2/containers/c1/rootfs" ]
+    [ -d "${LXD_DIR}/storage-pools/lxdtest-$(basename "${LXD_DIR}")-dir2/snapshots/c1/snap0" ]
+    [ ! -e "${LXD_DIR}/storage-pools/lxdtest-$(basename "${LXD_DIR}")-dir1/containers/c1" ]
+    lxc file pull c1/root/moved - | grep -q "^moved$"
+    lxc info c1 | grep -q snap0
+
+    # Running containers can't be moved.
+    lxc start c1
+    ! lxc move c1 c1 --storage "lxdtest-$(basename "${LXD_DIR}")-dir1"
+    lxc stop c1 --force
+
+    if which zfs >/dev/null 2>&1; then
+      lxc storage create "lxdtest-$(basename "${LXD_DIR}")-zfs" zfs
+
+      # Moving between different drivers goes through rsync.
+      lxc move c1 c1 --storage "lxdtest-$(basename "${LXD_DIR}")-zfs"
+      [ "$(lxc config device get c1 root pool)" = "lxdtest-$(basename "${LXD_DIR}")-zfs" ]
+      zfs list -H -o name "lxdtest-$(basename "${LXD_DIR}")-zfs/containers/c1@snapshot-snap0"
+      lxc file pull c1/root/moved - | grep -q "^moved$"
+
+      lxc move c1 c1 --storage "lxdtest-$(basename "${LXD_DIR}")-dir1"
+    fi
+
+    lxc delete c1
+    lxc image delete testimage
+
+    lxc storage delete "lxdtest-$(basename "${LXD_DIR}")-dir1"
+    lxc storage delete "lxdtest-$(basename "${LXD_DIR}")-dir2"
+    if which zfs >/dev/null 2>&1; then
+      lxc storage delete "lxdtest-$(basename "${LXD_DIR}")-zfs"
+    fi
+  )
+
+  # shellcheck disable=SC2031
+  LXD_DIR="${LXD_DIR}"
+  kill_lxd "${LXD_STORAGE_DIR}"
+}
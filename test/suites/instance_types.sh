@@ -0,0 +1,38 @@
+#!/bin/sh
+
+test_instance_types() {
+  ensure_import_testimage
+
+  # Explicit CPU and memory
+  lxc init testimage c1 -t c2-m4
+  [ "$(lxc config get c1 limits.cpu)" = "2" ]
+  [ "$(lxc config get c1 limits.memory)" = "4096MB" ]
+  lxc delete c1
+
+  # Built-in preset, with and without its cloud
+  lxc init testimage c2 -t t2.micro
+  [ "$(lxc config get c2 limits.cpu)" = "1" ]
+  [ "$(lxc config get c2 limits.memory)" = "1024MB" ]
+  lxc delete c2
+
+  lxc init testimage c3 -t aws:t2.nano -c limits.memory=256MB
+  [ "$(lxc config get c3 limits.cpu)" = "1" ]
+  [ "$(lxc config get c3 limits.memory)" = "256MB" ]
+  lxc delete c3
+
+  # Local presets
+  cat > "${LXD_DIR}/instance-types.yaml" << EOF2
+local:
+  tiny:
+    cpu: 0.5
+    mem: 0.25
+EOF2
+  lxc init testimage c4 -t tiny
+  [ "$(lxc config get c4 limits.cpu.allowance)" = "50ms/100ms" ]
+  [ "$(lxc config get c4 limits.memory)" = "256MB" ]
+  lxc delete c4
+  rm "${LXD_DIR}/instance-types.yaml"
+
+  ! lxc init testimage c5 -t tiny || false
+  ! lxc init testimage c5 -t aws:m9.huge || false
+}
@@ -0,0 +1,39 @@
+#!/bin/sh
+
+test_metrics() {
+  ensure_import_testimage
+  ensure_has_localhost_remote "${LXD_ADDR}"
+
+  lxc launch testimage c1
+  lxc init testimage c2
+
+  gen_cert metrics
+  lxc config trust add "${LXD_CONF}/metrics.crt" --metrics
+
+  metrics_curl() {
+    curl -k -s --cert "${LXD_CONF}/metrics.crt" --key "${LXD_CONF}/metrics.key" "$@"
+  }
+
+  # The metrics are readable with the metrics certificate
+  metrics_curl "https://${LXD_ADDR}/1.0/metrics" > "${LXD_DIR}/metrics.txt"
+  grep -q '^lxd_container_running{name="c1",project="default"} 1$' "${LXD_DIR}/metrics.txt"
+  grep -q '^lxd_container_running{name="c2",project="default"} 0$' "${LXD_DIR}/metrics.txt"
+  grep -q '^lxd_processes{name="c1",project="default"}' "${LXD_DIR}/metrics.txt"
+  grep -q '^# TYPE lxd_goroutines gauge$' "${LXD_DIR}/metrics.txt"
+  grep -q '^lxd_event_listeners ' "${LXD_DIR}/metrics.txt"
+  grep -q '^lxd_images_cached ' "${LXD_DIR}/metrics.txt"
+  tail -n1 "${LXD_DIR}/metrics.txt" | grep -q '^# EOF$'
+  rm -f "${LXD_DIR}/metrics.txt"
+
+  # But nothing else is
+  [ "$(metrics_curl "https://${LXD_ADDR}/1.0/containers" | jq -r .error_code)" = "403" ]
+  [ "$(metrics_curl "https://${LXD_ADDR}/1.0" | jq -r .metadata.auth)" = "untrusted" ]
+
+  # Untrusted clients can't read the metrics
+  gen_cert untrusted
+  [ "$(curl -k -s --cert "${LXD_CONF}/untrusted.crt" --key "${LXD_CONF}/untrusted.key" "https://${LXD_ADDR}/1.0/metrics" | jq -r .error_code)" = "403" ]
+
+  lxc delete c1 --force
+  lxc delete c2
+  lxc config trust remove "$(openssl x509 -in "${LXD_CONF}/metrics.crt" -noout -fingerprint -sha256 | cut -d= -f2 | tr -d : | tr '[:upper:]' '[:lower:]')"
+}
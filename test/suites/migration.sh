@@ -85,6 +85,28 @@ test_migration() {
   lxc_remote list l2: | grep RUNNING | grep nonlive
   lxc_remote delete l2:nonlive --force
 
+  # test refreshing an existing copy
+  lxc_remote init testimage l1:refreshee
+  lxc_remote snapshot l1:refreshee snap0
+  lxc_remote copy l1:refreshee l2:refreshee --refresh
+  lxc_remote info l2:refreshee | grep snap0
+
+  lxc_remote snapshot l1:refreshee snap1
+  lxc_remote config set l1:refreshee user.tester foo
+  lxc_remote copy l1:refreshee l2:refreshee --refresh
+  lxc_remote info l2:refreshee | grep snap1
+  [ "$(lxc_remote config get l2:refreshee user.tester)" = "foo" ]
+
+  # snapshots removed from the source go away on the target
+  lxc_remote delete l1:refreshee/snap1
+  lxc_remote copy l1:refreshee l2:refreshee --refresh
+  ! lxc_remote info l2:refreshee | grep -q snap1 || false
+
+  # refreshing a running container isn't allowed
+  lxc_remote start l2:refreshee
+  ! lxc_remote copy l1:refreshee l2:refreshee --refresh || false
+  lxc_remote delete l1:refreshee l2:refreshee --force
+
   if ! which criu >/dev/null 2>&1; then
     echo "==> SKIP: live migration with CRIU (missing binary)"
     return
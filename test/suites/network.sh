@@ -13,6 +13,8 @@ test_network() {
   lxc network set lxdt$$ ipv4.routing false
   lxc network set lxdt$$ ipv6.routing false
   lxc network set lxdt$$ ipv6.dhcp.stateful true
+  lxc network info lxdt$$ | grep -q "MTU: "
+  [ "$(my_curl "https://${LXD_ADDR}/1.0/networks/lxdt$$/state" | jq -r .metadata.hwaddr)" = "$(cat /sys/class/net/lxdt$$/address)" ]
   lxc network delete lxdt$$
 
   # Unconfigured bridge
@@ -28,6 +30,27 @@ test_network() {
   lxc config device set nettest eth0 ipv6.address "${v6_addr}"
   grep -q "${v4_addr}.*nettest" "${LXD_DIR}/networks/lxdt$$/dnsmasq.hosts"
   grep -q "${v6_addr}.*nettest" "${LXD_DIR}/networks/lxdt$$/dnsmasq.hosts"
+
+  # Static reservations are listed even for stopped containers
+  lxc network list-leases lxdt$$ | grep STATIC | grep -q "${v4_addr}"
+  lxc network list-leases lxdt$$ | grep STATIC | grep -q "${v6_addr}"
+  [ "$(my_curl "https://${LXD_ADDR}/1.0/networks/lxdt$$/leases" | jq -r '.metadata[] | select(.type == "static") | .hostname' | sort -u)" = "nettest" ]
+
+  # The static records are served by the built-in DNS server
+  dns_port="$(local_tcp_port)"
+  lxc config set core.dns_address "127.0.0.1:${dns_port}"
+  if which dig >/dev/null 2>&1; then
+    dig +short -p "${dns_port}" @127.0.0.1 nettest.test A | grep -qx "${v4_addr}"
+    [ "$(dig +short -p "${dns_port}" @127.0.0.1 -x "${v4_addr}")" = "nettest.test." ]
+    dig -p "${dns_port}" @127.0.0.1 missing.test A | grep -q NXDOMAIN
+    dig -p "${dns_port}" @127.0.0.1 example.com A | grep -q REFUSED
+
+    # Failing to bind the new address keeps the current server running
+    ! lxc config set core.dns_address "${LXD_ADDR}"
+    dig +short -p "${dns_port}" @127.0.0.1 nettest.test A | grep -qx "${v4_addr}"
+  fi
+  lxc config unset core.dns_address
+
   lxc start nettest
 
   SUCCESS=0
@@ -40,5 +63,33 @@ test_network() {
   [ "${SUCCESS}" = "0" ] && (echo "Container static IP wasn't applied" && false)
 
   lxc delete nettest -f
+
+  # VLANs on bridged nics
+  lxc init testimage nettest
+  lxc network attach lxdt$$ nettest eth0
+  ! lxc config device set nettest eth0 vlan 4095 || false
+  ! lxc config device set nettest eth0 vlan.tagged 10,abc || false
+  lxc config device set nettest eth0 host_name "vlan$$"
+  lxc config device set nettest eth0 vlan 10
+  lxc start nettest
+
+  SUCCESS=0
+  # shellcheck disable=SC2034
+  for i in $(seq 10); do
+    bridge vlan show dev "vlan$$" | grep -q "10 PVID" && SUCCESS=1 && break
+    sleep 1
+  done
+
+  [ "${SUCCESS}" = "0" ] && (echo "Container VLAN wasn't applied" && false)
+  [ "$(cat /sys/class/net/lxdt$$/bridge/vlan_filtering)" = "1" ]
+
+  # Changes are applied live
+  lxc config device set nettest eth0 vlan.tagged 20,30
+  bridge vlan show dev "vlan$$" | grep -q "20"
+  bridge vlan show dev "vlan$$" | grep -q "30"
+  lxc config device unset nettest eth0 vlan
+  ! bridge vlan show dev "vlan$$" | grep -q "10 PVID" || false
+  lxc delete nettest -f
+
   lxc network delete lxdt$$
 }
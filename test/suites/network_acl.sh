@@ -0,0 +1,81 @@
+#!/bin/sh
+
+test_network_acl() {
+  ensure_import_testimage
+  ensure_has_localhost_remote "${LXD_ADDR}"
+
+  # Basic management
+  lxc network acl create aclt$$
+  lxc network acl list | grep -q "aclt$$"
+  cat <<EOL | lxc network acl edit aclt$$
+description: Test ACL
+ingress:
+- action: allow
+  protocol: tcp
+  destination_port: "22,80-90"
+- action: drop
+  source: 10.0.0.0/8
+egress: []
+EOL
+  lxc network acl show aclt$$ | grep -q "description: Test ACL"
+  [ "$(my_curl "https://${LXD_ADDR}/1.0/network-acls/aclt$$" | jq -r '.metadata.ingress | length')" = "2" ]
+
+  # Invalid rules are refused
+  ! cat <<EOL | lxc network acl edit aclt$$ || false
+ingress:
+- action: accept
+EOL
+  ! cat <<EOL | lxc network acl edit aclt$$ || false
+ingress:
+- action: allow
+  destination_port: "22"
+EOL
+
+  # PATCH keeps the rules
+  my_curl -X PATCH "https://${LXD_ADDR}/1.0/network-acls/aclt$$" -d '{"description": "Patched"}'
+  [ "$(my_curl "https://${LXD_ADDR}/1.0/network-acls/aclt$$" | jq -r '.metadata.ingress | length')" = "2" ]
+  lxc network acl show aclt$$ | grep -q "description: Patched"
+
+  # Unknown ACLs can't be referenced
+  lxc network create lxdt$$ ipv6.address=none
+  ! lxc network set lxdt$$ security.acls missing$$ || false
+  ! lxc network create lxdt2$$ security.acls=missing$$ || false
+
+  # Network ACLs
+  lxc network set lxdt$$ security.acls aclt$$
+  lxc network acl show aclt$$ | grep -q "/1.0/networks/lxdt$$"
+  iptables -w -S "lxdacl-in-lxdt$$" | grep -q "10.0.0.0/8"
+  ! lxc network acl delete aclt$$ || false
+  ! lxc network acl rename aclt$$ aclt2$$ || false
+
+  # Rule changes are applied live
+  cat <<EOL | lxc network acl edit aclt$$
+ingress:
+- action: reject
+  source: 192.168.0.0/16
+egress: []
+EOL
+  iptables -w -S "lxdacl-in-lxdt$$" | grep -q "192.168.0.0/16"
+  ! iptables -w -S "lxdacl-in-lxdt$$" | grep -q "10.0.0.0/8" || false
+
+  lxc network unset lxdt$$ security.acls
+  ! iptables -w -S "lxdacl-in-lxdt$$" || false
+
+  # Nic ACLs
+  lxc init testimage acltest
+  ! lxc config device add acltest eth1 nic nictype=macvlan parent=lxdt$$ security.acls=aclt$$ || false
+  lxc network attach lxdt$$ acltest eth0
+  lxc config device set acltest eth0 security.acls aclt$$
+  lxc network acl show aclt$$ | grep -q "/1.0/containers/acltest"
+  ! lxc network acl delete aclt$$ || false
+  lxc delete acltest --force
+
+  # Renaming and deleting unused ACLs
+  lxc network acl create aclt2$$
+  ! lxc network acl rename aclt$$ aclt2$$ || false
+  lxc network acl delete aclt2$$
+  lxc network acl rename aclt$$ aclt2$$
+  ! lxc network acl show aclt$$ || false
+  lxc network acl delete aclt2$$
+  lxc network delete lxdt$$
+}
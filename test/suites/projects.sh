@@ -0,0 +1,50 @@
+#!/bin/sh
+
+test_projects() {
+  ensure_import_testimage
+  ensure_has_localhost_remote "${LXD_ADDR}"
+
+  # Create a project
+  lxc project create foo
+  lxc project list | grep -q foo
+  lxc project show foo | grep -q "features.images"
+
+  # Invalid names and keys are rejected
+  ! lxc project create foo_bar || false
+  ! lxc project create bar blah=1 || false
+
+  # The default project can't be removed or renamed
+  ! lxc project delete default || false
+  ! lxc project rename default bar || false
+
+  # Empty projects can be renamed
+  lxc project rename foo bar
+  lxc project rename bar foo
+
+  # Containers with the same name can live in different projects
+  lxc init testimage c1
+  lxc project set foo features.images false
+  lxc project switch foo
+  lxc init testimage c1
+  lxc list | grep -q c1
+  lxc info c1 | grep -q "Name: c1"
+  [ "$(my_curl "https://${LXD_ADDR}/1.0/containers?project=foo" | jq -r '.metadata[0]')" = "/1.0/containers/c1?project=foo" ]
+
+  # Profiles are per project
+  lxc profile list | grep -q default
+  lxc profile create p1
+  lxc project switch default
+  ! lxc profile show p1 || false
+
+  # Non-empty projects can't be removed
+  ! lxc project delete foo || false
+
+  lxc project switch foo
+  lxc delete c1
+  lxc profile delete p1
+  lxc project switch default
+
+  lxc delete c1
+  lxc project delete foo
+  ! lxc project list | grep -q foo || false
+}
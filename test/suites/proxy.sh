@@ -0,0 +1,57 @@
+#!/bin/sh
+
+test_proxy_device() {
+  ensure_import_testimage
+  ensure_has_localhost_remote "${LXD_ADDR}"
+
+  HOST_TCP_PORT=$(local_tcp_port)
+
+  # invalid configurations
+  lxc init testimage proxyTester
+  ! lxc config device add proxyTester proxyDev proxy listen="tcp:127.0.0.1:${HOST_TCP_PORT}" || false
+  ! lxc config device add proxyTester proxyDev proxy listen="sctp:127.0.0.1:${HOST_TCP_PORT}" connect="tcp:127.0.0.1:4321" || false
+  ! lxc config device add proxyTester proxyDev proxy listen="udp:127.0.0.1:${HOST_TCP_PORT}" connect="tcp:127.0.0.1:4321" || false
+  ! lxc config device add proxyTester proxyDev proxy listen="unix:${LXD_DIR}/proxy.sock" connect="tcp:127.0.0.1:4321" || false
+
+  # the forwarder is spawned on start
+  lxc config device add proxyTester proxyDev proxy listen="tcp:127.0.0.1:${HOST_TCP_PORT}" connect="tcp:127.0.0.1:4321"
+  lxc start proxyTester
+  pgrep -f "forkproxy.*tcp:127.0.0.1:${HOST_TCP_PORT}"
+
+  # and forwards connections into the container
+  (lxc exec proxyTester -- nc -l -p 4321 > "${LXD_DIR}/proxyTest.out" &)
+  sleep 1
+  echo "ping" | nc -w 1 127.0.0.1 "${HOST_TCP_PORT}" || true
+  sleep 1
+  grep -q ping "${LXD_DIR}/proxyTest.out"
+  rm -f "${LXD_DIR}/proxyTest.out"
+
+  # unix connect paths are resolved inside the container, symlinks included
+  lxc config device remove proxyTester proxyDev
+  (nc -lU "${LXD_DIR}/proxyHost.sock" > "${LXD_DIR}/proxyHost.out" &)
+  sleep 1
+  lxc exec proxyTester -- ln -s "${LXD_DIR}/proxyHost.sock" /tmp/app.sock
+  lxc config device add proxyTester proxyDev proxy listen="tcp:127.0.0.1:${HOST_TCP_PORT}" connect="unix:/tmp/app.sock"
+  echo "ping" | nc -w 1 127.0.0.1 "${HOST_TCP_PORT}" || true
+  sleep 1
+  ! grep -q ping "${LXD_DIR}/proxyHost.out" || false
+  pkill -f "nc -lU ${LXD_DIR}/proxyHost.sock" || true
+  rm -f "${LXD_DIR}/proxyHost.sock" "${LXD_DIR}/proxyHost.out"
+  lxc exec proxyTester -- rm /tmp/app.sock
+  lxc config device remove proxyTester proxyDev
+  lxc config device add proxyTester proxyDev proxy listen="tcp:127.0.0.1:${HOST_TCP_PORT}" connect="tcp:127.0.0.1:4321"
+
+  # hot-unplug
+  lxc config device remove proxyTester proxyDev
+  ! pgrep -f "forkproxy.*tcp:127.0.0.1:${HOST_TCP_PORT}" || false
+
+  # hotplug
+  lxc config device add proxyTester proxyDev proxy listen="tcp:127.0.0.1:${HOST_TCP_PORT}" connect="tcp:127.0.0.1:4321"
+  pgrep -f "forkproxy.*tcp:127.0.0.1:${HOST_TCP_PORT}"
+
+  # the forwarder goes away with the container
+  lxc stop proxyTester --force
+  ! pgrep -f "forkproxy.*tcp:127.0.0.1:${HOST_TCP_PORT}" || false
+
+  lxc delete proxyTester
+}
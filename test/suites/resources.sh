@@ -0,0 +1,20 @@
+#!/bin/sh
+
+test_resources() {
+  ensure_has_localhost_remote "${LXD_ADDR}"
+
+  RES=$(lxc info --resources)
+  echo "${RES}" | grep -q "^CPU:"
+  echo "${RES}" | grep -q "Total threads: $(grep -c ^processor /proc/cpuinfo)"
+  echo "${RES}" | grep -q "^Memory:"
+
+  # The resources are also exposed over the REST API
+  [ "$(my_curl "https://${LXD_ADDR}/1.0/resources" | jq -r .metadata.cpu.total)" = "$(grep -c ^processor /proc/cpuinfo)" ]
+  [ "$(my_curl "https://${LXD_ADDR}/1.0/resources" | jq -r .metadata.memory.total)" -gt 0 ]
+
+  pool=$(lxc profile device get default root pool)
+  echo "${RES}" | grep -q "  ${pool}:"
+  [ "$(my_curl "https://${LXD_ADDR}/1.0/storage-pools/${pool}/resources" | jq -r .metadata.space.total)" -gt 0 ]
+
+  [ "$(my_curl "https://${LXD_ADDR}/1.0/storage-pools/not-a-pool/resources" | jq -r .error_code)" = "404" ]
+}
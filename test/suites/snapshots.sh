@@ -64,6 +64,16 @@ test_snapshots() {
     [ -d "${LXD_DIR}/snapshots/foople/namechange" ]
   fi
 
+  # scheduled snapshots configuration
+  ! lxc config set foople snapshots.schedule "61 * * * *"
+  ! lxc config set foople snapshots.pattern "auto"
+  ! lxc config set foople snapshots.pattern "auto%s%d"
+  ! lxc config set foople snapshots.pattern "100%"
+  ! lxc config set foople snapshots.expiry "2x"
+  lxc config set foople snapshots.schedule "@daily"
+  lxc config set foople snapshots.pattern "auto%d"
+  lxc config set foople snapshots.expiry "1w 2d"
+
   lxc delete foople
   lxc delete foosnap1
   [ ! -d "${LXD_DIR}/containers/foople" ]
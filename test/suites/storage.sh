@@ -380,6 +380,33 @@ test_storage() {
       lxc storage volume delete "lxdtest-$(basename "${LXD_DIR}")-pool4" c8pool4
     fi
 
+    # Snapshot, restore, copy, rename and move custom volumes.
+    lxc storage volume create "lxdtest-$(basename "${LXD_DIR}")-pool5" vol1
+    lxc storage volume snapshot "lxdtest-$(basename "${LXD_DIR}")-pool5" vol1 snap0
+    lxc storage volume restore "lxdtest-$(basename "${LXD_DIR}")-pool5" vol1 snap0
+    ! lxc storage volume list "lxdtest-$(basename "${LXD_DIR}")-pool5" | grep -q "vol1/snap0"
+    lxc storage volume copy "lxdtest-$(basename "${LXD_DIR}")-pool5/vol1" "lxdtest-$(basename "${LXD_DIR}")-pool5/vol2"
+    lxc storage volume show "lxdtest-$(basename "${LXD_DIR}")-pool5" vol2
+    ! lxc storage volume move "lxdtest-$(basename "${LXD_DIR}")-pool5/vol1" "lxdtest-$(basename "${LXD_DIR}")-pool1/vol1"
+    lxc storage volume rename "lxdtest-$(basename "${LXD_DIR}")-pool5" vol2 vol3
+    ! lxc storage volume show "lxdtest-$(basename "${LXD_DIR}")-pool5" vol2
+    lxc storage volume delete "lxdtest-$(basename "${LXD_DIR}")-pool5" vol1/snap0
+
+    # Automatic snapshot names keep increasing past snap9.
+    # shellcheck disable=SC2034
+    for i in $(seq 0 10); do
+      lxc storage volume snapshot "lxdtest-$(basename "${LXD_DIR}")-pool5" vol1
+    done
+    lxc storage volume show "lxdtest-$(basename "${LXD_DIR}")-pool5" vol1/snap10
+    lxc storage volume snapshot "lxdtest-$(basename "${LXD_DIR}")-pool5" vol1
+    lxc storage volume show "lxdtest-$(basename "${LXD_DIR}")-pool5" vol1/snap11
+    for i in $(seq 0 11); do
+      lxc storage volume delete "lxdtest-$(basename "${LXD_DIR}")-pool5" "vol1/snap${i}"
+    done
+
+    lxc storage volume delete "lxdtest-$(basename "${LXD_DIR}")-pool5" vol1
+    lxc storage volume delete "lxdtest-$(basename "${LXD_DIR}")-pool5" vol3
+
     lxc delete -f c9pool5
     lxc delete -f c11pool5
 
@@ -0,0 +1,48 @@
+#!/bin/sh
+
+test_storage_check() {
+  ensure_import_testimage
+
+  pool=$(lxc profile device get default root pool)
+
+  lxc init testimage c1
+
+  # Symlinks pointing to the wrong place or left for deleted containers.
+  rm "${LXD_DIR}/containers/c1"
+  ln -s /nonexistent "${LXD_DIR}/containers/c1"
+  ln -s "${LXD_DIR}/storage-pools/${pool}/containers/c2" "${LXD_DIR}/containers/c2"
+
+  ! lxd storage-check > "${LXD_DIR}/storage-check.out"
+  grep "wrong mountpoint: containers/c1" "${LXD_DIR}/storage-check.out"
+  grep "stale symlink: ${LXD_DIR}/containers/c2" "${LXD_DIR}/storage-check.out"
+  [ "$(readlink "${LXD_DIR}/containers/c1")" = "/nonexistent" ]
+
+  lxd storage-check --repair | grep -q "\[repaired\]"
+  [ "$(readlink "${LXD_DIR}/containers/c1")" = "${LXD_DIR}/storage-pools/${pool}/containers/c1" ]
+  [ ! -L "${LXD_DIR}/containers/c2" ]
+  ! lxd storage-check | grep -q "containers/c[12]"
+
+  lxc delete c1
+
+  if [ "${LXD_BACKEND}" = "dir" ]; then
+    # Volumes known to only one of the database and the storage pool.
+    lxc storage volume create "${pool}" vol1
+    lxc storage volume create "${pool}" vol2
+    sqlite3 "${LXD_DIR}/lxd.db" "DELETE FROM storage_volumes WHERE name='vol1' AND type=2"
+    rmdir "${LXD_DIR}/storage-pools/${pool}/custom/vol2"
+
+    ! lxd storage-check > "${LXD_DIR}/storage-check.out"
+    grep "${pool}: orphaned volume: custom/vol1" "${LXD_DIR}/storage-check.out"
+    grep "${pool}: missing volume: custom/vol2" "${LXD_DIR}/storage-check.out"
+
+    # Neither is safe to repair.
+    ! lxd storage-check --repair
+    [ -d "${LXD_DIR}/storage-pools/${pool}/custom/vol1" ]
+    lxc storage volume show "${pool}" vol2
+
+    rmdir "${LXD_DIR}/storage-pools/${pool}/custom/vol1"
+    sqlite3 "${LXD_DIR}/lxd.db" "DELETE FROM storage_volumes WHERE name='vol2' AND type=2"
+  fi
+
+  rm -f "${LXD_DIR}/storage-check.out"
+}
@@ -0,0 +1,32 @@
+#!/bin/sh
+
+test_storage_pool_gc() {
+  ensure_import_testimage
+  ensure_has_localhost_remote "${LXD_ADDR}"
+
+  pool=$(lxc profile device get default root pool)
+
+  # Nothing to collect on a pool with only referenced image volumes.
+  lxc storage gc "${pool}" --dry-run | grep -q "No unused image volumes"
+
+  if [ "${LXD_BACKEND}" = "dir" ]; then
+    return
+  fi
+
+  # Unpack the image on the pool, then drop its database record.
+  lxc init testimage c1
+  lxc delete c1
+  fingerprint=$(lxc image info testimage | grep "^Fingerprint" | cut -d' ' -f2)
+  sqlite3 "${LXD_DIR}/lxd.db" "DELETE FROM storage_volumes WHERE name='${fingerprint}' AND type=1"
+
+  # A dry run only reports the leaked volume, so it is still found afterwards.
+  lxc storage gc "${pool}" --dry-run | grep "Would remove" | grep -q "${fingerprint}"
+  lxc storage gc "${pool}" --dry-run | grep "Would remove" | grep -q "${fingerprint}"
+
+  lxc storage gc "${pool}" | grep "Removed" | grep -q "${fingerprint}"
+  lxc storage gc "${pool}" --dry-run | grep -q "No unused image volumes"
+
+  # The image can still be used, its volume gets unpacked again.
+  lxc launch testimage c1
+  lxc delete --force c1
+}
@@ -0,0 +1,55 @@
+#!/bin/sh
+
+test_storage_pool_resize() {
+  # shellcheck disable=2039
+
+  LXD_STORAGE_DIR=$(mktemp -d -p "${TEST_DIR}" XXXXXXXXX)
+  chmod +x "${LXD_STORAGE_DIR}"
+  spawn_lxd "${LXD_STORAGE_DIR}" false
+  (
+    set -e
+    # shellcheck disable=2030
+    LXD_DIR="${LXD_STORAGE_DIR}"
+
+    if which zfs >/dev/null 2>&1; then
+      lxc storage create "lxdtest-$(basename "${LXD_DIR}")-zfs" zfs size=1GB
+
+      # Growing extends the loop file and the zpool.
+      lxc storage set "lxdtest-$(basename "${LXD_DIR}")-zfs" size=2GB
+      [ "$(stat -c %s "${LXD_DIR}/disks/lxdtest-$(basename "${LXD_DIR}")-zfs.img")" = "2147483648" ]
+      [ "$(zpool get -H -p -o value size "lxdtest-$(basename "${LXD_DIR}")-zfs")" -gt 1000000000 ]
+
+      # ZFS can't shrink.
+      ! lxc storage set "lxdtest-$(basename "${LXD_DIR}")-zfs" size=1GB
+      [ "$(lxc storage get "lxdtest-$(basename "${LXD_DIR}")-zfs" size)" = "2GB" ]
+
+      lxc storage delete "lxdtest-$(basename "${LXD_DIR}")-zfs"
+    fi
+
+    if which btrfs >/dev/null 2>&1; then
+      lxc storage create "lxdtest-$(basename "${LXD_DIR}")-btrfs" btrfs size=1GB
+
+      lxc storage set "lxdtest-$(basename "${LXD_DIR}")-btrfs" size=2GB
+      [ "$(stat -c %s "${LXD_DIR}/disks/lxdtest-$(basename "${LXD_DIR}")-btrfs.img")" = "2147483648" ]
+
+      # Shrinking works as long as the data still fits.
+      lxc storage set "lxdtest-$(basename "${LXD_DIR}")-btrfs" size=1500MB
+      [ "$(stat -c %s "${LXD_DIR}/disks/lxdtest-$(basename "${LXD_DIR}")-btrfs.img")" = "1572864000" ]
+
+      lxc storage delete "lxdtest-$(basename "${LXD_DIR}")-btrfs"
+
+      # Only loop backed pools can be resized.
+      configure_loop_device loop_file_1 loop_device_1
+      # shellcheck disable=SC2154
+      lxc storage create "lxdtest-$(basename "${LXD_DIR}")-btrfs-dev" btrfs source="${loop_device_1}"
+      ! lxc storage set "lxdtest-$(basename "${LXD_DIR}")-btrfs-dev" size=2GB
+      lxc storage delete "lxdtest-$(basename "${LXD_DIR}")-btrfs-dev"
+      # shellcheck disable=SC2154
+      deconfigure_loop_device "${loop_file_1}" "${loop_device_1}"
+    fi
+  )
+
+  # shellcheck disable=SC2031
+  LXD_DIR="${LXD_DIR}"
+  kill_lxd "${LXD_STORAGE_DIR}"
+}
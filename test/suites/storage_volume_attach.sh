@@ -0,0 +1,42 @@
+#!/bin/sh
+
+test_storage_volume_attach() {
+  ensure_import_testimage
+  ensure_has_localhost_remote "${LXD_ADDR}"
+
+  pool=$(lxc profile device get default root pool)
+
+  lxc storage volume create "${pool}" vol1
+  lxc launch testimage c1
+  lxc launch testimage c2
+
+  # Attachments are only tracked for running containers.
+  lxc storage volume attach "${pool}" vol1 c1 data /mnt
+  [ "$(my_curl "https://${LXD_ADDR}/1.0/storage-pools/${pool}/volumes/custom/vol1" | jq -r '.metadata.attachments | length')" = "1" ]
+  lxc storage volume show "${pool}" vol1 | grep -q "container: c1"
+
+  if [ "${LXD_BACKEND}" = "lvm" ] || [ "${LXD_BACKEND}" = "ceph" ]; then
+    # Block backed volumes can only be used by a single running container.
+    ! lxc storage volume attach "${pool}" vol1 c2 data /mnt
+
+    lxc storage volume set "${pool}" vol1 security.shared true
+    lxc storage volume attach "${pool}" vol1 c2 data /mnt
+    [ "$(my_curl "https://${LXD_ADDR}/1.0/storage-pools/${pool}/volumes/custom/vol1" | jq -r '.metadata.attachments | length')" = "2" ]
+
+    # Sharing can't be turned off while in use by two containers.
+    ! lxc storage volume unset "${pool}" vol1 security.shared
+    lxc config device remove c2 data
+  else
+    lxc storage volume attach "${pool}" vol1 c2 data /mnt
+    [ "$(my_curl "https://${LXD_ADDR}/1.0/storage-pools/${pool}/volumes/custom/vol1" | jq -r '.metadata.attachments | length')" = "2" ]
+    lxc config device remove c2 data
+  fi
+
+  # Stopping the container releases the volume.
+  lxc stop c1 --force
+  [ "$(my_curl "https://${LXD_ADDR}/1.0/storage-pools/${pool}/volumes/custom/vol1" | jq -r '.metadata.attachments | length')" = "0" ]
+
+  lxc delete --force c1
+  lxc delete --force c2
+  lxc storage volume delete "${pool}" vol1
+}
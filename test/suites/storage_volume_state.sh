@@ -0,0 +1,40 @@
+#!/bin/sh
+
+test_storage_volume_state() {
+  ensure_import_testimage
+  ensure_has_localhost_remote "${LXD_ADDR}"
+
+  # Volume usage relies on quotas which neither the dir backend nor btrfs
+  # without quota groups enabled provide.
+  if [ "${LXD_BACKEND}" = "dir" ] || [ "${LXD_BACKEND}" = "btrfs" ]; then
+    return
+  fi
+
+  pool=$(lxc profile device get default root pool)
+
+  lxc storage volume create "${pool}" vol1 size=50MB
+  [ "$(my_curl "https://${LXD_ADDR}/1.0/storage-pools/${pool}/volumes/custom/vol1/state" | jq -r .metadata.usage.used)" -ge 0 ]
+  [ "$(my_curl "https://${LXD_ADDR}/1.0/storage-pools/${pool}/volumes/custom/vol1/state" | jq -r .metadata.usage.total)" = "50000000" ]
+
+  # Growing the volume is applied live.
+  lxc storage volume set "${pool}" vol1 size=75MB
+  [ "$(my_curl "https://${LXD_ADDR}/1.0/storage-pools/${pool}/volumes/custom/vol1/state" | jq -r .metadata.usage.total)" = "75000000" ]
+
+  if [ "${LXD_BACKEND}" = "zfs" ]; then
+    [ "$(zfs get -H -p -o value quota "lxdtest-$(basename "${LXD_DIR}")/custom/vol1")" = "75000000" ]
+
+    # Shrinking and removing the quota are only possible on zfs.
+    lxc storage volume set "${pool}" vol1 size=25MB
+    [ "$(zfs get -H -p -o value quota "lxdtest-$(basename "${LXD_DIR}")/custom/vol1")" = "25000000" ]
+    lxc storage volume unset "${pool}" vol1 size
+    [ "$(zfs get -H -p -o value quota "lxdtest-$(basename "${LXD_DIR}")/custom/vol1")" = "0" ]
+  else
+    ! lxc storage volume set "${pool}" vol1 size=25MB
+  fi
+
+  # Only custom volumes have a state.
+  [ "$(my_curl "https://${LXD_ADDR}/1.0/storage-pools/${pool}/volumes/container/vol1/state" | jq -r .error_code)" = "400" ]
+  [ "$(my_curl "https://${LXD_ADDR}/1.0/storage-pools/${pool}/volumes/custom/not-a-volume/state" | jq -r .error_code)" = "404" ]
+
+  lxc storage volume delete "${pool}" vol1
+}